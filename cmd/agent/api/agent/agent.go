@@ -22,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/cmd/agent/gui"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/runner"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/flare"
 	"github.com/DataDog/datadog-agent/pkg/secrets"
@@ -50,6 +51,8 @@ func SetupHandlers(r *mux.Router) {
 	r.HandleFunc("/{component}/configs", componentConfigHandler).Methods("GET")
 	r.HandleFunc("/gui/csrf-token", getCSRFToken).Methods("GET")
 	r.HandleFunc("/config-check", getConfigCheck).Methods("GET")
+	r.HandleFunc("/check-history", getCheckHistory).Methods("GET")
+	r.HandleFunc("/check-history/{name}", getCheckHistory).Methods("GET")
 	r.HandleFunc("/config", getRuntimeConfig).Methods("GET")
 	r.HandleFunc("/tagger-list", getTaggerList).Methods("GET")
 	r.HandleFunc("/secrets", secretInfo).Methods("GET")
@@ -268,6 +271,27 @@ func getConfigCheck(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonConfig)
 }
 
+func getCheckHistory(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	response := response.CheckHistoryResponse{
+		Checks: runner.GetChecksHistory(name),
+	}
+	if name != "" && len(response.Checks) == 0 {
+		body, _ := json.Marshal(map[string]string{"error": fmt.Sprintf("no run of the check %s was recorded", name)})
+		http.Error(w, string(body), 404)
+		return
+	}
+
+	jsonHistory, err := json.Marshal(response)
+	if err != nil {
+		log.Errorf("Unable to marshal check history response: %s", err)
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		http.Error(w, string(body), 500)
+		return
+	}
+	w.Write(jsonHistory)
+}
+
 func getRuntimeConfig(w http.ResponseWriter, r *http.Request) {
 	runtimeConfig, err := yaml.Marshal(config.Datadog.AllSettings())
 	if err != nil {
@@ -7,6 +7,7 @@ package response
 
 import (
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
 )
 
 // ConfigCheckResponse holds the config check response
@@ -27,3 +28,9 @@ type TaggerListEntity struct {
 	Sources []string `json:"sources"`
 	Tags    []string `json:"tags"`
 }
+
+// CheckHistoryResponse holds the recent runs of check instances, by check name
+// and instance ID
+type CheckHistoryResponse struct {
+	Checks map[string]map[check.ID][]check.Run `json:"checks"`
+}
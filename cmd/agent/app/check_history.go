@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package app
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/flare"
+)
+
+func init() {
+	AgentCmd.AddCommand(checkHistoryCommand)
+}
+
+var checkHistoryCommand = &cobra.Command{
+	Use:   "check-history <check_name>",
+	Short: "Print the recent runs of the instances of a check in a running agent",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var checkName string
+		if len(args) != 0 {
+			checkName = args[0]
+		} else {
+			return fmt.Errorf("missing arguments")
+		}
+
+		err := common.SetupConfigWithoutSecrets(confFilePath)
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+		if flagNoColor {
+			color.NoColor = true
+		}
+		return flare.GetCheckHistory(color.Output, checkName)
+	},
+}
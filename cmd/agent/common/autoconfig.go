@@ -7,6 +7,7 @@ package common
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers"
@@ -44,13 +45,14 @@ func SetupAutoConfig(confdPath string) {
 	AC = autodiscovery.NewAutoConfig(metaScheduler)
 
 	// Add the configuration providers
-	// File Provider is hardocded and always enabled
+	// File Provider is hardocded and always enabled, it's watched for changes
+	// or polled if the files can't be watched
 	confSearchPaths := []string{
 		confdPath,
 		filepath.Join(GetDistPath(), "conf.d"),
 		"",
 	}
-	AC.AddConfigProvider(providers.NewFileConfigProvider(confSearchPaths), false, 0)
+	AC.AddConfigProvider(providers.NewFileConfigProvider(confSearchPaths), true, config.Datadog.GetDuration("ad_config_poll_interval")*time.Second)
 
 	// Register additional configuration providers
 	var CP []config.ConfigurationProviders
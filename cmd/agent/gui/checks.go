@@ -17,6 +17,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/collector"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/collector/runner"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/gorilla/mux"
@@ -39,6 +40,7 @@ var (
 // Adds the specific handlers for /checks/ endpoints
 func checkHandler(r *mux.Router) {
 	r.HandleFunc("/running", http.HandlerFunc(sendRunningChecks)).Methods("POST")
+	r.HandleFunc("/history/{name}", http.HandlerFunc(sendCheckHistory)).Methods("POST")
 	r.HandleFunc("/run/{name}", http.HandlerFunc(runCheck)).Methods("POST")
 	r.HandleFunc("/run/{name}/once", http.HandlerFunc(runCheckOnce)).Methods("POST")
 	r.HandleFunc("/reload/{name}", http.HandlerFunc(reloadCheck)).Methods("POST")
@@ -64,6 +66,19 @@ func sendRunningChecks(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
+// Sends the recent runs of the instances of a check
+func sendCheckHistory(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	html, e := renderCheckHistory(name, runner.GetChecksHistory(name)[name])
+	if e != nil {
+		w.Write([]byte("Error generating history html: " + e.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(html))
+}
+
 // Schedules a specific check
 func runCheck(w http.ResponseWriter, r *http.Request) {
 	// Fetch the desired check
@@ -8,6 +8,7 @@ import (
 	"io"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
@@ -23,6 +24,8 @@ func init() {
 	fmap["lastErrorMessage"] = lastErrorMessage
 	fmap["pythonLoaderError"] = pythonLoaderError
 	fmap["status"] = displayStatus
+	fmap["runStart"] = runStart
+	fmap["runErrorMessage"] = status.CheckErrorMessage
 }
 
 // Data is a struct used for filling templates
@@ -32,6 +35,7 @@ type Data struct {
 	ConfigErrs map[string]string
 	Stats      map[string]interface{}
 	CheckStats []*check.Stats
+	History    map[check.ID][]check.Run
 }
 
 func renderStatus(rawData []byte, request string) (string, error) {
@@ -75,6 +79,17 @@ func renderCheck(name string, stats []*check.Stats) (string, error) {
 	return b.String(), nil
 }
 
+func renderCheckHistory(name string, history map[check.ID][]check.Run) (string, error) {
+	var b = new(bytes.Buffer)
+
+	data := Data{Name: name, History: history}
+	e := fillTemplate(b, data, "checkHistory")
+	if e != nil {
+		return "", e
+	}
+	return b.String(), nil
+}
+
 func renderError(name string) (string, error) {
 	var b = new(bytes.Buffer)
 
@@ -138,6 +153,10 @@ func lastErrorMessage(value string) string {
 	return "UNKNOWN ERROR"
 }
 
+func runStart(start int64) string {
+	return time.Unix(0, start*int64(time.Millisecond)).Format(time.RFC3339)
+}
+
 func displayStatus(check map[string]interface{}) template.HTML {
 	if check["LastError"].(string) != "" {
 		return template.HTML("[<span class=\"error\">ERROR</span>]")
@@ -678,6 +678,16 @@ function seeRunningChecks() {
   });
 }
 
+// Display the recent runs of the instances of a check
+function seeCheckHistory(name) {
+  sendMessage("checks/history/" + encodeURIComponent(name), "", "post",
+  function(data, status, xhr){
+    $("#running_checks").html(data);
+  }, function() {
+    $("#running_checks").html("An error occurred.");
+  });
+}
+
 
 /*************************************************************************
                                 Flare
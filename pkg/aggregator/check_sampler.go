@@ -6,8 +6,11 @@
 package aggregator
 
 import (
+	"math"
+
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
@@ -16,16 +19,30 @@ const checksSourceTypeName = "System"
 // CheckSampler aggregates metrics from one Check instance
 type CheckSampler struct {
 	series          []*metrics.Serie
+	sketches        metrics.SketchSeriesList
 	contextResolver *ContextResolver
 	metrics         metrics.ContextMetrics
+	sketchMap       sketchMap
+	lastBucketValue map[bucketKey]int64
+}
+
+// bucketKey identifies a histogram bucket, the buckets of a histogram sharing
+// the context of its distribution
+type bucketKey struct {
+	contextKey ckey.ContextKey
+	lowerBound float64
+	upperBound float64
 }
 
 // newCheckSampler returns a newly initialized CheckSampler
 func newCheckSampler() *CheckSampler {
 	return &CheckSampler{
 		series:          make([]*metrics.Serie, 0),
+		sketches:        make(metrics.SketchSeriesList, 0),
 		contextResolver: newContextResolver(),
 		metrics:         metrics.MakeContextMetrics(),
+		sketchMap:       make(sketchMap),
+		lastBucketValue: make(map[bucketKey]int64),
 	}
 }
 
@@ -37,6 +54,39 @@ func (cs *CheckSampler) addSample(metricSample *metrics.MetricSample) {
 	}
 }
 
+func (cs *CheckSampler) addBucket(bucket *metrics.HistogramBucket) {
+	contextKey := cs.contextResolver.trackContext(&metrics.MetricSample{
+		Name: bucket.Name,
+		Tags: bucket.Tags,
+		Host: bucket.Host,
+	}, bucket.Timestamp)
+
+	value := bucket.Value
+	if bucket.Monotonic {
+		// like monotonic counts, the first value of a bucket is only used as a reference
+		key := bucketKey{contextKey, bucket.LowerBound, bucket.UpperBound}
+		last, found := cs.lastBucketValue[key]
+		cs.lastBucketValue[key] = bucket.Value
+		if !found {
+			return
+		}
+		value -= last
+	}
+	if value <= 0 {
+		// the counter of a monotonic bucket was reset, or no value was counted
+		return
+	}
+
+	// the values of the +Inf bucket are counted at its lower bound
+	upperBound := bucket.UpperBound
+	if math.IsInf(upperBound, 1) {
+		upperBound = bucket.LowerBound
+	}
+	if !cs.sketchMap.insertInterp(int64(bucket.Timestamp), contextKey, bucket.LowerBound, upperBound, uint(value)) {
+		log.Debugf("Ignoring histogram bucket '%s' on host '%s' and tags '%s': invalid bounds [%f, %f]", bucket.Name, bucket.Host, bucket.Tags, bucket.LowerBound, bucket.UpperBound)
+	}
+}
+
 func (cs *CheckSampler) commit(timestamp float64) {
 	series, errors := cs.metrics.Flush(timestamp)
 	for ckey, err := range errors {
@@ -62,7 +112,39 @@ func (cs *CheckSampler) commit(timestamp float64) {
 		cs.series = append(cs.series, serie)
 	}
 
+	cs.commitSketches(timestamp)
+
 	cs.contextResolver.expireContexts(timestamp - defaultExpiry)
+	for key := range cs.lastBucketValue {
+		if _, ok := cs.contextResolver.contextsByKey[key.contextKey]; !ok {
+			delete(cs.lastBucketValue, key)
+		}
+	}
+}
+
+func (cs *CheckSampler) commitSketches(timestamp float64) {
+	pointsByCtx := make(map[ckey.ContextKey][]metrics.SketchPoint)
+
+	cs.sketchMap.flushBefore(int64(timestamp)+1, func(ck ckey.ContextKey, p metrics.SketchPoint) {
+		if p.Sketch == nil {
+			return
+		}
+		pointsByCtx[ck] = append(pointsByCtx[ck], p)
+	})
+	for ck, points := range pointsByCtx {
+		context, ok := cs.contextResolver.contextsByKey[ck]
+		if !ok {
+			log.Errorf("Ignoring all sketches on context key '%v': inconsistent context resolver state: the context is not tracked", ck)
+			continue
+		}
+		cs.sketches = append(cs.sketches, metrics.SketchSeries{
+			Name:       context.Name,
+			Tags:       context.Tags,
+			Host:       context.Host,
+			Points:     points,
+			ContextKey: ck,
+		})
+	}
 }
 
 func (cs *CheckSampler) flush() metrics.Series {
@@ -70,3 +152,9 @@ func (cs *CheckSampler) flush() metrics.Series {
 	cs.series = make([]*metrics.Serie, 0)
 	return series
 }
+
+func (cs *CheckSampler) flushSketches() metrics.SketchSeriesList {
+	sketches := cs.sketches
+	cs.sketches = make(metrics.SketchSeriesList, 0)
+	return sketches
+}
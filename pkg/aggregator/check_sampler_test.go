@@ -7,6 +7,7 @@ package aggregator
 
 import (
 	// stdlib
+	"math"
 	"sort"
 	"testing"
 
@@ -190,3 +191,87 @@ func TestHistogramIntervalSampling(t *testing.T) {
 
 	assert.True(t, foundCount)
 }
+
+func TestCheckHistogramBucketSampling(t *testing.T) {
+	checkSampler := newCheckSampler()
+
+	bucket1 := &metrics.HistogramBucket{
+		Name:       "my.histogram",
+		Value:      4,
+		LowerBound: 10.0,
+		UpperBound: 20.0,
+		Tags:       []string{"foo", "bar"},
+		Timestamp:  12345.0,
+	}
+	bucket2 := &metrics.HistogramBucket{
+		Name:       "my.histogram",
+		Value:      2,
+		LowerBound: 20.0,
+		UpperBound: math.Inf(1),
+		Tags:       []string{"foo", "bar"},
+		Timestamp:  12346.0,
+	}
+	checkSampler.addBucket(bucket1)
+	checkSampler.addBucket(bucket2)
+
+	checkSampler.commit(12349.0)
+	assert.Len(t, checkSampler.flush(), 0)
+	sketches := checkSampler.flushSketches()
+	require.Len(t, sketches, 1)
+	assert.Equal(t, "my.histogram", sketches[0].Name)
+	assert.ElementsMatch(t, []string{"foo", "bar"}, sketches[0].Tags)
+
+	var points []metrics.SketchPoint
+	points = append(points, sketches[0].Points...)
+	sort.Slice(points, func(i, j int) bool { return points[i].Ts < points[j].Ts })
+	require.Len(t, points, 2)
+	assert.Equal(t, int64(12345), points[0].Ts)
+	assert.EqualValues(t, 4, points[0].Sketch.Basic.Cnt)
+	assert.True(t, points[0].Sketch.Basic.Min >= 10 && points[0].Sketch.Basic.Max <= 20)
+	// the values of the +Inf bucket are counted at its lower bound
+	assert.EqualValues(t, 2, points[1].Sketch.Basic.Cnt)
+	assert.Equal(t, 20.0, points[1].Sketch.Basic.Min)
+	assert.Equal(t, 20.0, points[1].Sketch.Basic.Max)
+
+	assert.Len(t, checkSampler.flushSketches(), 0)
+}
+
+func TestCheckHistogramBucketMonotonic(t *testing.T) {
+	checkSampler := newCheckSampler()
+	bucket := func(value int64, lowerBound, upperBound, ts float64) *metrics.HistogramBucket {
+		return &metrics.HistogramBucket{
+			Name:       "my.histogram",
+			Value:      value,
+			LowerBound: lowerBound,
+			UpperBound: upperBound,
+			Monotonic:  true,
+			Tags:       []string{"foo"},
+			Timestamp:  ts,
+		}
+	}
+
+	// the first values are references
+	checkSampler.addBucket(bucket(10, 0, 1, 12345.0))
+	checkSampler.addBucket(bucket(3, 1, 2, 12345.0))
+	checkSampler.commit(12346.0)
+	assert.Len(t, checkSampler.flushSketches(), 0)
+
+	// the increase of each bucket is counted
+	checkSampler.addBucket(bucket(15, 0, 1, 12355.0))
+	checkSampler.addBucket(bucket(3, 1, 2, 12355.0))
+	checkSampler.commit(12356.0)
+	sketches := checkSampler.flushSketches()
+	require.Len(t, sketches, 1)
+	require.Len(t, sketches[0].Points, 1)
+	assert.EqualValues(t, 5, sketches[0].Points[0].Sketch.Basic.Cnt)
+	assert.True(t, sketches[0].Points[0].Sketch.Basic.Max <= 1)
+
+	// a counter reset isn't counted
+	checkSampler.addBucket(bucket(2, 0, 1, 12365.0))
+	checkSampler.commit(12366.0)
+	assert.Len(t, checkSampler.flushSketches(), 0)
+
+	// the references of the expired contexts are dropped
+	checkSampler.commit(12366.0 + defaultExpiry + 1)
+	assert.Len(t, checkSampler.lastBucketValue, 0)
+}
@@ -98,6 +98,17 @@ func (m sketchMap) insert(ts int64, ck ckey.ContextKey, v float64) bool {
 	return true
 }
 
+// insertInterp inserts count values spread between lower and upper into a sketch
+// for the given (ts, contextKey)
+func (m sketchMap) insertInterp(ts int64, ck ckey.ContextKey, lower, upper float64, count uint) bool {
+	if math.IsInf(lower, 0) || math.IsNaN(lower) || math.IsInf(upper, 0) || math.IsNaN(upper) {
+		return false
+	}
+
+	m.getOrCreate(ts, ck).InsertInterpolate(lower, upper, count)
+	return true
+}
+
 func (m sketchMap) getOrCreate(ts int64, ck ckey.ContextKey) *quantile.Agent {
 	// level 1: ts -> ctx
 	byCtx, ok := m[ts]
@@ -39,6 +39,11 @@ func (m *MockSender) Historate(metric string, value float64, hostname string, ta
 	m.Called(metric, value, hostname, tags)
 }
 
+//HistogramBucket adds a histogram bucket type to the mock calls.
+func (m *MockSender) HistogramBucket(metric string, value int64, lowerBound, upperBound float64, monotonic bool, hostname string, tags []string) {
+	m.Called(metric, value, lowerBound, upperBound, monotonic, hostname, tags)
+}
+
 //Gauge adds a gauge type to the mock calls.
 func (m *MockSender) Gauge(metric string, value float64, hostname string, tags []string) {
 	m.Called(metric, value, hostname, tags)
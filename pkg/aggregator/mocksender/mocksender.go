@@ -6,11 +6,14 @@
 package mocksender
 
 import (
+	"testing"
 	"time"
 
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 )
 
@@ -25,6 +28,17 @@ func NewMockSender(id check.ID) *MockSender {
 	return mockSender
 }
 
+// NewConfiguredMockSender configures the check with the given instance and init
+// configurations, failing the test on error, and returns a functional mocked
+// Sender for it, accepting any call
+func NewConfiguredMockSender(t *testing.T, c check.Check, instance, initConfig string) *MockSender {
+	require.NoError(t, c.Configure(integration.Data(instance), integration.Data(initConfig)))
+	mockSender := NewMockSender(c.ID())
+	mockSender.SetupAcceptAll()
+
+	return mockSender
+}
+
 //MockSender allows mocking of the checks sender for unit testing
 type MockSender struct {
 	mock.Mock
@@ -41,6 +55,15 @@ func (m *MockSender) SetupAcceptAll() {
 			mock.AnythingOfType("[]string"), // Tags
 		).Return()
 	}
+	m.On("HistogramBucket",
+		mock.AnythingOfType("string"),   // Metric
+		mock.AnythingOfType("int64"),    // Value
+		mock.AnythingOfType("float64"),  // LowerBound
+		mock.AnythingOfType("float64"),  // UpperBound
+		mock.AnythingOfType("bool"),     // Monotonic
+		mock.AnythingOfType("string"),   // Hostname
+		mock.AnythingOfType("[]string"), // Tags
+	).Return()
 	m.On("ServiceCheck",
 		mock.AnythingOfType("string"),                     // checkName (e.g: docker.exit)
 		mock.AnythingOfType("metrics.ServiceCheckStatus"), // (e.g: metrics.ServiceCheckOK)
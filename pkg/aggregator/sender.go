@@ -31,6 +31,7 @@ type Sender interface {
 	Counter(metric string, value float64, hostname string, tags []string)
 	Histogram(metric string, value float64, hostname string, tags []string)
 	Historate(metric string, value float64, hostname string, tags []string)
+	HistogramBucket(metric string, value int64, lowerBound, upperBound float64, monotonic bool, hostname string, tags []string)
 	ServiceCheck(checkName string, status metrics.ServiceCheckStatus, hostname string, tags []string, message string)
 	Event(e metrics.Event)
 	GetMetricStats() map[string]int64
@@ -60,6 +61,7 @@ type checkSender struct {
 	metricStats             metricStats
 	priormetricStats        metricStats
 	smsOut                  chan<- senderMetricSample
+	bucketOut               chan<- senderHistogramBucket
 	serviceCheckOut         chan<- metrics.ServiceCheck
 	eventOut                chan<- metrics.Event
 	checkTags               []string
@@ -71,6 +73,11 @@ type senderMetricSample struct {
 	commit       bool
 }
 
+type senderHistogramBucket struct {
+	id     check.ID
+	bucket *metrics.HistogramBucket
+}
+
 type checkSenderPool struct {
 	senders map[check.ID]Sender
 	m       sync.Mutex
@@ -82,11 +89,12 @@ func init() {
 	}
 }
 
-func newCheckSender(id check.ID, defaultHostname string, smsOut chan<- senderMetricSample, serviceCheckOut chan<- metrics.ServiceCheck, eventOut chan<- metrics.Event) *checkSender {
+func newCheckSender(id check.ID, defaultHostname string, smsOut chan<- senderMetricSample, bucketOut chan<- senderHistogramBucket, serviceCheckOut chan<- metrics.ServiceCheck, eventOut chan<- metrics.Event) *checkSender {
 	return &checkSender{
 		id:               id,
 		defaultHostname:  defaultHostname,
 		smsOut:           smsOut,
+		bucketOut:        bucketOut,
 		serviceCheckOut:  serviceCheckOut,
 		eventOut:         eventOut,
 		metricStats:      metricStats{},
@@ -133,7 +141,7 @@ func GetDefaultSender() (Sender, error) {
 	senderInit.Do(func() {
 		var defaultCheckID check.ID // the default value is the zero value
 		aggregatorInstance.registerSender(defaultCheckID)
-		senderInstance = newCheckSender(defaultCheckID, aggregatorInstance.hostname, aggregatorInstance.checkMetricIn, aggregatorInstance.serviceCheckIn, aggregatorInstance.eventIn)
+		senderInstance = newCheckSender(defaultCheckID, aggregatorInstance.hostname, aggregatorInstance.checkMetricIn, aggregatorInstance.checkHistogramBucketIn, aggregatorInstance.serviceCheckIn, aggregatorInstance.eventIn)
 	})
 
 	return senderInstance, nil
@@ -263,6 +271,36 @@ func (s *checkSender) Historate(metric string, value float64, hostname string, t
 	s.sendMetricSample(metric, value, hostname, tags, metrics.HistorateType)
 }
 
+// HistogramBucket should be used to submit the count of the values of a histogram bucket,
+// between lowerBound and upperBound. The buckets are aggregated into a distribution.
+// If monotonic is true, value is a raw counter of which the increase is counted.
+func (s *checkSender) HistogramBucket(metric string, value int64, lowerBound, upperBound float64, monotonic bool, hostname string, tags []string) {
+	tags = append(tags, s.checkTags...)
+
+	log.Tracef("Histogram bucket submitted: %s [%f-%f]: %d for hostname: %s tags: %v", metric, lowerBound, upperBound, value, hostname, tags)
+
+	bucket := &metrics.HistogramBucket{
+		Name:       metric,
+		Value:      value,
+		LowerBound: lowerBound,
+		UpperBound: upperBound,
+		Monotonic:  monotonic,
+		Tags:       tags,
+		Host:       hostname,
+		Timestamp:  timeNowNano(),
+	}
+
+	if hostname == "" && !s.defaultHostnameDisabled {
+		bucket.Host = s.defaultHostname
+	}
+
+	s.bucketOut <- senderHistogramBucket{s.id, bucket}
+
+	s.metricStats.Lock.Lock()
+	s.metricStats.MetricSamples++
+	s.metricStats.Lock.Unlock()
+}
+
 // SendRawServiceCheck sends the raw service check
 // Useful for testing - submitting precomputed service check.
 func (s *checkSender) SendRawServiceCheck(sc *metrics.ServiceCheck) {
@@ -337,7 +375,7 @@ func (sp *checkSenderPool) mkSender(id check.ID) (Sender, error) {
 	defer sp.m.Unlock()
 
 	err := aggregatorInstance.registerSender(id)
-	sender := newCheckSender(id, aggregatorInstance.hostname, aggregatorInstance.checkMetricIn, aggregatorInstance.serviceCheckIn, aggregatorInstance.eventIn)
+	sender := newCheckSender(id, aggregatorInstance.hostname, aggregatorInstance.checkMetricIn, aggregatorInstance.checkHistogramBucketIn, aggregatorInstance.serviceCheckIn, aggregatorInstance.eventIn)
 	sp.senders[id] = sender
 	return sender, err
 }
@@ -109,9 +109,10 @@ func TestGetAndSetSender(t *testing.T) {
 	InitAggregator(nil, "", "")
 
 	senderMetricSampleChan := make(chan senderMetricSample, 10)
+	bucketChan := make(chan senderHistogramBucket, 10)
 	serviceCheckChan := make(chan metrics.ServiceCheck, 10)
 	eventChan := make(chan metrics.Event, 10)
-	testCheckSender := newCheckSender(checkID1, "", senderMetricSampleChan, serviceCheckChan, eventChan)
+	testCheckSender := newCheckSender(checkID1, "", senderMetricSampleChan, bucketChan, serviceCheckChan, eventChan)
 
 	err := SetSender(testCheckSender, checkID1)
 	assert.Nil(t, err)
@@ -140,9 +141,10 @@ func TestGetSenderAddCheckCustomTagsMetrics(t *testing.T) {
 	InitAggregator(nil, "testhostname", "")
 
 	senderMetricSampleChan := make(chan senderMetricSample, 10)
+	bucketChan := make(chan senderHistogramBucket, 10)
 	serviceCheckChan := make(chan metrics.ServiceCheck, 10)
 	eventChan := make(chan metrics.Event, 10)
-	checkSender := newCheckSender(checkID1, "", senderMetricSampleChan, serviceCheckChan, eventChan)
+	checkSender := newCheckSender(checkID1, "", senderMetricSampleChan, bucketChan, serviceCheckChan, eventChan)
 
 	// no custom tags
 	checkSender.sendMetricSample("metric.test", 42.0, "testhostname", nil, metrics.CounterType)
@@ -176,9 +178,10 @@ func TestGetSenderAddCheckCustomTagsService(t *testing.T) {
 	InitAggregator(nil, "testhostname", "")
 
 	senderMetricSampleChan := make(chan senderMetricSample, 10)
+	bucketChan := make(chan senderHistogramBucket, 10)
 	serviceCheckChan := make(chan metrics.ServiceCheck, 10)
 	eventChan := make(chan metrics.Event, 10)
-	checkSender := newCheckSender(checkID1, "", senderMetricSampleChan, serviceCheckChan, eventChan)
+	checkSender := newCheckSender(checkID1, "", senderMetricSampleChan, bucketChan, serviceCheckChan, eventChan)
 
 	// no custom tags
 	checkSender.ServiceCheck("test", metrics.ServiceCheckOK, "testhostname", nil, "test message")
@@ -212,9 +215,10 @@ func TestGetSenderAddCheckCustomTagsEvent(t *testing.T) {
 	InitAggregator(nil, "testhostname", "")
 
 	senderMetricSampleChan := make(chan senderMetricSample, 10)
+	bucketChan := make(chan senderHistogramBucket, 10)
 	serviceCheckChan := make(chan metrics.ServiceCheck, 10)
 	eventChan := make(chan metrics.Event, 10)
-	checkSender := newCheckSender(checkID1, "", senderMetricSampleChan, serviceCheckChan, eventChan)
+	checkSender := newCheckSender(checkID1, "", senderMetricSampleChan, bucketChan, serviceCheckChan, eventChan)
 
 	event := metrics.Event{
 		Title: "title",
@@ -256,15 +260,17 @@ func TestGetSenderAddCheckCustomTagsEvent(t *testing.T) {
 
 func TestCheckSenderInterface(t *testing.T) {
 	senderMetricSampleChan := make(chan senderMetricSample, 10)
+	bucketChan := make(chan senderHistogramBucket, 10)
 	serviceCheckChan := make(chan metrics.ServiceCheck, 10)
 	eventChan := make(chan metrics.Event, 10)
-	checkSender := newCheckSender(checkID1, "default-hostname", senderMetricSampleChan, serviceCheckChan, eventChan)
+	checkSender := newCheckSender(checkID1, "default-hostname", senderMetricSampleChan, bucketChan, serviceCheckChan, eventChan)
 	checkSender.Gauge("my.metric", 1.0, "my-hostname", []string{"foo", "bar"})
 	checkSender.Rate("my.rate_metric", 2.0, "my-hostname", []string{"foo", "bar"})
 	checkSender.Count("my.count_metric", 123.0, "my-hostname", []string{"foo", "bar"})
 	checkSender.MonotonicCount("my.monotonic_count_metric", 12.0, "my-hostname", []string{"foo", "bar"})
 	checkSender.Counter("my.counter_metric", 1.0, "my-hostname", []string{"foo", "bar"})
 	checkSender.Histogram("my.histo_metric", 3.0, "my-hostname", []string{"foo", "bar"})
+	checkSender.HistogramBucket("my.histogram_bucket", 42, 1.0, 2.0, true, "my-hostname", []string{"foo", "bar"})
 	checkSender.Commit()
 	checkSender.ServiceCheck("my_service.can_connect", metrics.ServiceCheckOK, "my-hostname", []string{"foo", "bar"}, "message")
 	submittedEvent := metrics.Event{
@@ -311,6 +317,16 @@ func TestCheckSenderInterface(t *testing.T) {
 	assert.Equal(t, metrics.HistogramType, histoSenderSample.metricSample.Mtype)
 	assert.Equal(t, false, histoSenderSample.commit)
 
+	histogramBucket := <-bucketChan
+	assert.EqualValues(t, checkID1, histogramBucket.id)
+	assert.Equal(t, "my.histogram_bucket", histogramBucket.bucket.Name)
+	assert.Equal(t, int64(42), histogramBucket.bucket.Value)
+	assert.Equal(t, 1.0, histogramBucket.bucket.LowerBound)
+	assert.Equal(t, 2.0, histogramBucket.bucket.UpperBound)
+	assert.Equal(t, true, histogramBucket.bucket.Monotonic)
+	assert.Equal(t, "my-hostname", histogramBucket.bucket.Host)
+	assert.Equal(t, []string{"foo", "bar"}, histogramBucket.bucket.Tags)
+
 	commitSenderSample := <-senderMetricSampleChan
 	assert.EqualValues(t, checkID1, commitSenderSample.id)
 	assert.Equal(t, true, commitSenderSample.commit)
@@ -357,9 +373,10 @@ func TestCheckSenderHostname(t *testing.T) {
 	} {
 		t.Run(fmt.Sprintf("case %d: %q -> %q", nb, tc.submittedHostname, tc.expectedHostname), func(t *testing.T) {
 			senderMetricSampleChan := make(chan senderMetricSample, 10)
+			bucketChan := make(chan senderHistogramBucket, 10)
 			serviceCheckChan := make(chan metrics.ServiceCheck, 10)
 			eventChan := make(chan metrics.Event, 10)
-			checkSender := newCheckSender(checkID1, defaultHostname, senderMetricSampleChan, serviceCheckChan, eventChan)
+			checkSender := newCheckSender(checkID1, defaultHostname, senderMetricSampleChan, bucketChan, serviceCheckChan, eventChan)
 			checkSender.DisableDefaultHostname(tc.defaultHostnameDisabled)
 
 			checkSender.Gauge("my.metric", 1.0, tc.submittedHostname, []string{"foo", "bar"})
@@ -402,9 +419,10 @@ func TestCheckSenderHostname(t *testing.T) {
 
 func TestChangeAllSendersDefaultHostname(t *testing.T) {
 	senderMetricSampleChan := make(chan senderMetricSample, 10)
+	bucketChan := make(chan senderHistogramBucket, 10)
 	serviceCheckChan := make(chan metrics.ServiceCheck, 10)
 	eventChan := make(chan metrics.Event, 10)
-	checkSender := newCheckSender(checkID1, "hostname1", senderMetricSampleChan, serviceCheckChan, eventChan)
+	checkSender := newCheckSender(checkID1, "hostname1", senderMetricSampleChan, bucketChan, serviceCheckChan, eventChan)
 	SetSender(checkSender, checkID1)
 
 	checkSender.Gauge("my.metric", 1.0, "", nil)
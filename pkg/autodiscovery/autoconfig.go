@@ -204,29 +204,7 @@ func (ac *AutoConfig) GetAllConfigs() []integration.Config {
 		}
 
 		if fileConfPd, ok := pd.provider.(*providers.FileConfigProvider); ok {
-			var goodConfs []integration.Config
-			for _, cfg := range cfgs {
-				// JMX checks can have 2 YAML files: one containing the metrics to collect, one containing the
-				// instance configuration
-				// If the file provider finds any of these metric YAMLs, we store them in a map for future access
-				if cfg.MetricConfig != nil {
-					// We don't want to save metric files, it's enough to store them in the map
-					ac.store.setJMXMetricsForConfigName(cfg.Name, cfg.MetricConfig)
-					continue
-				}
-
-				goodConfs = append(goodConfs, cfg)
-
-				// Clear any old errors if a valid config file is found
-				errorStats.removeConfigError(cfg.Name)
-			}
-
-			// Grab any errors that occurred when reading the YAML file
-			for name, e := range fileConfPd.Errors {
-				errorStats.setConfigError(name, e)
-			}
-
-			cfgs = goodConfs
+			cfgs = ac.filterFileConfigs(fileConfPd, cfgs)
 		}
 		// Store all raw configs in the provider
 		pd.configs = cfgs
@@ -242,6 +220,34 @@ func (ac *AutoConfig) GetAllConfigs() []integration.Config {
 	return resolvedConfigs
 }
 
+// filterFileConfigs stores the JMX metric configs collected by the file
+// provider, and returns the other configs. It also updates the config errors.
+func (ac *AutoConfig) filterFileConfigs(provider *providers.FileConfigProvider, cfgs []integration.Config) []integration.Config {
+	var goodConfs []integration.Config
+	for _, cfg := range cfgs {
+		// JMX checks can have 2 YAML files: one containing the metrics to collect, one containing the
+		// instance configuration
+		// If the file provider finds any of these metric YAMLs, we store them in a map for future access
+		if cfg.MetricConfig != nil {
+			// We don't want to save metric files, it's enough to store them in the map
+			ac.store.setJMXMetricsForConfigName(cfg.Name, cfg.MetricConfig)
+			continue
+		}
+
+		goodConfs = append(goodConfs, cfg)
+
+		// Clear any old errors if a valid config file is found
+		errorStats.removeConfigError(cfg.Name)
+	}
+
+	// Grab any errors that occurred when reading the YAML file
+	for name, e := range provider.GetErrors() {
+		errorStats.setConfigError(name, e)
+	}
+
+	return goodConfs
+}
+
 // schedule takes a slice of configs and schedule them
 func (ac *AutoConfig) schedule(configs []integration.Config) {
 	ac.scheduler.Schedule(configs)
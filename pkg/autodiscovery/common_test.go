@@ -55,6 +55,31 @@ func (s *dummyService) GetHostname() (string, error) {
 	return s.Hostname, nil
 }
 
+// GetContainerName return nil and an error, not supported
+func (s *dummyService) GetContainerName() (string, error) {
+	return "", listeners.ErrNotSupported
+}
+
+// GetKubeNamespace return nil and an error, not supported
+func (s *dummyService) GetKubeNamespace() (string, error) {
+	return "", listeners.ErrNotSupported
+}
+
+// GetKubePodName return nil and an error, not supported
+func (s *dummyService) GetKubePodName() (string, error) {
+	return "", listeners.ErrNotSupported
+}
+
+// GetLabels return nil and an error, not supported
+func (s *dummyService) GetLabels() (map[string]string, error) {
+	return nil, listeners.ErrNotSupported
+}
+
+// GetAnnotations return nil and an error, not supported
+func (s *dummyService) GetAnnotations() (map[string]string, error) {
+	return nil, listeners.ErrNotSupported
+}
+
 // GetCreationTime return a dummy creation time
 func (s *dummyService) GetCreationTime() integration.CreationTime {
 	return s.CreationTime
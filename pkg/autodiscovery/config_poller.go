@@ -48,14 +48,15 @@ func (pd *configPoller) contains(c *integration.Config) bool {
 
 // stop stops the provider descriptor if it's polling
 func (pd *configPoller) stop() {
-	if !pd.canPoll || pd.isPolling {
+	if !pd.canPoll || !pd.isPolling {
 		return
 	}
 	pd.stopChan <- struct{}{}
 	pd.isPolling = false
 }
 
-// start starts polling the provider descriptor
+// start starts polling the provider descriptor, or watching it if the provider
+// supports it. A provider that can't be watched is polled.
 func (pd *configPoller) start(ac *AutoConfig) {
 	if !pd.canPoll {
 		return
@@ -63,6 +64,17 @@ func (pd *configPoller) start(ac *AutoConfig) {
 	pd.stopChan = make(chan struct{})
 	pd.healthHandle = health.Register(fmt.Sprintf("ad-config-provider-%s", pd.provider.String()))
 	pd.isPolling = true
+
+	if provider, ok := pd.provider.(providers.WatchingConfigProvider); ok {
+		changes := make(chan struct{}, 1)
+		err := provider.Watch(changes)
+		if err == nil {
+			log.Infof("Watching the %v config provider for changes", pd.provider)
+			go pd.watch(ac, provider, changes)
+			return
+		}
+		log.Warnf("Could not watch the %v config provider, polling it every %s instead: %s", pd.provider, pd.pollInterval, err)
+	}
 	go pd.poll(ac)
 }
 
@@ -87,33 +99,55 @@ func (pd *configPoller) poll(ac *AutoConfig) {
 				log.Debugf("No modifications in the templates stored in %v configuration provider", pd.provider)
 				break
 			}
+			pd.update(ac)
+		}
+	}
+}
 
-			// retrieve the list of newly added configurations as well
-			// as removed configurations
-			newConfigs, removedConfigs := pd.collect()
-			if len(newConfigs) > 0 || len(removedConfigs) > 0 {
-				log.Infof("%v provider: collected %d new configurations, removed %d", pd.provider, len(newConfigs), len(removedConfigs))
-			} else {
-				log.Debugf("%v provider: no configuration change", pd.provider)
-			}
-			// Process removed configs first to handle the case where a
-			// container churn would result in the same configuration hash.
-			ac.processRemovedConfigs(removedConfigs)
-			// We can also remove any cached template
-			ac.removeConfigTemplates(removedConfigs)
-
-			for _, config := range newConfigs {
-				config.Provider = pd.provider.String()
-				resolvedConfigs := ac.processNewConfig(config)
-				ac.schedule(resolvedConfigs)
-			}
+// watch collects the configs of the corresponding config provider whenever
+// it notifies a change
+func (pd *configPoller) watch(ac *AutoConfig, provider providers.WatchingConfigProvider, changes <-chan struct{}) {
+	for {
+		select {
+		case <-pd.healthHandle.C:
+		case <-pd.stopChan:
+			provider.StopWatching()
+			pd.healthHandle.Deregister()
+			return
+		case <-changes:
+			log.Tracef("%s config provider notified a change", pd.provider.String())
+			pd.update(ac)
 		}
 	}
 }
 
+// update collects the configs of the provider, and processes the ones added
+// and removed since the last collection
+func (pd *configPoller) update(ac *AutoConfig) {
+	// retrieve the list of newly added configurations as well
+	// as removed configurations
+	newConfigs, removedConfigs := pd.collect(ac)
+	if len(newConfigs) > 0 || len(removedConfigs) > 0 {
+		log.Infof("%v provider: collected %d new configurations, removed %d", pd.provider, len(newConfigs), len(removedConfigs))
+	} else {
+		log.Debugf("%v provider: no configuration change", pd.provider)
+	}
+	// Process removed configs first to handle the case where a
+	// container churn would result in the same configuration hash.
+	ac.processRemovedConfigs(removedConfigs)
+	// We can also remove any cached template
+	ac.removeConfigTemplates(removedConfigs)
+
+	for _, config := range newConfigs {
+		config.Provider = pd.provider.String()
+		resolvedConfigs := ac.processNewConfig(config)
+		ac.schedule(resolvedConfigs)
+	}
+}
+
 // collect is just a convenient wrapper to fetch configurations from a provider and
 // see what changed from the last time we called Collect().
-func (pd *configPoller) collect() ([]integration.Config, []integration.Config) {
+func (pd *configPoller) collect(ac *AutoConfig) ([]integration.Config, []integration.Config) {
 	var newConf []integration.Config
 	var removedConf []integration.Config
 	old := pd.configs
@@ -123,6 +157,9 @@ func (pd *configPoller) collect() ([]integration.Config, []integration.Config) {
 		log.Errorf("Unable to collect configurations from provider %s: %s", pd.provider, err)
 		return nil, nil
 	}
+	if fileConfPd, ok := pd.provider.(*providers.FileConfigProvider); ok {
+		fetched = ac.filterFileConfigs(fileConfPd, fetched)
+	}
 
 	for _, c := range fetched {
 		if !pd.contains(&c) {
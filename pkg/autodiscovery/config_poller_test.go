@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package autodiscovery
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/scheduler"
+)
+
+type mockWatchingProvider struct {
+	sync.Mutex
+	configs  []integration.Config
+	watchErr error
+	changes  chan<- struct{}
+	collects int
+	stopped  bool
+}
+
+func (p *mockWatchingProvider) Collect() ([]integration.Config, error) {
+	p.Lock()
+	defer p.Unlock()
+	p.collects++
+	return p.configs, nil
+}
+
+func (p *mockWatchingProvider) String() string {
+	return "watched"
+}
+
+func (p *mockWatchingProvider) IsUpToDate() (bool, error) {
+	return false, nil
+}
+
+func (p *mockWatchingProvider) Watch(changes chan<- struct{}) error {
+	p.Lock()
+	defer p.Unlock()
+	p.changes = changes
+	return p.watchErr
+}
+
+func (p *mockWatchingProvider) StopWatching() {
+	p.Lock()
+	defer p.Unlock()
+	p.stopped = true
+}
+
+func (p *mockWatchingProvider) setConfigs(configs ...integration.Config) {
+	p.Lock()
+	defer p.Unlock()
+	p.configs = configs
+}
+
+// waitFor polls cond until it's true
+func waitFor(t *testing.T, cond func() bool) {
+	for timeout := time.Now().Add(5 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(timeout) {
+			require.FailNow(t, "timeout waiting for the condition")
+		}
+	}
+}
+
+func loadedConfigNames(ac *AutoConfig) map[string]bool {
+	names := make(map[string]bool)
+	for _, c := range ac.GetLoadedConfigs() {
+		names[c.Name] = true
+	}
+	return names
+}
+
+func TestConfigPollerWatch(t *testing.T) {
+	ac := NewAutoConfig(scheduler.NewMetaScheduler())
+	p := &mockWatchingProvider{configs: []integration.Config{{Name: "foo"}}}
+	ac.AddConfigProvider(p, true, time.Hour)
+	ac.LoadAndRun()
+	assert.Equal(t, map[string]bool{"foo": true}, loadedConfigNames(ac))
+	require.NotNil(t, p.changes)
+
+	// only the changed configs are processed on a change notification
+	p.setConfigs(integration.Config{Name: "foo"}, integration.Config{Name: "bar"})
+	p.changes <- struct{}{}
+	waitFor(t, func() bool { return loadedConfigNames(ac)["bar"] })
+	assert.Equal(t, map[string]bool{"foo": true, "bar": true}, loadedConfigNames(ac))
+
+	p.setConfigs(integration.Config{Name: "bar"})
+	p.changes <- struct{}{}
+	waitFor(t, func() bool { return !loadedConfigNames(ac)["foo"] })
+	assert.Equal(t, map[string]bool{"bar": true}, loadedConfigNames(ac))
+
+	p.Lock()
+	assert.Equal(t, 3, p.collects)
+	p.Unlock()
+
+	ac.Stop()
+	waitFor(t, func() bool {
+		p.Lock()
+		defer p.Unlock()
+		return p.stopped
+	})
+}
+
+func TestConfigPollerWatchFallback(t *testing.T) {
+	ac := NewAutoConfig(scheduler.NewMetaScheduler())
+	p := &mockWatchingProvider{watchErr: errors.New("no inotify")}
+	ac.AddConfigProvider(p, true, 10*time.Millisecond)
+
+	// the provider is polled when it can't be watched
+	p.setConfigs(integration.Config{Name: "foo"})
+	waitFor(t, func() bool { return loadedConfigNames(ac)["foo"] })
+	ac.Stop()
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
@@ -21,11 +22,15 @@ import (
 type variableGetter func(key []byte, svc listeners.Service) ([]byte, error)
 
 var templateVariables = map[string]variableGetter{
-	"host":     getHost,
-	"pid":      getPid,
-	"port":     getPort,
-	"env":      getEnvvar,
-	"hostname": getHostname,
+	"host":       getHost,
+	"pid":        getPid,
+	"port":       getPort,
+	"container":  getContainerName,
+	"kube":       getKubeVariable,
+	"label":      getLabel,
+	"annotation": getAnnotation,
+	"env":        getEnvvar,
+	"hostname":   getHostname,
 }
 
 // Resolve takes a template and a service and generates a config with
@@ -181,3 +186,76 @@ func getEnvvar(tplVar []byte, svc listeners.Service) ([]byte, error) {
 	}
 	return []byte(value), nil
 }
+
+// getContainerName returns the name of the container of the service
+func getContainerName(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if string(tplVar) != "name" {
+		return nil, fmt.Errorf("unknown template variable %%%%container_%s%%%%, skipping service %s", tplVar, svc.GetEntity())
+	}
+	name, err := svc.GetContainerName()
+	if err != nil {
+		return nil, variableError("container_name", svc, err)
+	}
+	return []byte(name), nil
+}
+
+// getKubeVariable returns the kubernetes namespace or pod name of the service
+func getKubeVariable(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	var value string
+	var err error
+	switch string(tplVar) {
+	case "namespace":
+		value, err = svc.GetKubeNamespace()
+	case "pod_name":
+		value, err = svc.GetKubePodName()
+	default:
+		return nil, fmt.Errorf("unknown template variable %%%%kube_%s%%%%, skipping service %s", tplVar, svc.GetEntity())
+	}
+	if err != nil {
+		return nil, variableError("kube_"+string(tplVar), svc, err)
+	}
+	return []byte(value), nil
+}
+
+// getLabel returns the value of a label of the service
+func getLabel(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if len(tplVar) == 0 {
+		return nil, fmt.Errorf("label name is missing, skipping service %s", svc.GetEntity())
+	}
+	labels, err := svc.GetLabels()
+	if err != nil {
+		return nil, variableError("label_"+string(tplVar), svc, err)
+	}
+	value, found := labels[string(tplVar)]
+	if !found {
+		return nil, fmt.Errorf("label %s not found, skipping service %s", tplVar, svc.GetEntity())
+	}
+	return []byte(value), nil
+}
+
+// getAnnotation returns the value of an annotation of the service
+func getAnnotation(tplVar []byte, svc listeners.Service) ([]byte, error) {
+	if len(tplVar) == 0 {
+		return nil, fmt.Errorf("annotation name is missing, skipping service %s", svc.GetEntity())
+	}
+	annotations, err := svc.GetAnnotations()
+	if err != nil {
+		return nil, variableError("annotation_"+string(tplVar), svc, err)
+	}
+	value, found := annotations[string(tplVar)]
+	if !found {
+		return nil, fmt.Errorf("annotation %s not found, skipping service %s", tplVar, svc.GetEntity())
+	}
+	return []byte(value), nil
+}
+
+// variableError returns the error of a template variable the service failed
+// to resolve, telling apart the variables its listener doesn't support
+func variableError(variable string, svc listeners.Service, err error) error {
+	entity := svc.GetEntity()
+	if err == listeners.ErrNotSupported {
+		kind := strings.SplitN(entity, "://", 2)[0]
+		return fmt.Errorf("template variable %%%%%s%%%% is not available for %s services, skipping service %s", variable, kind, entity)
+	}
+	return fmt.Errorf("failed to get %%%%%s%%%% for service %s, skipping config - %s", variable, entity, err)
+}
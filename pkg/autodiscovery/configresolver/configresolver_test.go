@@ -27,6 +27,11 @@ type dummyService struct {
 	Ports         []listeners.ContainerPort
 	Pid           int
 	Hostname      string
+	ContainerName string
+	KubeNamespace string
+	KubePodName   string
+	Labels        map[string]string
+	Annotations   map[string]string
 	CreationTime  integration.CreationTime
 }
 
@@ -65,6 +70,46 @@ func (s *dummyService) GetHostname() (string, error) {
 	return s.Hostname, nil
 }
 
+// GetContainerName return a dummy container name, if set
+func (s *dummyService) GetContainerName() (string, error) {
+	if s.ContainerName == "" {
+		return "", listeners.ErrNotSupported
+	}
+	return s.ContainerName, nil
+}
+
+// GetKubeNamespace return a dummy namespace, if set
+func (s *dummyService) GetKubeNamespace() (string, error) {
+	if s.KubeNamespace == "" {
+		return "", listeners.ErrNotSupported
+	}
+	return s.KubeNamespace, nil
+}
+
+// GetKubePodName return a dummy pod name, if set
+func (s *dummyService) GetKubePodName() (string, error) {
+	if s.KubePodName == "" {
+		return "", listeners.ErrNotSupported
+	}
+	return s.KubePodName, nil
+}
+
+// GetLabels return dummy labels, if set
+func (s *dummyService) GetLabels() (map[string]string, error) {
+	if s.Labels == nil {
+		return nil, listeners.ErrNotSupported
+	}
+	return s.Labels, nil
+}
+
+// GetAnnotations return dummy annotations, if set
+func (s *dummyService) GetAnnotations() (map[string]string, error) {
+	if s.Annotations == nil {
+		return nil, listeners.ErrNotSupported
+	}
+	return s.Annotations, nil
+}
+
 // GetCreationTime return a dummy creation time
 func (s *dummyService) GetCreationTime() integration.CreationTime {
 	return s.CreationTime
@@ -385,6 +430,135 @@ func TestResolve(t *testing.T) {
 				Entity:        "a5901276aed1",
 			},
 		},
+		//// container and kubernetes metadata
+		{
+			testName: "simple %%container_name%%",
+			svc: &dummyService{
+				ID:            "a5901276aed1",
+				ADIdentifiers: []string{"redis"},
+				ContainerName: "redis-cache",
+			},
+			tpl: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("name: %%container_name%%")},
+			},
+			out: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("name: redis-cache")},
+				Entity:        "a5901276aed1",
+			},
+		},
+		{
+			testName: "invalid %%container_id%%",
+			svc: &dummyService{
+				ID:            "a5901276aed1",
+				ADIdentifiers: []string{"redis"},
+				ContainerName: "redis-cache",
+			},
+			tpl: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("id: %%container_id%%")},
+			},
+			errorString: "unknown template variable %%container_id%%, skipping service a5901276aed1",
+		},
+		{
+			testName: "%%kube_namespace%% and %%kube_pod_name%%",
+			svc: &dummyService{
+				ID:            "a5901276aed1",
+				ADIdentifiers: []string{"redis"},
+				KubeNamespace: "default",
+				KubePodName:   "redis-0",
+			},
+			tpl: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("url: http://%%kube_pod_name%%.%%kube_namespace%%")},
+			},
+			out: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("url: http://redis-0.default")},
+				Entity:        "a5901276aed1",
+			},
+		},
+		{
+			testName: "%%kube_pod_name%% not supported by the service",
+			svc: &dummyService{
+				ID:            "process://1337",
+				ADIdentifiers: []string{"redis"},
+			},
+			tpl: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("pod: %%kube_pod_name%%")},
+			},
+			errorString: "template variable %%kube_pod_name%% is not available for process services, skipping service process://1337",
+		},
+		{
+			testName: "simple %%label_<key>%% and %%annotation_<key>%%",
+			svc: &dummyService{
+				ID:            "a5901276aed1",
+				ADIdentifiers: []string{"redis"},
+				Labels:        map[string]string{"app.kubernetes.io/name": "cache"},
+				Annotations:   map[string]string{"team_name": "storage"},
+			},
+			tpl: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("app: %%label_app.kubernetes.io/name%%\nteam: %%annotation_team_name%%")},
+			},
+			out: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("app: cache\nteam: storage")},
+				Entity:        "a5901276aed1",
+			},
+		},
+		{
+			testName: "not found %%label_<key>%%",
+			svc: &dummyService{
+				ID:            "a5901276aed1",
+				ADIdentifiers: []string{"redis"},
+				Labels:        map[string]string{"app": "cache"},
+			},
+			tpl: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("app: %%label_version%%")},
+			},
+			errorString: "label version not found, skipping service a5901276aed1",
+		},
+		{
+			testName: "%%annotation_<key>%% not supported by the service",
+			svc: &dummyService{
+				ID:            "docker://a5901276aed1",
+				ADIdentifiers: []string{"redis"},
+				Labels:        map[string]string{"app": "cache"},
+			},
+			tpl: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("team: %%annotation_team%%")},
+			},
+			errorString: "template variable %%annotation_team%% is not available for docker services, skipping service docker://a5901276aed1",
+		},
+		{
+			testName: "invalid %%label%%",
+			svc: &dummyService{
+				ID:            "a5901276aed1",
+				ADIdentifiers: []string{"redis"},
+				Labels:        map[string]string{"app": "cache"},
+			},
+			tpl: integration.Config{
+				Name:          "cpu",
+				ADIdentifiers: []string{"redis"},
+				Instances:     []integration.Data{integration.Data("app: %%label%%")},
+			},
+			errorString: "label name is missing, skipping service a5901276aed1",
+		},
 		//// other tags testing
 		{
 			testName: "simple %%pid%%",
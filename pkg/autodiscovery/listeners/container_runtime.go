@@ -0,0 +1,406 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listeners
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// kubernetesPortsAnnotation is set by the kubelet on the containers it
+	// creates through the CRI, with the ports of the container spec
+	kubernetesPortsAnnotation = "io.kubernetes.container.ports"
+	// kubernetesContainerNameLabel is set by the kubelet on the containers it
+	// creates through the CRI
+	kubernetesContainerNameLabel = "io.kubernetes.container.name"
+	// kubernetesPodNameLabel and kubernetesPodNamespaceLabel are set by the
+	// kubelet on the containers it creates through the CRI
+	kubernetesPodNameLabel      = "io.kubernetes.pod.name"
+	kubernetesPodNamespaceLabel = "io.kubernetes.pod.namespace"
+)
+
+// containerRuntime is a container runtime other than docker the
+// ContainerRuntimeListener discovers containers from
+type containerRuntime interface {
+	// runtimeName returns the name of the runtime, the prefix of the entities of its containers
+	runtimeName() string
+	// runningContainers returns the IDs of the running containers
+	runningContainers() ([]string, error)
+	// inspect returns a running container
+	inspect(containerID string) (*runtimeContainer, error)
+}
+
+// containerEventStreamer is implemented by the container runtimes streaming the
+// events of their containers. The other ones are polled.
+type containerEventStreamer interface {
+	// subscribe returns a channel receiving when a container starts or stops
+	subscribe() (<-chan struct{}, <-chan error, error)
+	unsubscribe()
+}
+
+// runtimeContainer is a container as seen by a containerRuntime
+type runtimeContainer struct {
+	id          string
+	name        string
+	image       string
+	labels      map[string]string
+	annotations map[string]string // nil if the runtime doesn't report them
+	hosts       map[string]string
+	ports       []ContainerPort
+	pid         int
+}
+
+// ContainerRuntimeListener implements the ServiceListener interface for the
+// container runtimes other than docker: containerd, and the CRI runtimes.
+// It refreshes its services when the runtime reports a container started or
+// stopped, and polls the runtime when it can't stream its events.
+type ContainerRuntimeListener struct {
+	runtime    containerRuntime
+	healthName string
+	filter     *containers.Filter
+	services   map[string]*ContainerRuntimeService // by container ID
+	excluded   map[string]bool                     // the IDs of the containers filtered out
+	newService chan<- Service
+	delService chan<- Service
+	ticker     *time.Ticker
+	stop       chan bool
+	health     *health.Handle
+}
+
+// ContainerRuntimeService implements and store results from the Service interface for the ContainerRuntimeListener
+type ContainerRuntimeService struct {
+	entity        string
+	adIdentifiers []string
+	hosts         map[string]string
+	ports         []ContainerPort
+	pid           int
+	containerName string
+	labels        map[string]string
+	annotations   map[string]string
+	creationTime  integration.CreationTime
+}
+
+func newContainerRuntimeListener(runtime containerRuntime, healthName string) (*ContainerRuntimeListener, error) {
+	filter, err := containers.NewFilterFromConfigIncludePause()
+	if err != nil {
+		return nil, err
+	}
+	return &ContainerRuntimeListener{
+		runtime:    runtime,
+		healthName: healthName,
+		filter:     filter,
+		services:   make(map[string]*ContainerRuntimeService),
+		excluded:   make(map[string]bool),
+		stop:       make(chan bool),
+	}, nil
+}
+
+// Listen streams the container events of the runtime, or polls it, and
+// reports the running containers as services
+func (l *ContainerRuntimeListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {
+	// setup the I/O channels
+	l.newService = newSvc
+	l.delService = delSvc
+	l.ticker = time.NewTicker(config.Datadog.GetDuration("cri_listener_polling_interval") * time.Second)
+	l.health = health.Register(l.healthName)
+
+	go func() {
+		// subscribe first not to miss the containers started during the first refresh
+		events, errs := l.subscribe()
+		l.refreshServices(true)
+		for {
+			// the runtime is only polled when its events aren't streamed
+			var tick <-chan time.Time
+			if events == nil {
+				tick = l.ticker.C
+			}
+			select {
+			case <-l.stop:
+				if events != nil {
+					l.runtime.(containerEventStreamer).unsubscribe()
+				}
+				l.health.Deregister()
+				return
+			case <-l.health.C:
+			case <-events:
+				l.refreshServices(false)
+			case err := <-errs:
+				log.Warnf("Error streaming the %s events, polling the containers until the stream is back: %s", l.runtime.runtimeName(), err)
+				l.runtime.(containerEventStreamer).unsubscribe()
+				events, errs = nil, nil
+				l.refreshServices(false)
+			case <-tick:
+				events, errs = l.subscribe()
+				l.refreshServices(false)
+			}
+		}
+	}()
+}
+
+// Stop queues a shutdown of ContainerRuntimeListener
+func (l *ContainerRuntimeListener) Stop() {
+	l.ticker.Stop()
+	l.stop <- true
+}
+
+// subscribe subscribes to the container events of the runtime, it returns nil
+// channels if the runtime must be polled
+func (l *ContainerRuntimeListener) subscribe() (<-chan struct{}, <-chan error) {
+	streamer, ok := l.runtime.(containerEventStreamer)
+	if !ok {
+		return nil, nil
+	}
+	events, errs, err := streamer.subscribe()
+	if err != nil {
+		log.Warnf("Could not subscribe to the %s events, polling the containers: %s", l.runtime.runtimeName(), err)
+		return nil, nil
+	}
+	return events, errs
+}
+
+// refreshServices lists the running containers, and sends the services of the
+// containers stopped and started since the last refresh
+func (l *ContainerRuntimeListener) refreshServices(firstRun bool) {
+	ids, err := l.runtime.runningContainers()
+	if err != nil {
+		log.Errorf("Could not list the %s containers, not refreshing services - %s", l.runtime.runtimeName(), err)
+		return
+	}
+	running := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		running[id] = true
+	}
+
+	for id, svc := range l.services {
+		if !running[id] {
+			delete(l.services, id)
+			l.delService <- svc
+		}
+	}
+	for id := range l.excluded {
+		if !running[id] {
+			delete(l.excluded, id)
+		}
+	}
+
+	for _, id := range ids {
+		if _, found := l.services[id]; found || l.excluded[id] {
+			continue
+		}
+		ctr, err := l.runtime.inspect(id)
+		if err != nil {
+			// the container may have stopped since it was listed, it's retried on the next refresh
+			log.Debugf("Could not inspect the %s container %s: %s", l.runtime.runtimeName(), id, err)
+			continue
+		}
+		if l.filter.IsExcluded(ctr.name, ctr.image) {
+			log.Debugf("container %s filtered out: name %q image %q", id, ctr.name, ctr.image)
+			l.excluded[id] = true
+			continue
+		}
+		svc := newContainerRuntimeService(l.runtime.runtimeName(), ctr, firstRun)
+		l.services[id] = svc
+		l.newService <- svc
+	}
+}
+
+func newContainerRuntimeService(runtime string, ctr *runtimeContainer, firstRun bool) *ContainerRuntimeService {
+	var crTime integration.CreationTime
+	if firstRun {
+		crTime = integration.Before
+	} else {
+		crTime = integration.After
+	}
+	entity := containers.BuildEntityName(runtime, ctr.id)
+	return &ContainerRuntimeService{
+		entity:        entity,
+		adIdentifiers: ComputeContainerServiceIDs(entity, ctr.image, ctr.labels),
+		hosts:         ctr.hosts,
+		ports:         ctr.ports,
+		pid:           ctr.pid,
+		containerName: ctr.name,
+		labels:        ctr.labels,
+		annotations:   ctr.annotations,
+		creationTime:  crTime,
+	}
+}
+
+// parseExposedPorts parses the exposed ports of an image config, like
+// 6379/tcp or a range like 8000-8002/tcp. It returns the ports sorted.
+func parseExposedPorts(exposedPorts map[string]struct{}) []ContainerPort {
+	seen := make(map[int]bool)
+	for spec := range exposedPorts {
+		portRange := strings.SplitN(spec, "/", 2)[0]
+		bounds := strings.SplitN(portRange, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first <= 0 {
+			log.Warnf("failed to extract port from: %s", spec)
+			continue
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				log.Warnf("failed to extract port from: %s", spec)
+				continue
+			}
+		}
+		for p := first; p <= last; p++ {
+			seen[p] = true
+		}
+	}
+	return sortedPorts(seen, nil)
+}
+
+// parseKubernetesPorts parses the ports the kubelet annotates the containers
+// with, in the format of the pod spec
+func parseKubernetesPorts(annotations map[string]string) []ContainerPort {
+	raw, found := annotations[kubernetesPortsAnnotation]
+	if !found {
+		return nil
+	}
+	var specs []struct {
+		Name          string `json:"name"`
+		ContainerPort int    `json:"containerPort"`
+	}
+	if err := json.Unmarshal([]byte(raw), &specs); err != nil {
+		log.Warnf("failed to parse the %s annotation: %s", kubernetesPortsAnnotation, err)
+		return nil
+	}
+	seen := make(map[int]bool)
+	names := make(map[int]string)
+	for _, spec := range specs {
+		if spec.ContainerPort > 0 {
+			seen[spec.ContainerPort] = true
+			names[spec.ContainerPort] = spec.Name
+		}
+	}
+	return sortedPorts(seen, names)
+}
+
+func sortedPorts(ports map[int]bool, names map[int]string) []ContainerPort {
+	// a non-nil slice, a container without ports is inspected once
+	sorted := []ContainerPort{}
+	for p := range ports {
+		sorted = append(sorted, ContainerPort{Port: p, Name: names[p]})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Port < sorted[j].Port
+	})
+	return sorted
+}
+
+// GetEntity returns the unique entity name linked to that service
+func (s *ContainerRuntimeService) GetEntity() string {
+	return s.entity
+}
+
+// GetADIdentifiers returns a set of AD identifiers for a container.
+// These id are sorted to reflect the priority we want the ConfigResolver to
+// use when matching a template.
+//
+// When the special identifier label in `identifierLabel` is set by the user,
+// it overrides any other meaning of template identification for the service
+// and the return value will contain only the label value.
+//
+// If the special label was not set, the priority order is the following:
+//   1. Entity name
+//   2. Long image name
+//   3. Short image name
+func (s *ContainerRuntimeService) GetADIdentifiers() ([]string, error) {
+	return s.adIdentifiers, nil
+}
+
+// GetHosts returns the container's hosts
+func (s *ContainerRuntimeService) GetHosts() (map[string]string, error) {
+	return s.hosts, nil
+}
+
+// GetPorts returns the container's ports
+func (s *ContainerRuntimeService) GetPorts() ([]ContainerPort, error) {
+	return s.ports, nil
+}
+
+// GetTags retrieves tags using the Tagger
+func (s *ContainerRuntimeService) GetTags() ([]string, error) {
+	tags, err := tagger.Tag(s.entity, tagger.ChecksCardinality)
+	if err != nil {
+		return []string{}, err
+	}
+	return tags, nil
+}
+
+// GetPid returns the pid of the container
+func (s *ContainerRuntimeService) GetPid() (int, error) {
+	if s.pid <= 0 {
+		return -1, ErrNotSupported
+	}
+	return s.pid, nil
+}
+
+// GetHostname returns nil and an error because the runtimes don't report the hostname of the containers
+func (s *ContainerRuntimeService) GetHostname() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetContainerName returns the name of the container
+func (s *ContainerRuntimeService) GetContainerName() (string, error) {
+	return s.containerName, nil
+}
+
+// GetKubeNamespace returns the namespace of the pod of the container, if the
+// kubelet created it
+func (s *ContainerRuntimeService) GetKubeNamespace() (string, error) {
+	return s.kubernetesLabel(kubernetesPodNamespaceLabel)
+}
+
+// GetKubePodName returns the name of the pod of the container, if the
+// kubelet created it
+func (s *ContainerRuntimeService) GetKubePodName() (string, error) {
+	return s.kubernetesLabel(kubernetesPodNameLabel)
+}
+
+func (s *ContainerRuntimeService) kubernetesLabel(label string) (string, error) {
+	value, found := s.labels[label]
+	if !found {
+		return "", ErrNotSupported
+	}
+	return value, nil
+}
+
+// GetLabels returns the labels of the container
+func (s *ContainerRuntimeService) GetLabels() (map[string]string, error) {
+	return s.labels, nil
+}
+
+// GetAnnotations returns the annotations of the container, or an error if the
+// runtime doesn't report them
+func (s *ContainerRuntimeService) GetAnnotations() (map[string]string, error) {
+	if s.annotations == nil {
+		return nil, ErrNotSupported
+	}
+	return s.annotations, nil
+}
+
+// GetCreationTime returns the creation time of the container compared to the agent start
+func (s *ContainerRuntimeService) GetCreationTime() integration.CreationTime {
+	return s.creationTime
+}
+
+// IsReady returns true, the running containers are services as soon as they're discovered
+func (s *ContainerRuntimeService) IsReady() bool {
+	return true
+}
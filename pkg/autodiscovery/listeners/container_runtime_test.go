@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listeners
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+)
+
+type fakeRuntime struct {
+	sync.Mutex
+	containers map[string]*runtimeContainer
+	running    []string
+	inspected  map[string]int
+}
+
+func (r *fakeRuntime) runtimeName() string {
+	return "containerd"
+}
+
+func (r *fakeRuntime) runningContainers() ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string{}, r.running...), nil
+}
+
+func (r *fakeRuntime) inspect(containerID string) (*runtimeContainer, error) {
+	r.Lock()
+	defer r.Unlock()
+	r.inspected[containerID]++
+	ctr, found := r.containers[containerID]
+	if !found {
+		return nil, fmt.Errorf("container %s not found", containerID)
+	}
+	return ctr, nil
+}
+
+func (r *fakeRuntime) setRunning(ids ...string) {
+	r.Lock()
+	defer r.Unlock()
+	r.running = ids
+}
+
+func newTestContainerRuntimeListener(t *testing.T, runtime containerRuntime) (*ContainerRuntimeListener, chan Service, chan Service) {
+	filter, err := containers.NewFilter(nil, []string{"image:excluded"})
+	require.NoError(t, err)
+	newSvc := make(chan Service, 10)
+	delSvc := make(chan Service, 10)
+	l := &ContainerRuntimeListener{
+		runtime:    runtime,
+		filter:     filter,
+		services:   make(map[string]*ContainerRuntimeService),
+		excluded:   make(map[string]bool),
+		newService: newSvc,
+		delService: delSvc,
+	}
+	return l, newSvc, delSvc
+}
+
+func receiveEntities(ch chan Service) []string {
+	var entities []string
+	for {
+		select {
+		case svc := <-ch:
+			entities = append(entities, svc.GetEntity())
+		default:
+			return entities
+		}
+	}
+}
+
+func TestContainerRuntimeListenerRefreshServices(t *testing.T) {
+	runtime := &fakeRuntime{
+		containers: map[string]*runtimeContainer{
+			"redis": {
+				id:    "redis",
+				name:  "redis",
+				image: "docker.io/library/redis:5",
+				labels: map[string]string{
+					"maintainer":                "redis",
+					kubernetesPodNameLabel:      "redis-0",
+					kubernetesPodNamespaceLabel: "default",
+				},
+				annotations: map[string]string{"io.kubernetes.container.restartCount": "0"},
+				hosts:       map[string]string{"eth0": "10.0.0.5"},
+				ports:       []ContainerPort{{Port: 6379}},
+				pid:         4242,
+			},
+			"nginx": {
+				id:     "nginx",
+				name:   "nginx",
+				image:  "nginx:latest",
+				labels: map[string]string{"com.datadoghq.ad.check.id": "custom-nginx"},
+			},
+			"filtered": {
+				id:    "filtered",
+				name:  "filtered",
+				image: "excluded",
+			},
+		},
+		running:   []string{"redis", "filtered", "gone"},
+		inspected: make(map[string]int),
+	}
+	l, newSvc, delSvc := newTestContainerRuntimeListener(t, runtime)
+
+	l.refreshServices(true)
+	svc := <-newSvc
+	assert.Empty(t, receiveEntities(newSvc))
+	assert.Empty(t, receiveEntities(delSvc))
+
+	assert.Equal(t, "containerd://redis", svc.GetEntity())
+	ids, err := svc.GetADIdentifiers()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"containerd://redis", "docker.io/library/redis", "redis"}, ids)
+	hosts, err := svc.GetHosts()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"eth0": "10.0.0.5"}, hosts)
+	ports, err := svc.GetPorts()
+	require.NoError(t, err)
+	assert.Equal(t, []ContainerPort{{Port: 6379}}, ports)
+	pid, err := svc.GetPid()
+	require.NoError(t, err)
+	assert.Equal(t, 4242, pid)
+	_, err = svc.GetHostname()
+	assert.Equal(t, ErrNotSupported, err)
+	name, err := svc.GetContainerName()
+	require.NoError(t, err)
+	assert.Equal(t, "redis", name)
+	namespace, err := svc.GetKubeNamespace()
+	require.NoError(t, err)
+	assert.Equal(t, "default", namespace)
+	podName, err := svc.GetKubePodName()
+	require.NoError(t, err)
+	assert.Equal(t, "redis-0", podName)
+	labels, err := svc.GetLabels()
+	require.NoError(t, err)
+	assert.Equal(t, "redis", labels["maintainer"])
+	annotations, err := svc.GetAnnotations()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"io.kubernetes.container.restartCount": "0"}, annotations)
+	assert.Equal(t, integration.Before, svc.GetCreationTime())
+	assert.True(t, svc.IsReady())
+
+	// a container started, the filtered out container isn't inspected again,
+	// and the container failing inspection is retried
+	runtime.setRunning("redis", "filtered", "gone", "nginx")
+	l.refreshServices(false)
+	svc = <-newSvc
+	assert.Equal(t, "containerd://nginx", svc.GetEntity())
+	ids, err = svc.GetADIdentifiers()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"custom-nginx"}, ids)
+	_, err = svc.GetPid()
+	assert.Equal(t, ErrNotSupported, err)
+	_, err = svc.GetKubePodName()
+	assert.Equal(t, ErrNotSupported, err)
+	_, err = svc.GetAnnotations()
+	assert.Equal(t, ErrNotSupported, err)
+	assert.Equal(t, integration.After, svc.GetCreationTime())
+	assert.Empty(t, receiveEntities(delSvc))
+	assert.Equal(t, map[string]int{"redis": 1, "filtered": 1, "gone": 2, "nginx": 1}, runtime.inspected)
+
+	// a container stopped
+	runtime.setRunning("nginx")
+	l.refreshServices(false)
+	assert.Equal(t, []string{"containerd://redis"}, receiveEntities(delSvc))
+	assert.Empty(t, receiveEntities(newSvc))
+	assert.Empty(t, l.excluded)
+
+	// nothing changed
+	l.refreshServices(false)
+	assert.Empty(t, receiveEntities(delSvc))
+	assert.Empty(t, receiveEntities(newSvc))
+}
+
+func TestParseExposedPorts(t *testing.T) {
+	ports := parseExposedPorts(map[string]struct{}{
+		"6379/tcp":      {},
+		"8000-8002/tcp": {},
+		"53/udp":        {},
+		"80":            {},
+		"invalid/tcp":   {},
+		"9-1/tcp":       {},
+	})
+	assert.Equal(t, []ContainerPort{{Port: 53}, {Port: 80}, {Port: 6379}, {Port: 8000}, {Port: 8001}, {Port: 8002}}, ports)
+
+	assert.Equal(t, []ContainerPort{}, parseExposedPorts(nil))
+}
+
+func TestParseKubernetesPorts(t *testing.T) {
+	ports := parseKubernetesPorts(map[string]string{
+		kubernetesPortsAnnotation: `[{"name":"http","containerPort":8080,"protocol":"TCP"},{"containerPort":9090,"protocol":"TCP"}]`,
+	})
+	assert.Equal(t, []ContainerPort{{Port: 8080, Name: "http"}, {Port: 9090}}, ports)
+
+	assert.Nil(t, parseKubernetesPorts(map[string]string{}))
+	assert.Nil(t, parseKubernetesPorts(map[string]string{kubernetesPortsAnnotation: "invalid"}))
+}
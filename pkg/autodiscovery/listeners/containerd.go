@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build containerd
+
+package listeners
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/namespaces"
+
+	cutil "github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// containerdRuntime implements the containerRuntime interface for the
+// containers of the containerd namespace of the agent. It streams the events
+// of their tasks.
+type containerdRuntime struct {
+	util       cutil.ContainerdItf
+	procRoot   string
+	containers map[string]containerd.Container // the running containers of the last listing, by ID
+	cancel     context.CancelFunc
+}
+
+func init() {
+	Register("containerd", NewContainerdListener)
+}
+
+// NewContainerdListener creates a ContainerRuntimeListener for the containers of containerd
+func NewContainerdListener() (ServiceListener, error) {
+	util, err := cutil.GetContainerdUtil()
+	if err != nil {
+		return nil, err
+	}
+	runtime := &containerdRuntime{
+		util:       util,
+		procRoot:   procfsRoot(),
+		containers: make(map[string]containerd.Container),
+	}
+	return newContainerRuntimeListener(runtime, "ad-containerdlistener")
+}
+
+func (r *containerdRuntime) runtimeName() string {
+	return containers.RuntimeNameContainerd
+}
+
+// runningContainers returns the IDs of the containers with a running task
+func (r *containerdRuntime) runningContainers() ([]string, error) {
+	ctns, err := r.util.Containers()
+	if err != nil {
+		return nil, err
+	}
+	r.containers = make(map[string]containerd.Container, len(ctns))
+	var ids []string
+	for _, ctn := range ctns {
+		if _, err := r.util.TaskPid(ctn); err != nil {
+			continue
+		}
+		r.containers[ctn.ID()] = ctn
+		ids = append(ids, ctn.ID())
+	}
+	return ids, nil
+}
+
+// inspect returns a running container. Its addresses are the ones of the
+// network namespace of its task, its ports are the exposed ports of its image.
+func (r *containerdRuntime) inspect(containerID string) (*runtimeContainer, error) {
+	ctn, found := r.containers[containerID]
+	if !found {
+		return nil, fmt.Errorf("container %s is not running", containerID)
+	}
+	info, err := r.util.Info(ctn)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := r.util.TaskPid(ctn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr := &runtimeContainer{
+		id:     containerID,
+		name:   containerID,
+		image:  info.Image,
+		labels: info.Labels,
+		pid:    int(pid),
+	}
+	// the containers created by the CRI plugin are named after the kubernetes containers
+	if name, found := info.Labels[kubernetesContainerNameLabel]; found {
+		ctr.name = name
+	}
+	ctr.hosts, err = namespaceHosts(r.procRoot, ctr.pid)
+	if err != nil {
+		log.Debugf("Could not get the addresses of the container %s: %s", containerID, err)
+	}
+	imageConfig, err := r.util.ImageConfig(ctn)
+	if err != nil {
+		log.Debugf("Could not get the exposed ports of the container %s: %s", containerID, err)
+	}
+	ctr.ports = parseExposedPorts(imageConfig.ExposedPorts)
+	return ctr, nil
+}
+
+// subscribe streams the events of the tasks started and stopped
+func (r *containerdRuntime) subscribe() (<-chan struct{}, <-chan error, error) {
+	ns := r.util.Namespace()
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), ns))
+	envelopes, errs := r.util.GetEvents().Subscribe(ctx, cutil.TaskLifecycleFilters(ns)...)
+	r.cancel = cancel
+
+	events := make(chan struct{}, 1)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-envelopes:
+				if !ok {
+					return
+				}
+				select {
+				case events <- struct{}{}:
+				default:
+					// a refresh is already pending
+				}
+			}
+		}
+	}()
+	return events, errs, nil
+}
+
+func (r *containerdRuntime) unsubscribe() {
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
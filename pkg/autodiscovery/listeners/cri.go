@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build cri
+
+package listeners
+
+import (
+	"fmt"
+
+	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+
+	"github.com/DataDog/datadog-agent/pkg/util/containers/cri"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// criRuntime implements the containerRuntime interface for the runtimes
+// implementing the CRI, like CRI-O. The CRI doesn't stream events, the
+// runtime is polled.
+type criRuntime struct {
+	util       *cri.CRIUtil
+	procRoot   string
+	containers map[string]*pb.Container // the running containers of the last listing, by ID
+}
+
+func init() {
+	Register("cri", NewCRIListener)
+}
+
+// NewCRIListener creates a ContainerRuntimeListener for the containers of the CRI runtime
+func NewCRIListener() (ServiceListener, error) {
+	util, err := cri.GetUtil()
+	if err != nil {
+		return nil, err
+	}
+	runtime := &criRuntime{
+		util:       util,
+		procRoot:   procfsRoot(),
+		containers: make(map[string]*pb.Container),
+	}
+	return newContainerRuntimeListener(runtime, "ad-crilistener")
+}
+
+// runtimeName returns the name the runtime reports, cri-o or containerd
+func (r *criRuntime) runtimeName() string {
+	return r.util.Runtime
+}
+
+func (r *criRuntime) runningContainers() ([]string, error) {
+	ctns, err := r.util.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+	r.containers = make(map[string]*pb.Container, len(ctns))
+	ids := make([]string, 0, len(ctns))
+	for _, ctn := range ctns {
+		r.containers[ctn.GetId()] = ctn
+		ids = append(ids, ctn.GetId())
+	}
+	return ids, nil
+}
+
+// inspect returns a running container. Its address is the one of its pod, its
+// ports are the ones the kubelet annotates it with.
+func (r *criRuntime) inspect(containerID string) (*runtimeContainer, error) {
+	ctn, found := r.containers[containerID]
+	if !found {
+		return nil, fmt.Errorf("container %s is not running", containerID)
+	}
+	status, pid, err := r.util.GetContainerStatus(containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr := &runtimeContainer{
+		id:          containerID,
+		name:        ctn.GetMetadata().GetName(),
+		image:       status.GetImage().GetImage(),
+		labels:      status.GetLabels(),
+		annotations: status.GetAnnotations(),
+		ports:       parseKubernetesPorts(status.GetAnnotations()),
+		pid:         pid,
+	}
+	if ctr.annotations == nil {
+		// the CRI reports the annotations, a container may have none
+		ctr.annotations = map[string]string{}
+	}
+
+	sandbox, err := r.util.GetPodSandboxStatus(ctn.GetPodSandboxId())
+	if err != nil {
+		log.Debugf("Could not get the status of the pod of the container %s: %s", containerID, err)
+	} else if ip := sandbox.GetNetwork().GetIp(); ip != "" {
+		ctr.hosts = map[string]string{"pod": ip}
+	}
+	if len(ctr.hosts) == 0 && pid > 0 {
+		ctr.hosts, err = namespaceHosts(r.procRoot, pid)
+		if err != nil {
+			log.Debugf("Could not get the addresses of the container %s: %s", containerID, err)
+		}
+	}
+	return ctr, nil
+}
@@ -543,6 +543,50 @@ func (s *DockerService) GetHostname() (string, error) {
 	return s.hostname, nil
 }
 
+// GetContainerName returns the name of the container, without the leading slash
+func (s *DockerService) GetContainerName() (string, error) {
+	du, err := docker.GetDockerUtil()
+	if err != nil {
+		return "", err
+	}
+	cInspect, err := du.Inspect(s.cID, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container %s", s.cID[:12])
+	}
+	return strings.TrimPrefix(cInspect.Name, "/"), nil
+}
+
+// GetKubeNamespace returns nil and an error because the plain docker containers aren't in a pod
+func (s *DockerService) GetKubeNamespace() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetKubePodName returns nil and an error because the plain docker containers aren't in a pod
+func (s *DockerService) GetKubePodName() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetLabels returns the docker labels of the container
+func (s *DockerService) GetLabels() (map[string]string, error) {
+	du, err := docker.GetDockerUtil()
+	if err != nil {
+		return nil, err
+	}
+	cInspect, err := du.Inspect(s.cID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s", s.cID[:12])
+	}
+	if cInspect.Config == nil {
+		return nil, fmt.Errorf("invalid inspect for container %s", s.cID[:12])
+	}
+	return cInspect.Config.Labels, nil
+}
+
+// GetAnnotations returns nil and an error because the docker containers don't have annotations
+func (s *DockerService) GetAnnotations() (map[string]string, error) {
+	return nil, ErrNotSupported
+}
+
 // GetCreationTime returns the creation time of the container compare to the agent start.
 func (s *DockerService) GetCreationTime() integration.CreationTime {
 	return s.creationTime
@@ -96,6 +96,57 @@ func (s *DockerKubeletService) GetPorts() ([]ContainerPort, error) {
 	return ports, nil
 }
 
+// GetContainerName returns the name of the container in the pod spec
+func (s *DockerKubeletService) GetContainerName() (string, error) {
+	pod, err := s.getPod()
+	if err != nil {
+		return "", err
+	}
+	searchedId := s.GetEntity()
+	for _, container := range pod.Status.Containers {
+		if container.ID == searchedId {
+			return container.Name, nil
+		}
+	}
+	return "", fmt.Errorf("can't find container %s in pod %s", searchedId, pod.Metadata.Name)
+}
+
+// GetKubeNamespace returns the namespace of the pod of the container
+func (s *DockerKubeletService) GetKubeNamespace() (string, error) {
+	pod, err := s.getPod()
+	if err != nil {
+		return "", err
+	}
+	return pod.Metadata.Namespace, nil
+}
+
+// GetKubePodName returns the name of the pod of the container
+func (s *DockerKubeletService) GetKubePodName() (string, error) {
+	pod, err := s.getPod()
+	if err != nil {
+		return "", err
+	}
+	return pod.Metadata.Name, nil
+}
+
+// GetLabels returns the labels of the pod of the container
+func (s *DockerKubeletService) GetLabels() (map[string]string, error) {
+	pod, err := s.getPod()
+	if err != nil {
+		return nil, err
+	}
+	return pod.Metadata.Labels, nil
+}
+
+// GetAnnotations returns the annotations of the pod of the container
+func (s *DockerKubeletService) GetAnnotations() (map[string]string, error) {
+	pod, err := s.getPod()
+	if err != nil {
+		return nil, err
+	}
+	return pod.Metadata.Annotations, nil
+}
+
 // IsReady returns if the service is ready
 func (s *DockerKubeletService) IsReady() bool {
 	pod, err := s.getPod()
@@ -41,6 +41,8 @@ type ECSService struct {
 	ADIdentifiers []string
 	hosts         map[string]string
 	tags          []string
+	containerName string
+	labels        map[string]string
 	clusterName   string
 	taskFamily    string
 	taskVersion   string
@@ -156,12 +158,14 @@ func (l *ECSListener) createService(c ecs.Container, firstRun bool) (ECSService,
 		crTime = integration.After
 	}
 	svc := ECSService{
-		cID:          c.DockerID,
-		runtime:      containers.RuntimeNameDocker,
-		clusterName:  l.task.ClusterName,
-		taskFamily:   l.task.Family,
-		taskVersion:  l.task.Version,
-		creationTime: crTime,
+		cID:           c.DockerID,
+		runtime:       containers.RuntimeNameDocker,
+		containerName: c.Name,
+		labels:        c.Labels,
+		clusterName:   l.task.ClusterName,
+		taskFamily:    l.task.Family,
+		taskVersion:   l.task.Version,
+		creationTime:  crTime,
 	}
 
 	// ADIdentifiers
@@ -236,6 +240,31 @@ func (s *ECSService) GetHostname() (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetContainerName returns the name of the container in the task definition
+func (s *ECSService) GetContainerName() (string, error) {
+	return s.containerName, nil
+}
+
+// GetKubeNamespace returns nil and an error because ECS tasks aren't kubernetes pods
+func (s *ECSService) GetKubeNamespace() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetKubePodName returns nil and an error because ECS tasks aren't kubernetes pods
+func (s *ECSService) GetKubePodName() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetLabels returns the docker labels of the container
+func (s *ECSService) GetLabels() (map[string]string, error) {
+	return s.labels, nil
+}
+
+// GetAnnotations returns nil and an error because the ECS containers don't have annotations
+func (s *ECSService) GetAnnotations() (map[string]string, error) {
+	return nil, ErrNotSupported
+}
+
 // GetCreationTime returns the creation time of the container compare to the agent start.
 func (s *ECSService) GetCreationTime() integration.CreationTime {
 	return s.creationTime
@@ -43,6 +43,9 @@ type KubeServiceService struct {
 	tags         []string
 	hosts        map[string]string
 	ports        []ContainerPort
+	namespace    string
+	labels       map[string]string
+	annotations  map[string]string
 	creationTime integration.CreationTime
 }
 
@@ -182,6 +185,9 @@ func (l *KubeServiceListener) createService(ksvc *v1.Service, firstRun bool) {
 func processService(ksvc *v1.Service, firstRun bool) *KubeServiceService {
 	svc := &KubeServiceService{
 		entity:       apiserver.EntityForService(ksvc),
+		namespace:    ksvc.Namespace,
+		labels:       ksvc.Labels,
+		annotations:  ksvc.Annotations,
 		creationTime: integration.After,
 	}
 	if firstRun {
@@ -269,6 +275,31 @@ func (s *KubeServiceService) GetHostname() (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetContainerName returns nil and an error because a kubernetes service isn't a container
+func (s *KubeServiceService) GetContainerName() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetKubeNamespace returns the namespace of the service
+func (s *KubeServiceService) GetKubeNamespace() (string, error) {
+	return s.namespace, nil
+}
+
+// GetKubePodName returns nil and an error because a kubernetes service isn't a pod
+func (s *KubeServiceService) GetKubePodName() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetLabels returns the labels of the service
+func (s *KubeServiceService) GetLabels() (map[string]string, error) {
+	return s.labels, nil
+}
+
+// GetAnnotations returns the annotations of the service
+func (s *KubeServiceService) GetAnnotations() (map[string]string, error) {
+	return s.annotations, nil
+}
+
 // GetCreationTime returns the creation time of the service compare to the agent start.
 func (s *KubeServiceService) GetCreationTime() integration.CreationTime {
 	return s.creationTime
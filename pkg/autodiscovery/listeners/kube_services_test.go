@@ -29,6 +29,7 @@ func TestProcessService(t *testing.T) {
 				"ad.datadoghq.com/service.init_configs": "[{}]",
 				"ad.datadoghq.com/service.instances":    "[{\"name\": \"My service\", \"url\": \"http://%%host%%\", \"timeout\": 1}]",
 			},
+			Labels:    map[string]string{"app": "myapp"},
 			Name:      "myservice",
 			Namespace: "default",
 		},
@@ -61,6 +62,21 @@ func TestProcessService(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"kube_service:myservice", "kube_namespace:default"}, tags)
 
+	namespace, err := svc.GetKubeNamespace()
+	assert.NoError(t, err)
+	assert.Equal(t, "default", namespace)
+
+	labels, err := svc.GetLabels()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "myapp"}, labels)
+
+	annotations, err := svc.GetAnnotations()
+	assert.NoError(t, err)
+	assert.Equal(t, "[{}]", annotations["ad.datadoghq.com/service.init_configs"])
+
+	_, err = svc.GetKubePodName()
+	assert.Equal(t, ErrNotSupported, err)
+
 	svc = processService(ksvc, false)
 	assert.Equal(t, integration.After, svc.GetCreationTime())
 }
@@ -46,6 +46,11 @@ type KubeContainerService struct {
 	adIdentifiers []string
 	hosts         map[string]string
 	ports         []ContainerPort
+	containerName string
+	namespace     string
+	podName       string
+	labels        map[string]string
+	annotations   map[string]string
 	creationTime  integration.CreationTime
 	ready         bool
 }
@@ -57,6 +62,10 @@ type KubePodService struct {
 	adIdentifiers []string
 	hosts         map[string]string
 	ports         []ContainerPort
+	namespace     string
+	podName       string
+	labels        map[string]string
+	annotations   map[string]string
 	creationTime  integration.CreationTime
 }
 
@@ -179,6 +188,10 @@ func (l *KubeletListener) createPodService(pod *kubelet.Pod, firstRun bool) {
 		adIdentifiers: []string{entity},
 		hosts:         map[string]string{"pod": podIp},
 		ports:         ports,
+		namespace:     pod.Metadata.Namespace,
+		podName:       pod.Metadata.Name,
+		labels:        pod.Metadata.Labels,
+		annotations:   pod.Metadata.Annotations,
 		creationTime:  crTime,
 	}
 
@@ -198,6 +211,10 @@ func (l *KubeletListener) createService(entity string, pod *kubelet.Pod, firstRu
 	}
 	svc := KubeContainerService{
 		entity:       entity,
+		namespace:    pod.Metadata.Namespace,
+		podName:      pod.Metadata.Name,
+		labels:       pod.Metadata.Labels,
+		annotations:  pod.Metadata.Annotations,
 		creationTime: crTime,
 		ready:        kubelet.IsPodReady(pod),
 	}
@@ -212,6 +229,7 @@ func (l *KubeletListener) createService(entity string, pod *kubelet.Pod, firstRu
 				return
 			}
 			containerName = container.Name
+			svc.containerName = containerName
 
 			// Add container uid as ID
 			svc.adIdentifiers = append(svc.adIdentifiers, container.ID)
@@ -331,6 +349,31 @@ func (s *KubeContainerService) GetHostname() (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetContainerName returns the name of the container in the pod spec
+func (s *KubeContainerService) GetContainerName() (string, error) {
+	return s.containerName, nil
+}
+
+// GetKubeNamespace returns the namespace of the pod of the container
+func (s *KubeContainerService) GetKubeNamespace() (string, error) {
+	return s.namespace, nil
+}
+
+// GetKubePodName returns the name of the pod of the container
+func (s *KubeContainerService) GetKubePodName() (string, error) {
+	return s.podName, nil
+}
+
+// GetLabels returns the labels of the pod of the container
+func (s *KubeContainerService) GetLabels() (map[string]string, error) {
+	return s.labels, nil
+}
+
+// GetAnnotations returns the annotations of the pod of the container
+func (s *KubeContainerService) GetAnnotations() (map[string]string, error) {
+	return s.annotations, nil
+}
+
 // GetCreationTime returns the creation time of the container compare to the agent start.
 func (s *KubeContainerService) GetCreationTime() integration.CreationTime {
 	return s.creationTime
@@ -376,6 +419,31 @@ func (s *KubePodService) GetHostname() (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetContainerName returns nil and an error because a pod isn't a container
+func (s *KubePodService) GetContainerName() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetKubeNamespace returns the namespace of the pod
+func (s *KubePodService) GetKubeNamespace() (string, error) {
+	return s.namespace, nil
+}
+
+// GetKubePodName returns the name of the pod
+func (s *KubePodService) GetKubePodName() (string, error) {
+	return s.podName, nil
+}
+
+// GetLabels returns the labels of the pod
+func (s *KubePodService) GetLabels() (map[string]string, error) {
+	return s.labels, nil
+}
+
+// GetAnnotations returns the annotations of the pod
+func (s *KubePodService) GetAnnotations() (map[string]string, error) {
+	return s.annotations, nil
+}
+
 // GetCreationTime returns the creation time of the container compare to the agent start.
 func (s *KubePodService) GetCreationTime() integration.CreationTime {
 	return s.creationTime
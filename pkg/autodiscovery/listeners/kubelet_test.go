@@ -130,8 +130,10 @@ func getMockedPods() []*kubelet.Pod {
 			Spec:   kubeletSpec,
 			Status: kubeletStatus,
 			Metadata: kubelet.PodMetadata{
-				UID:  "mock-pod-uid",
-				Name: "mock-pod",
+				UID:       "mock-pod-uid",
+				Name:      "mock-pod",
+				Namespace: "mock-namespace",
+				Labels:    map[string]string{"app": "mock-app"},
 				Annotations: map[string]string{
 					"ad.datadoghq.com/baz.instances": "[]",
 				},
@@ -174,6 +176,21 @@ func TestProcessNewPod(t *testing.T) {
 		assert.Equal(t, []ContainerPort{{1337, "footcpport"}, {1339, "fooudpport"}}, ports)
 		_, err = service.GetPid()
 		assert.Equal(t, ErrNotSupported, err)
+		containerName, err := service.GetContainerName()
+		assert.Nil(t, err)
+		assert.Equal(t, "foo", containerName)
+		namespace, err := service.GetKubeNamespace()
+		assert.Nil(t, err)
+		assert.Equal(t, "mock-namespace", namespace)
+		podName, err := service.GetKubePodName()
+		assert.Nil(t, err)
+		assert.Equal(t, "mock-pod", podName)
+		labels, err := service.GetLabels()
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"app": "mock-app"}, labels)
+		annotations, err := service.GetAnnotations()
+		assert.Nil(t, err)
+		assert.Equal(t, "[]", annotations["ad.datadoghq.com/baz.instances"])
 	default:
 		assert.FailNow(t, "first service not in channel")
 	}
@@ -247,6 +264,11 @@ func TestProcessNewPod(t *testing.T) {
 		assert.Equal(t, []ContainerPort{{1122, "barport"}, {1122, "barport"}, {1122, "barport"}, {1122, "barport"}, {1337, "footcpport"}, {1339, "fooudpport"}}, ports)
 		_, err = service.GetPid()
 		assert.Equal(t, ErrNotSupported, err)
+		_, err = service.GetContainerName()
+		assert.Equal(t, ErrNotSupported, err)
+		podName, err := service.GetKubePodName()
+		assert.Nil(t, err)
+		assert.Equal(t, "mock-pod", podName)
 	default:
 		assert.FailNow(t, "pod service not in channel")
 	}
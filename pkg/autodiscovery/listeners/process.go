@@ -0,0 +1,482 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build linux
+
+package listeners
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/procmatch"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/procfs"
+)
+
+const (
+	processEntityPrefix = "process://"
+
+	// tcpListen is the state of the listening sockets in /proc/net/tcp
+	tcpListen = "0A"
+)
+
+// ProcessListener discovers the host processes matching a procmatch signature,
+// and their listening TCP ports, by polling procfs. The processes running in
+// containers are left to the container listeners.
+//
+// A service is identified by the signature, the pid and the start time of its
+// process. When the process binds or releases ports, its service is removed and
+// added again with its new ports, so that its templates are resolved again. A
+// process of the same signature as a running one, like a restarted one, isn't a
+// service until it listens on other ports or the former one exited, so that its
+// checks are never scheduled twice.
+type ProcessListener struct {
+	procRoot     string
+	cgroupPrefix string
+	matcher      procmatch.Matcher
+	processes    map[int]*hostProcess       // the processes of the last scan, by pid
+	services     map[string]*ProcessService // by signature, pid and start time
+	newService   chan<- Service
+	delService   chan<- Service
+	ticker       *time.Ticker
+	stop         chan bool
+	health       *health.Handle
+}
+
+// ProcessService implements and store results from the Service interface for the process listener
+type ProcessService struct {
+	pid           int
+	startTime     uint64
+	signature     string
+	adIdentifiers []string
+	hosts         map[string]string
+	ports         []ContainerPort
+	creationTime  integration.CreationTime
+}
+
+// hostProcess is a process of the host. Its signature is empty if it doesn't
+// match any, or if it runs in a container.
+type hostProcess struct {
+	pid       int
+	ppid      int
+	startTime uint64 // in clock ticks after boot, tells a process from a former one with the same pid
+	match     procmatch.Integration
+}
+
+func init() {
+	Register("process", NewProcessListener)
+}
+
+// NewProcessListener creates a ProcessListener
+func NewProcessListener() (ServiceListener, error) {
+	matcher, err := procmatch.NewDefault()
+	if err != nil {
+		return nil, err
+	}
+	return newProcessListener(procfsRoot(), matcher), nil
+}
+
+func newProcessListener(procRoot string, matcher procmatch.Matcher) *ProcessListener {
+	return &ProcessListener{
+		procRoot:     procRoot,
+		cgroupPrefix: config.Datadog.GetString("container_cgroup_prefix"),
+		matcher:      matcher,
+		processes:    make(map[int]*hostProcess),
+		services:     make(map[string]*ProcessService),
+		stop:         make(chan bool),
+	}
+}
+
+// Listen polls the processes regularly, and reports the ones matching a
+// signature as services
+func (l *ProcessListener) Listen(newSvc chan<- Service, delSvc chan<- Service) {
+	// setup the I/O channels
+	l.newService = newSvc
+	l.delService = delSvc
+	l.ticker = time.NewTicker(config.Datadog.GetDuration("process_listener_polling_interval") * time.Second)
+	l.health = health.Register("ad-processlistener")
+
+	go func() {
+		l.refreshServices(true)
+		for {
+			select {
+			case <-l.stop:
+				l.health.Deregister()
+				return
+			case <-l.health.C:
+			case <-l.ticker.C:
+				l.refreshServices(false)
+			}
+		}
+	}()
+}
+
+// Stop queues a shutdown of ProcessListener
+func (l *ProcessListener) Stop() {
+	l.ticker.Stop()
+	l.stop <- true
+}
+
+// refreshServices scans the processes, and sends the services removed and
+// added since the last scan, the removed ones first
+func (l *ProcessListener) refreshServices(firstRun bool) {
+	if err := l.scan(); err != nil {
+		log.Errorf("Could not list the processes, not refreshing services - %s", err)
+		return
+	}
+
+	// the services of the known processes, and the candidates for new ones. The
+	// workers forked by a process, matching the same signature, aren't services.
+	services := make(map[string]*ProcessService, len(l.services))
+	var candidates []*ProcessService
+	for _, p := range l.processes {
+		if p.match.Signature == "" {
+			continue
+		}
+		if parent, found := l.processes[p.ppid]; found && parent.match.Signature == p.match.Signature {
+			continue
+		}
+		key := serviceKey(p.match.Signature, p.pid, p.startTime)
+		if svc, found := l.services[key]; found {
+			services[key] = l.updateService(svc)
+			continue
+		}
+		candidates = append(candidates, l.createService(p, firstRun))
+	}
+
+	// a process of the same signature as a running one isn't a service until it
+	// listens on other ports: a restarted process may not have bound the ports of
+	// the former one yet
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].startTime < candidates[j].startTime })
+	for _, candidate := range candidates {
+		duplicate := false
+		for _, svc := range services {
+			if svc.signature == candidate.signature && (len(candidate.ports) == 0 || samePorts(svc.ports, candidate.ports)) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			services[serviceKey(candidate.signature, candidate.pid, candidate.startTime)] = candidate
+		}
+	}
+
+	for key, svc := range l.services {
+		if services[key] != svc {
+			log.Debugf("Process %d (%s) is not a service anymore", svc.pid, key)
+			l.delService <- svc
+		}
+	}
+	for key, svc := range services {
+		if l.services[key] != svc {
+			log.Debugf("Process %d (%s) is a new service", svc.pid, key)
+			l.newService <- svc
+		}
+	}
+	l.services = services
+}
+
+// serviceKey returns the key of a service, the signature, pid and start time of its process
+func serviceKey(signature string, pid int, startTime uint64) string {
+	return fmt.Sprintf("%s:%d:%d", signature, pid, startTime)
+}
+
+// samePorts returns whether two sorted lists of ports are the same
+func samePorts(a, b []ContainerPort) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Port != b[i].Port {
+			return false
+		}
+	}
+	return true
+}
+
+// scan lists the running processes. The processes already known aren't matched again.
+func (l *ProcessListener) scan() error {
+	dirs, err := ioutil.ReadDir(l.procRoot)
+	if err != nil {
+		return err
+	}
+	processes := make(map[int]*hostProcess, len(l.processes))
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil || !d.IsDir() {
+			continue
+		}
+		stat, err := procfs.ReadStat(l.path(pid, "stat"))
+		if err != nil {
+			// The process exited
+			continue
+		}
+		p, found := l.processes[pid]
+		if !found || p.startTime != stat.StartTime {
+			p = &hostProcess{pid: pid, startTime: stat.StartTime}
+			p.match = l.matchProcess(pid)
+		}
+		p.ppid = stat.PPid
+		processes[pid] = p
+	}
+	l.processes = processes
+	return nil
+}
+
+// matchProcess returns the integration matching the command line of a host process
+func (l *ProcessListener) matchProcess(pid int) procmatch.Integration {
+	data, err := ioutil.ReadFile(l.path(pid, "cmdline"))
+	if err != nil {
+		return procmatch.Integration{}
+	}
+	cmdline := strings.Replace(string(bytes.TrimRight(data, "\x00")), "\x00", " ", -1)
+	if cmdline == "" {
+		// kernel thread
+		return procmatch.Integration{}
+	}
+	match := l.matcher.Match(cmdline)
+	if match.Signature == "" {
+		return match
+	}
+	containerID, _, err := metrics.ReadCgroupsForPath(l.path(pid, "cgroup"), l.cgroupPrefix)
+	if err != nil || containerID != "" {
+		return procmatch.Integration{}
+	}
+	return match
+}
+
+func (l *ProcessListener) createService(p *hostProcess, firstRun bool) *ProcessService {
+	var crTime integration.CreationTime
+	if firstRun {
+		crTime = integration.Before
+	} else {
+		crTime = integration.After
+	}
+	svc := &ProcessService{
+		pid:           p.pid,
+		startTime:     p.startTime,
+		signature:     p.match.Signature,
+		adIdentifiers: []string{p.match.Signature},
+		creationTime:  crTime,
+	}
+	if p.match.Name != p.match.Signature {
+		svc.adIdentifiers = append(svc.adIdentifiers, p.match.Name)
+	}
+	svc.hosts, svc.ports = l.readAddresses(p.pid)
+	return svc
+}
+
+// updateService returns svc if its process still listens on the same addresses,
+// or a new service with the new addresses, replacing svc, if the process bound or
+// released ports since it was discovered
+func (l *ProcessListener) updateService(svc *ProcessService) *ProcessService {
+	hosts, ports := l.readAddresses(svc.pid)
+	if samePorts(svc.ports, ports) && hosts["host"] == svc.hosts["host"] {
+		return svc
+	}
+	log.Debugf("Process %d now listens on %v", svc.pid, ports)
+	updated := *svc
+	updated.hosts, updated.ports = hosts, ports
+	updated.creationTime = integration.After
+	return &updated
+}
+
+// readAddresses returns the host and the sorted ports a process listens on
+func (l *ProcessListener) readAddresses(pid int) (map[string]string, []ContainerPort) {
+	addresses, err := l.listeningAddresses(pid)
+	if err != nil {
+		log.Debugf("Could not get the listening ports of process %d: %s", pid, err)
+	}
+	hosts := make(map[string]string)
+	var ports []ContainerPort
+	for _, addr := range addresses {
+		if len(ports) == 0 || ports[len(ports)-1].Port != addr.Port {
+			ports = append(ports, ContainerPort{Port: addr.Port})
+		}
+		if _, found := hosts["host"]; found {
+			continue
+		}
+		// the processes listening on all the addresses are reached locally
+		if addr.IP.IsUnspecified() {
+			hosts["host"] = "127.0.0.1"
+		} else {
+			hosts["host"] = addr.IP.String()
+		}
+	}
+	return hosts, ports
+}
+
+// listeningAddresses returns the addresses of the listening TCP sockets of a
+// process, sorted by port. The sockets of a process are only readable by its
+// owner, or by root.
+func (l *ProcessListener) listeningAddresses(pid int) ([]net.TCPAddr, error) {
+	fds, err := ioutil.ReadDir(l.path(pid, "fd"))
+	if err != nil {
+		return nil, err
+	}
+	inodes := make(map[string]bool)
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(l.path(pid, "fd"), fd.Name()))
+		if err == nil && strings.HasPrefix(link, "socket:[") {
+			inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return nil, nil
+	}
+
+	var addresses []net.TCPAddr
+	// the sockets of the network namespace of the process
+	for _, file := range []string{"tcp", "tcp6"} {
+		addrs, err := readListeningSockets(l.path(pid, filepath.Join("net", file)), inodes)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		addresses = append(addresses, addrs...)
+	}
+	sort.SliceStable(addresses, func(i, j int) bool { return addresses[i].Port < addresses[j].Port })
+	return addresses, nil
+}
+
+func (l *ProcessListener) path(pid int, file string) string {
+	return filepath.Join(l.procRoot, strconv.Itoa(pid), file)
+}
+
+// readListeningSockets returns the addresses of the listening sockets of a
+// /proc/net/tcp file with the given inodes
+func readListeningSockets(path string, inodes map[string]bool) ([]net.TCPAddr, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var addresses []net.TCPAddr
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen || !inodes[fields[9]] {
+			continue
+		}
+		addr, err := parseSocketAddress(fields[1])
+		if err != nil {
+			log.Debugf("Invalid socket address in %s: %s", path, err)
+			continue
+		}
+		addresses = append(addresses, addr)
+	}
+	return addresses, scanner.Err()
+}
+
+// parseSocketAddress parses an address of /proc/net/tcp, an IP address made
+// of 32 bits words in host byte order, and a port, in hexadecimal
+func parseSocketAddress(s string) (net.TCPAddr, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return net.TCPAddr{}, fmt.Errorf("invalid address %q", s)
+	}
+	ip, err := hex.DecodeString(parts[0])
+	if err != nil || (len(ip) != net.IPv4len && len(ip) != net.IPv6len) {
+		return net.TCPAddr{}, fmt.Errorf("invalid address %q", s)
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return net.TCPAddr{}, fmt.Errorf("invalid address %q", s)
+	}
+	// the words are little endian on the architectures the agent supports
+	for i := 0; i < len(ip); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+	return net.TCPAddr{IP: net.IP(ip), Port: int(port)}, nil
+}
+
+// GetEntity returns the unique entity name linked to that service
+func (s *ProcessService) GetEntity() string {
+	return processEntityPrefix + strconv.Itoa(s.pid)
+}
+
+// GetADIdentifiers returns the signature matching the process, and the name
+// of its integration if different
+func (s *ProcessService) GetADIdentifiers() ([]string, error) {
+	return s.adIdentifiers, nil
+}
+
+// GetHosts returns the address the process listens on, the local one if it
+// listens on all the addresses
+func (s *ProcessService) GetHosts() (map[string]string, error) {
+	return s.hosts, nil
+}
+
+// GetPorts returns the TCP ports the process listens on
+func (s *ProcessService) GetPorts() ([]ContainerPort, error) {
+	return s.ports, nil
+}
+
+// GetTags returns no tags, the host tags are added to all the checks
+func (s *ProcessService) GetTags() ([]string, error) {
+	return []string{}, nil
+}
+
+// GetPid returns the pid of the process
+func (s *ProcessService) GetPid() (int, error) {
+	return s.pid, nil
+}
+
+// GetHostname returns nil and an error because the hostname of a host process is the one of the host
+func (s *ProcessService) GetHostname() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetContainerName returns nil and an error because a host process isn't a container
+func (s *ProcessService) GetContainerName() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetKubeNamespace returns nil and an error because a host process isn't in a pod
+func (s *ProcessService) GetKubeNamespace() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetKubePodName returns nil and an error because a host process isn't in a pod
+func (s *ProcessService) GetKubePodName() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetLabels returns nil and an error because the processes don't have labels
+func (s *ProcessService) GetLabels() (map[string]string, error) {
+	return nil, ErrNotSupported
+}
+
+// GetAnnotations returns nil and an error because the processes don't have annotations
+func (s *ProcessService) GetAnnotations() (map[string]string, error) {
+	return nil, ErrNotSupported
+}
+
+// GetCreationTime returns the creation time of the process compared to the agent start
+func (s *ProcessService) GetCreationTime() integration.CreationTime {
+	return s.creationTime
+}
+
+// IsReady returns true, the processes are services as soon as they're discovered
+func (s *ProcessService) IsReady() bool {
+	return true
+}
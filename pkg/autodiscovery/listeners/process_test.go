@@ -0,0 +1,282 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build linux
+
+package listeners
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/procmatch"
+)
+
+// mockMatcher matches the name of the executable of a command line
+type mockMatcher map[string]procmatch.Integration
+
+func (m mockMatcher) Match(cmdline string) procmatch.Integration {
+	return m[filepath.Base(strings.Fields(cmdline)[0])]
+}
+
+var testMatcher = mockMatcher{
+	"redis-server": {Name: "redisdb", Signature: "redis-server"},
+	"httpd":        {Name: "apache", Signature: "httpd"},
+	"postgres":     {Name: "postgres", Signature: "postgres"},
+}
+
+type fakeProcess struct {
+	pid       int
+	ppid      int
+	startTime uint64
+	cmdline   []string
+	cgroup    string
+	sockets   map[int]string // listening sockets, by inode
+}
+
+// writeFakeProcess writes the files of a process in a fake procfs
+func writeFakeProcess(t *testing.T, root string, p fakeProcess) {
+	dir := filepath.Join(root, strconv.Itoa(p.pid))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "fd"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "net"), 0755))
+
+	stat := fmt.Sprintf("%d (%s) S %d 0 0 0 -1 4194560 0 0 0 0 0 0 0 0 20 0 1 0 %d 0 0\n",
+		p.pid, filepath.Base(p.cmdline[0]), p.ppid, p.startTime)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644))
+	cmdline := strings.Join(p.cmdline, "\x00") + "\x00"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cgroup"), []byte(p.cgroup), 0644))
+
+	tcp := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	// a socket of another process
+	tcp += "   0: 0100007F:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 999 1 0000000000000000 100 0 0 10 0\n"
+	fd := 3
+	for inode, addr := range p.sockets {
+		tcp += fmt.Sprintf("   %d: %s 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 %d 1 0000000000000000 100 0 0 10 0\n", fd, addr, inode)
+		require.NoError(t, os.Symlink(fmt.Sprintf("socket:[%d]", inode), filepath.Join(dir, "fd", strconv.Itoa(fd))))
+		fd++
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "net", "tcp"), []byte(tcp), 0644))
+}
+
+// receiveServices returns the services sent to a channel
+func receiveServices(ch chan Service) []*ProcessService {
+	var services []*ProcessService
+	for {
+		select {
+		case svc := <-ch:
+			services = append(services, svc.(*ProcessService))
+		default:
+			return services
+		}
+	}
+}
+
+func TestProcessListenerRefreshServices(t *testing.T) {
+	root, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	redis := fakeProcess{
+		pid:       100,
+		ppid:      1,
+		startTime: 1000,
+		cmdline:   []string{"/usr/bin/redis-server", "*:6379"},
+		cgroup:    "1:name=systemd:/system.slice/redis.service\n",
+		sockets:   map[int]string{111: "00000000:18EB"},
+	}
+	writeFakeProcess(t, root, redis)
+	// an httpd server and its worker, sharing the listening socket
+	writeFakeProcess(t, root, fakeProcess{
+		pid:       200,
+		ppid:      1,
+		startTime: 1000,
+		cmdline:   []string{"/usr/sbin/httpd", "-DFOREGROUND"},
+		sockets:   map[int]string{222: "0100007F:0050"},
+	})
+	writeFakeProcess(t, root, fakeProcess{
+		pid:       201,
+		ppid:      200,
+		startTime: 1100,
+		cmdline:   []string{"/usr/sbin/httpd", "-DFOREGROUND"},
+		sockets:   map[int]string{222: "0100007F:0050"},
+	})
+	writeFakeProcess(t, root, fakeProcess{
+		pid:       300,
+		ppid:      1,
+		startTime: 1000,
+		cmdline:   []string{"/bin/bash"},
+	})
+	// the processes running in containers are left to the container listeners
+	writeFakeProcess(t, root, fakeProcess{
+		pid:       400,
+		ppid:      1,
+		startTime: 1000,
+		cmdline:   []string{"postgres", "-D", "/var/lib/postgresql/data"},
+		cgroup:    "1:name=systemd:/docker/3726184226f5d3147c25fdeab5b60097e378e8a720503a5e19ecfdf29f869860\n",
+		sockets:   map[int]string{444: "00000000:1538"},
+	})
+
+	newSvc := make(chan Service, 10)
+	delSvc := make(chan Service, 10)
+	l := newProcessListener(root, testMatcher)
+	l.newService = newSvc
+	l.delService = delSvc
+
+	l.refreshServices(true)
+	services := receiveServices(newSvc)
+	require.Len(t, services, 2)
+	if services[0].pid != 100 {
+		services[0], services[1] = services[1], services[0]
+	}
+	assert.Empty(t, receiveServices(delSvc))
+
+	svc := services[0]
+	assert.Equal(t, "process://100", svc.GetEntity())
+	ids, _ := svc.GetADIdentifiers()
+	assert.Equal(t, []string{"redis-server", "redisdb"}, ids)
+	hosts, _ := svc.GetHosts()
+	assert.Equal(t, map[string]string{"host": "127.0.0.1"}, hosts)
+	ports, _ := svc.GetPorts()
+	assert.Equal(t, []ContainerPort{{Port: 6379}}, ports)
+	pid, _ := svc.GetPid()
+	assert.Equal(t, 100, pid)
+	_, err = svc.GetHostname()
+	assert.Equal(t, ErrNotSupported, err)
+	assert.Equal(t, integration.Before, svc.GetCreationTime())
+	assert.True(t, svc.IsReady())
+
+	// the worker isn't a service
+	svc = services[1]
+	assert.Equal(t, "process://200", svc.GetEntity())
+	ids, _ = svc.GetADIdentifiers()
+	assert.Equal(t, []string{"httpd", "apache"}, ids)
+	ports, _ = svc.GetPorts()
+	assert.Equal(t, []ContainerPort{{Port: 80}}, ports)
+
+	// nothing changed
+	l.refreshServices(false)
+	assert.Empty(t, receiveServices(newSvc))
+	assert.Empty(t, receiveServices(delSvc))
+
+	// a restarted process isn't a service until the former one exited, even
+	// before it binds its ports
+	restarted := redis
+	restarted.pid = 150
+	restarted.startTime = 2000
+	restarted.sockets = nil
+	writeFakeProcess(t, root, restarted)
+	l.refreshServices(false)
+	assert.Empty(t, receiveServices(newSvc))
+	assert.Empty(t, receiveServices(delSvc))
+
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "150")))
+	restarted.sockets = redis.sockets
+	writeFakeProcess(t, root, restarted)
+	l.refreshServices(false)
+	assert.Empty(t, receiveServices(newSvc))
+	assert.Empty(t, receiveServices(delSvc))
+
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "100")))
+	l.refreshServices(false)
+	deleted := receiveServices(delSvc)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, "process://100", deleted[0].GetEntity())
+	services = receiveServices(newSvc)
+	require.Len(t, services, 1)
+	assert.Equal(t, "process://150", services[0].GetEntity())
+	assert.Equal(t, integration.After, services[0].GetCreationTime())
+
+	// a process listening on a new port is replaced by a service with its new ports
+	svc = services[0]
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "150")))
+	restarted.sockets = map[int]string{111: "00000000:18EB", 112: "00000000:18EC"}
+	writeFakeProcess(t, root, restarted)
+	l.refreshServices(false)
+	deleted = receiveServices(delSvc)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, svc, deleted[0])
+	services = receiveServices(newSvc)
+	require.Len(t, services, 1)
+	assert.Equal(t, "process://150", services[0].GetEntity())
+	ports, _ = services[0].GetPorts()
+	assert.Equal(t, []ContainerPort{{Port: 6379}, {Port: 6380}}, ports)
+
+	// a process binding its port after it was discovered is a service again once
+	// it listens, so that the templates using its port are resolved
+	postgres := fakeProcess{
+		pid:       500,
+		ppid:      1,
+		startTime: 3000,
+		cmdline:   []string{"postgres", "-D", "/var/lib/postgresql/data"},
+	}
+	writeFakeProcess(t, root, postgres)
+	l.refreshServices(false)
+	services = receiveServices(newSvc)
+	require.Len(t, services, 1)
+	svc = services[0]
+	assert.Equal(t, "process://500", svc.GetEntity())
+	ports, _ = svc.GetPorts()
+	assert.Empty(t, ports)
+
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "500")))
+	postgres.sockets = map[int]string{555: "0100007F:1538"}
+	writeFakeProcess(t, root, postgres)
+	l.refreshServices(false)
+	deleted = receiveServices(delSvc)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, svc, deleted[0])
+	services = receiveServices(newSvc)
+	require.Len(t, services, 1)
+	assert.Equal(t, "process://500", services[0].GetEntity())
+	ports, _ = services[0].GetPorts()
+	assert.Equal(t, []ContainerPort{{Port: 5432}}, ports)
+	hosts, _ = services[0].GetHosts()
+	assert.Equal(t, map[string]string{"host": "127.0.0.1"}, hosts)
+	assert.Equal(t, integration.After, services[0].GetCreationTime())
+
+	// a process exiting with its workers
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "200")))
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "201")))
+	l.refreshServices(false)
+	deleted = receiveServices(delSvc)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, "process://200", deleted[0].GetEntity())
+	assert.Empty(t, receiveServices(newSvc))
+}
+
+func TestParseSocketAddress(t *testing.T) {
+	for _, tc := range []struct {
+		addr string
+		ip   string
+		port int
+	}{
+		{"0100007F:18EB", "127.0.0.1", 6379},
+		{"00000000:0050", "0.0.0.0", 80},
+		{"00000000000000000000000001000000:1F90", "::1", 8080},
+		{"0000000000000000FFFF00000100007F:01BB", "127.0.0.1", 443},
+	} {
+		t.Run(tc.addr, func(t *testing.T) {
+			addr, err := parseSocketAddress(tc.addr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ip, addr.IP.String())
+			assert.Equal(t, tc.port, addr.Port)
+		})
+	}
+
+	for _, addr := range []string{"", "0100007F", "0100:18EB", "0100007F:FFFFF", "zz00007F:18EB"} {
+		_, err := parseSocketAddress(addr)
+		assert.Error(t, err, addr)
+	}
+}
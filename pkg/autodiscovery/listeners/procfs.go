@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listeners
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// procfsRoot returns the root of the procfs of the host, procfs_path if set
+func procfsRoot() string {
+	if config.Datadog.IsSet("procfs_path") {
+		return filepath.Clean(config.Datadog.GetString("procfs_path"))
+	}
+	return "/proc"
+}
+
+// namespaceHosts returns the IPv4 addresses of the network namespace of a
+// process, by interface. The addresses are the local ones of the fib_trie of
+// the namespace, their interface is the one of the route of their network.
+// The loopback addresses are left out.
+func namespaceHosts(procRoot string, pid int) (map[string]string, error) {
+	netDir := filepath.Join(procRoot, strconv.Itoa(pid), "net")
+	ips, err := readLocalAddresses(filepath.Join(netDir, "fib_trie"))
+	if err != nil {
+		return nil, err
+	}
+	routes, err := readRoutes(filepath.Join(netDir, "route"))
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make(map[string]string)
+	for _, ip := range ips {
+		// the most specific route
+		iface := "container"
+		longest := -1
+		for _, route := range routes {
+			if ones, _ := route.Mask.Size(); route.Contains(ip) && ones > longest {
+				iface = route.iface
+				longest = ones
+			}
+		}
+		if _, found := hosts[iface]; !found {
+			hosts[iface] = ip.String()
+		}
+	}
+	return hosts, nil
+}
+
+// readLocalAddresses returns the local IPv4 addresses listed in a fib_trie
+// file, sorted, in the lines following their address:
+//
+//      |-- 172.17.0.2
+//         /32 host LOCAL
+func readLocalAddresses(path string) ([]net.IP, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	var ips []net.IP
+	var last string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "|-- ") {
+			last = strings.TrimPrefix(line, "|-- ")
+			continue
+		}
+		if line != "/32 host LOCAL" || seen[last] {
+			continue
+		}
+		seen[last] = true
+		ip := net.ParseIP(last).To4()
+		if ip == nil || ip.IsLoopback() {
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		return binary.BigEndian.Uint32(ips[i]) < binary.BigEndian.Uint32(ips[j])
+	})
+	return ips, scanner.Err()
+}
+
+type route struct {
+	net.IPNet
+	iface string
+}
+
+// readRoutes returns the routes of a /proc/net/route file, except the default one
+func readRoutes(path string) ([]route, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var routes []route
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		dest, err := parseHexIPv4(fields[1])
+		if err != nil || dest.Equal(net.IPv4zero) {
+			continue
+		}
+		mask, err := parseHexIPv4(fields[7])
+		if err != nil {
+			continue
+		}
+		routes = append(routes, route{IPNet: net.IPNet{IP: dest, Mask: net.IPMask(mask)}, iface: fields[0]})
+	}
+	return routes, scanner.Err()
+}
+
+// parseHexIPv4 parses an IPv4 address of /proc/net/route, in hexadecimal and
+// host byte order
+func parseHexIPv4(s string) (net.IP, error) {
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	// the agent only supports little endian architectures
+	ip := make(net.IP, net.IPv4len)
+	binary.LittleEndian.PutUint32(ip, uint32(v))
+	return ip, nil
+}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package listeners
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFibTrie = `Main:
+  +-- 0.0.0.0/0 3 0 5
+     |-- 0.0.0.0
+        /0 universe UNICAST
+     +-- 127.0.0.0/8 2 0 2
+        +-- 127.0.0.0/31 1 0 0
+           |-- 127.0.0.0
+              /32 link BROADCAST
+              /8 host LOCAL
+           |-- 127.0.0.1
+              /32 host LOCAL
+     +-- 172.17.0.0/16 2 0 2
+        |-- 172.17.0.0
+           /16 link UNICAST
+        |-- 172.17.0.3
+           /32 host LOCAL
+        |-- 172.17.255.255
+           /32 link BROADCAST
+     |-- 192.168.10.7
+        /32 host LOCAL
+Local:
+  +-- 0.0.0.0/0 3 0 5
+     +-- 172.17.0.0/16 2 0 2
+        |-- 172.17.0.3
+           /32 host LOCAL
+     |-- 192.168.10.7
+        /32 host LOCAL
+`
+
+const testRoute = `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	010011AC	0003	0	0	0	00000000	0	0	0
+eth0	000011AC	00000000	0001	0	0	0	0000FFFF	0	0	0
+eth1	000AA8C0	00000000	0001	0	0	0	00FFFFFF	0	0	0
+`
+
+func TestNamespaceHosts(t *testing.T) {
+	root, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	netDir := filepath.Join(root, "42", "net")
+	require.NoError(t, os.MkdirAll(netDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(netDir, "fib_trie"), []byte(testFibTrie), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(netDir, "route"), []byte(testRoute), 0644))
+
+	hosts, err := namespaceHosts(root, 42)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"eth0": "172.17.0.3", "eth1": "192.168.10.7"}, hosts)
+
+	// an address without route
+	routes := strings.Join(strings.Split(testRoute, "\n")[:3], "\n")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(netDir, "route"), []byte(routes), 0644))
+	hosts, err = namespaceHosts(root, 42)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"eth0": "172.17.0.3", "container": "192.168.10.7"}, hosts)
+
+	_, err = namespaceHosts(root, 43)
+	assert.Error(t, err)
+}
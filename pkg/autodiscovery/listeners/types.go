@@ -25,15 +25,20 @@ type ContainerPort struct {
 // It should be matched with a check template by the ConfigResolver using the
 // ADIdentifiers field.
 type Service interface {
-	GetEntity() string                         // unique entity name
-	GetADIdentifiers() ([]string, error)       // identifiers on which templates will be matched
-	GetHosts() (map[string]string, error)      // network --> IP address
-	GetPorts() ([]ContainerPort, error)        // network ports
-	GetTags() ([]string, error)                // tags
-	GetPid() (int, error)                      // process identifier
-	GetHostname() (string, error)              // hostname.domainname for the entity
-	GetCreationTime() integration.CreationTime // created before or after the agent start
-	IsReady() bool                             // is the service ready
+	GetEntity() string                          // unique entity name
+	GetADIdentifiers() ([]string, error)        // identifiers on which templates will be matched
+	GetHosts() (map[string]string, error)       // network --> IP address
+	GetPorts() ([]ContainerPort, error)         // network ports
+	GetTags() ([]string, error)                 // tags
+	GetPid() (int, error)                       // process identifier
+	GetHostname() (string, error)               // hostname.domainname for the entity
+	GetContainerName() (string, error)          // name of the container
+	GetKubeNamespace() (string, error)          // kubernetes namespace of the entity
+	GetKubePodName() (string, error)            // name of the kubernetes pod of the entity
+	GetLabels() (map[string]string, error)      // labels of the entity
+	GetAnnotations() (map[string]string, error) // annotations of the entity
+	GetCreationTime() integration.CreationTime  // created before or after the agent start
+	IsReady() bool                              // is the service ready
 }
 
 // ServiceListener monitors running services and triggers check (un)scheduling
@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build containerd
+
+package providers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/containerd/containerd/namespaces"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+	cutil "github.com/DataDog/datadog-agent/pkg/util/containerd"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ContainerdConfigProvider implements the ConfigProvider interface for the labels of the containerd containers.
+type ContainerdConfigProvider struct {
+	sync.RWMutex
+	util      cutil.ContainerdItf
+	upToDate  bool
+	streaming bool
+	health    *health.Handle
+}
+
+// NewContainerdConfigProvider returns a new ConfigProvider connected to containerd.
+// Connectivity is not checked at this stage to allow for retries, Collect will do it.
+func NewContainerdConfigProvider(config config.ConfigurationProviders) (ConfigProvider, error) {
+	return &ContainerdConfigProvider{}, nil
+}
+
+// String returns a string representation of the ContainerdConfigProvider
+func (c *ContainerdConfigProvider) String() string {
+	return Containerd
+}
+
+// Collect retrieves all running containers and extract AD templates from their labels.
+func (c *ContainerdConfigProvider) Collect() ([]integration.Config, error) {
+	var err error
+	if c.util == nil {
+		c.util, err = cutil.GetContainerdUtil()
+		if err != nil {
+			return []integration.Config{}, err
+		}
+		go c.listen()
+	}
+
+	ctns, err := c.util.Containers()
+	if err != nil {
+		return []integration.Config{}, err
+	}
+
+	c.Lock()
+	c.upToDate = true
+	c.Unlock()
+
+	labels := make(map[string]map[string]string)
+	for _, ctn := range ctns {
+		if _, err := c.util.TaskPid(ctn); err != nil {
+			// not running
+			continue
+		}
+		info, err := c.util.Info(ctn)
+		if err != nil {
+			log.Debugf("Could not get the labels of the container %s: %s", ctn.ID(), err)
+			continue
+		}
+		labels[containers.BuildEntityName(containers.RuntimeNameContainerd, ctn.ID())] = info.Labels
+	}
+	return parseContainerLabels(labels), nil
+}
+
+// We listen to the task events and invalidate our cache when a task starts or stops
+func (c *ContainerdConfigProvider) listen() {
+	c.Lock()
+	c.streaming = true
+	c.health = health.Register("ad-containerdprovider")
+	c.Unlock()
+
+	ns := c.util.Namespace()
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), ns))
+	defer cancel()
+	envelopes, errs := c.util.GetEvents().Subscribe(ctx, cutil.TaskLifecycleFilters(ns)...)
+
+STREAM:
+	for {
+		select {
+		case <-c.health.C:
+		case _, ok := <-envelopes:
+			if !ok {
+				break STREAM
+			}
+			c.Lock()
+			c.upToDate = false
+			c.Unlock()
+		case err := <-errs:
+			// We disable streaming and revert to always-pull behaviour
+			log.Warnf("error getting containerd events: %s", err)
+			break STREAM
+		}
+	}
+
+	c.Lock()
+	c.streaming = false
+	c.health.Deregister()
+	c.Unlock()
+}
+
+// IsUpToDate checks whether we have new containers to parse, based on events received by the listen goroutine.
+// If listening fails, we fallback to Collecting everytime.
+func (c *ContainerdConfigProvider) IsUpToDate() (bool, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return (c.streaming && c.upToDate), nil
+}
+
+func init() {
+	RegisterProvider("containerd", NewContainerdConfigProvider)
+}
@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build cri
+
+package providers
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/containers/cri"
+)
+
+// CRIConfigProvider implements the ConfigProvider interface for the labels of
+// the containers of the CRI runtime. The CRI doesn't stream events, the
+// containers are listed on every collection.
+type CRIConfigProvider struct{}
+
+// NewCRIConfigProvider returns a new ConfigProvider connected to the CRI runtime.
+// Connectivity is not checked at this stage to allow for retries, Collect will do it.
+func NewCRIConfigProvider(config config.ConfigurationProviders) (ConfigProvider, error) {
+	return &CRIConfigProvider{}, nil
+}
+
+// String returns a string representation of the CRIConfigProvider
+func (c *CRIConfigProvider) String() string {
+	return CRI
+}
+
+// Collect retrieves all running containers and extract AD templates from their labels.
+func (c *CRIConfigProvider) Collect() ([]integration.Config, error) {
+	util, err := cri.GetUtil()
+	if err != nil {
+		return []integration.Config{}, err
+	}
+	ctns, err := util.ListContainers()
+	if err != nil {
+		return []integration.Config{}, err
+	}
+
+	labels := make(map[string]map[string]string, len(ctns))
+	for _, ctn := range ctns {
+		labels[containers.BuildEntityName(util.Runtime, ctn.GetId())] = ctn.GetLabels()
+	}
+	return parseContainerLabels(labels), nil
+}
+
+// IsUpToDate returns false, the containers are listed on every collection
+func (c *CRIConfigProvider) IsUpToDate() (bool, error) {
+	return false, nil
+}
+
+func init() {
+	RegisterProvider("cri", NewCRIConfigProvider)
+}
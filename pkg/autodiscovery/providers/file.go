@@ -11,6 +11,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
@@ -43,10 +45,27 @@ type configEntry struct {
 	err        error
 }
 
+// fileCacheEntry is the entry collected from a file, valid as long as the
+// modification time and size of the file don't change
+type fileCacheEntry struct {
+	modTime time.Time
+	size    int64
+	entry   configEntry
+	errMsg  string
+}
+
 // FileConfigProvider collect configuration files from disk
 type FileConfigProvider struct {
 	paths  []string
 	Errors map[string]string
+
+	m sync.Mutex
+	// cache holds the entries collected by the last Collect, by path, so that
+	// only the files modified since are parsed again
+	cache     map[string]fileCacheEntry
+	nextCache map[string]fileCacheEntry
+	// stopWatching stops the watch of the paths, nil when not watching
+	stopWatching chan struct{}
 }
 
 // NewFileConfigProvider creates a new FileConfigProvider searching for
@@ -55,6 +74,7 @@ func NewFileConfigProvider(paths []string) *FileConfigProvider {
 	return &FileConfigProvider{
 		paths:  paths,
 		Errors: make(map[string]string),
+		cache:  make(map[string]fileCacheEntry),
 	}
 }
 
@@ -62,6 +82,14 @@ func NewFileConfigProvider(paths []string) *FileConfigProvider {
 // it parses the files and try to unmarshall Yaml contents into a CheckConfig
 // instance
 func (c *FileConfigProvider) Collect() ([]integration.Config, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.nextCache = make(map[string]fileCacheEntry)
+	defer func() {
+		c.cache = c.nextCache
+		c.nextCache = nil
+	}()
+
 	configs := []integration.Config{}
 	configNames := make(map[string]struct{}) // use this map as a python set
 	defaultConfigs := []integration.Config{}
@@ -127,7 +155,8 @@ func (c *FileConfigProvider) Collect() ([]integration.Config, error) {
 	return configs, nil
 }
 
-// IsUpToDate is not implemented for the file Providers as the files are not meant to change very often.
+// IsUpToDate is not implemented for the file Providers, the unmodified files
+// aren't parsed again by Collect.
 func (c *FileConfigProvider) IsUpToDate() (bool, error) {
 	return false, nil
 }
@@ -174,12 +203,32 @@ func (c *FileConfigProvider) collectEntry(file os.FileInfo, path string, integra
 		return entry
 	}
 
+	// the files of the Kubernetes ConfigMaps are symlinks whose target changes,
+	// the cache is kept as long as the target is unchanged
+	modTime, size := file.ModTime(), file.Size()
+	if target, err := os.Stat(absPath); err == nil {
+		modTime, size = target.ModTime(), target.Size()
+	}
+	if cached, found := c.cache[absPath]; found && cached.modTime.Equal(modTime) && cached.size == size && cached.entry.name == integrationName {
+		c.nextCache[absPath] = cached
+		entry = cached.entry
+		if entry.err != nil {
+			c.Errors[integrationName] = cached.errMsg
+			return entry
+		}
+		// the instances are modified in place by the secrets decryption
+		entry.conf.Instances = append([]integration.Data(nil), entry.conf.Instances...)
+		delete(c.Errors, integrationName)
+		return entry
+	}
+
 	var err error
 	entry.conf, err = GetIntegrationConfigFromFile(integrationName, absPath)
 	if err != nil {
 		log.Warnf("%s is not a valid config file: %s", absPath, err)
 		c.Errors[integrationName] = err.Error()
 		entry.err = errors.New("Invalid config file format")
+		c.nextCache[absPath] = fileCacheEntry{modTime: modTime, size: size, entry: entry, errMsg: err.Error()}
 		return entry
 	}
 
@@ -188,11 +237,46 @@ func (c *FileConfigProvider) collectEntry(file os.FileInfo, path string, integra
 		entry.isLogsOnly = true
 	}
 
+	cached := fileCacheEntry{modTime: modTime, size: size, entry: entry}
+	cached.entry.conf.Instances = append([]integration.Data(nil), entry.conf.Instances...)
+	c.nextCache[absPath] = cached
+
 	delete(c.Errors, integrationName) // noop if entry is nonexistant
 	log.Debug("Found valid configuration in file:", absPath)
 	return entry
 }
 
+// GetErrors returns a copy of the errors of the invalid configuration files,
+// by check name
+func (c *FileConfigProvider) GetErrors() map[string]string {
+	c.m.Lock()
+	defer c.m.Unlock()
+	errors := make(map[string]string, len(c.Errors))
+	for name, err := range c.Errors {
+		errors[name] = err
+	}
+	return errors
+}
+
+// invalidate drops the cached entry of a file, to parse it again on the next
+// Collect even if its modification time and size didn't change
+func (c *FileConfigProvider) invalidate(path string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	delete(c.cache, path)
+}
+
+// invalidateDir drops the cached entries of the files of a directory
+func (c *FileConfigProvider) invalidateDir(dir string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	for path := range c.cache {
+		if filepath.Dir(path) == dir {
+			delete(c.cache, path)
+		}
+	}
+}
+
 // collectDir collects entries in subdirectories of the main conf folder
 func (c *FileConfigProvider) collectDir(parentPath string, folder os.FileInfo) configPkg {
 	configs := []integration.Config{}
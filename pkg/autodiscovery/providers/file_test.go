@@ -6,7 +6,11 @@
 package providers
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/stretchr/testify/assert"
@@ -125,3 +129,85 @@ func TestCollect(t *testing.T) {
 	// incorrect configs get saved in the Errors map (invalid.yaml & notaconfig.yaml & ad_deprecated.yaml)
 	assert.Equal(t, 3, len(provider.Errors))
 }
+
+func TestCollectCache(t *testing.T) {
+	var reads []string
+	defer func(readFile func(string) ([]byte, error)) { readFilePtr = readFile }(readFilePtr)
+	readFilePtr = func(path string) ([]byte, error) {
+		reads = append(reads, path)
+		return ioutil.ReadFile(path)
+	}
+
+	paths := []string{"tests", "foo/bar"}
+	provider := NewFileConfigProvider(paths)
+	configs, err := provider.Collect()
+	require.Nil(t, err)
+	assert.Equal(t, 14, len(configs))
+	assert.NotEmpty(t, reads)
+
+	// the unmodified files aren't read again, their errors are kept
+	reads = nil
+	cached, err := provider.Collect()
+	require.Nil(t, err)
+	assert.Empty(t, reads)
+	assert.Equal(t, configs, cached)
+	assert.Equal(t, 3, len(provider.Errors))
+
+	// the instances are modified in place by the secrets decryption, the
+	// cached ones are copies
+	for _, c := range cached {
+		for i := range c.Instances {
+			c.Instances[i] = integration.Data("modified")
+		}
+	}
+	cached, err = provider.Collect()
+	require.Nil(t, err)
+	assert.Equal(t, configs, cached)
+}
+
+func TestCollectCacheModifiedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conf.d")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "foo.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("instances:\n  - key: 1\n"), 0644))
+
+	provider := NewFileConfigProvider([]string{dir})
+	configs, err := provider.Collect()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "key: 1\n", string(configs[0].Instances[0]))
+
+	// a modification time change is enough to parse a file again
+	require.NoError(t, ioutil.WriteFile(path, []byte("instances:\n  - key: 2\n"), 0644))
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	configs, err = provider.Collect()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "key: 2\n", string(configs[0].Instances[0]))
+
+	// a removed file is dropped from the cache
+	require.NoError(t, os.Remove(path))
+	configs, err = provider.Collect()
+	require.NoError(t, err)
+	assert.Empty(t, configs)
+	assert.Empty(t, provider.cache)
+
+	// invalidated entries are parsed again even if unmodified
+	require.NoError(t, ioutil.WriteFile(path, []byte("instances:\n  - key: 3\n"), 0644))
+	_, err = provider.Collect()
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, []byte("instances:\n  - key: 4\n"), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	provider.cache[path] = fileCacheEntry{modTime: info.ModTime(), size: info.Size(), entry: provider.cache[path].entry}
+	configs, err = provider.Collect()
+	require.NoError(t, err)
+	assert.Equal(t, "key: 3\n", string(configs[0].Instances[0]))
+	provider.invalidate(path)
+	configs, err = provider.Collect()
+	require.NoError(t, err)
+	assert.Equal(t, "key: 4\n", string(configs[0].Instances[0]))
+}
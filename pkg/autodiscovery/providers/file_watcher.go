@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build !android
+
+package providers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// fileWatchDebounce is the time the configuration files must stay unchanged
+// before a change is notified, for tools writing several files in a row
+var fileWatchDebounce = time.Second
+
+// Watch watches the configuration paths and their check directories, and sends
+// to changes once the configuration files stopped changing. The paths that
+// don't exist aren't watched.
+func (c *FileConfigProvider) Watch(changes chan<- struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	roots := make(map[string]bool)
+	for _, path := range c.paths {
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			log.Debugf("Not watching the configuration path %q, it's not a directory", path)
+			continue
+		}
+		if err := watchConfigDir(watcher, path); err != nil {
+			watcher.Close()
+			return err
+		}
+		roots[filepath.Clean(path)] = true
+	}
+
+	stop := make(chan struct{})
+	c.m.Lock()
+	c.stopWatching = stop
+	c.m.Unlock()
+	go c.watch(watcher, roots, changes, stop)
+	return nil
+}
+
+// StopWatching stops watching the configuration paths
+func (c *FileConfigProvider) StopWatching() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.stopWatching != nil {
+		close(c.stopWatching)
+		c.stopWatching = nil
+	}
+}
+
+// watch handles the events of the watcher until stopped. An event on a
+// configuration file drops its cached entry, an event on another file drops
+// the entries of its directory, like the rename of the ..data symlink of a
+// Kubernetes ConfigMap, and any event delays the notification of the change.
+func (c *FileConfigProvider) watch(watcher *fsnotify.Watcher, roots map[string]bool, changes chan<- struct{}, stop <-chan struct{}) {
+	defer watcher.Close()
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case event := <-watcher.Events:
+			if event.Op&^fsnotify.Chmod == 0 {
+				continue
+			}
+			name := filepath.Base(event.Name)
+			isCheckDir := roots[filepath.Dir(event.Name)] && filepath.Ext(name) == ".d"
+			log.Tracef("Configuration file event: %s", event)
+			if isCheckDir {
+				if event.Op&fsnotify.Create != 0 {
+					// the files created before the watch is added are collected anyway
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						if err := watcher.Add(event.Name); err != nil {
+							log.Warnf("Could not watch the configuration directory %s, its changes will be collected on the next change of another file: %s", event.Name, err)
+						}
+					}
+				} else if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+					watcher.Remove(event.Name) // noop if the watch is already gone
+				}
+			} else if isConfigFileName(name) {
+				c.invalidate(filepath.Clean(event.Name))
+			} else {
+				c.invalidateDir(filepath.Dir(filepath.Clean(event.Name)))
+			}
+			debounce = time.After(fileWatchDebounce)
+		case err := <-watcher.Errors:
+			// events may have been missed, parse all the files again
+			log.Warnf("Error watching the configuration files: %s", err)
+			c.m.Lock()
+			c.cache = make(map[string]fileCacheEntry)
+			c.m.Unlock()
+			debounce = time.After(fileWatchDebounce)
+		case <-debounce:
+			debounce = nil
+			select {
+			case changes <- struct{}{}:
+			default:
+				// a change is already pending
+			}
+		}
+	}
+}
+
+// watchConfigDir watches a configuration path and its check directories
+func watchConfigDir(watcher *fsnotify.Watcher, path string) error {
+	if err := watcher.Add(path); err != nil {
+		return fmt.Errorf("could not watch %s: %s", path, err)
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || filepath.Ext(entry.Name()) != ".d" {
+			continue
+		}
+		dirPath := filepath.Join(path, entry.Name())
+		if err := watcher.Add(dirPath); err != nil {
+			return fmt.Errorf("could not watch %s: %s", dirPath, err)
+		}
+	}
+	return nil
+}
+
+// isConfigFileName returns whether the file is collected, or a default one
+func isConfigFileName(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".default":
+		return true
+	}
+	return false
+}
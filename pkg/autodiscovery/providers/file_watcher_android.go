@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build android
+
+package providers
+
+import (
+	"errors"
+)
+
+// Watch isn't supported on Android, the configuration files are assets
+func (c *FileConfigProvider) Watch(changes chan<- struct{}) error {
+	return errors.New("the configuration files can't be watched on Android")
+}
+
+// StopWatching is a noop on Android
+func (c *FileConfigProvider) StopWatching() {}
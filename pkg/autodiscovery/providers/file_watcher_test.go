@@ -0,0 +1,206 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build !android
+
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+)
+
+// writeFileAtomically writes a file the way config management tools do, in a
+// temporary file renamed to the path
+func writeFileAtomically(t *testing.T, path, content string) {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	require.NoError(t, ioutil.WriteFile(tmp, []byte(content), 0644))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+// collectUntil waits for changes until the collected configs satisfy cond
+func collectUntil(t *testing.T, provider *FileConfigProvider, changes <-chan struct{}, cond func([]integration.Config) bool) []integration.Config {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case <-changes:
+		case <-timeout:
+			require.FailNow(t, "timeout waiting for a configuration change")
+		}
+		configs, err := provider.Collect()
+		require.NoError(t, err)
+		if cond(configs) {
+			return configs
+		}
+	}
+}
+
+func findConfig(configs []integration.Config, name string) *integration.Config {
+	for i := range configs {
+		if configs[i].Name == name {
+			return &configs[i]
+		}
+	}
+	return nil
+}
+
+func TestFileConfigProviderWatch(t *testing.T) {
+	fileWatchDebounce = 50 * time.Millisecond
+	defer func() { fileWatchDebounce = time.Second }()
+
+	dir, err := ioutil.TempDir("", "conf.d")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	provider := NewFileConfigProvider([]string{dir, filepath.Join(dir, "missing")})
+	configs, err := provider.Collect()
+	require.NoError(t, err)
+	assert.Empty(t, configs)
+
+	changes := make(chan struct{}, 1)
+	require.NoError(t, provider.Watch(changes))
+	defer provider.StopWatching()
+
+	// a configuration file written in the root directory
+	writeFileAtomically(t, filepath.Join(dir, "foo.yaml"), "instances:\n  - key: 1\n")
+	configs = collectUntil(t, provider, changes, func(configs []integration.Config) bool {
+		return findConfig(configs, "foo") != nil
+	})
+	assert.Len(t, configs, 1)
+
+	// a check directory created with a configuration file
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "bar.d"), 0755))
+	writeFileAtomically(t, filepath.Join(dir, "bar.d", "conf.yaml"), "instances:\n  - key: 1\n")
+	collectUntil(t, provider, changes, func(configs []integration.Config) bool {
+		return findConfig(configs, "bar") != nil
+	})
+
+	// a file replaced with the same size and modification time is parsed again
+	path := filepath.Join(dir, "bar.d", "conf.yaml")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	writeFileAtomically(t, path, "instances:\n  - key: 2\n")
+	require.NoError(t, os.Chtimes(path, info.ModTime(), info.ModTime()))
+	collectUntil(t, provider, changes, func(configs []integration.Config) bool {
+		bar := findConfig(configs, "bar")
+		return bar != nil && len(bar.Instances) == 1 && string(bar.Instances[0]) == "key: 2\n"
+	})
+
+	// other files may be symlinks of the configuration files
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bar.d", "notes.txt"), []byte("notes"), 0644))
+	collectUntil(t, provider, changes, func(configs []integration.Config) bool {
+		return findConfig(configs, "bar") != nil
+	})
+
+	// a removed check directory
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "bar.d")))
+	configs = collectUntil(t, provider, changes, func(configs []integration.Config) bool {
+		return findConfig(configs, "bar") == nil
+	})
+	assert.Len(t, configs, 1)
+
+	// no notification once stopped
+	provider.StopWatching()
+	require.NoError(t, os.Remove(filepath.Join(dir, "foo.yaml")))
+	select {
+	case <-changes:
+		assert.Fail(t, "unexpected change notification")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// writeConfigMap writes the files of a Kubernetes ConfigMap volume the way the
+// kubelet does: in a new directory, then switched to by renaming the ..data symlink
+func writeConfigMap(t *testing.T, dir, version string, files map[string]string) {
+	versionDir := filepath.Join(dir, ".."+version)
+	require.NoError(t, os.Mkdir(versionDir, 0755))
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(versionDir, name), []byte(content), 0644))
+		// the modification time doesn't help detecting the change
+		require.NoError(t, os.Chtimes(filepath.Join(versionDir, name), time.Unix(0, 0), time.Unix(0, 0)))
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			require.NoError(t, os.Symlink(filepath.Join("..data", name), link))
+		}
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	require.NoError(t, os.Symlink(filepath.Base(versionDir), tmp))
+	require.NoError(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+}
+
+func TestFileConfigProviderWatchConfigMap(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Kubernetes ConfigMaps are mounted on Linux")
+	}
+	fileWatchDebounce = 50 * time.Millisecond
+	defer func() { fileWatchDebounce = time.Second }()
+
+	dir, err := ioutil.TempDir("", "conf.d")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	checkDir := filepath.Join(dir, "foo.d")
+	require.NoError(t, os.Mkdir(checkDir, 0755))
+	writeConfigMap(t, checkDir, "v1", map[string]string{"conf.yaml": "instances:\n  - key: 1\n"})
+
+	provider := NewFileConfigProvider([]string{dir})
+	configs, err := provider.Collect()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "key: 1\n", string(configs[0].Instances[0]))
+
+	changes := make(chan struct{}, 1)
+	require.NoError(t, provider.Watch(changes))
+	defer provider.StopWatching()
+
+	// only the ..data symlink changes, the file has the same size and modification time
+	writeConfigMap(t, checkDir, "v2", map[string]string{"conf.yaml": "instances:\n  - key: 2\n"})
+	collectUntil(t, provider, changes, func(configs []integration.Config) bool {
+		foo := findConfig(configs, "foo")
+		return foo != nil && len(foo.Instances) == 1 && string(foo.Instances[0]) == "key: 2\n"
+	})
+}
+
+func TestFileConfigProviderWatchDebounce(t *testing.T) {
+	fileWatchDebounce = 200 * time.Millisecond
+	defer func() { fileWatchDebounce = time.Second }()
+
+	dir, err := ioutil.TempDir("", "conf.d")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	provider := NewFileConfigProvider([]string{dir})
+	changes := make(chan struct{}, 1)
+	require.NoError(t, provider.Watch(changes))
+	defer provider.StopWatching()
+
+	for _, name := range []string{"foo.yaml", "bar.yaml", "baz.yaml"} {
+		writeFileAtomically(t, filepath.Join(dir, name), "instances:\n  - key: 1\n")
+		time.Sleep(50 * time.Millisecond)
+	}
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for a configuration change")
+	}
+	configs, err := provider.Collect()
+	require.NoError(t, err)
+	assert.Len(t, configs, 3)
+
+	// the files written in a row are notified once
+	select {
+	case <-changes:
+		assert.Fail(t, "unexpected change notification")
+	case <-time.After(400 * time.Millisecond):
+	}
+}
@@ -14,6 +14,8 @@ import (
 const (
 	Consul          = "consul"
 	ClusterChecks   = "cluster-checks"
+	Containerd      = "containerd"
+	CRI             = "cri"
 	Docker          = "docker"
 	ECS             = "ecs"
 	EndpointsChecks = "endpoints-checks"
@@ -21,6 +23,7 @@ const (
 	File            = "file"
 	Kubernetes      = "kubernetes"
 	KubeServices    = "kubernetes-services"
+	SNMP            = "snmp"
 	Zookeeper       = "zookeeper"
 )
 
@@ -63,3 +66,15 @@ type ConfigProvider interface {
 	String() string
 	IsUpToDate() (bool, error)
 }
+
+// WatchingConfigProvider is implemented by the config providers able to watch
+// their source, to be collected whenever it changes rather than polled.
+//
+// Watch starts watching, and sends to changes when the configurations may have
+// changed. It returns an error when the source can't be watched.
+// StopWatching stops watching.
+type WatchingConfigProvider interface {
+	ConfigProvider
+	Watch(changes chan<- struct{}) error
+	StopWatching()
+}
@@ -0,0 +1,343 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package providers
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/snmp"
+)
+
+const (
+	snmpCheckName = "snmp_core"
+
+	// maxSubnetSize is the largest number of addresses scanned in a network
+	maxSubnetSize = 65536
+)
+
+// sysObjectIDOID is polled to discover the devices, all SNMP agents expose it
+var sysObjectIDOID = snmp.OID{1, 3, 6, 1, 2, 1, 1, 2, 0}
+
+// snmpSubnetConfig is the configuration of a network to scan. Its parameters but
+// the network and the ignored addresses are the ones of the checks of the devices.
+type snmpSubnetConfig struct {
+	Network            string   `mapstructure:"network" yaml:"-"`
+	IgnoredIPAddresses []string `mapstructure:"ignored_ip_addresses" yaml:"-"`
+	Port               uint16   `mapstructure:"port" yaml:"port,omitempty"`
+	SNMPVersion        int      `mapstructure:"snmp_version" yaml:"snmp_version,omitempty"`
+	CommunityString    string   `mapstructure:"community_string" yaml:"community_string,omitempty"`
+	User               string   `mapstructure:"user" yaml:"user,omitempty"`
+	AuthProtocol       string   `mapstructure:"auth_protocol" yaml:"auth_protocol,omitempty"`
+	AuthKey            string   `mapstructure:"auth_key" yaml:"auth_key,omitempty"`
+	PrivProtocol       string   `mapstructure:"priv_protocol" yaml:"priv_protocol,omitempty"`
+	PrivKey            string   `mapstructure:"priv_key" yaml:"priv_key,omitempty"`
+	ContextName        string   `mapstructure:"context_name" yaml:"context_name,omitempty"`
+	Timeout            int      `mapstructure:"timeout" yaml:"timeout,omitempty"`
+	Retries            *int     `mapstructure:"retries" yaml:"retries,omitempty"`
+	Profile            string   `mapstructure:"profile" yaml:"profile,omitempty"`
+	Tags               []string `mapstructure:"tags" yaml:"tags,omitempty"`
+}
+
+type snmpInstance struct {
+	IPAddress        string `yaml:"ip_address"`
+	snmpSubnetConfig `yaml:",inline"`
+}
+
+type snmpSubnet struct {
+	config  snmpSubnetConfig
+	network *net.IPNet
+	ignored map[string]bool
+	client  snmp.Client
+	// devices holds the number of consecutive discoveries that missed the devices
+	// found in the network, by IP address
+	devices map[string]int
+}
+
+// SNMPConfigProvider implements the ConfigProvider interface. It scans networks
+// for SNMP devices in the background, and schedules an snmp_core check for every
+// device found.
+type SNMPConfigProvider struct {
+	subnets         []*snmpSubnet
+	interval        time.Duration
+	workers         int
+	allowedFailures int
+
+	m        sync.Mutex
+	upToDate bool
+}
+
+// NewSNMPConfigProvider returns a new SNMPConfigProvider scanning the networks
+// set in snmp_autodiscovery. The first discovery starts right away.
+func NewSNMPConfigProvider(cfg config.ConfigurationProviders) (ConfigProvider, error) {
+	var configs []snmpSubnetConfig
+	if err := config.Datadog.UnmarshalKey("snmp_autodiscovery.configs", &configs); err != nil {
+		return nil, fmt.Errorf("could not read snmp_autodiscovery.configs: %s", err)
+	}
+	p, err := newSNMPConfigProvider(configs)
+	if err != nil {
+		return nil, err
+	}
+	p.interval = time.Duration(config.Datadog.GetInt("snmp_autodiscovery.discovery_interval")) * time.Second
+	p.workers = config.Datadog.GetInt("snmp_autodiscovery.workers")
+	p.allowedFailures = config.Datadog.GetInt("snmp_autodiscovery.allowed_failures")
+	if p.interval <= 0 {
+		return nil, fmt.Errorf("invalid snmp_autodiscovery.discovery_interval %v", p.interval)
+	}
+	if p.workers <= 0 {
+		p.workers = 1
+	}
+	go p.run()
+	return p, nil
+}
+
+func newSNMPConfigProvider(configs []snmpSubnetConfig) (*SNMPConfigProvider, error) {
+	p := &SNMPConfigProvider{workers: 1}
+	for _, cfg := range configs {
+		subnet, err := newSNMPSubnet(cfg)
+		if err != nil {
+			return nil, err
+		}
+		p.subnets = append(p.subnets, subnet)
+	}
+	return p, nil
+}
+
+func newSNMPSubnet(cfg snmpSubnetConfig) (*snmpSubnet, error) {
+	_, network, err := net.ParseCIDR(cfg.Network)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNMP autodiscovery network %q: %s", cfg.Network, err)
+	}
+	ones, bits := network.Mask.Size()
+	if bits-ones > 16 {
+		return nil, fmt.Errorf("the SNMP autodiscovery network %s is too large, it can have at most %d addresses", cfg.Network, maxSubnetSize)
+	}
+
+	s := &snmpSubnet{
+		config:  cfg,
+		network: network,
+		ignored: make(map[string]bool),
+		devices: make(map[string]int),
+		client: snmp.Client{
+			Port:        cfg.Port,
+			Community:   cfg.CommunityString,
+			ContextName: cfg.ContextName,
+			Timeout:     time.Duration(cfg.Timeout) * time.Second,
+		},
+	}
+	for _, ip := range cfg.IgnoredIPAddresses {
+		s.ignored[ip] = true
+	}
+	if cfg.Timeout <= 0 {
+		s.client.Timeout = time.Second
+	}
+	// the probes aren't retried unless set, the checks use their own default
+	if cfg.Retries != nil {
+		s.client.Retries = *cfg.Retries
+	}
+
+	switch cfg.SNMPVersion {
+	case 1:
+		s.client.Version = snmp.Version1
+	case 0, 2:
+		s.client.Version = snmp.Version2c
+	case 3:
+		s.client.Version = snmp.Version3
+		authProtocol, err := snmp.ParseAuthProtocol(cfg.AuthProtocol)
+		if err != nil {
+			return nil, err
+		}
+		privProtocol, err := snmp.ParsePrivProtocol(cfg.PrivProtocol)
+		if err != nil {
+			return nil, err
+		}
+		// same defaults as the check
+		if authProtocol == snmp.NoAuth && cfg.AuthKey != "" {
+			authProtocol = snmp.MD5
+		}
+		if privProtocol == snmp.NoPriv && cfg.PrivKey != "" {
+			privProtocol = snmp.DES
+		}
+		s.client.User = snmp.User{
+			Name:           cfg.User,
+			AuthProtocol:   authProtocol,
+			AuthPassphrase: cfg.AuthKey,
+			PrivProtocol:   privProtocol,
+			PrivPassphrase: cfg.PrivKey,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported snmp_version %d in the SNMP autodiscovery of %s", cfg.SNMPVersion, cfg.Network)
+	}
+	return s, nil
+}
+
+// String returns a string representation of the SNMPConfigProvider
+func (p *SNMPConfigProvider) String() string {
+	return SNMP
+}
+
+// IsUpToDate returns whether the devices found by the discoveries changed since
+// the last Collect
+func (p *SNMPConfigProvider) IsUpToDate() (bool, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.upToDate, nil
+}
+
+// Collect returns a check configuration for every device found
+func (p *SNMPConfigProvider) Collect() ([]integration.Config, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	var configs []integration.Config
+	for _, s := range p.subnets {
+		ips := make([]string, 0, len(s.devices))
+		for ip := range s.devices {
+			ips = append(ips, ip)
+		}
+		sort.Strings(ips)
+		for _, ip := range ips {
+			instance := snmpInstance{IPAddress: ip, snmpSubnetConfig: s.config}
+			instance.Tags = append([]string{"autodiscovery_subnet:" + s.config.Network}, s.config.Tags...)
+			data, err := yaml.Marshal(instance)
+			if err != nil {
+				return nil, err
+			}
+			configs = append(configs, integration.Config{
+				Name:       snmpCheckName,
+				Instances:  []integration.Data{data},
+				InitConfig: integration.Data("{}"),
+			})
+		}
+	}
+	p.upToDate = true
+	return configs, nil
+}
+
+// run discovers the devices periodically.
+func (p *SNMPConfigProvider) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.discover()
+		<-ticker.C
+	}
+}
+
+// discover scans the networks, and updates their devices. A device is removed
+// once missed by more consecutive discoveries than allowed.
+func (p *SNMPConfigProvider) discover() {
+	for _, s := range p.subnets {
+		start := time.Now()
+		found := s.scan(p.workers)
+		log.Debugf("SNMP autodiscovery found %d devices in %s in %s", len(found), s.config.Network, time.Since(start))
+
+		p.m.Lock()
+		for ip, failures := range s.devices {
+			if found[ip] {
+				continue
+			}
+			if failures >= p.allowedFailures {
+				log.Infof("SNMP device %s doesn't answer anymore, its check is unscheduled", ip)
+				delete(s.devices, ip)
+				p.upToDate = false
+				continue
+			}
+			s.devices[ip] = failures + 1
+		}
+		for ip := range found {
+			if _, ok := s.devices[ip]; !ok {
+				log.Infof("Discovered SNMP device %s in %s", ip, s.config.Network)
+				p.upToDate = false
+			}
+			s.devices[ip] = 0
+		}
+		p.m.Unlock()
+	}
+}
+
+// scan probes the addresses of the network with the given number of concurrent
+// workers, and returns the addresses of the devices that answered.
+func (s *snmpSubnet) scan(workers int) map[string]bool {
+	ips := make(chan string)
+	var m sync.Mutex
+	var wg sync.WaitGroup
+	found := make(map[string]bool)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range ips {
+				if s.probe(ip) {
+					m.Lock()
+					found[ip] = true
+					m.Unlock()
+				}
+			}
+		}()
+	}
+
+	ones, bits := s.network.Mask.Size()
+	ip := append(net.IP(nil), s.network.IP...)
+	for {
+		// the network and broadcast addresses of IPv4 networks aren't devices
+		isEdge := bits == 32 && ones < 31 && (ip.Equal(s.network.IP) || isBroadcast(ip, s.network))
+		if !isEdge && !s.ignored[ip.String()] {
+			ips <- ip.String()
+		}
+		ip = nextIP(ip)
+		if !s.network.Contains(ip) {
+			break
+		}
+	}
+	close(ips)
+	wg.Wait()
+	return found
+}
+
+// probe returns whether an SNMP agent answers at the given address.
+func (s *snmpSubnet) probe(ip string) bool {
+	client := s.client
+	client.Target = ip
+	if err := client.Connect(); err != nil {
+		return false
+	}
+	defer client.Close()
+	variables, err := client.Get([]snmp.OID{sysObjectIDOID})
+	return err == nil && len(variables) == 1 && !variables[0].IsException()
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := append(net.IP(nil), ip...)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+func isBroadcast(ip net.IP, network *net.IPNet) bool {
+	for i := range ip {
+		if ip[i]|network.Mask[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	RegisterProvider("snmp", NewSNMPConfigProvider)
+}
@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/snmp"
+	"github.com/DataDog/datadog-agent/pkg/util/snmp/snmptest"
+)
+
+func TestSNMPDiscovery(t *testing.T) {
+	agent := snmptest.NewAgent([]snmp.Variable{
+		{OID: snmp.OID{1, 3, 6, 1, 2, 1, 1, 2, 0}, Type: snmp.TagOID, Value: snmp.OID{1, 3, 6, 1, 4, 1, 8072, 3, 2, 10}},
+	})
+	require.NoError(t, agent.Start())
+
+	retries := 0
+	p, err := newSNMPConfigProvider([]snmpSubnetConfig{
+		{
+			// 127.0.0.0 and 127.0.0.3 aren't probed, nothing listens on 127.0.0.2
+			Network:         "127.0.0.0/30",
+			Port:            agent.Port(),
+			CommunityString: "public",
+			Retries:         &retries,
+			Tags:            []string{"env:test"},
+		},
+		{
+			Network:            "127.0.0.1/32",
+			IgnoredIPAddresses: []string{"127.0.0.1"},
+			Port:               agent.Port(),
+			CommunityString:    "public",
+		},
+		{
+			Network:         "127.0.0.1/32",
+			Port:            agent.Port(),
+			CommunityString: "private",
+			Retries:         &retries,
+		},
+	})
+	require.NoError(t, err)
+	p.allowedFailures = 1
+
+	upToDate, err := p.IsUpToDate()
+	require.NoError(t, err)
+	assert.False(t, upToDate)
+	configs, err := p.Collect()
+	require.NoError(t, err)
+	assert.Empty(t, configs)
+	upToDate, _ = p.IsUpToDate()
+	assert.True(t, upToDate)
+
+	p.discover()
+	upToDate, _ = p.IsUpToDate()
+	assert.False(t, upToDate)
+	configs, err = p.Collect()
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "snmp_core", configs[0].Name)
+	assert.Empty(t, configs[0].ADIdentifiers)
+	require.Len(t, configs[0].Instances, 1)
+	assert.YAMLEq(t, fmt.Sprintf(`
+ip_address: 127.0.0.1
+port: %d
+community_string: public
+retries: 0
+tags:
+  - autodiscovery_subnet:127.0.0.0/30
+  - env:test
+`, agent.Port()), string(configs[0].Instances[0]))
+	// 127.0.0.1 is probed once with the right community, once with the wrong one
+	assert.Equal(t, 2, agent.Requests())
+
+	p.discover()
+	upToDate, _ = p.IsUpToDate()
+	assert.True(t, upToDate)
+
+	// the device is removed after missing more discoveries than allowed
+	agent.Stop()
+	p.discover()
+	upToDate, _ = p.IsUpToDate()
+	assert.True(t, upToDate)
+	p.discover()
+	upToDate, _ = p.IsUpToDate()
+	assert.False(t, upToDate)
+	configs, err = p.Collect()
+	require.NoError(t, err)
+	assert.Empty(t, configs)
+}
+
+func TestSNMPConfigProviderErrors(t *testing.T) {
+	for _, cfg := range []snmpSubnetConfig{
+		{Network: "10.0.0.0"},
+		{Network: "10.0.0.0/8"},
+		{Network: "10.0.0.0/24", SNMPVersion: 4},
+		{Network: "10.0.0.0/24", SNMPVersion: 3, AuthProtocol: "SHA1024"},
+	} {
+		_, err := newSNMPConfigProvider([]snmpSubnetConfig{cfg})
+		assert.Error(t, err, cfg.Network)
+	}
+
+	config.Datadog.Set("snmp_autodiscovery.configs", []map[string]interface{}{
+		{"network": "10.0.0.0/24", "community_string": "public", "retries": 1, "tags": []string{"env:test"}},
+	})
+	config.Datadog.Set("snmp_autodiscovery.discovery_interval", 0)
+	defer config.Datadog.Set("snmp_autodiscovery.configs", nil)
+	defer config.Datadog.Set("snmp_autodiscovery.discovery_interval", 3600)
+	_, err := NewSNMPConfigProvider(config.ConfigurationProviders{})
+	assert.EqualError(t, err, "invalid snmp_autodiscovery.discovery_interval 0s")
+
+	var configs []snmpSubnetConfig
+	require.NoError(t, config.Datadog.UnmarshalKey("snmp_autodiscovery.configs", &configs))
+	require.Len(t, configs, 1)
+	require.NotNil(t, configs[0].Retries)
+	assert.Equal(t, 1, *configs[0].Retries)
+	assert.Equal(t, []string{"env:test"}, configs[0].Tags)
+}
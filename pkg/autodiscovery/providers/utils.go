@@ -22,6 +22,8 @@ const (
 	checkNamePath  string = "check_names"
 	initConfigPath string = "init_configs"
 	logsConfigPath string = "logs"
+
+	containerADLabelPrefix = "com.datadoghq.ad."
 )
 
 func init() {
@@ -150,6 +152,22 @@ func extractTemplatesFromMap(key string, input map[string]string, prefix string)
 	return configs, errors
 }
 
+// parseContainerLabels extracts the templates of the labels of containers,
+// given by container entity name
+func parseContainerLabels(containers map[string]map[string]string) []integration.Config {
+	var configs []integration.Config
+	for entity, labels := range containers {
+		c, errors := extractTemplatesFromMap(entity, labels, containerADLabelPrefix)
+
+		for _, err := range errors {
+			log.Errorf("Can't parse template for container %s: %s", entity, err)
+		}
+
+		configs = append(configs, c...)
+	}
+	return configs
+}
+
 // extractCheckTemplatesFromMap returns all the check configurations from a given map.
 func extractCheckTemplatesFromMap(key string, input map[string]string, prefix string) ([]integration.Config, error) {
 	value, found := input[prefix+checkNamePath]
@@ -377,6 +377,28 @@ func TestExtractTemplatesFromMap(t *testing.T) {
 	}
 }
 
+func TestParseContainerLabels(t *testing.T) {
+	configs := parseContainerLabels(map[string]map[string]string{
+		"containerd://redis": {
+			"com.datadoghq.ad.check_names":  "[\"redisdb\"]",
+			"com.datadoghq.ad.init_configs": "[{}]",
+			"com.datadoghq.ad.instances":    "[{\"host\": \"%%host%%\", \"port\": 6379}]",
+		},
+		"cri-o://invalid": {
+			"com.datadoghq.ad.check_names":  "[\"redisdb\"]",
+			"com.datadoghq.ad.init_configs": "[{}]",
+			"com.datadoghq.ad.instances":    "invalid",
+		},
+		"containerd://nginx": {
+			"maintainer": "nginx",
+		},
+	})
+	require.Len(t, configs, 1)
+	assert.Equal(t, "redisdb", configs[0].Name)
+	assert.Equal(t, []string{"containerd://redis"}, configs[0].ADIdentifiers)
+	assert.Equal(t, integration.Data("{\"host\":\"%%host%%\",\"port\":6379}"), configs[0].Instances[0])
+}
+
 func TestGetPollInterval(t *testing.T) {
 	cp := config.ConfigurationProviders{}
 	assert.Equal(t, GetPollInterval(cp), 10*time.Second)
@@ -123,11 +123,15 @@ func (s *store) removeLoadedConfig(config integration.Config) {
 	delete(s.loadedConfigs, config.Digest())
 }
 
-// getLoadedConfigs returns all loaded and resolved configs
+// getLoadedConfigs returns a copy of all loaded and resolved configs
 func (s *store) getLoadedConfigs() map[string]integration.Config {
 	s.m.RLock()
 	defer s.m.RUnlock()
-	return s.loadedConfigs
+	loadedConfigs := make(map[string]integration.Config, len(s.loadedConfigs))
+	for digest, config := range s.loadedConfigs {
+		loadedConfigs[digest] = config
+	}
+	return loadedConfigs
 }
 
 // setJMXMetricsForConfigName stores the jmx metrics config for a config name
@@ -22,3 +22,17 @@ type Check interface {
 	GetMetricStats() (map[string]int64, error)           // get metric stats from the sender
 	Version() string                                     // return the version of the check if available
 }
+
+// RunTimeouter is implemented by checks configuring their own run timeout, the
+// time after which the runner stops waiting for a run to complete. A zero
+// duration means the check uses the agent's default run timeout.
+type RunTimeouter interface {
+	RunTimeout() time.Duration
+}
+
+// Canceler is implemented by checks running work beyond their runs, such as
+// probes left to complete in the background. Cancel is called once the check is
+// unscheduled, and stops that work before the check is removed.
+type Canceler interface {
+	Cancel()
+}
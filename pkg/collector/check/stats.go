@@ -10,6 +10,21 @@ import (
 	"time"
 )
 
+// historySize is the number of runs kept in the history of check instances
+const historySize = 20
+
+// Run holds the results of a single check run
+type Run struct {
+	Start         int64    // start of the run, unix timestamp in milliseconds
+	Duration      int64    // run duration in milliseconds
+	Error         string   // error that occurred during the run, if any
+	Warnings      []string // warnings that occurred during the run, if any
+	TimedOut      bool     // whether the run didn't complete within the run timeout
+	MetricSamples int64
+	Events        int64
+	ServiceChecks int64
+}
+
 // Stats holds basic runtime statistics about check instances
 type Stats struct {
 	CheckName            string
@@ -18,6 +33,7 @@ type Stats struct {
 	TotalRuns            uint64
 	TotalErrors          uint64
 	TotalWarnings        uint64
+	TotalTimeouts        uint64
 	MetricSamples        int64
 	Events               int64
 	ServiceChecks        int64
@@ -29,8 +45,10 @@ type Stats struct {
 	LastExecutionTime    int64     // most recent run duration, provided for convenience
 	LastError            string    // error that occurred in the last run, if any
 	LastWarnings         []string  // warnings that occurred in the last run, if any
+	LongRunning          bool      // whether the last run timed out and hasn't returned yet
 	UpdateTimestamp      int64     // latest update to this instance, unix timestamp in seconds
 	m                    sync.Mutex
+	history              [historySize]Run // circular buffer of recent runs, most recent at [(TotalRuns+historySize-1) % historySize]
 }
 
 // NewStats returns a new check stats instance
@@ -47,6 +65,32 @@ func (cs *Stats) Add(t time.Duration, err error, warnings []error, metricStats m
 	cs.m.Lock()
 	defer cs.m.Unlock()
 
+	cs.add(t, err, warnings, metricStats)
+}
+
+// AddTimeout tracks a run that didn't complete within the run timeout, and is
+// still running. err is reported as the error of the run.
+func (cs *Stats) AddTimeout(t time.Duration, err error) {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+
+	cs.add(t, err, nil, nil)
+	cs.TotalTimeouts++
+	cs.LongRunning = true
+	cs.history[(cs.TotalRuns-1)%historySize].TimedOut = true
+}
+
+// SetReturned tracks the completion of a run that timed out. Its results aren't
+// recorded, the run was already reported as failed.
+func (cs *Stats) SetReturned() {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+
+	cs.LongRunning = false
+	cs.UpdateTimestamp = time.Now().Unix()
+}
+
+func (cs *Stats) add(t time.Duration, err error, warnings []error, metricStats map[string]int64) {
 	// store execution times in Milliseconds
 	tms := t.Nanoseconds() / 1e6
 	cs.LastExecutionTime = tms
@@ -94,4 +138,30 @@ func (cs *Stats) Add(t time.Duration, err error, warnings []error, metricStats m
 			cs.TotalServiceChecks += sc
 		}
 	}
+
+	cs.history[(cs.TotalRuns-1)%historySize] = Run{
+		Start:         time.Now().Add(-t).UnixNano() / 1e6,
+		Duration:      tms,
+		Error:         cs.LastError,
+		Warnings:      cs.LastWarnings,
+		MetricSamples: metricStats["MetricSamples"],
+		Events:        metricStats["Events"],
+		ServiceChecks: metricStats["ServiceChecks"],
+	}
+}
+
+// History returns the recent runs of the check instance, most recent first
+func (cs *Stats) History() []Run {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+
+	n := cs.TotalRuns
+	if n > historySize {
+		n = historySize
+	}
+	runs := make([]Run, 0, n)
+	for i := uint64(1); i <= n; i++ {
+		runs = append(runs, cs.history[(cs.TotalRuns-i)%historySize])
+	}
+	return runs
 }
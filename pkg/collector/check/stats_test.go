@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package check
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHistory(t *testing.T) {
+	s := &Stats{}
+	assert.Empty(t, s.History())
+
+	s.Add(10*time.Millisecond, nil, nil, map[string]int64{"MetricSamples": 3, "ServiceChecks": 1})
+	s.Add(20*time.Millisecond, errors.New("connection refused"), []error{errors.New("deprecated")}, nil)
+	s.AddTimeout(30*time.Millisecond, errors.New("check run timed out after 30ms"))
+
+	history := s.History()
+	require.Len(t, history, 3)
+	assert.Equal(t, int64(30), history[0].Duration)
+	assert.True(t, history[0].TimedOut)
+	assert.Equal(t, "check run timed out after 30ms", history[0].Error)
+	assert.Equal(t, "connection refused", history[1].Error)
+	assert.Equal(t, []string{"deprecated"}, history[1].Warnings)
+	assert.False(t, history[1].TimedOut)
+	assert.Equal(t, Run{Start: history[2].Start, Duration: 10, Warnings: []string{}, MetricSamples: 3, ServiceChecks: 1}, history[2])
+	assert.InDelta(t, time.Now().UnixNano()/1e6, history[2].Start, 1000)
+
+	for i := 0; i < historySize; i++ {
+		s.Add(time.Duration(i)*time.Millisecond, nil, nil, nil)
+	}
+	history = s.History()
+	require.Len(t, history, historySize)
+	assert.Equal(t, int64(historySize-1), history[0].Duration)
+	assert.Equal(t, int64(0), history[historySize-1].Duration)
+}
@@ -182,6 +182,14 @@ func (c *Collector) StopCheck(id check.ID) error {
 		return fmt.Errorf("an error occurred while stopping the check: %s", err)
 	}
 
+	// stop the work the check runs beyond its runs
+	c.m.RLock()
+	ch := c.checks[id]
+	c.m.RUnlock()
+	if canceler, ok := ch.(check.Canceler); ok {
+		canceler.Cancel()
+	}
+
 	// remove the check from the stats map
 	runner.RemoveCheckStats(id)
 
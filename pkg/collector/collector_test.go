@@ -51,6 +51,14 @@ func NewCheckUnique(id check.ID, name string) *TestCheck {
 	return &TestCheck{uniqueID: id, name: name, stop: make(chan bool)}
 }
 
+// CancelingCheck is a TestCheck implementing check.Canceler
+type CancelingCheck struct {
+	TestCheck
+	canceled bool
+}
+
+func (c *CancelingCheck) Cancel() { c.canceled = true }
+
 // ChecksList is a sort.Interface so we can use the Sort function
 type ChecksList []check.ID
 
@@ -131,6 +139,19 @@ func (suite *CollectorTestSuite) TestStopCheck() {
 	assert.Zero(suite.T(), len(suite.c.checks))
 }
 
+func (suite *CollectorTestSuite) TestStopCheckCancel() {
+	ch := &CancelingCheck{TestCheck: TestCheck{stop: make(chan bool)}}
+
+	_, err := suite.c.RunCheck(ch)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), ch.canceled)
+
+	err = suite.c.StopCheck("TestCheck")
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), ch.canceled)
+	assert.Zero(suite.T(), len(suite.c.checks))
+}
+
 func (suite *CollectorTestSuite) TestFind() {
 	assert.False(suite.T(), suite.c.find("bar"))
 	suite.c.checks["bar"] = nil
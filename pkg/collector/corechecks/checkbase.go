@@ -41,6 +41,7 @@ type CheckBase struct {
 	checkID        check.ID
 	latestWarnings []error
 	checkInterval  time.Duration
+	runTimeout     time.Duration
 }
 
 // NewCheckBase returns a check base struct with a given check name
@@ -79,6 +80,11 @@ func (c *CheckBase) CommonConfigure(instance integration.Data) error {
 		c.checkInterval = time.Duration(commonOptions.MinCollectionInterval) * time.Second
 	}
 
+	// See if a run timeout was specified
+	if commonOptions.RunTimeout > 0 {
+		c.runTimeout = time.Duration(commonOptions.RunTimeout) * time.Second
+	}
+
 	// Disable default hostname if specified
 	if commonOptions.EmptyDefaultHostname {
 		s, err := aggregator.GetSender(c.checkID)
@@ -128,6 +134,12 @@ func (c *CheckBase) Interval() time.Duration {
 	return c.checkInterval
 }
 
+// RunTimeout returns the time after which the runner stops waiting for a run
+// to complete, 0 to use the agent's default.
+func (c *CheckBase) RunTimeout() time.Duration {
+	return c.runTimeout
+}
+
 // String returns the name of the check, the same for every instance
 func (c *CheckBase) String() string {
 	return c.checkName
@@ -40,6 +40,7 @@ func TestCommonConfigure(t *testing.T) {
 	err := mycheck.CommonConfigure([]byte(defaultsInstance))
 	assert.NoError(t, err)
 	assert.Equal(t, defaults.DefaultCheckInterval, mycheck.Interval())
+	assert.Equal(t, time.Duration(0), mycheck.RunTimeout())
 	mockSender.AssertNumberOfCalls(t, "DisableDefaultHostname", 0)
 
 	mockSender.On("DisableDefaultHostname", true).Return().Once()
@@ -68,3 +69,14 @@ func TestCommonConfigureCustomID(t *testing.T) {
 	assert.Equal(t, string(mycheck.ID()), "test:foobar:bd63a7031add5db9")
 	mockSender.AssertExpectations(t)
 }
+
+func TestCommonConfigureRunTimeout(t *testing.T) {
+	mycheck := &dummyCheck{
+		CheckBase: NewCheckBase("test"),
+	}
+
+	err := mycheck.CommonConfigure([]byte("run_timeout: 30"))
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, mycheck.RunTimeout())
+	assert.Equal(t, defaults.DefaultCheckInterval, mycheck.Interval())
+}
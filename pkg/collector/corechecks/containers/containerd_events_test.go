@@ -19,6 +19,7 @@ import (
 	"github.com/containerd/containerd/containers"
 	"github.com/containerd/containerd/events"
 	prototypes "github.com/gogo/protobuf/types"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -29,12 +30,22 @@ type mockItf struct {
 	mockEvents      func() containerd.EventService
 	mockContainer   func() ([]containerd.Container, error)
 	mockMetadata    func() (containerd.Version, error)
+	mockImageConfig func(ctn containerd.Container) (ocispec.ImageConfig, error)
 	mockImageSize   func(ctn containerd.Container) (int64, error)
 	mockTaskMetrics func(ctn containerd.Container) (*types.Metric, error)
+	mockTaskPid     func(ctn containerd.Container) (uint32, error)
 	mockInfo        func(ctn containerd.Container) (containers.Container, error)
 	mockNamespace   func() string
 }
 
+func (m *mockItf) ImageConfig(ctn containerd.Container) (ocispec.ImageConfig, error) {
+	return m.mockImageConfig(ctn)
+}
+
+func (m *mockItf) TaskPid(ctn containerd.Container) (uint32, error) {
+	return m.mockTaskPid(ctn)
+}
+
 func (m *mockItf) ImageSize(ctn containerd.Container) (int64, error) {
 	return m.mockImageSize(ctn)
 }
@@ -231,6 +231,12 @@ func (d *DockerCheck) Run() error {
 			log.Debugf("Empty IO metrics for container %s", c.ID[:12])
 		}
 
+		if c.Pressure != nil {
+			reportPressureMetrics("docker.cpu.pressure", c.Pressure.CPU, tags, sender)
+			reportPressureMetrics("docker.mem.pressure", c.Pressure.Memory, tags, sender)
+			reportPressureMetrics("docker.io.pressure", c.Pressure.IO, tags, sender)
+		}
+
 		if c.ThreadCount != 0 {
 			sender.Gauge("docker.thread.count", float64(c.ThreadCount), "", tags)
 		}
@@ -350,6 +356,18 @@ func (d *DockerCheck) Run() error {
 	return nil
 }
 
+// reportPressureMetrics reports the share of time, in percent, during which some
+// or all the tasks of the container were stalled waiting for a resource.
+func reportPressureMetrics(prefix string, stat cmetrics.PressureStat, tags []string, sender aggregator.Sender) {
+	// total stall times are in microseconds, the rate of total/1e4 is a percentage
+	if stat.Some != nil {
+		sender.Rate(prefix+".some", float64(stat.Some.Total)/1e4, "", tags)
+	}
+	if stat.Full != nil {
+		sender.Rate(prefix+".full", float64(stat.Full.Total)/1e4, "", tags)
+	}
+}
+
 func (d *DockerCheck) reportIOMetrics(io *cmetrics.CgroupIOStat, tags []string, sender aggregator.Sender) {
 	if io == nil {
 		return
@@ -12,6 +12,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
 	cmetrics "github.com/DataDog/datadog-agent/pkg/util/containers/metrics"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestReportIOMetrics(t *testing.T) {
@@ -53,3 +54,26 @@ func TestReportIOMetrics(t *testing.T) {
 	mockSender.AssertMetric(t, "Rate", "docker.io.read_bytes", float64(1130496), "", sdbTags)
 	mockSender.AssertMetric(t, "Rate", "docker.io.write_bytes", float64(0), "", sdbTags)
 }
+
+func TestReportPressureMetrics(t *testing.T) {
+	dockerCheck := &DockerCheck{
+		instance: &DockerConfig{},
+	}
+	mockSender := mocksender.NewMockSender(dockerCheck.ID())
+	mockSender.SetupAcceptAll()
+
+	tags := []string{"container_name:dummy"}
+	reportPressureMetrics("docker.mem.pressure", cmetrics.PressureStat{
+		Some: &cmetrics.PSIStat{Total: 120000},
+		Full: &cmetrics.PSIStat{Total: 50000},
+	}, tags, mockSender)
+	mockSender.AssertMetric(t, "Rate", "docker.mem.pressure.some", float64(12), "", tags)
+	mockSender.AssertMetric(t, "Rate", "docker.mem.pressure.full", float64(5), "", tags)
+
+	// cpu.pressure has no full line on older kernels
+	reportPressureMetrics("docker.cpu.pressure", cmetrics.PressureStat{
+		Some: &cmetrics.PSIStat{Total: 30000},
+	}, tags, mockSender)
+	mockSender.AssertMetric(t, "Rate", "docker.cpu.pressure.some", float64(3), "", tags)
+	mockSender.AssertNotCalled(t, "Rate", "docker.cpu.pressure.full", mock.Anything, "", tags)
+}
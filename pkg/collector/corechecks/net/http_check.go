@@ -0,0 +1,294 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package net
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The check is not named "http_check" so as not to be shadowed by the Python check
+// of the same name, which is loaded first. Its instances use the same options.
+const httpCheckName = "http_check_core"
+
+const (
+	httpCanConnectServiceCheck = "http.can_connect"
+	httpSSLCertServiceCheck    = "http.ssl_cert"
+
+	defaultHTTPTimeout    = 10
+	defaultHTTPStatusCode = `(1|2|3)\d\d`
+	defaultDaysWarning    = 14
+	defaultDaysCritical   = 7
+	maxResponseBodySize   = 10 * 1024 * 1024
+)
+
+// HTTPCheck checks the availability and the response of an HTTP endpoint
+type HTTPCheck struct {
+	core.CheckBase
+	cfg    *httpConfig
+	client *http.Client
+	probe  asyncProbe
+}
+
+type httpInstanceConfig struct {
+	Name                       string            `yaml:"name"`
+	URL                        string            `yaml:"url"`
+	Method                     string            `yaml:"method"`
+	Data                       string            `yaml:"data"`
+	Headers                    map[string]string `yaml:"headers"`
+	Timeout                    int               `yaml:"timeout"`
+	HTTPResponseStatusCode     string            `yaml:"http_response_status_code"`
+	ContentMatch               string            `yaml:"content_match"`
+	ReverseContentMatch        bool              `yaml:"reverse_content_match"`
+	AllowRedirects             *bool             `yaml:"allow_redirects"`
+	TLSVerify                  *bool             `yaml:"tls_verify"`
+	CheckCertificateExpiration *bool             `yaml:"check_certificate_expiration"`
+	DaysWarning                int               `yaml:"days_warning"`
+	DaysCritical               int               `yaml:"days_critical"`
+}
+
+type httpConfig struct {
+	httpInstanceConfig
+	statusCode   *regexp.Regexp
+	contentMatch *regexp.Regexp
+	timeout      time.Duration
+	checkCert    bool
+	tags         []string
+}
+
+func (c *httpConfig) parse(data []byte) error {
+	var instance httpInstanceConfig
+	if err := yaml.Unmarshal(data, &instance); err != nil {
+		return err
+	}
+	if instance.URL == "" {
+		return errors.New("url is required")
+	}
+	if !strings.HasPrefix(instance.URL, "http://") && !strings.HasPrefix(instance.URL, "https://") {
+		return fmt.Errorf("url %s must start with http:// or https://", instance.URL)
+	}
+	if instance.Method == "" {
+		instance.Method = http.MethodGet
+	}
+	instance.Method = strings.ToUpper(instance.Method)
+	if instance.HTTPResponseStatusCode == "" {
+		instance.HTTPResponseStatusCode = defaultHTTPStatusCode
+	}
+	if instance.DaysWarning <= 0 {
+		instance.DaysWarning = defaultDaysWarning
+	}
+	if instance.DaysCritical <= 0 {
+		instance.DaysCritical = defaultDaysCritical
+	}
+	c.httpInstanceConfig = instance
+
+	var err error
+	if c.statusCode, err = regexp.Compile("^(" + instance.HTTPResponseStatusCode + ")$"); err != nil {
+		return fmt.Errorf("invalid http_response_status_code: %s", err)
+	}
+	c.contentMatch = nil
+	if instance.ContentMatch != "" {
+		if c.contentMatch, err = regexp.Compile(instance.ContentMatch); err != nil {
+			return fmt.Errorf("invalid content_match: %s", err)
+		}
+	}
+	c.timeout = time.Duration(instance.Timeout) * time.Second
+	if instance.Timeout <= 0 {
+		c.timeout = defaultHTTPTimeout * time.Second
+	}
+	c.checkCert = strings.HasPrefix(instance.URL, "https://") &&
+		(instance.CheckCertificateExpiration == nil || *instance.CheckCertificateExpiration)
+
+	c.tags = []string{"url:" + instance.URL}
+	if instance.Name != "" {
+		c.tags = append(c.tags, "instance:"+instance.Name)
+	}
+	return nil
+}
+
+// newClient returns the client making the requests of the check.
+func (c *httpConfig) newClient() *http.Client {
+	transport := &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		DisableKeepAlives: true,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: c.TLSVerify != nil && !*c.TLSVerify,
+		},
+	}
+	client := &http.Client{Timeout: c.timeout, Transport: transport}
+	if c.AllowRedirects != nil && !*c.AllowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client
+}
+
+// Configure parses the check configuration
+func (c *HTTPCheck) Configure(data integration.Data, initConfig integration.Data) error {
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+	cfg := new(httpConfig)
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+	c.cfg = cfg
+	c.client = cfg.newClient()
+	return nil
+}
+
+// Run starts a request to the endpoint. If the endpoint is slow to respond, Run
+// returns before the request completes, and its results are submitted later.
+func (c *HTTPCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+	if !c.probe.run(func(ctx context.Context) { c.check(ctx, sender) }) {
+		log.Debugf("The previous request to %s is still running, skipping this run", c.cfg.URL)
+	}
+	return nil
+}
+
+// Cancel stops the running request and waits for it to return, so that no
+// results are submitted once the check is unscheduled.
+func (c *HTTPCheck) Cancel() {
+	c.probe.stop()
+}
+
+// check sends a request to the endpoint and submits the results, unless ctx is
+// done before the request completes.
+func (c *HTTPCheck) check(ctx context.Context, sender aggregator.Sender) {
+	status, message, resp := c.request(ctx, sender)
+	if ctx.Err() != nil {
+		return
+	}
+	sender.Gauge("network.http.can_connect", boolToFloat(resp != nil), "", c.cfg.tags)
+	sender.Gauge("network.http.cant_connect", boolToFloat(resp == nil), "", c.cfg.tags)
+	sender.ServiceCheck(httpCanConnectServiceCheck, status, "", c.cfg.tags, message)
+
+	if c.cfg.checkCert && resp != nil {
+		status, message := c.checkCertificate(sender, resp)
+		sender.ServiceCheck(httpSSLCertServiceCheck, status, "", c.cfg.tags, message)
+	}
+	sender.Commit()
+}
+
+// request sends the request and checks its response. The response is nil if the
+// endpoint could not be reached.
+func (c *HTTPCheck) request(ctx context.Context, sender aggregator.Sender) (metrics.ServiceCheckStatus, string, *http.Response) {
+	req, err := http.NewRequest(c.cfg.Method, c.cfg.URL, strings.NewReader(c.cfg.Data))
+	if err != nil {
+		return metrics.ServiceCheckCritical, err.Error(), nil
+	}
+	req = req.WithContext(ctx)
+	for k, v := range c.cfg.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+		} else {
+			req.Header.Set(k, v)
+		}
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if e, ok := err.(interface{ Timeout() bool }); ok && e.Timeout() {
+			return metrics.ServiceCheckCritical, fmt.Sprintf("Timeout error: %s. Connection failed after %d ms", err, time.Since(start)/time.Millisecond), nil
+		}
+		return metrics.ServiceCheckCritical, fmt.Sprintf("Connection error: %s", err), nil
+	}
+	defer resp.Body.Close()
+	// The body is only kept to match its content, and is read up to the same size
+	// otherwise so that the response time covers its transfer.
+	var body []byte
+	if c.cfg.contentMatch != nil {
+		body, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	} else {
+		_, err = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseBodySize))
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		return metrics.ServiceCheckCritical, fmt.Sprintf("Error reading the response: %s", err), resp
+	}
+	sender.Gauge("network.http.response_time", elapsed.Seconds(), "", c.cfg.tags)
+
+	if !c.cfg.statusCode.MatchString(fmt.Sprint(resp.StatusCode)) {
+		return metrics.ServiceCheckCritical, fmt.Sprintf("Incorrect HTTP return code for url %s. Expected %s, got %d.",
+			c.cfg.URL, c.cfg.HTTPResponseStatusCode, resp.StatusCode), resp
+	}
+	if c.cfg.contentMatch != nil {
+		found := c.cfg.contentMatch.Match(body)
+		if found && c.cfg.ReverseContentMatch {
+			return metrics.ServiceCheckCritical, fmt.Sprintf("Content %q found in response with return code %d.",
+				c.cfg.ContentMatch, resp.StatusCode), resp
+		}
+		if !found && !c.cfg.ReverseContentMatch {
+			return metrics.ServiceCheckCritical, fmt.Sprintf("Content %q not found in response with return code %d.",
+				c.cfg.ContentMatch, resp.StatusCode), resp
+		}
+	}
+	return metrics.ServiceCheckOK, "", resp
+}
+
+// checkCertificate checks the expiration of the certificate of the endpoint.
+func (c *HTTPCheck) checkCertificate(sender aggregator.Sender, resp *http.Response) (metrics.ServiceCheckStatus, string) {
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return metrics.ServiceCheckUnknown, "The endpoint did not present any certificate"
+	}
+	cert := resp.TLS.PeerCertificates[0]
+	left := time.Until(cert.NotAfter)
+	days := int(math.Floor(left.Hours() / 24))
+	sender.Gauge("http.ssl.days_left", left.Hours()/24, "", c.cfg.tags)
+
+	switch {
+	case left < 0:
+		return metrics.ServiceCheckCritical, fmt.Sprintf("The certificate of %s expired on %s", c.cfg.URL, cert.NotAfter.Format(time.RFC3339))
+	case days < c.cfg.DaysCritical:
+		return metrics.ServiceCheckCritical, fmt.Sprintf("This cert TTL is critical: only %d days before it expires", days)
+	case days < c.cfg.DaysWarning:
+		return metrics.ServiceCheckWarning, fmt.Sprintf("This cert is almost expired, only %d days left", days)
+	}
+	return metrics.ServiceCheckOK, fmt.Sprintf("Days left: %d", days)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func httpCheckFactory() check.Check {
+	return &HTTPCheck{
+		CheckBase: core.NewCheckBase(httpCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(httpCheckName, httpCheckFactory)
+}
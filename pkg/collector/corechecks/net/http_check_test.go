@@ -0,0 +1,238 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package net
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// serviceCheckMessage returns the message of the last submission of a service check.
+func serviceCheckMessage(sender *mocksender.MockSender, name string) string {
+	message := ""
+	for _, call := range sender.Calls {
+		if call.Method == "ServiceCheck" && call.Arguments.String(0) == name {
+			message = call.Arguments.String(4)
+		}
+	}
+	return message
+}
+
+// runHTTPCheck configures and runs an http check, and waits for its results.
+func runHTTPCheck(t *testing.T, config string) (*HTTPCheck, *mocksender.MockSender) {
+	c := httpCheckFactory().(*HTTPCheck)
+	sender := mocksender.NewConfiguredMockSender(t, c, config, "")
+	require.NoError(t, c.Run())
+	c.probe.wait()
+	return c, sender
+}
+
+func TestHTTPConfigure(t *testing.T) {
+	var cfg httpConfig
+	require.NoError(t, cfg.parse([]byte(`
+url: https://example.com
+name: example
+method: post
+`)))
+	assert.Equal(t, "POST", cfg.Method)
+	assert.Equal(t, 10*time.Second, cfg.timeout)
+	assert.True(t, cfg.checkCert)
+	assert.Equal(t, 14, cfg.DaysWarning)
+	assert.Equal(t, 7, cfg.DaysCritical)
+	assert.Equal(t, []string{"url:https://example.com", "instance:example"}, cfg.tags)
+	assert.True(t, cfg.statusCode.MatchString("302"))
+	assert.False(t, cfg.statusCode.MatchString("404"))
+	assert.False(t, cfg.statusCode.MatchString("2000"))
+
+	require.NoError(t, cfg.parse([]byte(`
+url: http://example.com
+http_response_status_code: 404
+`)))
+	assert.False(t, cfg.checkCert)
+	assert.True(t, cfg.statusCode.MatchString("404"))
+	assert.False(t, cfg.statusCode.MatchString("200"))
+
+	assert.Error(t, cfg.parse([]byte(`name: example`)))
+	assert.Error(t, cfg.parse([]byte(`url: example.com`)))
+	assert.Error(t, cfg.parse([]byte(`{url: "http://example.com", content_match: "("}`)))
+}
+
+func TestHTTPCheckOK(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != "PUT" || r.Header.Get("X-Test") != "value" || string(body) != "payload" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, "status: all good")
+	}))
+	defer server.Close()
+
+	_, sender := runHTTPCheck(t, fmt.Sprintf(`
+url: %s
+name: test
+method: PUT
+data: payload
+headers:
+  X-Test: value
+content_match: "all (good|fine)"
+`, server.URL))
+
+	tags := []string{"url:" + server.URL, "instance:test"}
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckOK, "", tags, "")
+	sender.AssertMetric(t, "Gauge", "network.http.can_connect", 1, "", tags)
+	sender.AssertMetric(t, "Gauge", "network.http.cant_connect", 0, "", tags)
+	sender.AssertMetricInRange(t, "Gauge", "network.http.response_time", 0, 5, "", tags)
+	sender.AssertNotCalled(t, "ServiceCheck", "http.ssl_cert", metrics.ServiceCheckOK, "", tags, "")
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestHTTPCheckContentMatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "status: error")
+	}))
+	defer server.Close()
+
+	_, sender := runHTTPCheck(t, fmt.Sprintf(`{url: "%s", content_match: "good"}`, server.URL))
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckCritical, "", nil, `Content "good" not found in response with return code 200.`)
+	sender.AssertMetric(t, "Gauge", "network.http.can_connect", 1, "", nil)
+
+	_, sender = runHTTPCheck(t, fmt.Sprintf(`{url: "%s", content_match: "error", reverse_content_match: true}`, server.URL))
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckCritical, "", nil, `Content "error" found in response with return code 200.`)
+
+	_, sender = runHTTPCheck(t, fmt.Sprintf(`{url: "%s", content_match: "good", reverse_content_match: true}`, server.URL))
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckOK, "", nil, "")
+}
+
+func TestHTTPCheckStatusCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, sender := runHTTPCheck(t, fmt.Sprintf(`{url: "%s"}`, server.URL))
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckCritical, "", nil,
+		fmt.Sprintf(`Incorrect HTTP return code for url %s. Expected (1|2|3)\d\d, got 404.`, server.URL))
+
+	_, sender = runHTTPCheck(t, fmt.Sprintf(`{url: "%s", http_response_status_code: "404"}`, server.URL))
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckOK, "", nil, "")
+}
+
+func TestHTTPCheckRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer server.Close()
+
+	_, sender := runHTTPCheck(t, fmt.Sprintf(`{url: "%s"}`, server.URL))
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckCritical, "", nil,
+		fmt.Sprintf(`Incorrect HTTP return code for url %s. Expected (1|2|3)\d\d, got 503.`, server.URL))
+
+	_, sender = runHTTPCheck(t, fmt.Sprintf(`{url: "%s", allow_redirects: false}`, server.URL))
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckOK, "", nil, "")
+}
+
+func TestHTTPCheckConnectionError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	_, sender := runHTTPCheck(t, fmt.Sprintf(`{url: "%s"}`, url))
+	sender.AssertMetric(t, "Gauge", "network.http.can_connect", 0, "", nil)
+	sender.AssertMetric(t, "Gauge", "network.http.cant_connect", 1, "", nil)
+	sender.AssertNotCalled(t, "Gauge", "network.http.response_time", mocksender.AnythingBut(nil), "", mocksender.AnythingBut(nil))
+	assert.Contains(t, serviceCheckMessage(sender, "http.can_connect"), "Connection error: ")
+}
+
+func TestHTTPCheckCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	// The certificate of the test server is self-signed
+	_, sender := runHTTPCheck(t, fmt.Sprintf(`{url: "%s"}`, server.URL))
+	sender.AssertMetric(t, "Gauge", "network.http.can_connect", 0, "", nil)
+	assert.Contains(t, serviceCheckMessage(sender, "http.can_connect"), "certificate")
+
+	config := fmt.Sprintf(`{url: "%s", tls_verify: false, http_response_status_code: "404"}`, server.URL)
+	_, sender = runHTTPCheck(t, config)
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckOK, "", nil, "")
+	days := int(time.Until(server.Certificate().NotAfter).Hours() / 24)
+	sender.AssertServiceCheck(t, "http.ssl_cert", metrics.ServiceCheckOK, "", nil, fmt.Sprintf("Days left: %d", days))
+	sender.AssertMetricInRange(t, "Gauge", "http.ssl.days_left", float64(days), float64(days+1), "", nil)
+
+	_, sender = runHTTPCheck(t, fmt.Sprintf(`{url: "%s", tls_verify: false, days_warning: %d, days_critical: 1}`, server.URL, days+1))
+	sender.AssertServiceCheck(t, "http.ssl_cert", metrics.ServiceCheckWarning, "", nil,
+		fmt.Sprintf("This cert is almost expired, only %d days left", days))
+
+	_, sender = runHTTPCheck(t, fmt.Sprintf(`{url: "%s", tls_verify: false, days_warning: %d, days_critical: %d}`, server.URL, days+2, days+1))
+	sender.AssertServiceCheck(t, "http.ssl_cert", metrics.ServiceCheckCritical, "", nil,
+		fmt.Sprintf("This cert TTL is critical: only %d days before it expires", days))
+
+	_, sender = runHTTPCheck(t, fmt.Sprintf(`{url: "%s", tls_verify: false, check_certificate_expiration: false}`, server.URL))
+	sender.AssertNotCalled(t, "Gauge", "http.ssl.days_left", mocksender.AnythingBut(nil), "", mocksender.AnythingBut(nil))
+}
+
+func TestHTTPCheckSlowEndpoint(t *testing.T) {
+	defer func(wait time.Duration) { probeWait = wait }(probeWait)
+	probeWait = 10 * time.Millisecond
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	c := httpCheckFactory().(*HTTPCheck)
+	sender := mocksender.NewConfiguredMockSender(t, c, fmt.Sprintf(`{url: "%s"}`, server.URL), "")
+
+	// Run returns before the request completes, and doesn't start another
+	// one while it is running.
+	require.NoError(t, c.Run())
+	require.NoError(t, c.Run())
+	sender.AssertNotCalled(t, "Commit")
+
+	close(release)
+	c.probe.wait()
+	sender.AssertServiceCheck(t, "http.can_connect", metrics.ServiceCheckOK, "", nil, "")
+	sender.AssertNumberOfCalls(t, "ServiceCheck", 1)
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestHTTPCheckCancel(t *testing.T) {
+	defer func(wait time.Duration) { probeWait = wait }(probeWait)
+	probeWait = 10 * time.Millisecond
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := httpCheckFactory().(*HTTPCheck)
+	sender := mocksender.NewConfiguredMockSender(t, c, fmt.Sprintf(`{url: "%s"}`, server.URL), "")
+
+	// Cancel stops the running request without submitting its results, and no
+	// request is started afterwards.
+	require.NoError(t, c.Run())
+	c.Cancel()
+	require.NoError(t, c.Run())
+	c.probe.wait()
+	sender.AssertNumberOfCalls(t, "ServiceCheck", 0)
+	sender.AssertNumberOfCalls(t, "Commit", 0)
+}
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package net
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// probeWait is how long Run waits for a probe to complete before returning,
+// leaving it to complete in the background.
+var probeWait = time.Second
+
+// asyncProbe runs the probes of an endpoint check in the background, so that a slow
+// endpoint doesn't hold a runner worker until it times out. Probes submit and commit
+// their own results, and a probe isn't started while the previous one is running.
+type asyncProbe struct {
+	mu       sync.Mutex
+	done     chan struct{} // closed when the last probe completed, nil if none ran
+	ctx      context.Context
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+// run starts probe, unless the previous one is still running, and waits for it to
+// complete for at most probeWait. It returns false if the previous probe was still
+// running or if the probes were canceled, in which case probe is not started. The
+// context passed to probe is done once the probes are canceled.
+func (a *asyncProbe) run(probe func(ctx context.Context)) bool {
+	a.mu.Lock()
+	if a.canceled {
+		a.mu.Unlock()
+		return false
+	}
+	if a.done != nil {
+		select {
+		case <-a.done:
+		default:
+			a.mu.Unlock()
+			return false
+		}
+	}
+	if a.ctx == nil {
+		a.ctx, a.cancel = context.WithCancel(context.Background())
+	}
+	ctx := a.ctx
+	done := make(chan struct{})
+	a.done = done
+	a.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		probe(ctx)
+	}()
+
+	timer := time.NewTimer(probeWait)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+	return true
+}
+
+// stop cancels the running probe, prevents new ones from starting and waits for
+// the last one to return.
+func (a *asyncProbe) stop() {
+	a.mu.Lock()
+	a.canceled = true
+	if a.cancel != nil {
+		a.cancel()
+	}
+	a.mu.Unlock()
+	a.wait()
+}
+
+// wait blocks until the last probe completes.
+func (a *asyncProbe) wait() {
+	a.mu.Lock()
+	done := a.done
+	a.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
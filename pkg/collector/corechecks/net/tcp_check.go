@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package net
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The check is not named "tcp_check" so as not to be shadowed by the Python check
+// of the same name, which is loaded first. Its instances use the same options.
+const tcpCheckName = "tcp_check_core"
+
+const (
+	tcpCanConnectServiceCheck = "tcp.can_connect"
+
+	defaultTCPTimeout = 10
+)
+
+// TCPCheck checks that a TCP port accepts connections
+type TCPCheck struct {
+	core.CheckBase
+	cfg   *tcpConfig
+	probe asyncProbe
+}
+
+type tcpInstanceConfig struct {
+	Name                string `yaml:"name"`
+	Host                string `yaml:"host"`
+	Port                int    `yaml:"port"`
+	Timeout             int    `yaml:"timeout"`
+	CollectResponseTime bool   `yaml:"collect_response_time"`
+}
+
+type tcpConfig struct {
+	tcpInstanceConfig
+	addr    string
+	timeout time.Duration
+	tags    []string
+}
+
+func (c *tcpConfig) parse(data []byte) error {
+	var instance tcpInstanceConfig
+	if err := yaml.Unmarshal(data, &instance); err != nil {
+		return err
+	}
+	if instance.Host == "" {
+		return errors.New("host is required")
+	}
+	if instance.Port <= 0 || instance.Port > 65535 {
+		return fmt.Errorf("invalid port %d", instance.Port)
+	}
+	c.tcpInstanceConfig = instance
+
+	c.addr = net.JoinHostPort(instance.Host, strconv.Itoa(instance.Port))
+	c.timeout = time.Duration(instance.Timeout) * time.Second
+	if instance.Timeout <= 0 {
+		c.timeout = defaultTCPTimeout * time.Second
+	}
+	c.tags = []string{"target_host:" + instance.Host, "port:" + strconv.Itoa(instance.Port)}
+	if instance.Name != "" {
+		c.tags = append(c.tags, "instance:"+instance.Name)
+	}
+	return nil
+}
+
+// Configure parses the check configuration
+func (c *TCPCheck) Configure(data integration.Data, initConfig integration.Data) error {
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+	cfg := new(tcpConfig)
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+	c.cfg = cfg
+	return nil
+}
+
+// Run starts a connection to the port. If the port is slow to respond, Run
+// returns before the connection completes, and its results are submitted later.
+func (c *TCPCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+	if !c.probe.run(func(ctx context.Context) { c.check(ctx, sender) }) {
+		log.Debugf("The previous connection to %s is still running, skipping this run", c.cfg.addr)
+	}
+	return nil
+}
+
+// Cancel stops the running connection and waits for it to return, so that no
+// results are submitted once the check is unscheduled.
+func (c *TCPCheck) Cancel() {
+	c.probe.stop()
+}
+
+// check connects to the port and submits the results, unless ctx is done before
+// the connection completes.
+func (c *TCPCheck) check(ctx context.Context, sender aggregator.Sender) {
+	start := time.Now()
+	dialer := net.Dialer{Timeout: c.cfg.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.cfg.addr)
+	elapsed := time.Since(start)
+	if ctx.Err() != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		return
+	}
+	if err != nil {
+		message := fmt.Sprintf("Connection error: %s", err)
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			message = fmt.Sprintf("Timeout error: %s. Connection failed after %d ms", err, elapsed/time.Millisecond)
+		}
+		sender.Gauge("network.tcp.can_connect", 0, "", c.cfg.tags)
+		sender.ServiceCheck(tcpCanConnectServiceCheck, metrics.ServiceCheckCritical, "", c.cfg.tags, message)
+		sender.Commit()
+		return
+	}
+	conn.Close()
+
+	if c.cfg.CollectResponseTime {
+		sender.Gauge("network.tcp.response_time", elapsed.Seconds(), "", c.cfg.tags)
+	}
+	sender.Gauge("network.tcp.can_connect", 1, "", c.cfg.tags)
+	sender.ServiceCheck(tcpCanConnectServiceCheck, metrics.ServiceCheckOK, "", c.cfg.tags, "")
+	sender.Commit()
+}
+
+func tcpCheckFactory() check.Check {
+	return &TCPCheck{
+		CheckBase: core.NewCheckBase(tcpCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(tcpCheckName, tcpCheckFactory)
+}
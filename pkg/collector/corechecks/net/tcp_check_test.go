@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package net
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// runTCPCheck configures and runs a tcp check, and waits for its results.
+func runTCPCheck(t *testing.T, config string) *mocksender.MockSender {
+	c := tcpCheckFactory().(*TCPCheck)
+	sender := mocksender.NewConfiguredMockSender(t, c, config, "")
+	require.NoError(t, c.Run())
+	c.probe.wait()
+	return sender
+}
+
+func TestTCPConfigure(t *testing.T) {
+	var cfg tcpConfig
+	require.NoError(t, cfg.parse([]byte(`{host: "::1", port: 22, name: ssh}`)))
+	assert.Equal(t, "[::1]:22", cfg.addr)
+	assert.Equal(t, []string{"target_host:::1", "port:22", "instance:ssh"}, cfg.tags)
+
+	assert.Error(t, cfg.parse([]byte(`port: 22`)))
+	assert.Error(t, cfg.parse([]byte(`host: localhost`)))
+	assert.Error(t, cfg.parse([]byte(`{host: localhost, port: 70000}`)))
+}
+
+func TestTCPCheck(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	tags := []string{"target_host:127.0.0.1", "port:" + port, "instance:test"}
+	sender := runTCPCheck(t, fmt.Sprintf(`{host: 127.0.0.1, port: %s, name: test, collect_response_time: true}`, port))
+	sender.AssertServiceCheck(t, "tcp.can_connect", metrics.ServiceCheckOK, "", tags, "")
+	sender.AssertMetric(t, "Gauge", "network.tcp.can_connect", 1, "", tags)
+	sender.AssertMetricInRange(t, "Gauge", "network.tcp.response_time", 0, 5, "", tags)
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+
+	sender = runTCPCheck(t, fmt.Sprintf(`{host: 127.0.0.1, port: %s}`, port))
+	sender.AssertServiceCheck(t, "tcp.can_connect", metrics.ServiceCheckOK, "", nil, "")
+	sender.AssertNotCalled(t, "Gauge", "network.tcp.response_time", mocksender.AnythingBut(nil), "", mocksender.AnythingBut(nil))
+
+	l.Close()
+	sender = runTCPCheck(t, fmt.Sprintf(`{host: 127.0.0.1, port: %s, name: closed}`, port))
+	sender.AssertMetric(t, "Gauge", "network.tcp.can_connect", 0, "", nil)
+	assert.Contains(t, serviceCheckMessage(sender, "tcp.can_connect"), "Connection error: ")
+	sender.AssertNotCalled(t, "ServiceCheck", "tcp.can_connect", metrics.ServiceCheckOK, "", mocksender.AnythingBut(nil), "")
+}
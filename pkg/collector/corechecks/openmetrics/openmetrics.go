@@ -0,0 +1,310 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The check is not named "openmetrics" so as not to be shadowed by the Python
+// check of the same name, which is loaded first. Its instances use the same options.
+const openmetricsCheckName = "openmetrics_core"
+
+const (
+	defaultTimeout            = 10
+	defaultMaxReturnedMetrics = 2000
+
+	// acceptHeader prefers the protobuf exposition format, and falls back to the text one.
+	acceptHeader = `application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3,*/*;q=0.1`
+)
+
+// OpenMetricsCheck scrapes the metrics exposed by an OpenMetrics/Prometheus endpoint
+type OpenMetricsCheck struct {
+	core.CheckBase
+	cfg    *openmetricsConfig
+	client *http.Client
+}
+
+type labelJoin struct {
+	LabelsToMatch []string `yaml:"labels_to_match"`
+	LabelsToGet   []string `yaml:"labels_to_get"`
+}
+
+type openmetricsInstanceConfig struct {
+	PrometheusURL           string               `yaml:"prometheus_url"`
+	Namespace               string               `yaml:"namespace"`
+	Metrics                 []interface{}        `yaml:"metrics"`
+	IgnoreMetrics           []string             `yaml:"ignore_metrics"`
+	LabelsMapper            map[string]string    `yaml:"labels_mapper"`
+	ExcludeLabels           []string             `yaml:"exclude_labels"`
+	LabelJoins              map[string]labelJoin `yaml:"label_joins"`
+	SendHistogramsBuckets   *bool                `yaml:"send_histograms_buckets"`
+	SendDistributionBuckets bool                 `yaml:"send_distribution_buckets"`
+	SendMonotonicCounter    *bool                `yaml:"send_monotonic_counter"`
+	HealthServiceCheck      *bool                `yaml:"health_service_check"`
+	MaxReturnedMetrics      int                  `yaml:"max_returned_metrics"`
+	Timeout                 int                  `yaml:"prometheus_timeout"`
+}
+
+// metricMatcher matches the names of the metrics to collect, and optionally gives
+// the name under which they are submitted.
+type metricMatcher struct {
+	re      *regexp.Regexp
+	rename  string
+	literal string
+}
+
+func (m *metricMatcher) match(name string) (string, bool) {
+	if m.re != nil {
+		return name, m.re.MatchString(name)
+	}
+	if name != m.literal {
+		return "", false
+	}
+	if m.rename != "" {
+		return m.rename, true
+	}
+	return name, true
+}
+
+type openmetricsConfig struct {
+	url                     string
+	namespace               string
+	metrics                 []*metricMatcher
+	ignored                 []*metricMatcher
+	labelsMapper            map[string]string
+	excludedLabels          map[string]bool
+	labelJoins              map[string]labelJoin
+	sendHistogramsBuckets   bool
+	sendDistributionBuckets bool
+	sendMonotonicCounter    bool
+	healthServiceCheck      bool
+	maxReturnedMetrics      int
+	timeout                 time.Duration
+}
+
+// newMetricMatcher returns a matcher for a metric name, in which "*" matches any
+// sequence of characters.
+func newMetricMatcher(pattern string) (*metricMatcher, error) {
+	if !strings.Contains(pattern, "*") {
+		return &metricMatcher{literal: pattern}, nil
+	}
+	re, err := regexp.Compile("^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$")
+	if err != nil {
+		return nil, err
+	}
+	return &metricMatcher{re: re}, nil
+}
+
+func (c *openmetricsConfig) parse(data []byte) error {
+	var instance openmetricsInstanceConfig
+	if err := yaml.Unmarshal(data, &instance); err != nil {
+		return err
+	}
+	if instance.PrometheusURL == "" {
+		return errors.New("prometheus_url is required")
+	}
+	if instance.Namespace == "" {
+		return errors.New("namespace is required")
+	}
+	if len(instance.Metrics) == 0 {
+		return errors.New("at least one metric must be listed in metrics, use \"*\" to collect all of them")
+	}
+
+	c.url = instance.PrometheusURL
+	c.namespace = instance.Namespace
+	c.metrics = nil
+	for _, m := range instance.Metrics {
+		switch v := m.(type) {
+		case string:
+			matcher, err := newMetricMatcher(v)
+			if err != nil {
+				return fmt.Errorf("invalid metric %q: %s", v, err)
+			}
+			c.metrics = append(c.metrics, matcher)
+		case map[interface{}]interface{}:
+			// {prometheus_name: datadog_name}
+			for name, rename := range v {
+				n, ok1 := name.(string)
+				r, ok2 := rename.(string)
+				if !ok1 || !ok2 {
+					return fmt.Errorf("invalid metric mapping %v, expected strings", v)
+				}
+				c.metrics = append(c.metrics, &metricMatcher{literal: n, rename: r})
+			}
+		default:
+			return fmt.Errorf("invalid metric %v, expected a name or a mapping", m)
+		}
+	}
+	c.ignored = nil
+	for _, m := range instance.IgnoreMetrics {
+		matcher, err := newMetricMatcher(m)
+		if err != nil {
+			return fmt.Errorf("invalid ignored metric %q: %s", m, err)
+		}
+		c.ignored = append(c.ignored, matcher)
+	}
+
+	c.labelsMapper = instance.LabelsMapper
+	c.excludedLabels = make(map[string]bool, len(instance.ExcludeLabels))
+	for _, l := range instance.ExcludeLabels {
+		c.excludedLabels[l] = true
+	}
+	c.labelJoins = instance.LabelJoins
+	for name, join := range c.labelJoins {
+		if len(join.LabelsToMatch) == 0 || len(join.LabelsToGet) == 0 {
+			return fmt.Errorf("label join on %s needs labels_to_match and labels_to_get", name)
+		}
+	}
+
+	c.sendHistogramsBuckets = instance.SendHistogramsBuckets == nil || *instance.SendHistogramsBuckets
+	c.sendDistributionBuckets = instance.SendDistributionBuckets
+	c.sendMonotonicCounter = instance.SendMonotonicCounter == nil || *instance.SendMonotonicCounter
+	c.healthServiceCheck = instance.HealthServiceCheck == nil || *instance.HealthServiceCheck
+
+	c.maxReturnedMetrics = instance.MaxReturnedMetrics
+	if c.maxReturnedMetrics <= 0 {
+		c.maxReturnedMetrics = defaultMaxReturnedMetrics
+	}
+	c.timeout = time.Duration(instance.Timeout) * time.Second
+	if instance.Timeout <= 0 {
+		c.timeout = defaultTimeout * time.Second
+	}
+	return nil
+}
+
+// metricName returns the name, without namespace, under which a metric is submitted,
+// and false if the metric is not collected.
+func (c *openmetricsConfig) metricName(name string) (string, bool) {
+	for _, m := range c.ignored {
+		if _, ok := m.match(name); ok {
+			return "", false
+		}
+	}
+	for _, m := range c.metrics {
+		if n, ok := m.match(name); ok {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+// Configure parses the check configuration
+func (c *OpenMetricsCheck) Configure(data integration.Data, initConfig integration.Data) error {
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+	cfg := new(openmetricsConfig)
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+	c.cfg = cfg
+	c.client = &http.Client{Timeout: cfg.timeout}
+	return nil
+}
+
+// Run scrapes the endpoint and submits its metrics
+func (c *OpenMetricsCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	families, err := c.scrape()
+	if c.cfg.healthServiceCheck {
+		status, message := metrics.ServiceCheckOK, ""
+		if err != nil {
+			status, message = metrics.ServiceCheckCritical, err.Error()
+		}
+		sender.ServiceCheck(c.cfg.namespace+".prometheus.health", status, "", []string{"endpoint:" + c.cfg.url}, message)
+	}
+	if err != nil {
+		sender.Commit()
+		return err
+	}
+
+	if n := c.cfg.submit(sender, families); n > c.cfg.maxReturnedMetrics {
+		c.Warnf("Endpoint %s exposed %d metric contexts, only the first %d were submitted: "+
+			"raise max_returned_metrics or filter out the unneeded metrics", c.cfg.url, n, c.cfg.maxReturnedMetrics)
+	}
+	sender.Commit()
+	return nil
+}
+
+// scrape fetches and decodes the metric families exposed by the endpoint.
+func (c *OpenMetricsCheck) scrape() ([]*dto.MetricFamily, error) {
+	req, err := http.NewRequest("GET", c.cfg.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", acceptHeader)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", c.cfg.url, resp.Status)
+	}
+	families, err := parseMetricFamilies(resp.Body, expfmt.ResponseFormat(resp.Header))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the metrics of %s: %s", c.cfg.url, err)
+	}
+	return families, nil
+}
+
+// parseMetricFamilies decodes metric families in the text or protobuf exposition format.
+// They are sorted by name, so that the same contexts are dropped at every run when
+// an endpoint exposes more than max_returned_metrics.
+func parseMetricFamilies(r io.Reader, format expfmt.Format) ([]*dto.MetricFamily, error) {
+	dec := expfmt.NewDecoder(r, format)
+	var families []*dto.MetricFamily
+	for {
+		mf := &dto.MetricFamily{}
+		err := dec.Decode(mf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		families = append(families, mf)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].GetName() < families[j].GetName()
+	})
+	return families, nil
+}
+
+func openmetricsFactory() check.Check {
+	return &OpenMetricsCheck{
+		CheckBase: core.NewCheckBase(openmetricsCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(openmetricsCheckName, openmetricsFactory)
+}
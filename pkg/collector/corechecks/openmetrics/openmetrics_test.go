@@ -0,0 +1,233 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// newTestServer serves testdata/metrics.txt, encoded in the format negotiated
+// with the Accept header of the request, unless text is forced.
+func newTestServer(t *testing.T, forceText bool) *httptest.Server {
+	f, err := os.Open("testdata/metrics.txt")
+	require.NoError(t, err)
+	defer f.Close()
+	families, err := parseMetricFamilies(f, expfmt.FmtText)
+	require.NoError(t, err)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := expfmt.Negotiate(r.Header)
+		if forceText {
+			format = expfmt.FmtText
+		}
+		w.Header().Set("Content-Type", string(format))
+		enc := expfmt.NewEncoder(w, format)
+		for _, mf := range families {
+			enc.Encode(mf)
+		}
+	}))
+}
+
+func TestConfigure(t *testing.T) {
+	for name, tc := range map[string]struct {
+		config string
+		err    string
+	}{
+		"no-url":        {config: "namespace: app\nmetrics: ['*']", err: "prometheus_url is required"},
+		"no-namespace":  {config: "prometheus_url: http://localhost/metrics\nmetrics: ['*']", err: "namespace is required"},
+		"no-metrics":    {config: "prometheus_url: http://localhost/metrics\nnamespace: app", err: "at least one metric"},
+		"invalid-entry": {config: "prometheus_url: http://localhost/metrics\nnamespace: app\nmetrics: [1]", err: "invalid metric"},
+		"invalid-join": {
+			config: "prometheus_url: http://localhost/metrics\nnamespace: app\nmetrics: ['*']\nlabel_joins: {pod_info: {labels_to_match: [pod]}}",
+			err:    "label join on pod_info",
+		},
+		"valid": {config: "prometheus_url: http://%%host%%:%%port%%/metrics\nnamespace: app\nmetrics: ['*', {a: b}]"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := openmetricsFactory().Configure([]byte(tc.config), nil)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+
+	cfg := new(openmetricsConfig)
+	require.NoError(t, cfg.parse([]byte("prometheus_url: http://localhost/metrics\nnamespace: app\nmetrics: ['*']")))
+	assert.True(t, cfg.sendHistogramsBuckets)
+	assert.True(t, cfg.sendMonotonicCounter)
+	assert.True(t, cfg.healthServiceCheck)
+	assert.Equal(t, defaultMaxReturnedMetrics, cfg.maxReturnedMetrics)
+}
+
+func TestMetricName(t *testing.T) {
+	cfg := new(openmetricsConfig)
+	require.NoError(t, cfg.parse([]byte(`
+prometheus_url: http://localhost/metrics
+namespace: app
+metrics:
+  - http_*
+  - queue_depth: queue.depth
+ignore_metrics:
+  - http_*_bytes
+`)))
+	for name, want := range map[string]string{
+		"http_requests_total": "http_requests_total",
+		"queue_depth":         "queue.depth",
+		"http_request_bytes":  "",
+		"go_goroutines":       "",
+	} {
+		got, ok := cfg.metricName(name)
+		assert.Equal(t, want != "", ok, name)
+		assert.Equal(t, want, got, name)
+	}
+}
+
+func TestRun(t *testing.T) {
+	for name, forceText := range map[string]bool{"protobuf": false, "text": true} {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer(t, forceText)
+			defer server.Close()
+
+			c := openmetricsFactory()
+			sender := mocksender.NewConfiguredMockSender(t, c, `
+prometheus_url: `+server.URL+`
+namespace: app
+metrics:
+  - http_requests_total: http.requests
+  - queue_depth
+  - request_duration_seconds
+  - rpc_duration_seconds
+ignore_metrics:
+  - go_*
+labels_mapper:
+  code: status_code
+exclude_labels:
+  - method
+label_joins:
+  pod_info:
+    labels_to_match: [pod]
+    labels_to_get: [node]
+`, "")
+			require.NoError(t, c.Run())
+
+			sender.AssertServiceCheck(t, "app.prometheus.health", metrics.ServiceCheckOK, "", []string{"endpoint:" + server.URL}, "")
+			sender.AssertMetric(t, "MonotonicCount", "app.http.requests", 1027, "", []string{"status_code:200", "pod:web-1", "node:node-a"})
+			sender.AssertMetric(t, "MonotonicCount", "app.http.requests", 3, "", []string{"status_code:400", "pod:web-1", "node:node-a"})
+			sender.AssertMetric(t, "Gauge", "app.queue_depth", 42, "", []string{"pod:web-1", "node:node-a"})
+			sender.AssertMetric(t, "MonotonicCount", "app.request_duration_seconds.sum", 53423, "", []string{})
+			sender.AssertMetric(t, "MonotonicCount", "app.request_duration_seconds.count", 144320, "", []string{})
+			sender.AssertMetric(t, "MonotonicCount", "app.request_duration_seconds.count", 24054, "", []string{"upper_bound:0.05"})
+			sender.AssertMetric(t, "MonotonicCount", "app.request_duration_seconds.count", 144320, "", []string{"upper_bound:none"})
+			sender.AssertMetric(t, "MonotonicCount", "app.rpc_duration_seconds.count", 2693, "", []string{})
+			sender.AssertMetric(t, "Gauge", "app.rpc_duration_seconds.quantile", 76656, "", []string{"quantile:0.99"})
+			// NaN values, ignored metrics and join targets are not submitted
+			sender.AssertMetricNotTaggedWith(t, "Gauge", "app.queue_depth", []string{"pod:web-2"})
+			sender.AssertNotCalled(t, "Gauge", "app.go_goroutines", 12.0, "", []string(nil))
+			sender.AssertMetricNotTaggedWith(t, "Gauge", "app.pod_info", []string{})
+			// 2 counters, 5 histogram and 2 summary monotonic counts, 1 gauge and 2 quantiles
+			sender.AssertNumberOfCalls(t, "MonotonicCount", 9)
+			sender.AssertNumberOfCalls(t, "Gauge", 3)
+			sender.AssertNumberOfCalls(t, "Commit", 1)
+		})
+	}
+}
+
+func TestRunOptions(t *testing.T) {
+	server := newTestServer(t, false)
+	defer server.Close()
+
+	c := openmetricsFactory()
+	sender := mocksender.NewConfiguredMockSender(t, c, `
+prometheus_url: `+server.URL+`
+namespace: app
+metrics: [http_requests_total, request_duration_seconds]
+send_monotonic_counter: false
+send_histograms_buckets: false
+health_service_check: false
+`, "")
+	require.NoError(t, c.Run())
+
+	sender.AssertMetric(t, "Gauge", "app.http_requests_total", 1027, "", []string{"method:post", "code:200", "pod:web-1"})
+	sender.AssertMetric(t, "Gauge", "app.request_duration_seconds.count", 144320, "", []string{})
+	sender.AssertMetricNotTaggedWith(t, "Gauge", "app.request_duration_seconds.count", []string{"upper_bound:none"})
+	sender.AssertNumberOfCalls(t, "MonotonicCount", 0)
+	sender.AssertNumberOfCalls(t, "Gauge", 4)
+	sender.AssertNumberOfCalls(t, "ServiceCheck", 0)
+}
+
+func TestRunDistributionBuckets(t *testing.T) {
+	server := newTestServer(t, false)
+	defer server.Close()
+
+	c := openmetricsFactory()
+	sender := mocksender.NewConfiguredMockSender(t, c, `
+prometheus_url: `+server.URL+`
+namespace: app
+metrics: [request_duration_seconds]
+send_distribution_buckets: true
+`, "")
+	require.NoError(t, c.Run())
+
+	sender.AssertMetric(t, "MonotonicCount", "app.request_duration_seconds.sum", 53423, "", []string{})
+	sender.AssertMetric(t, "MonotonicCount", "app.request_duration_seconds.count", 144320, "", []string{})
+	sender.AssertHistogramBucket(t, "app.request_duration_seconds", 24054, 0, 0.05, true, "", []string{})
+	sender.AssertHistogramBucket(t, "app.request_duration_seconds", 105335, 0.05, 0.5, true, "", []string{})
+	sender.AssertHistogramBucket(t, "app.request_duration_seconds", 14931, 0.5, math.Inf(1), true, "", []string{})
+	sender.AssertMetricNotTaggedWith(t, "MonotonicCount", "app.request_duration_seconds.count", []string{"upper_bound:none"})
+	sender.AssertNumberOfCalls(t, "HistogramBucket", 3)
+	sender.AssertNumberOfCalls(t, "MonotonicCount", 2)
+}
+
+func TestRunContextLimit(t *testing.T) {
+	server := newTestServer(t, false)
+	defer server.Close()
+
+	c := openmetricsFactory()
+	sender := mocksender.NewConfiguredMockSender(t, c, `
+prometheus_url: `+server.URL+`
+namespace: app
+metrics: ['*']
+max_returned_metrics: 5
+`, "")
+	require.NoError(t, c.Run())
+
+	// families are sorted: go_goroutines, http_requests_total, pod_info...
+	sender.AssertNumberOfCalls(t, "Gauge", 3)
+	sender.AssertNumberOfCalls(t, "MonotonicCount", 2)
+	sender.AssertMetric(t, "Gauge", "app.go_goroutines", 12, "", []string{})
+	warnings := c.GetWarnings()
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0].Error(), "only the first 5 were submitted")
+}
+
+func TestRunError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	c := openmetricsFactory()
+	sender := mocksender.NewConfiguredMockSender(t, c, "prometheus_url: "+server.URL+"\nnamespace: app\nmetrics: ['*']", "")
+	err := c.Run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "500 Internal Server Error")
+	sender.AssertServiceCheck(t, "app.prometheus.health", metrics.ServiceCheckCritical, "", []string{"endpoint:" + server.URL}, err.Error())
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+}
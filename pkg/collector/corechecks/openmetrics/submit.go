@@ -0,0 +1,209 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package openmetrics
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+)
+
+// limitedSender submits at most limit metric contexts, and counts all the
+// contexts it was given.
+type limitedSender struct {
+	limit int
+	count int
+}
+
+func (s *limitedSender) send(submit func(string, float64, string, []string), name string, value float64, tags []string) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	s.count++
+	if s.count > s.limit {
+		return
+	}
+	submit(name, value, "", tags)
+}
+
+// sendBuckets submits the buckets of a histogram, counting as one context, as
+// a distribution. The values of each bucket are spread between the upper bound
+// of the previous bucket, or 0, and its own upper bound.
+func (s *limitedSender) sendBuckets(sender aggregator.Sender, name string, buckets []*dto.Bucket, tags []string) {
+	s.count++
+	if s.count > s.limit {
+		return
+	}
+	var (
+		lowerBound float64
+		lowerCount uint64
+	)
+	for i, b := range buckets {
+		upperBound := b.GetUpperBound()
+		if math.IsNaN(upperBound) {
+			continue
+		}
+		if i == 0 && upperBound < 0 {
+			lowerBound = upperBound
+		}
+		// the bucket counts are cumulative since the start of the target
+		sender.HistogramBucket(name, int64(b.GetCumulativeCount()-lowerCount), lowerBound, upperBound, true, "", tags)
+		lowerBound, lowerCount = upperBound, b.GetCumulativeCount()
+	}
+}
+
+// joinedLabels holds the labels retrieved by a label join, indexed by the values
+// of the labels to match.
+type joinedLabels struct {
+	join   labelJoin
+	values map[string][]*dto.LabelPair
+}
+
+// joinKey returns the key of the labels to match of a join, false if one is missing.
+func joinKey(labels []*dto.LabelPair, toMatch []string) (string, bool) {
+	values := make([]string, len(toMatch))
+	for i, name := range toMatch {
+		found := false
+		for _, l := range labels {
+			if l.GetName() == name {
+				values[i], found = l.GetValue(), true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+	return strings.Join(values, "\x00"), true
+}
+
+// collectJoins indexes the labels to get of the metrics targeted by label joins.
+func (c *openmetricsConfig) collectJoins(families []*dto.MetricFamily) []*joinedLabels {
+	var joins []*joinedLabels
+	for _, mf := range families {
+		join, ok := c.labelJoins[mf.GetName()]
+		if !ok {
+			continue
+		}
+		j := &joinedLabels{join: join, values: make(map[string][]*dto.LabelPair)}
+		for _, m := range mf.Metric {
+			key, ok := joinKey(m.Label, join.LabelsToMatch)
+			if !ok {
+				continue
+			}
+			for _, l := range m.Label {
+				for _, name := range join.LabelsToGet {
+					if l.GetName() == name {
+						j.values[key] = append(j.values[key], l)
+					}
+				}
+			}
+		}
+		joins = append(joins, j)
+	}
+	return joins
+}
+
+// tags returns the tags of a metric, built from its labels and the labels joined to it.
+func (c *openmetricsConfig) tags(labels []*dto.LabelPair, joins []*joinedLabels) []string {
+	tags := make([]string, 0, len(labels))
+	add := func(l *dto.LabelPair) {
+		name, value := l.GetName(), l.GetValue()
+		if value == "" || c.excludedLabels[name] {
+			return
+		}
+		if mapped, ok := c.labelsMapper[name]; ok {
+			name = mapped
+		}
+		tags = append(tags, name+":"+value)
+	}
+	for _, l := range labels {
+		add(l)
+	}
+	for _, j := range joins {
+		if key, ok := joinKey(labels, j.join.LabelsToMatch); ok {
+			for _, l := range j.values[key] {
+				add(l)
+			}
+		}
+	}
+	return tags
+}
+
+// submit submits the metrics of the families to collect and returns the number of
+// metric contexts found, including the ones over the max_returned_metrics limit.
+//
+// Counters are submitted as monotonic counts, or gauges if send_monotonic_counter
+// is disabled, gauges and untyped metrics as gauges. Histograms are submitted as
+// <name>.sum and <name>.count, along with one <name>.count per bucket tagged with
+// its upper_bound, or a <name> distribution if send_distribution_buckets is enabled,
+// and summaries as <name>.sum, <name>.count and one <name>.quantile per quantile
+// tagged with it.
+func (c *openmetricsConfig) submit(sender aggregator.Sender, families []*dto.MetricFamily) int {
+	s := &limitedSender{limit: c.maxReturnedMetrics}
+	counter := sender.MonotonicCount
+	if !c.sendMonotonicCounter {
+		counter = sender.Gauge
+	}
+	joins := c.collectJoins(families)
+
+	for _, mf := range families {
+		name, ok := c.metricName(mf.GetName())
+		if !ok {
+			continue
+		}
+		name = c.namespace + "." + name
+		for _, m := range mf.Metric {
+			tags := c.tags(m.Label, joins)
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				s.send(counter, name, m.GetCounter().GetValue(), tags)
+			case dto.MetricType_GAUGE:
+				s.send(sender.Gauge, name, m.GetGauge().GetValue(), tags)
+			case dto.MetricType_UNTYPED:
+				s.send(sender.Gauge, name, m.GetUntyped().GetValue(), tags)
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				s.send(counter, name+".sum", h.GetSampleSum(), tags)
+				s.send(counter, name+".count", float64(h.GetSampleCount()), tags)
+				switch {
+				case c.sendDistributionBuckets:
+					s.sendBuckets(sender, name, h.Bucket, tags)
+				case c.sendHistogramsBuckets:
+					for _, b := range h.Bucket {
+						s.send(counter, name+".count", float64(b.GetCumulativeCount()), withTag(tags, "upper_bound:"+formatBound(b.GetUpperBound())))
+					}
+				}
+			case dto.MetricType_SUMMARY:
+				sum := m.GetSummary()
+				s.send(counter, name+".sum", sum.GetSampleSum(), tags)
+				s.send(counter, name+".count", float64(sum.GetSampleCount()), tags)
+				for _, q := range sum.Quantile {
+					s.send(sender.Gauge, name+".quantile", q.GetValue(), withTag(tags, "quantile:"+formatBound(q.GetQuantile())))
+				}
+			}
+		}
+	}
+	return s.count
+}
+
+// withTag returns a copy of tags with tag appended, as the samples keep their tags.
+func withTag(tags []string, tag string) []string {
+	return append(append(make([]string, 0, len(tags)+1), tags...), tag)
+}
+
+// formatBound formats a bucket upper bound or a quantile for a tag. The +Inf bucket
+// is tagged "none", like in the Python check.
+func formatBound(v float64) string {
+	if math.IsInf(v, +1) {
+		return "none"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,397 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+// +build linux
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/procmatch"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The check is not named "process" so as not to be shadowed by the Python check
+// of the same name, which is loaded first.
+const processCheckName = "process_core"
+
+const (
+	processServiceCheck     = "process.up"
+	defaultPidCacheDuration = 120
+)
+
+// ProcessCheck reports the resource usage of a group of processes
+type ProcessCheck struct {
+	core.CheckBase
+	cfg   *processConfig
+	table *procTable
+	users map[string]string // usernames by uid
+
+	selected []*procEntry
+	lastScan time.Time
+}
+
+type processInstanceConfig struct {
+	Name             string           `yaml:"name"`
+	SearchString     []string         `yaml:"search_string"`
+	ExactMatch       *bool            `yaml:"exact_match"`
+	CmdlineRegex     string           `yaml:"cmdline_regex"`
+	User             string           `yaml:"user"`
+	PidFile          string           `yaml:"pid_file"`
+	Signatures       []string         `yaml:"signatures"`
+	Integration      string           `yaml:"integration"`
+	Thresholds       map[string][]int `yaml:"thresholds"`
+	PidCacheDuration int              `yaml:"pid_cache_duration"`
+}
+
+// bounds are the minimum and maximum numbers of processes of a threshold.
+type bounds struct {
+	min, max int
+}
+
+func (b *bounds) contains(n int) bool {
+	return b == nil || (n >= b.min && n <= b.max)
+}
+
+type processConfig struct {
+	processInstanceConfig
+	exactMatch       bool
+	cmdlineRegex     *regexp.Regexp
+	matcher          procmatch.Matcher
+	integration      string // the name of the integration the signatures match
+	warning          *bounds
+	critical         *bounds
+	pidCacheDuration time.Duration
+	tags             []string
+}
+
+func (c *processConfig) parse(data []byte) error {
+	var instance processInstanceConfig
+	if err := yaml.Unmarshal(data, &instance); err != nil {
+		return err
+	}
+	if instance.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(instance.SearchString) == 0 && instance.CmdlineRegex == "" && instance.User == "" &&
+		instance.PidFile == "" && len(instance.Signatures) == 0 && instance.Integration == "" {
+		return errors.New("one of search_string, cmdline_regex, user, pid_file, signatures or integration is required")
+	}
+	if len(instance.Signatures) > 0 && instance.Integration != "" {
+		return errors.New("signatures and integration can't be used together")
+	}
+	c.processInstanceConfig = instance
+	c.exactMatch = instance.ExactMatch == nil || *instance.ExactMatch
+
+	var err error
+	c.cmdlineRegex = nil
+	if instance.CmdlineRegex != "" {
+		if c.cmdlineRegex, err = regexp.Compile(instance.CmdlineRegex); err != nil {
+			return fmt.Errorf("invalid cmdline_regex: %s", err)
+		}
+	}
+	c.matcher = nil
+	switch {
+	case len(instance.Signatures) > 0:
+		c.integration = instance.Name
+		c.matcher, err = procmatch.NewMatcher(procmatch.IntegrationCatalog{
+			{Name: c.integration, Signatures: instance.Signatures},
+		})
+	case instance.Integration != "":
+		c.integration = instance.Integration
+		c.matcher, err = procmatch.NewDefault()
+	}
+	if err != nil {
+		return err
+	}
+
+	c.warning, c.critical = nil, &bounds{min: 1, max: math.MaxInt32}
+	for level, b := range instance.Thresholds {
+		if len(b) != 2 || b[0] > b[1] {
+			return fmt.Errorf("invalid %s threshold %v, expected [min, max]", level, b)
+		}
+		switch level {
+		case "warning":
+			c.warning = &bounds{min: b[0], max: b[1]}
+		case "critical":
+			c.critical = &bounds{min: b[0], max: b[1]}
+		default:
+			return fmt.Errorf("invalid threshold %s, expected warning or critical", level)
+		}
+	}
+	if _, ok := instance.Thresholds["critical"]; !ok && len(instance.Thresholds) > 0 {
+		c.critical = nil
+	}
+
+	c.pidCacheDuration = time.Duration(instance.PidCacheDuration) * time.Second
+	if instance.PidCacheDuration <= 0 {
+		c.pidCacheDuration = defaultPidCacheDuration * time.Second
+	}
+	c.tags = []string{"process_name:" + instance.Name}
+	return nil
+}
+
+// matches returns whether a process matches all the selection criteria, except
+// the pid file and the user, which are checked separately.
+func (c *processConfig) matches(p *procEntry) bool {
+	cmdline := strings.Join(p.cmdline, " ")
+	if len(c.SearchString) > 0 {
+		found := false
+		for _, s := range c.SearchString {
+			if (c.exactMatch && p.name == s) || (!c.exactMatch && strings.Contains(cmdline, s)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.cmdlineRegex != nil && !c.cmdlineRegex.MatchString(cmdline) {
+		return false
+	}
+	if c.matcher != nil && !strings.EqualFold(c.matcher.Match(cmdline).Name, c.integration) {
+		return false
+	}
+	return true
+}
+
+// status returns the status of the service check for a number of processes.
+func (c *processConfig) status(n int) (metrics.ServiceCheckStatus, string) {
+	switch {
+	case !c.critical.contains(n):
+		return metrics.ServiceCheckCritical, fmt.Sprintf("Found %d processes, expected between %d and %d", n, c.critical.min, c.critical.max)
+	case !c.warning.contains(n):
+		return metrics.ServiceCheckWarning, fmt.Sprintf("Found %d processes, expected between %d and %d", n, c.warning.min, c.warning.max)
+	}
+	return metrics.ServiceCheckOK, ""
+}
+
+// Configure parses the check configuration
+func (c *ProcessCheck) Configure(data integration.Data, initConfig integration.Data) error {
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+	cfg := new(processConfig)
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+	c.cfg = cfg
+	return nil
+}
+
+// Run submits the metrics of the selected processes
+func (c *ProcessCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	procs, err := c.processes(now)
+	if err != nil {
+		sender.ServiceCheck(processServiceCheck, metrics.ServiceCheckUnknown, "", c.serviceCheckTags(), err.Error())
+		sender.Commit()
+		return err
+	}
+	c.submit(sender, procs, now)
+
+	status, message := c.cfg.status(len(procs))
+	sender.ServiceCheck(processServiceCheck, status, "", c.serviceCheckTags(), message)
+	sender.Commit()
+	return nil
+}
+
+func (c *ProcessCheck) serviceCheckTags() []string {
+	return []string{"process:" + c.cfg.Name}
+}
+
+// processes returns the selected processes with up to date stats. The process table
+// is only scanned every pid_cache_duration, in between the processes selected at
+// the last scan are used.
+func (c *ProcessCheck) processes(now time.Time) ([]*procEntry, error) {
+	if c.selected == nil || now.Sub(c.lastScan) >= c.cfg.pidCacheDuration {
+		selected, err := c.scan()
+		if err != nil {
+			return nil, err
+		}
+		c.selected, c.lastScan = selected, now
+	}
+
+	procs := make([]*procEntry, 0, len(c.selected))
+	for _, p := range c.selected {
+		if c.table.update(p, now) {
+			procs = append(procs, p)
+		}
+	}
+	// Forget the processes that exited until the next scan
+	c.selected = procs
+	return procs, nil
+}
+
+// scan returns the running processes matching the configuration.
+func (c *ProcessCheck) scan() ([]*procEntry, error) {
+	var candidates []*procEntry
+	if c.cfg.PidFile != "" {
+		pid, err := readPidFile(c.cfg.PidFile)
+		if err != nil {
+			log.Debugf("Could not read the pid file of %s: %s", c.cfg.Name, err)
+			return []*procEntry{}, nil
+		}
+		p, err := c.table.entry(pid)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			candidates = append(candidates, p)
+		}
+	} else {
+		var err error
+		if candidates, err = c.table.scan(); err != nil {
+			return nil, err
+		}
+	}
+
+	selected := []*procEntry{}
+	for _, p := range candidates {
+		if c.cfg.User != "" && c.cfg.User != p.uid && c.cfg.User != c.username(p.uid) {
+			continue
+		}
+		if c.cfg.matches(p) {
+			selected = append(selected, p)
+		}
+	}
+	return selected, nil
+}
+
+// username returns the name of the user with the given uid, cached for the
+// lifetime of the check.
+func (c *ProcessCheck) username(uid string) string {
+	name, ok := c.users[uid]
+	if !ok {
+		if u, err := user.LookupId(uid); err == nil {
+			name = u.Username
+		}
+		c.users[uid] = name
+	}
+	return name
+}
+
+// submit submits the metrics of a group of processes. The CPU usage, IO and context
+// switches are computed from the stats of the previous run, so they don't include
+// the processes that just started.
+func (c *ProcessCheck) submit(sender aggregator.Sender, procs []*procEntry, now time.Time) {
+	tags := c.cfg.tags
+	sender.Gauge("system.processes.number", float64(len(procs)), "", tags)
+	if len(procs) == 0 {
+		return
+	}
+
+	var rss, vms, threads, fds, cpu float64
+	var readCount, writeCount, readBytes, writeBytes, volCtx, involCtx float64
+	hasFDs, hasCPU, hasIO := false, false, false
+	minRunTime, maxRunTime, sumRunTime := math.MaxFloat64, 0.0, 0.0
+	for _, p := range procs {
+		s := p.stats
+		rss += float64(s.rss)
+		vms += float64(s.vms)
+		threads += float64(s.threads)
+		if s.fds >= 0 {
+			fds += float64(s.fds)
+			hasFDs = true
+		}
+
+		runTime := c.table.runTime(p, now).Seconds()
+		minRunTime = math.Min(minRunTime, runTime)
+		maxRunTime = math.Max(maxRunTime, runTime)
+		sumRunTime += runTime
+
+		prev := p.prev
+		if prev == nil {
+			continue
+		}
+		if elapsed := s.time.Sub(prev.time).Seconds(); elapsed > 0 {
+			cpu += float64(s.cpuTicks-prev.cpuTicks) / userHZ / elapsed * 100
+			hasCPU = true
+		}
+		volCtx += float64(s.volCtx - prev.volCtx)
+		involCtx += float64(s.involCtx - prev.involCtx)
+		if s.io != nil && prev.io != nil {
+			readCount += float64(s.io.readCount - prev.io.readCount)
+			writeCount += float64(s.io.writeCount - prev.io.writeCount)
+			readBytes += float64(s.io.readBytes - prev.io.readBytes)
+			writeBytes += float64(s.io.writeBytes - prev.io.writeBytes)
+			hasIO = true
+		}
+	}
+
+	sender.Gauge("system.processes.mem.rss", rss, "", tags)
+	sender.Gauge("system.processes.mem.vms", vms, "", tags)
+	sender.Gauge("system.processes.threads", threads, "", tags)
+	if hasFDs {
+		sender.Gauge("system.processes.open_file_descriptors", fds, "", tags)
+	}
+	sender.Gauge("system.processes.run_time.min", minRunTime, "", tags)
+	sender.Gauge("system.processes.run_time.max", maxRunTime, "", tags)
+	sender.Gauge("system.processes.run_time.avg", sumRunTime/float64(len(procs)), "", tags)
+	if hasCPU {
+		sender.Gauge("system.processes.cpu.pct", cpu, "", tags)
+		sender.Count("system.processes.voluntary_ctx_switches", volCtx, "", tags)
+		sender.Count("system.processes.involuntary_ctx_switches", involCtx, "", tags)
+	}
+	if hasIO {
+		sender.Count("system.processes.ioread_count", readCount, "", tags)
+		sender.Count("system.processes.iowrite_count", writeCount, "", tags)
+		sender.Count("system.processes.ioread_bytes", readBytes, "", tags)
+		sender.Count("system.processes.iowrite_bytes", writeBytes, "", tags)
+	}
+}
+
+func readPidFile(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// procRoot returns the procfs mount point, which is the one of the host in containers.
+func procRoot() string {
+	if config.Datadog.IsSet("procfs_path") {
+		return filepath.Clean(config.Datadog.GetString("procfs_path"))
+	}
+	return "/proc"
+}
+
+func processFactory() check.Check {
+	return &ProcessCheck{
+		CheckBase: core.NewCheckBase(processCheckName),
+		table:     newProcTable(procRoot()),
+		users:     make(map[string]string),
+	}
+}
+
+func init() {
+	core.RegisterCheck(processCheckName, processFactory)
+}
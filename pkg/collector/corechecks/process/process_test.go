@@ -0,0 +1,252 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+// +build linux
+
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/procfs"
+)
+
+// fakeProc is a process of a fake procfs.
+type fakeProc struct {
+	pid       int
+	comm      string
+	cmdline   []string
+	uid       int
+	cpuTicks  int
+	startTime int
+	fds       int
+	ctx       int
+	ioBytes   int
+}
+
+func writeFakeProc(t *testing.T, root string, p fakeProc) {
+	dir := filepath.Join(root, strconv.Itoa(p.pid))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "fd"), 0755))
+	files := map[string]string{
+		// utime and stime are fields 14 and 15, num_threads 20, starttime 22, vsize 23 and rss 24
+		"stat": fmt.Sprintf("%d (%s) S 1 1 1 0 -1 4194560 100 0 0 0 %d %d 0 0 20 0 3 0 %d 1048576 4 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n",
+			p.pid, p.comm, p.cpuTicks, p.cpuTicks, p.startTime),
+		"cmdline": strings.Join(p.cmdline, "\x00") + "\x00",
+		"status": fmt.Sprintf("Name:\t%s\nUid:\t%d\t%d\t%d\t%d\nvoluntary_ctxt_switches:\t%d\nnonvoluntary_ctxt_switches:\t%d\n",
+			p.comm, p.uid, p.uid, p.uid, p.uid, p.ctx, p.ctx),
+		"io": fmt.Sprintf("rchar: 0\nwchar: 0\nsyscr: %d\nsyscw: %d\nread_bytes: %d\nwrite_bytes: %d\n",
+			p.ioBytes/10, p.ioBytes/10, p.ioBytes, p.ioBytes),
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	for i := 0; i < p.fds; i++ {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "fd", strconv.Itoa(i)), nil, 0644))
+	}
+}
+
+// newFakeProcfs returns the root of a fake procfs with the given processes, booted
+// an hour ago.
+func newFakeProcfs(t *testing.T, procs ...fakeProc) string {
+	root, err := ioutil.TempDir("", "procfs")
+	require.NoError(t, err)
+	btime := time.Now().Add(-time.Hour).Unix()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "stat"), []byte(fmt.Sprintf("cpu  1 2 3 4\nbtime %d\nprocesses 100\n", btime)), 0644))
+	for _, p := range procs {
+		writeFakeProc(t, root, p)
+	}
+	return root
+}
+
+func newTestCheck(t *testing.T, root, config string) (*ProcessCheck, *mocksender.MockSender) {
+	c := processFactory().(*ProcessCheck)
+	c.table = newProcTable(root)
+	sender := mocksender.NewConfiguredMockSender(t, c, config, "")
+	return c, sender
+}
+
+func TestConfigure(t *testing.T) {
+	var cfg processConfig
+	require.NoError(t, cfg.parse([]byte(`{name: web, search_string: [nginx], thresholds: {warning: [2, 4]}}`)))
+	assert.True(t, cfg.exactMatch)
+	assert.Nil(t, cfg.critical)
+	assert.Equal(t, &bounds{2, 4}, cfg.warning)
+	assert.Equal(t, 120*time.Second, cfg.pidCacheDuration)
+
+	require.NoError(t, cfg.parse([]byte(`{name: web, search_string: [nginx]}`)))
+	assert.Equal(t, &bounds{1, 2147483647}, cfg.critical)
+	assert.Nil(t, cfg.warning)
+
+	assert.Error(t, cfg.parse([]byte(`search_string: [nginx]`)))
+	assert.Error(t, cfg.parse([]byte(`name: web`)))
+	assert.Error(t, cfg.parse([]byte(`{name: web, cmdline_regex: "("}`)))
+	assert.Error(t, cfg.parse([]byte(`{name: web, signatures: [nginx], integration: nginx}`)))
+	assert.Error(t, cfg.parse([]byte(`{name: web, user: www, thresholds: {critical: [3, 1]}}`)))
+	assert.Error(t, cfg.parse([]byte(`{name: web, user: www, thresholds: {ok: [1, 3]}}`)))
+}
+
+func TestMatches(t *testing.T) {
+	nginx := &procEntry{name: "nginx", cmdline: []string{"nginx: worker process"}}
+	java := &procEntry{name: "java", cmdline: []string{"/usr/bin/java", "-Xmx1g", "org.apache.cassandra.service.CassandraDaemon"}}
+
+	for _, tc := range []struct {
+		config  string
+		matches []bool
+	}{
+		{`{name: t, search_string: [nginx, java]}`, []bool{true, true}},
+		{`{name: t, search_string: [nginx:]}`, []bool{false, false}},
+		{`{name: t, search_string: [nginx:], exact_match: false}`, []bool{true, false}},
+		{`{name: t, cmdline_regex: "-Xmx\\d+g"}`, []bool{false, true}},
+		{`{name: t, search_string: [java], cmdline_regex: "elasticsearch"}`, []bool{false, false}},
+		{`{name: t, integration: cassandra}`, []bool{false, true}},
+		{`{name: t, integration: Apache}`, []bool{false, false}},
+		{`{name: t, signatures: ["worker process"]}`, []bool{true, false}},
+		{`{name: t, signatures: ["java cassandra"]}`, []bool{false, false}},
+	} {
+		t.Run(tc.config, func(t *testing.T) {
+			var cfg processConfig
+			require.NoError(t, cfg.parse([]byte(tc.config)))
+			assert.Equal(t, tc.matches, []bool{cfg.matches(nginx), cfg.matches(java)})
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	var cfg processConfig
+	require.NoError(t, cfg.parse([]byte(`{name: web, user: www, thresholds: {critical: [1, 10], warning: [2, 4]}}`)))
+	for n, expected := range map[int]metrics.ServiceCheckStatus{
+		0:  metrics.ServiceCheckCritical,
+		1:  metrics.ServiceCheckWarning,
+		3:  metrics.ServiceCheckOK,
+		5:  metrics.ServiceCheckWarning,
+		11: metrics.ServiceCheckCritical,
+	} {
+		status, _ := cfg.status(n)
+		assert.Equal(t, expected, status, "%d processes", n)
+	}
+	_, message := cfg.status(0)
+	assert.Equal(t, "Found 0 processes, expected between 1 and 10", message)
+}
+
+func TestReadStat(t *testing.T) {
+	root := newFakeProcfs(t, fakeProc{pid: 42, comm: "my (weird) proc", cpuTicks: 150, startTime: 1000})
+	defer os.RemoveAll(root)
+
+	stat, err := newProcTable(root).readStat(42)
+	require.NoError(t, err)
+	assert.Equal(t, &procfs.Stat{
+		Comm:      "my (weird) proc",
+		PPid:      1,
+		CPUTicks:  300,
+		Threads:   3,
+		StartTime: 1000,
+		VMS:       1048576,
+		RSS:       4,
+	}, stat)
+}
+
+func TestRun(t *testing.T) {
+	worker := fakeProc{pid: 10, comm: "nginx", cmdline: []string{"nginx: worker process"}, uid: 33, startTime: 1000, fds: 4, ctx: 10, ioBytes: 1000}
+	master := fakeProc{pid: 11, comm: "nginx", cmdline: []string{"nginx: master process", "/usr/sbin/nginx"}, uid: 0, startTime: 100, fds: 2, ctx: 10, ioBytes: 1000}
+	other := fakeProc{pid: 12, comm: "sshd", cmdline: []string{"/usr/sbin/sshd", "-D"}, startTime: 100}
+	root := newFakeProcfs(t, worker, master, other)
+	defer os.RemoveAll(root)
+	tags := []string{"process_name:web"}
+	scTags := []string{"process:web"}
+
+	c, sender := newTestCheck(t, root, `{name: web, search_string: [nginx], thresholds: {critical: [1, 5], warning: [1, 1]}}`)
+	require.NoError(t, c.Run())
+
+	pageSize := float64(os.Getpagesize())
+	sender.AssertMetric(t, "Gauge", "system.processes.number", 2, "", tags)
+	sender.AssertMetric(t, "Gauge", "system.processes.mem.rss", 8*pageSize, "", tags)
+	sender.AssertMetric(t, "Gauge", "system.processes.mem.vms", 2*1048576, "", tags)
+	sender.AssertMetric(t, "Gauge", "system.processes.threads", 6, "", tags)
+	sender.AssertMetric(t, "Gauge", "system.processes.open_file_descriptors", 6, "", tags)
+	// The processes started 10s and 1s after the boot, an hour ago
+	sender.AssertMetricInRange(t, "Gauge", "system.processes.run_time.max", 3599, 3600, "", tags)
+	sender.AssertMetricInRange(t, "Gauge", "system.processes.run_time.min", 3590, 3591, "", tags)
+	sender.AssertServiceCheck(t, "process.up", metrics.ServiceCheckWarning, "", scTags, "Found 2 processes, expected between 1 and 1")
+	// The CPU usage and the counters need a previous run
+	sender.AssertNotCalled(t, "Gauge", "system.processes.cpu.pct", mocksender.AnythingBut(nil), "", tags)
+	sender.AssertNotCalled(t, "Count", "system.processes.ioread_bytes", mocksender.AnythingBut(nil), "", tags)
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+
+	// The worker uses 1s of CPU and reads 500 bytes, the master exits
+	worker.cpuTicks += 50
+	worker.ioBytes += 500
+	worker.ctx += 5
+	writeFakeProc(t, root, worker)
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "11")))
+	c.selected[0].stats.time = c.selected[0].stats.time.Add(-10 * time.Second)
+
+	sender.ResetCalls()
+	require.NoError(t, c.Run())
+	sender.AssertMetric(t, "Gauge", "system.processes.number", 1, "", tags)
+	sender.AssertMetricInRange(t, "Gauge", "system.processes.cpu.pct", 9.9, 10.1, "", tags)
+	sender.AssertMetric(t, "Count", "system.processes.ioread_bytes", 500, "", tags)
+	sender.AssertMetric(t, "Count", "system.processes.ioread_count", 50, "", tags)
+	sender.AssertMetric(t, "Count", "system.processes.voluntary_ctx_switches", 5, "", tags)
+	sender.AssertServiceCheck(t, "process.up", metrics.ServiceCheckOK, "", scTags, "")
+}
+
+func TestRunPidCache(t *testing.T) {
+	root := newFakeProcfs(t, fakeProc{pid: 10, comm: "redis-server", cmdline: []string{"/usr/bin/redis-server"}, startTime: 1000})
+	defer os.RemoveAll(root)
+
+	c, sender := newTestCheck(t, root, `{name: redis, search_string: [redis-server]}`)
+	require.NoError(t, c.Run())
+	sender.AssertMetric(t, "Gauge", "system.processes.number", 1, "", nil)
+
+	// The process restarts with another pid, which is only found at the next scan
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "10")))
+	writeFakeProc(t, root, fakeProc{pid: 20, comm: "redis-server", cmdline: []string{"/usr/bin/redis-server"}, startTime: 2000})
+
+	sender.ResetCalls()
+	require.NoError(t, c.Run())
+	sender.AssertMetric(t, "Gauge", "system.processes.number", 0, "", nil)
+	sender.AssertServiceCheck(t, "process.up", metrics.ServiceCheckCritical, "", nil, "Found 0 processes, expected between 1 and 2147483647")
+
+	c.lastScan = c.lastScan.Add(-2 * time.Minute)
+	sender.ResetCalls()
+	require.NoError(t, c.Run())
+	sender.AssertMetric(t, "Gauge", "system.processes.number", 1, "", nil)
+	assert.Equal(t, 20, c.selected[0].pid)
+}
+
+func TestRunPidFile(t *testing.T) {
+	root := newFakeProcfs(t,
+		fakeProc{pid: 10, comm: "postgres", cmdline: []string{"postgres"}, uid: 0, startTime: 1000},
+		fakeProc{pid: 11, comm: "postgres", cmdline: []string{"postgres: writer process"}, uid: 0, startTime: 1000},
+	)
+	defer os.RemoveAll(root)
+	pidFile := filepath.Join(root, "postgres.pid")
+	require.NoError(t, ioutil.WriteFile(pidFile, []byte("10\n"), 0644))
+
+	c, sender := newTestCheck(t, root, fmt.Sprintf(`{name: postgres, pid_file: %s, user: root}`, pidFile))
+	require.NoError(t, c.Run())
+	sender.AssertMetric(t, "Gauge", "system.processes.number", 1, "", nil)
+	assert.Equal(t, 10, c.selected[0].pid)
+
+	c, sender = newTestCheck(t, root, fmt.Sprintf(`{name: postgres, pid_file: %s, user: "1000"}`, pidFile))
+	require.NoError(t, c.Run())
+	sender.AssertMetric(t, "Gauge", "system.processes.number", 0, "", nil)
+
+	c, sender = newTestCheck(t, root, `{name: postgres, pid_file: /does/not/exist}`)
+	require.NoError(t, c.Run())
+	sender.AssertMetric(t, "Gauge", "system.processes.number", 0, "", nil)
+	sender.AssertServiceCheck(t, "process.up", metrics.ServiceCheckCritical, "", nil, "Found 0 processes, expected between 1 and 2147483647")
+}
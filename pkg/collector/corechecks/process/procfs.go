@@ -0,0 +1,262 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+// +build linux
+
+package process
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/procfs"
+)
+
+// userHZ is the number of clock ticks per second in /proc, which is 100 on all
+// the architectures the agent supports.
+const userHZ = 100
+
+// procEntry is a process of the process table. Its name, command line and owner
+// are read once, its stats at every run.
+type procEntry struct {
+	pid       int
+	startTime uint64 // in clock ticks after boot, tells a process from a former one with the same pid
+	name      string
+	cmdline   []string
+	uid       string
+
+	stats *procStats
+	prev  *procStats // the stats of the previous run, nil if the process is new
+}
+
+// procStats holds the resource usage of a process at a given time.
+type procStats struct {
+	time     time.Time
+	cpuTicks uint64
+	threads  uint64
+	rss      uint64
+	vms      uint64
+	fds      int    // -1 if the fd directory can't be read
+	volCtx   uint64 // voluntary context switches
+	involCtx uint64 // involuntary context switches
+	io       *procIO
+}
+
+// procIO holds the IO counters of a process, only readable by its owner or root.
+type procIO struct {
+	readCount  uint64
+	writeCount uint64
+	readBytes  uint64
+	writeBytes uint64
+}
+
+// procTable reads processes under a procfs mount point, and caches them between runs.
+type procTable struct {
+	root     string
+	procs    map[int]*procEntry
+	bootTime time.Time
+}
+
+func newProcTable(root string) *procTable {
+	return &procTable{root: root, procs: make(map[int]*procEntry)}
+}
+
+// scan lists the running processes. The name, command line and owner of the processes
+// already known are not read again.
+func (t *procTable) scan() ([]*procEntry, error) {
+	if err := t.loadBootTime(); err != nil {
+		return nil, err
+	}
+	dirs, err := ioutil.ReadDir(t.root)
+	if err != nil {
+		return nil, err
+	}
+	procs := make(map[int]*procEntry, len(t.procs))
+	entries := make([]*procEntry, 0, len(t.procs))
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil || !d.IsDir() {
+			continue
+		}
+		stat, err := t.readStat(pid)
+		if err != nil {
+			// The process exited
+			continue
+		}
+		p, ok := t.procs[pid]
+		if !ok || p.startTime != stat.StartTime {
+			if p, err = t.readEntry(pid, stat); err != nil {
+				continue
+			}
+		}
+		procs[pid] = p
+		entries = append(entries, p)
+	}
+	t.procs = procs
+	return entries, nil
+}
+
+// entry returns the process with the given pid, nil if it isn't running. It is
+// used instead of scan to look up a single process, the others are forgotten.
+func (t *procTable) entry(pid int) (*procEntry, error) {
+	if err := t.loadBootTime(); err != nil {
+		return nil, err
+	}
+	stat, err := t.readStat(pid)
+	if err != nil {
+		return nil, nil
+	}
+	p, ok := t.procs[pid]
+	if !ok || p.startTime != stat.StartTime {
+		if p, err = t.readEntry(pid, stat); err != nil {
+			return nil, nil
+		}
+	}
+	t.procs = map[int]*procEntry{pid: p}
+	return p, nil
+}
+
+// readEntry reads the name, command line and owner of a process.
+func (t *procTable) readEntry(pid int, stat *procfs.Stat) (*procEntry, error) {
+	p := &procEntry{pid: pid, startTime: stat.StartTime, name: stat.Comm}
+	data, err := ioutil.ReadFile(t.path(pid, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	if data = bytes.TrimRight(data, "\x00"); len(data) > 0 {
+		p.cmdline = strings.Split(string(data), "\x00")
+	}
+	// comm is truncated to 15 characters, use the name of the executable instead
+	if len(p.cmdline) > 0 && len(p.name) == 15 {
+		if exe := filepath.Base(p.cmdline[0]); strings.HasPrefix(exe, p.name) {
+			p.name = exe
+		}
+	}
+	status, err := t.readStatus(pid)
+	if err != nil {
+		return nil, err
+	}
+	if uids := strings.Fields(status["Uid"]); len(uids) > 0 {
+		p.uid = uids[0]
+	}
+	return p, nil
+}
+
+// update reads the stats of a process, and returns false if it exited or if its pid
+// was reused.
+func (t *procTable) update(p *procEntry, now time.Time) bool {
+	stat, err := t.readStat(p.pid)
+	if err != nil || stat.StartTime != p.startTime {
+		return false
+	}
+	status, err := t.readStatus(p.pid)
+	if err != nil {
+		return false
+	}
+	stats := &procStats{
+		time:     now,
+		cpuTicks: stat.CPUTicks,
+		threads:  stat.Threads,
+		rss:      stat.RSS * uint64(os.Getpagesize()),
+		vms:      stat.VMS,
+		fds:      -1,
+		volCtx:   parseUint(status["voluntary_ctxt_switches"]),
+		involCtx: parseUint(status["nonvoluntary_ctxt_switches"]),
+	}
+	if fds, err := ioutil.ReadDir(t.path(p.pid, "fd")); err == nil {
+		stats.fds = len(fds)
+	}
+	stats.io, _ = t.readIO(p.pid)
+
+	p.prev, p.stats = p.stats, stats
+	return true
+}
+
+// runTime returns how long a process has been running.
+func (t *procTable) runTime(p *procEntry, now time.Time) time.Duration {
+	start := t.bootTime.Add(time.Duration(p.startTime) * time.Second / userHZ)
+	return now.Sub(start)
+}
+
+func (t *procTable) path(pid int, file string) string {
+	return filepath.Join(t.root, strconv.Itoa(pid), file)
+}
+
+// loadBootTime reads the boot time of the host from /proc/stat, once.
+func (t *procTable) loadBootTime() error {
+	if !t.bootTime.IsZero() {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(t.root, "stat"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "btime" {
+			btime, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return err
+			}
+			t.bootTime = time.Unix(btime, 0)
+			return nil
+		}
+	}
+	return fmt.Errorf("btime not found in %s", f.Name())
+}
+
+// readStat parses /proc/<pid>/stat.
+func (t *procTable) readStat(pid int) (*procfs.Stat, error) {
+	return procfs.ReadStat(t.path(pid, "stat"))
+}
+
+// readStatus parses the "key: value" lines of /proc/<pid>/status.
+func (t *procTable) readStatus(pid int) (map[string]string, error) {
+	return readKeyValues(t.path(pid, "status"))
+}
+
+// readIO parses /proc/<pid>/io.
+func (t *procTable) readIO(pid int) (*procIO, error) {
+	values, err := readKeyValues(t.path(pid, "io"))
+	if err != nil {
+		return nil, err
+	}
+	return &procIO{
+		readCount:  parseUint(values["syscr"]),
+		writeCount: parseUint(values["syscw"]),
+		readBytes:  parseUint(values["read_bytes"]),
+		writeBytes: parseUint(values["write_bytes"]),
+	}, nil
+}
+
+func readKeyValues(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 {
+			values[parts[0]] = strings.TrimSpace(parts[1])
+		}
+	}
+	return values, scanner.Err()
+}
+
+func parseUint(s string) uint64 {
+	v, _ := strconv.ParseUint(s, 10, 64)
+	return v
+}
@@ -0,0 +1,297 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/snmp"
+)
+
+// Types under which metrics can be forced to be submitted
+const (
+	forcedTypeGauge          = "gauge"
+	forcedTypeRate           = "rate"
+	forcedTypeMonotonicCount = "monotonic_count"
+)
+
+type symbolConfig struct {
+	OID  string `yaml:"OID"`
+	Name string `yaml:"name"`
+}
+
+// metricTagConfig is a tag set from the value of a scalar OID when OID is set,
+// and from a column or the index of the rows of a table otherwise.
+type metricTagConfig struct {
+	Tag    string       `yaml:"tag"`
+	OID    string       `yaml:"OID"`
+	Symbol string       `yaml:"symbol"`
+	Column symbolConfig `yaml:"column"`
+	Index  int          `yaml:"index"`
+}
+
+// metricsConfig is a scalar metric when Symbol is set, and the columns of a
+// table otherwise. The MIB is informative only.
+type metricsConfig struct {
+	MIB        string            `yaml:"MIB"`
+	Symbol     symbolConfig      `yaml:"symbol"`
+	Table      symbolConfig      `yaml:"table"`
+	Symbols    []symbolConfig    `yaml:"symbols"`
+	MetricTags []metricTagConfig `yaml:"metric_tags"`
+	ForcedType string            `yaml:"forced_type"`
+}
+
+type profileDefinition struct {
+	SysObjectIDs stringList        `yaml:"sysobjectid"`
+	Metrics      []metricsConfig   `yaml:"metrics"`
+	MetricTags   []metricTagConfig `yaml:"metric_tags"`
+}
+
+// stringList unmarshals a string or a list of strings
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*l = stringList{s}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+type scalarMetric struct {
+	oid        snmp.OID
+	name       string
+	forcedType string
+}
+
+type column struct {
+	oid  snmp.OID
+	name string
+}
+
+// columnTag tags the rows of a table with the value of a column, or with a
+// component of their index when column is nil.
+type columnTag struct {
+	tag    string
+	column snmp.OID
+	index  int
+}
+
+type tableMetric struct {
+	name       string
+	columns    []column
+	tags       []columnTag
+	forcedType string
+}
+
+type scalarTag struct {
+	tag  string
+	oid  snmp.OID
+	name string
+}
+
+// profile holds the metrics and tags to collect from a device
+type profile struct {
+	name         string
+	sysObjectIDs []string
+	scalars      []scalarMetric
+	tables       []tableMetric
+	tags         []scalarTag
+}
+
+func parseOID(s, name string) (snmp.OID, error) {
+	oid, err := snmp.ParseOID(s)
+	if err != nil {
+		if name != "" {
+			return nil, fmt.Errorf("invalid OID of %s: %s", name, err)
+		}
+		return nil, err
+	}
+	return oid, nil
+}
+
+func checkForcedType(t string) error {
+	switch t {
+	case "", forcedTypeGauge, forcedTypeRate, forcedTypeMonotonicCount:
+		return nil
+	}
+	return fmt.Errorf("unsupported forced_type %q, expected %s, %s or %s", t, forcedTypeGauge, forcedTypeRate, forcedTypeMonotonicCount)
+}
+
+// add compiles metrics and metric tags definitions into the profile.
+func (p *profile) add(metrics []metricsConfig, tags []metricTagConfig) error {
+	for _, m := range metrics {
+		if err := checkForcedType(m.ForcedType); err != nil {
+			return err
+		}
+		if m.Symbol.OID != "" {
+			if m.Symbol.Name == "" {
+				return fmt.Errorf("the symbol %s has no name", m.Symbol.OID)
+			}
+			oid, err := parseOID(m.Symbol.OID, m.Symbol.Name)
+			if err != nil {
+				return err
+			}
+			p.scalars = append(p.scalars, scalarMetric{oid: oid, name: m.Symbol.Name, forcedType: m.ForcedType})
+			continue
+		}
+		if len(m.Symbols) == 0 {
+			return errors.New("a metric needs either a symbol, or a table and its symbols")
+		}
+		table := tableMetric{name: m.Table.Name, forcedType: m.ForcedType}
+		for _, s := range m.Symbols {
+			if s.Name == "" {
+				return fmt.Errorf("the symbol %s of table %s has no name", s.OID, m.Table.Name)
+			}
+			oid, err := parseOID(s.OID, s.Name)
+			if err != nil {
+				return err
+			}
+			table.columns = append(table.columns, column{oid: oid, name: s.Name})
+		}
+		for _, t := range m.MetricTags {
+			if t.Tag == "" {
+				return fmt.Errorf("a metric tag of table %s has no tag name", m.Table.Name)
+			}
+			switch {
+			case t.Column.OID != "":
+				oid, err := parseOID(t.Column.OID, t.Column.Name)
+				if err != nil {
+					return err
+				}
+				table.tags = append(table.tags, columnTag{tag: t.Tag, column: oid})
+			case t.Index > 0:
+				table.tags = append(table.tags, columnTag{tag: t.Tag, index: t.Index})
+			default:
+				return fmt.Errorf("the tag %s of table %s needs a column or an index", t.Tag, m.Table.Name)
+			}
+		}
+		p.tables = append(p.tables, table)
+	}
+
+	for _, t := range tags {
+		if t.Tag == "" || t.OID == "" {
+			return errors.New("a metric tag needs a tag name and an OID")
+		}
+		oid, err := parseOID(t.OID, t.Symbol)
+		if err != nil {
+			return err
+		}
+		p.tags = append(p.tags, scalarTag{tag: t.Tag, oid: oid, name: t.Symbol})
+	}
+	return nil
+}
+
+// profileConfig is the configuration of a profile in init_config, defined in a
+// file or inline.
+type profileConfig struct {
+	DefinitionFile string            `yaml:"definition_file"`
+	Definition     profileDefinition `yaml:"definition"`
+}
+
+// loadProfiles returns the profiles defined in the files of dir, named after the
+// files, and in init_config. The definition files of init_config are relative to dir.
+func loadProfiles(dir string, configs map[string]profileConfig) (map[string]*profile, error) {
+	profiles := make(map[string]*profile)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".yaml")
+		p, err := loadProfileFile(name, file)
+		if err != nil {
+			// an invalid profile doesn't prevent the other ones from being used
+			log.Errorf("Could not load the SNMP profile %s: %s", file, err)
+			continue
+		}
+		profiles[name] = p
+	}
+
+	for name, cfg := range configs {
+		var p *profile
+		var err error
+		if cfg.DefinitionFile != "" {
+			file := cfg.DefinitionFile
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(dir, file)
+			}
+			p, err = loadProfileFile(name, file)
+		} else {
+			p, err = newProfile(name, cfg.Definition)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile %s: %s", name, err)
+		}
+		profiles[name] = p
+	}
+	return profiles, nil
+}
+
+func loadProfileFile(name, file string) (*profile, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var def profileDefinition
+	if err = yaml.Unmarshal(data, &def); err != nil {
+		return nil, err
+	}
+	return newProfile(name, def)
+}
+
+func newProfile(name string, def profileDefinition) (*profile, error) {
+	p := &profile{name: name, sysObjectIDs: def.SysObjectIDs}
+	for _, pattern := range p.sysObjectIDs {
+		if _, err := parseOID(strings.TrimSuffix(pattern, ".*"), "sysobjectid"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.add(def.Metrics, def.MetricTags); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// matchProfile returns the profile matching a sysObjectID most specifically.
+// Profiles match a sysObjectID exactly, or a subtree with a pattern ending in ".*".
+func matchProfile(profiles map[string]*profile, sysObjectID string) *profile {
+	var match *profile
+	matchLength := -1
+	for _, p := range profiles {
+		for _, pattern := range p.sysObjectIDs {
+			pattern = strings.TrimPrefix(pattern, ".")
+			length := len(pattern)
+			if strings.HasSuffix(pattern, ".*") {
+				pattern = strings.TrimSuffix(pattern, "*")
+				if !strings.HasPrefix(sysObjectID, pattern) {
+					continue
+				}
+				// an exact match is more specific than any subtree
+				length--
+			} else if pattern != sysObjectID {
+				continue
+			} else {
+				length++
+			}
+			// the name breaks ties, so that the match doesn't depend on the map order
+			if length > matchLength || length == matchLength && p.name < match.name {
+				match, matchLength = p, length
+			}
+		}
+	}
+	return match
+}
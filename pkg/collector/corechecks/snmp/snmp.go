@@ -0,0 +1,566 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/snmp"
+)
+
+// The check is not named "snmp" so as not to be shadowed by the Python check of
+// the same name, which is loaded first.
+const snmpCheckName = "snmp_core"
+
+const (
+	defaultTimeout            = 1
+	defaultRetries            = 5
+	defaultBulkMaxRepetitions = 10
+	defaultOIDBatchSize       = 10
+	defaultWorkers            = 1
+
+	metricPrefix     = "snmp."
+	serviceCheckName = "snmp.can_check"
+)
+
+// sysObjectIDOID identifies the kind of device, it selects the profile of the devices
+var sysObjectIDOID = snmp.OID{1, 3, 6, 1, 2, 1, 1, 2, 0}
+
+// SNMPCheck polls the metrics of a device over SNMP
+type SNMPCheck struct {
+	core.CheckBase
+	cfg      *snmpConfig
+	profiles map[string]*profile
+	// profile is the profile of the device, matched by its sysObjectID at the
+	// first run when the instance doesn't set one
+	profile *profile
+}
+
+type snmpInitConfig struct {
+	Profiles map[string]profileConfig `yaml:"profiles"`
+}
+
+type snmpInstanceConfig struct {
+	IPAddress          string            `yaml:"ip_address"`
+	Port               uint16            `yaml:"port"`
+	SNMPVersion        int               `yaml:"snmp_version"`
+	CommunityString    string            `yaml:"community_string"`
+	User               string            `yaml:"user"`
+	AuthProtocol       string            `yaml:"auth_protocol"`
+	AuthKey            string            `yaml:"auth_key"`
+	PrivProtocol       string            `yaml:"priv_protocol"`
+	PrivKey            string            `yaml:"priv_key"`
+	ContextName        string            `yaml:"context_name"`
+	Timeout            int               `yaml:"timeout"`
+	Retries            *int              `yaml:"retries"`
+	BulkMaxRepetitions int               `yaml:"bulk_max_repetitions"`
+	OIDBatchSize       int               `yaml:"oid_batch_size"`
+	Workers            int               `yaml:"workers"`
+	Profile            string            `yaml:"profile"`
+	Metrics            []metricsConfig   `yaml:"metrics"`
+	MetricTags         []metricTagConfig `yaml:"metric_tags"`
+}
+
+type snmpConfig struct {
+	client       snmp.Client
+	oidBatchSize int
+	workers      int
+	profileName  string
+	// custom holds the metrics and tags set in the instance, collected along
+	// with the ones of the profile
+	custom *profile
+}
+
+func (c *snmpConfig) parse(data []byte) error {
+	var instance snmpInstanceConfig
+	if err := yaml.Unmarshal(data, &instance); err != nil {
+		return err
+	}
+	if instance.IPAddress == "" {
+		return errors.New("ip_address is required")
+	}
+
+	c.client = snmp.Client{
+		Target:         instance.IPAddress,
+		Port:           instance.Port,
+		Community:      instance.CommunityString,
+		ContextName:    instance.ContextName,
+		Timeout:        time.Duration(instance.Timeout) * time.Second,
+		Retries:        defaultRetries,
+		MaxRepetitions: instance.BulkMaxRepetitions,
+	}
+	if c.client.Port == 0 {
+		c.client.Port = snmp.DefaultPort
+	}
+	if instance.Timeout <= 0 {
+		c.client.Timeout = defaultTimeout * time.Second
+	}
+	if instance.Retries != nil && *instance.Retries >= 0 {
+		c.client.Retries = *instance.Retries
+	}
+	if c.client.MaxRepetitions <= 0 {
+		c.client.MaxRepetitions = defaultBulkMaxRepetitions
+	}
+
+	switch instance.SNMPVersion {
+	case 1:
+		c.client.Version = snmp.Version1
+	case 0, 2:
+		c.client.Version = snmp.Version2c
+	case 3:
+		c.client.Version = snmp.Version3
+	default:
+		return fmt.Errorf("unsupported snmp_version %d, expected 1, 2 or 3", instance.SNMPVersion)
+	}
+	if c.client.Version == snmp.Version3 {
+		if err := c.parseUser(instance); err != nil {
+			return err
+		}
+	} else if c.client.Community == "" {
+		return fmt.Errorf("community_string is required by SNMP %s", c.client.Version)
+	}
+
+	c.oidBatchSize = instance.OIDBatchSize
+	if c.oidBatchSize <= 0 {
+		c.oidBatchSize = defaultOIDBatchSize
+	}
+	c.workers = instance.Workers
+	if c.workers <= 0 {
+		c.workers = defaultWorkers
+	}
+
+	c.profileName = instance.Profile
+	c.custom = &profile{}
+	return c.custom.add(instance.Metrics, instance.MetricTags)
+}
+
+// parseUser sets the SNMPv3 credentials. The authentication protocol defaults
+// to MD5 when a key is set, and the privacy protocol to DES.
+func (c *snmpConfig) parseUser(instance snmpInstanceConfig) error {
+	if instance.User == "" {
+		return errors.New("user is required by SNMP v3")
+	}
+	authProtocol, err := snmp.ParseAuthProtocol(instance.AuthProtocol)
+	if err != nil {
+		return err
+	}
+	privProtocol, err := snmp.ParsePrivProtocol(instance.PrivProtocol)
+	if err != nil {
+		return err
+	}
+	if authProtocol == snmp.NoAuth && instance.AuthKey != "" {
+		authProtocol = snmp.MD5
+	}
+	if privProtocol == snmp.NoPriv && instance.PrivKey != "" {
+		privProtocol = snmp.DES
+	}
+	if authProtocol != snmp.NoAuth && instance.AuthKey == "" {
+		return errors.New("auth_key is required by auth_protocol")
+	}
+	if privProtocol != snmp.NoPriv {
+		if instance.PrivKey == "" {
+			return errors.New("priv_key is required by priv_protocol")
+		}
+		if authProtocol == snmp.NoAuth {
+			return errors.New("privacy requires authentication, auth_protocol and auth_key must be set")
+		}
+	}
+	c.client.User = snmp.User{
+		Name:           instance.User,
+		AuthProtocol:   authProtocol,
+		AuthPassphrase: instance.AuthKey,
+		PrivProtocol:   privProtocol,
+		PrivPassphrase: instance.PrivKey,
+	}
+	return nil
+}
+
+// profilesDir returns the directory of the profile definition files
+func profilesDir() string {
+	return filepath.Join(config.Datadog.GetString("confd_path"), snmpCheckName+".d", "profiles")
+}
+
+// Configure parses the check configuration and loads the profiles
+func (c *SNMPCheck) Configure(data integration.Data, initConfig integration.Data) error {
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+	cfg := new(snmpConfig)
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+	var initCfg snmpInitConfig
+	if err := yaml.Unmarshal(initConfig, &initCfg); err != nil {
+		return err
+	}
+	profiles, err := loadProfiles(profilesDir(), initCfg.Profiles)
+	if err != nil {
+		return err
+	}
+	if cfg.profileName != "" {
+		if _, ok := profiles[cfg.profileName]; !ok {
+			return fmt.Errorf("unknown profile %s", cfg.profileName)
+		}
+	}
+	c.cfg = cfg
+	c.profiles = profiles
+	c.profile = profiles[cfg.profileName]
+	return nil
+}
+
+// Run polls the device and submits its metrics
+func (c *SNMPCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	tags := []string{"snmp_device:" + c.cfg.client.Target}
+	pool, err := c.connect()
+	if err != nil {
+		err = fmt.Errorf("could not connect to %s: %s", c.cfg.client.String(), err)
+		sender.ServiceCheck(serviceCheckName, metrics.ServiceCheckCritical, "", tags, err.Error())
+		sender.Commit()
+		return err
+	}
+	defer pool.close()
+
+	// the sysObjectID tells whether the device is reachable, and selects its profile
+	variables, err := pool.get([]snmp.OID{sysObjectIDOID})
+	if err == nil && (len(variables) != 1 || variables[0].Type != snmp.TagOID) {
+		err = errors.New("invalid sysObjectID")
+	}
+	if err != nil {
+		err = fmt.Errorf("could not poll %s: %s", c.cfg.client.String(), err)
+		sender.ServiceCheck(serviceCheckName, metrics.ServiceCheckCritical, "", tags, err.Error())
+		sender.Commit()
+		return err
+	}
+	sysObjectID := variables[0].Value.(snmp.OID).String()
+
+	if c.profile == nil && c.cfg.profileName == "" && len(c.cfg.custom.scalars) == 0 && len(c.cfg.custom.tables) == 0 {
+		if c.profile = matchProfile(c.profiles, sysObjectID); c.profile == nil {
+			err = fmt.Errorf("no profile matches the sysObjectID %s of %s, set the metrics to collect", sysObjectID, c.cfg.client.String())
+			sender.ServiceCheck(serviceCheckName, metrics.ServiceCheckCritical, "", tags, err.Error())
+			sender.Commit()
+			return err
+		}
+		log.Infof("Using the SNMP profile %s for %s, matching its sysObjectID %s", c.profile.name, c.cfg.client.String(), sysObjectID)
+	}
+	profiles := []*profile{c.cfg.custom}
+	metricTags := append([]string{}, tags...)
+	if c.profile != nil {
+		profiles = append(profiles, c.profile)
+		metricTags = append(metricTags, "snmp_profile:"+c.profile.name)
+	}
+
+	errs := c.collect(sender, pool, profiles, metricTags)
+	if len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for _, e := range errs {
+			messages = append(messages, e.Error())
+		}
+		err = fmt.Errorf("could not poll all the metrics of %s: %s", c.cfg.client.String(), strings.Join(messages, ", "))
+		sender.ServiceCheck(serviceCheckName, metrics.ServiceCheckWarning, "", tags, err.Error())
+	} else {
+		sender.ServiceCheck(serviceCheckName, metrics.ServiceCheckOK, "", tags, "")
+	}
+	sender.Commit()
+	return err
+}
+
+// connect returns a pool of clients connected to the device, as many as the
+// number of concurrent requests allowed.
+func (c *SNMPCheck) connect() (*clientPool, error) {
+	p := &clientPool{
+		clients:      make(chan *snmp.Client, c.cfg.workers),
+		oidBatchSize: c.cfg.oidBatchSize,
+	}
+	for i := 0; i < c.cfg.workers; i++ {
+		client := c.cfg.client
+		if err := client.Connect(); err != nil {
+			p.close()
+			return nil, err
+		}
+		p.clients <- &client
+	}
+	return p, nil
+}
+
+// collect polls the scalars and tables of the profiles and submits them. It
+// returns the errors of the requests that failed.
+func (c *SNMPCheck) collect(sender aggregator.Sender, pool *clientPool, profiles []*profile, tags []string) []error {
+	var scalarOIDs []snmp.OID
+	var scalarTags []scalarTag
+	var scalars []scalarMetric
+	var tables []tableMetric
+	for _, p := range profiles {
+		for _, t := range p.tags {
+			scalarOIDs = append(scalarOIDs, t.oid)
+		}
+		scalarTags = append(scalarTags, p.tags...)
+		for _, s := range p.scalars {
+			scalarOIDs = append(scalarOIDs, s.oid)
+		}
+		scalars = append(scalars, p.scalars...)
+		tables = append(tables, p.tables...)
+	}
+
+	// the columns of the tables are walked concurrently with the scalars
+	var columnOIDs []snmp.OID
+	for _, t := range tables {
+		for _, col := range t.columns {
+			columnOIDs = append(columnOIDs, col.oid)
+		}
+		for _, tag := range t.tags {
+			if tag.column != nil {
+				columnOIDs = append(columnOIDs, tag.column)
+			}
+		}
+	}
+	values, columns, errs := pool.poll(scalarOIDs, columnOIDs)
+
+	var missing []string
+	for _, t := range scalarTags {
+		v, ok := values[t.oid.String()]
+		if !ok {
+			missing = append(missing, symbolName(t.oid, t.name))
+			continue
+		}
+		tags = append(tags, t.tag+":"+v.String())
+	}
+
+	for _, s := range scalars {
+		v, ok := values[s.oid.String()]
+		if !ok {
+			missing = append(missing, symbolName(s.oid, s.name))
+			continue
+		}
+		c.submit(sender, s.name, s.forcedType, v, tags)
+	}
+	if len(missing) > 0 {
+		c.Warnf("The device %s doesn't expose %s", c.cfg.client.String(), strings.Join(missing, ", "))
+	}
+
+	for _, t := range tables {
+		for _, col := range t.columns {
+			rows := columns[col.oid.String()]
+			for _, index := range sortedIndexes(rows) {
+				rowTags, err := t.rowTags(index, columns)
+				if err != nil {
+					c.Warnf("Could not tag the row %s of %s: %s", index, col.name, err)
+					continue
+				}
+				c.submit(sender, col.name, t.forcedType, rows[index], append(rowTags, tags...))
+			}
+		}
+	}
+	return errs
+}
+
+func symbolName(oid snmp.OID, name string) string {
+	if name == "" {
+		return oid.String()
+	}
+	return fmt.Sprintf("%s (%s)", name, oid)
+}
+
+func sortedIndexes(rows map[string]snmp.Variable) []string {
+	indexes := make([]string, 0, len(rows))
+	for index := range rows {
+		indexes = append(indexes, index)
+	}
+	sort.Strings(indexes)
+	return indexes
+}
+
+// rowTags returns the tags of the row of the given index in a table.
+func (t *tableMetric) rowTags(index string, columns map[string]map[string]snmp.Variable) ([]string, error) {
+	var tags []string
+	for _, tag := range t.tags {
+		if tag.column != nil {
+			// tag columns may belong to another table sharing the same indexes
+			if v, ok := columns[tag.column.String()][index]; ok {
+				tags = append(tags, tag.tag+":"+v.String())
+			}
+			continue
+		}
+		components := strings.Split(index, ".")
+		if tag.index > len(components) {
+			return nil, fmt.Errorf("the index has no component %d", tag.index)
+		}
+		tags = append(tags, tag.tag+":"+components[tag.index-1])
+	}
+	return tags, nil
+}
+
+// submit sends a metric, as a rate for counters and a gauge otherwise, unless
+// its type is forced.
+func (c *SNMPCheck) submit(sender aggregator.Sender, name, forcedType string, v snmp.Variable, tags []string) {
+	value, ok := v.Float()
+	if !ok {
+		log.Debugf("The value of %s (%s) on %s isn't numeric, it isn't submitted", name, v.OID, c.cfg.client.String())
+		return
+	}
+	if forcedType == "" {
+		forcedType = forcedTypeGauge
+		if v.Type == snmp.TagCounter32 || v.Type == snmp.TagCounter64 {
+			forcedType = forcedTypeRate
+		}
+	}
+	switch forcedType {
+	case forcedTypeRate:
+		sender.Rate(metricPrefix+name, value, "", tags)
+	case forcedTypeMonotonicCount:
+		sender.MonotonicCount(metricPrefix+name, value, "", tags)
+	default:
+		sender.Gauge(metricPrefix+name, value, "", tags)
+	}
+}
+
+// clientPool limits the number of concurrent requests sent to a device to its
+// number of clients.
+type clientPool struct {
+	clients      chan *snmp.Client
+	oidBatchSize int
+}
+
+func (p *clientPool) close() {
+	close(p.clients)
+	for client := range p.clients {
+		client.Close()
+	}
+}
+
+func (p *clientPool) get(oids []snmp.OID) ([]snmp.Variable, error) {
+	client := <-p.clients
+	defer func() { p.clients <- client }()
+	return client.Get(oids)
+}
+
+// getScalars gets the variables of the OIDs with a client, and returns them by
+// OID. OIDs missing on the device aren't returned.
+func getScalars(client *snmp.Client, oids []snmp.OID) (map[string]snmp.Variable, error) {
+	values := make(map[string]snmp.Variable)
+	for len(oids) > 0 {
+		variables, err := client.Get(oids)
+		if rerr, ok := err.(*snmp.ResponseError); ok && rerr.Status == snmp.NoSuchName && rerr.Index > 0 && rerr.Index <= len(oids) {
+			// SNMPv1 fails the request on the first missing OID, it's retried without it
+			oids = append(oids[:rerr.Index-1:rerr.Index-1], oids[rerr.Index:]...)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range variables {
+			if !v.IsException() && v.Type != snmp.TagNull {
+				values[v.OID.String()] = v
+			}
+		}
+		break
+	}
+	return values, nil
+}
+
+// poll gets the scalars, in batches, and walks the columns, concurrently with
+// the clients of the pool. It returns the values of the scalars by OID, the
+// values of the columns by column OID then row index, and the errors of the
+// requests that failed.
+func (p *clientPool) poll(scalarOIDs, columnOIDs []snmp.OID) (map[string]snmp.Variable, map[string]map[string]snmp.Variable, []error) {
+	var m sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	values := make(map[string]snmp.Variable)
+	columns := make(map[string]map[string]snmp.Variable)
+
+	run := func(task func(*snmp.Client) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := <-p.clients
+			err := task(client)
+			p.clients <- client
+			if err != nil {
+				m.Lock()
+				errs = append(errs, err)
+				m.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < len(scalarOIDs); i += p.oidBatchSize {
+		end := i + p.oidBatchSize
+		if end > len(scalarOIDs) {
+			end = len(scalarOIDs)
+		}
+		batch := scalarOIDs[i:end:end]
+		run(func(client *snmp.Client) error {
+			batchValues, err := getScalars(client, batch)
+			if err != nil {
+				return fmt.Errorf("could not get %s: %s", batch[0], err)
+			}
+			m.Lock()
+			defer m.Unlock()
+			for oid, v := range batchValues {
+				values[oid] = v
+			}
+			return nil
+		})
+	}
+
+	walked := make(map[string]bool)
+	for _, oid := range columnOIDs {
+		root := oid
+		if walked[root.String()] {
+			continue
+		}
+		walked[root.String()] = true
+		run(func(client *snmp.Client) error {
+			rows := make(map[string]snmp.Variable)
+			err := client.Walk(root, func(v snmp.Variable) error {
+				rows[v.OID[len(root):].String()] = v
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("could not walk %s: %s", root, err)
+			}
+			m.Lock()
+			defer m.Unlock()
+			columns[root.String()] = rows
+			return nil
+		})
+	}
+
+	wg.Wait()
+	return values, columns, errs
+}
+
+func snmpFactory() check.Check {
+	return &SNMPCheck{
+		CheckBase: core.NewCheckBase(snmpCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(snmpCheckName, snmpFactory)
+}
@@ -0,0 +1,258 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package snmp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/snmp"
+	"github.com/DataDog/datadog-agent/pkg/util/snmp/snmptest"
+)
+
+func mustParseOID(s string) snmp.OID {
+	oid, err := snmp.ParseOID(s)
+	if err != nil {
+		panic(err)
+	}
+	return oid
+}
+
+var deviceVariables = []snmp.Variable{
+	{OID: mustParseOID("1.3.6.1.2.1.1.1.0"), Type: snmp.TagOctetString, Value: []byte("Linux test 4.19")},
+	{OID: mustParseOID("1.3.6.1.2.1.1.2.0"), Type: snmp.TagOID, Value: mustParseOID("1.3.6.1.4.1.8072.3.2.10")},
+	{OID: mustParseOID("1.3.6.1.2.1.1.3.0"), Type: snmp.TagTimeTicks, Value: uint64(12345)},
+	{OID: mustParseOID("1.3.6.1.2.1.1.5.0"), Type: snmp.TagOctetString, Value: []byte("test-router")},
+	{OID: mustParseOID("1.3.6.1.2.1.2.2.1.8.1"), Type: snmp.TagInteger, Value: int64(1)},
+	{OID: mustParseOID("1.3.6.1.2.1.2.2.1.8.2"), Type: snmp.TagInteger, Value: int64(2)},
+	{OID: mustParseOID("1.3.6.1.2.1.2.2.1.10.1"), Type: snmp.TagCounter32, Value: uint64(1000)},
+	{OID: mustParseOID("1.3.6.1.2.1.2.2.1.10.2"), Type: snmp.TagCounter32, Value: uint64(2000)},
+	{OID: mustParseOID("1.3.6.1.2.1.4.20.1.3.10.0.0.1"), Type: snmp.TagIPAddress, Value: "255.255.255.0"},
+	{OID: mustParseOID("1.3.6.1.2.1.31.1.1.1.1.1"), Type: snmp.TagOctetString, Value: []byte("eth0")},
+	{OID: mustParseOID("1.3.6.1.2.1.31.1.1.1.1.2"), Type: snmp.TagOctetString, Value: []byte("eth1")},
+	{OID: mustParseOID("1.3.6.1.4.1.2021.10.1.6.1"), Type: snmp.TagOpaque, Value: []byte("0.25")},
+}
+
+func startAgent(t *testing.T, users ...snmp.User) *snmptest.Agent {
+	agent := snmptest.NewAgent(deviceVariables)
+	agent.Users = users
+	require.NoError(t, agent.Start())
+	return agent
+}
+
+func newTestCheck(t *testing.T, instance, initConfig string) (*SNMPCheck, *mocksender.MockSender) {
+	config.Datadog.Set("confd_path", "testdata/conf.d")
+	defer config.Datadog.Set("confd_path", "")
+
+	c := snmpFactory().(*SNMPCheck)
+	sender := mocksender.NewConfiguredMockSender(t, c, instance, initConfig)
+	return c, sender
+}
+
+func TestConfigure(t *testing.T) {
+	c, _ := newTestCheck(t, "ip_address: 10.0.0.1\ncommunity_string: public", "")
+	assert.Equal(t, snmp.Version2c, c.cfg.client.Version)
+	assert.Equal(t, uint16(161), c.cfg.client.Port)
+	assert.Equal(t, time.Second, c.cfg.client.Timeout)
+	assert.Equal(t, 5, c.cfg.client.Retries)
+	assert.Equal(t, 10, c.cfg.client.MaxRepetitions)
+	assert.Equal(t, 1, c.cfg.workers)
+	assert.Nil(t, c.profile)
+	assert.Len(t, c.profiles, 2)
+
+	c, _ = newTestCheck(t, `
+ip_address: 10.0.0.1
+snmp_version: 3
+user: admin
+auth_key: secretauth
+priv_protocol: aes
+priv_key: secretpriv
+retries: 0
+profile: inline
+`, `
+profiles:
+  inline:
+    definition:
+      metrics:
+        - symbol: {OID: 1.3.6.1.2.1.1.3.0, name: sysUpTimeInstance}
+`)
+	assert.Equal(t, snmp.User{
+		Name:           "admin",
+		AuthProtocol:   snmp.MD5,
+		AuthPassphrase: "secretauth",
+		PrivProtocol:   snmp.AES,
+		PrivPassphrase: "secretpriv",
+	}, c.cfg.client.User)
+	assert.Equal(t, 0, c.cfg.client.Retries)
+	require.NotNil(t, c.profile)
+	assert.Equal(t, "inline", c.profile.name)
+	assert.Len(t, c.profile.scalars, 1)
+
+	for _, instance := range []string{
+		"community_string: public",
+		"{ip_address: 10.0.0.1}",
+		"{ip_address: 10.0.0.1, community_string: public, snmp_version: 4}",
+		"{ip_address: 10.0.0.1, snmp_version: 3}",
+		"{ip_address: 10.0.0.1, snmp_version: 3, user: admin, priv_key: secret}",
+		"{ip_address: 10.0.0.1, snmp_version: 3, user: admin, auth_protocol: sha1024, auth_key: secret}",
+		"{ip_address: 10.0.0.1, community_string: public, profile: unknown}",
+		"{ip_address: 10.0.0.1, community_string: public, metrics: [{symbol: {OID: 1.3.6.1.2.1.1.3.0}}]}",
+		"{ip_address: 10.0.0.1, community_string: public, metrics: [{symbol: {OID: 1.3.a, name: a}}]}",
+		"{ip_address: 10.0.0.1, community_string: public, metrics: [{symbol: {OID: 1.3.6, name: a}, forced_type: histogram}]}",
+		"{ip_address: 10.0.0.1, community_string: public, metrics: [{table: {name: t}, symbols: [{OID: 1.3.6, name: a}], metric_tags: [{tag: b}]}]}",
+	} {
+		check := snmpFactory().(*SNMPCheck)
+		assert.Error(t, check.Configure([]byte(instance), nil), instance)
+	}
+}
+
+func TestMatchProfile(t *testing.T) {
+	profiles, err := loadProfiles("testdata/conf.d/snmp_core.d/profiles", map[string]profileConfig{
+		"cisco-router": {Definition: profileDefinition{SysObjectIDs: []string{"1.3.6.1.4.1.9.1.*"}}},
+	})
+	require.NoError(t, err)
+
+	for sysObjectID, expected := range map[string]string{
+		"1.3.6.1.4.1.8072.3.2.10":  "generic-device",
+		"1.3.6.1.4.1.8072.3.2.255": "other-device",
+		"1.3.6.1.4.1.9.1.1208":     "cisco-router",
+		"1.3.6.1.4.1.9.2.1":        "other-device",
+	} {
+		p := matchProfile(profiles, sysObjectID)
+		if assert.NotNil(t, p, sysObjectID) {
+			assert.Equal(t, expected, p.name, sysObjectID)
+		}
+	}
+	assert.Nil(t, matchProfile(profiles, "1.3.6.1.4.1.8072.3.20"))
+}
+
+func TestRunProfile(t *testing.T) {
+	agent := startAgent(t)
+	defer agent.Stop()
+
+	c, sender := newTestCheck(t, fmt.Sprintf(`
+ip_address: 127.0.0.1
+port: %d
+community_string: public
+`, agent.Port()), "")
+	require.NoError(t, c.Run())
+	require.NotNil(t, c.profile)
+	assert.Equal(t, "generic-device", c.profile.name)
+
+	tags := []string{"snmp_device:127.0.0.1", "snmp_profile:generic-device", "snmp_host:test-router"}
+	sender.AssertMetric(t, "Gauge", "snmp.sysUpTimeInstance", 12345, "", tags)
+	sender.AssertMetric(t, "Rate", "snmp.ifInOctets", 1000, "", append([]string{"interface:eth0", "interface_index:1"}, tags...))
+	sender.AssertMetric(t, "Rate", "snmp.ifInOctets", 2000, "", append([]string{"interface:eth1", "interface_index:2"}, tags...))
+	sender.AssertMetric(t, "Gauge", "snmp.ifOperStatus", 1, "", append([]string{"interface:eth0", "interface_index:1"}, tags...))
+	sender.AssertMetric(t, "Gauge", "snmp.ifOperStatus", 2, "", append([]string{"interface:eth1", "interface_index:2"}, tags...))
+	sender.AssertNumberOfCalls(t, "Gauge", 3)
+	sender.AssertNumberOfCalls(t, "Rate", 2)
+	sender.AssertServiceCheck(t, "snmp.can_check", metrics.ServiceCheckOK, "", []string{"snmp_device:127.0.0.1"}, "")
+	assert.Empty(t, c.GetWarnings())
+}
+
+func TestRunCustomMetricsV1(t *testing.T) {
+	agent := startAgent(t)
+	defer agent.Stop()
+
+	c, sender := newTestCheck(t, fmt.Sprintf(`
+ip_address: 127.0.0.1
+port: %d
+community_string: public
+snmp_version: 1
+oid_batch_size: 2
+metric_tags:
+  - {OID: 1.3.6.1.2.1.1.5.0, symbol: sysName, tag: snmp_host}
+  - {OID: 1.3.6.1.2.1.1.6.0, symbol: sysLocation, tag: location}
+metrics:
+  - symbol: {OID: 1.3.6.1.2.1.1.3.0, name: sysUpTimeInstance}
+    forced_type: monotonic_count
+  - symbol: {OID: 1.3.6.1.2.1.1.1.0, name: sysDescr}
+  - symbol: {OID: 1.3.6.1.4.1.2021.10.1.6.1, name: laLoadFloat}
+  - symbol: {OID: 1.3.6.1.2.1.1.7.0, name: sysServices}
+  - table: {OID: 1.3.6.1.2.1.4.20, name: ipAddrTable}
+    symbols:
+      - {OID: 1.3.6.1.2.1.4.20.1.3, name: ipAdEntNetMask}
+    metric_tags:
+      - {tag: address, index: 1}
+      - {tag: invalid, index: 5}
+`, agent.Port()), "")
+	require.NoError(t, c.Run())
+
+	tags := []string{"snmp_device:127.0.0.1", "snmp_host:test-router"}
+	sender.AssertMetric(t, "MonotonicCount", "snmp.sysUpTimeInstance", 12345, "", tags)
+	sender.AssertMetric(t, "Gauge", "snmp.laLoadFloat", 0.25, "", tags)
+	sender.AssertNotCalled(t, "Gauge", "snmp.sysDescr", mock.Anything, mock.Anything, mock.Anything)
+	sender.AssertNotCalled(t, "Gauge", "snmp.ipAdEntNetMask", mock.Anything, mock.Anything, mock.Anything)
+	sender.AssertNumberOfCalls(t, "Gauge", 1)
+	sender.AssertServiceCheck(t, "snmp.can_check", metrics.ServiceCheckOK, "", []string{"snmp_device:127.0.0.1"}, "")
+
+	warnings := c.GetWarnings()
+	require.Len(t, warnings, 2)
+	assert.Equal(t, fmt.Sprintf("The device 127.0.0.1:%d doesn't expose sysLocation (1.3.6.1.2.1.1.6.0), sysServices (1.3.6.1.2.1.1.7.0)", agent.Port()), warnings[0].Error())
+	assert.Equal(t, "Could not tag the row 10.0.0.1 of ipAdEntNetMask: the index has no component 5", warnings[1].Error())
+}
+
+func TestRunV3Workers(t *testing.T) {
+	user := snmp.User{Name: "datadog", AuthProtocol: snmp.SHA, AuthPassphrase: "authpassword", PrivProtocol: snmp.AES, PrivPassphrase: "privpassword"}
+	for _, workers := range []int{1, 3} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			agent := snmptest.NewAgent(deviceVariables)
+			agent.Users = []snmp.User{user}
+			agent.Delay = 50 * time.Millisecond
+			require.NoError(t, agent.Start())
+			defer agent.Stop()
+
+			c, sender := newTestCheck(t, fmt.Sprintf(`
+ip_address: 127.0.0.1
+port: %d
+snmp_version: 3
+user: datadog
+auth_protocol: SHA
+auth_key: authpassword
+priv_protocol: AES
+priv_key: privpassword
+workers: %d
+profile: generic-device
+`, agent.Port(), workers), "")
+			require.NoError(t, c.Run())
+			sender.AssertMetric(t, "Rate", "snmp.ifInOctets", 2000, "", []string{"interface:eth1", "snmp_host:test-router"})
+			sender.AssertServiceCheck(t, "snmp.can_check", metrics.ServiceCheckOK, "", nil, "")
+			// the scalars and the 3 columns are polled concurrently
+			assert.Equal(t, workers, agent.MaxInFlight())
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	agent := startAgent(t)
+	port := agent.Port()
+	agent.Stop()
+
+	c, sender := newTestCheck(t, fmt.Sprintf(`{ip_address: 127.0.0.1, port: %d, community_string: public, retries: 0}`, port), "")
+	err := c.Run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("could not poll 127.0.0.1:%d: ", port))
+	sender.AssertServiceCheck(t, "snmp.can_check", metrics.ServiceCheckCritical, "", []string{"snmp_device:127.0.0.1"}, err.Error())
+	sender.AssertNumberOfCalls(t, "Gauge", 0)
+
+	// no profile matches the device
+	agent = startAgent(t)
+	defer agent.Stop()
+	c, sender = newTestCheck(t, fmt.Sprintf(`{ip_address: 127.0.0.1, port: %d, community_string: public}`, agent.Port()), "")
+	c.profiles = nil
+	err = c.Run()
+	assert.EqualError(t, err, fmt.Sprintf("no profile matches the sysObjectID 1.3.6.1.4.1.8072.3.2.10 of 127.0.0.1:%d, set the metrics to collect", agent.Port()))
+	sender.AssertServiceCheck(t, "snmp.can_check", metrics.ServiceCheckCritical, "", nil, err.Error())
+}
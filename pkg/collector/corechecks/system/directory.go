@@ -0,0 +1,320 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package system
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	directoryCheckName        = "directory_core"
+	directoryServiceCheckName = "system.disk.directory.status"
+
+	defaultMaxFileGaugeCount = 20
+)
+
+type directoryThreshold struct {
+	Warning  *float64 `yaml:"warning"`
+	Critical *float64 `yaml:"critical"`
+}
+
+type directoryThresholds struct {
+	FileCount     directoryThreshold `yaml:"file_count"`
+	TotalBytes    directoryThreshold `yaml:"total_bytes"`
+	OldestFileAge directoryThreshold `yaml:"oldest_file_age"`
+	NewestFileAge directoryThreshold `yaml:"newest_file_age"`
+}
+
+type directoryInstanceConfig struct {
+	Directory         string              `yaml:"directory"`
+	Name              string              `yaml:"name"`
+	DirTagName        string              `yaml:"dirtagname"`
+	FileTagName       string              `yaml:"filetagname"`
+	Pattern           string              `yaml:"pattern"`
+	Regex             string              `yaml:"regex"`
+	Recursive         bool                `yaml:"recursive"`
+	MaxDepth          int                 `yaml:"max_depth"`
+	FollowSymlinks    bool                `yaml:"follow_symlinks"`
+	FileGauges        bool                `yaml:"filegauges"`
+	MaxFileGaugeCount *int                `yaml:"max_filegauge_count"`
+	Thresholds        directoryThresholds `yaml:"thresholds"`
+}
+
+type directoryConfig struct {
+	directoryInstanceConfig
+	regex *regexp.Regexp
+	tags  []string
+}
+
+func (c *directoryConfig) parse(data []byte) error {
+	if err := yaml.Unmarshal(data, &c.directoryInstanceConfig); err != nil {
+		return err
+	}
+	if c.Directory == "" {
+		return errors.New("the directory to monitor is not set")
+	}
+	if c.Name == "" {
+		c.Name = c.Directory
+	}
+	if c.DirTagName == "" {
+		c.DirTagName = "name"
+	}
+	if c.FileTagName == "" {
+		c.FileTagName = "filename"
+	}
+	if c.Pattern != "" {
+		if _, err := filepath.Match(c.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %s", c.Pattern, err)
+		}
+	}
+	if c.Regex != "" {
+		re, err := regexp.Compile(c.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %s", c.Regex, err)
+		}
+		c.regex = re
+	}
+	if c.MaxDepth < 0 {
+		return fmt.Errorf("invalid max_depth %d", c.MaxDepth)
+	}
+	if c.MaxFileGaugeCount == nil {
+		maxFileGaugeCount := defaultMaxFileGaugeCount
+		c.MaxFileGaugeCount = &maxFileGaugeCount
+	}
+	c.tags = []string{c.DirTagName + ":" + c.Name}
+	return nil
+}
+
+// matches returns whether a file, given by its path relative to the directory,
+// is monitored
+func (c *directoryConfig) matches(relPath string) bool {
+	if c.Pattern != "" {
+		if ok, _ := filepath.Match(c.Pattern, filepath.Base(relPath)); !ok {
+			return false
+		}
+	}
+	return c.regex == nil || c.regex.MatchString(filepath.ToSlash(relPath))
+}
+
+// directoryStats holds the statistics of the files of a directory
+type directoryStats struct {
+	files      int
+	bytes      int64
+	oldest     time.Time
+	newest     time.Time
+	unreadable int
+	// firstError is the error of the first unreadable entry
+	firstError error
+}
+
+// DirectoryCheck reports the number, size and age of the files of a directory
+type DirectoryCheck struct {
+	core.CheckBase
+	cfg *directoryConfig
+}
+
+// Configure parses the check configuration
+func (c *DirectoryCheck) Configure(data integration.Data, initConfig integration.Data) error {
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+	cfg := new(directoryConfig)
+	if err := cfg.parse(data); err != nil {
+		log.Errorf("Error parsing configuration file: %s", err)
+		return err
+	}
+	c.cfg = cfg
+	return nil
+}
+
+// Run walks the directory and submits its metrics
+func (c *DirectoryCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stats, err := c.walk(sender, now)
+	if err != nil {
+		err = fmt.Errorf("could not read the directory %s: %s", c.cfg.Directory, err)
+		sender.ServiceCheck(directoryServiceCheckName, metrics.ServiceCheckCritical, "", c.cfg.tags, err.Error())
+		sender.Commit()
+		return err
+	}
+
+	sender.Gauge("system.disk.directory.files", float64(stats.files), "", c.cfg.tags)
+	sender.Gauge("system.disk.directory.bytes", float64(stats.bytes), "", c.cfg.tags)
+	sender.Gauge("system.disk.directory.unreadable_entries", float64(stats.unreadable), "", c.cfg.tags)
+	values := map[string]float64{
+		"file_count":  float64(stats.files),
+		"total_bytes": float64(stats.bytes),
+	}
+	if stats.files > 0 {
+		values["oldest_file_age"] = now.Sub(stats.oldest).Seconds()
+		values["newest_file_age"] = now.Sub(stats.newest).Seconds()
+		sender.Gauge("system.disk.directory.oldest_file_age", values["oldest_file_age"], "", c.cfg.tags)
+		sender.Gauge("system.disk.directory.newest_file_age", values["newest_file_age"], "", c.cfg.tags)
+	}
+
+	status, messages := c.checkThresholds(values)
+	if stats.unreadable > 0 {
+		c.Warnf("Could not read %d entries of %s, the first one: %s", stats.unreadable, c.cfg.Directory, stats.firstError)
+		if status == metrics.ServiceCheckOK {
+			status = metrics.ServiceCheckWarning
+		}
+		messages = append(messages, fmt.Sprintf("%d entries could not be read", stats.unreadable))
+	}
+	sender.ServiceCheck(directoryServiceCheckName, status, "", c.cfg.tags, strings.Join(messages, ", "))
+	sender.Commit()
+	return nil
+}
+
+// walk returns the statistics of the matching files of the directory, and
+// submits their per-file gauges. Unreadable entries are counted and skipped, only
+// an unreadable directory fails.
+func (c *DirectoryCheck) walk(sender aggregator.Sender, now time.Time) (*directoryStats, error) {
+	// the directory itself may be a symlink, which filepath.Walk doesn't follow
+	root, err := filepath.EvalSymlinks(c.cfg.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &directoryStats{}
+	fileGauges := 0
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			stats.unreadable++
+			if stats.firstError == nil {
+				stats.firstError = err
+			}
+			return nil
+		}
+		if path == root {
+			if !info.IsDir() {
+				return errors.New("not a directory")
+			}
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		depth := strings.Count(relPath, string(filepath.Separator)) + 1
+		if info.IsDir() {
+			if !c.cfg.Recursive || (c.cfg.MaxDepth > 0 && depth >= c.cfg.MaxDepth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !c.cfg.FollowSymlinks {
+				return nil
+			}
+			// symlinks to directories aren't traversed, to avoid cycles
+			if info, err = os.Stat(path); err != nil {
+				stats.unreadable++
+				if stats.firstError == nil {
+					stats.firstError = err
+				}
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+		}
+		if !info.Mode().IsRegular() || !c.cfg.matches(relPath) {
+			return nil
+		}
+
+		stats.files++
+		stats.bytes += info.Size()
+		modTime := info.ModTime()
+		if stats.files == 1 || modTime.Before(stats.oldest) {
+			stats.oldest = modTime
+		}
+		if stats.files == 1 || modTime.After(stats.newest) {
+			stats.newest = modTime
+		}
+
+		if c.cfg.FileGauges {
+			if fileGauges < *c.cfg.MaxFileGaugeCount {
+				tags := append([]string{c.cfg.FileTagName + ":" + filepath.Join(c.cfg.Directory, relPath)}, c.cfg.tags...)
+				sender.Gauge("system.disk.directory.file.bytes", float64(info.Size()), "", tags)
+				sender.Gauge("system.disk.directory.file.modified_sec_ago", now.Sub(modTime).Seconds(), "", tags)
+			} else if fileGauges == *c.cfg.MaxFileGaugeCount {
+				c.Warnf("More than %d files match in %s, only the first ones have per-file gauges, raise max_filegauge_count to get more", *c.cfg.MaxFileGaugeCount, c.cfg.Directory)
+			}
+			fileGauges++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// checkThresholds returns the status of the service check according to the
+// thresholds set, and a message for every threshold crossed
+func (c *DirectoryCheck) checkThresholds(values map[string]float64) (metrics.ServiceCheckStatus, []string) {
+	status := metrics.ServiceCheckOK
+	var messages []string
+	for _, t := range []struct {
+		name      string
+		threshold directoryThreshold
+	}{
+		{"file_count", c.cfg.Thresholds.FileCount},
+		{"total_bytes", c.cfg.Thresholds.TotalBytes},
+		{"oldest_file_age", c.cfg.Thresholds.OldestFileAge},
+		{"newest_file_age", c.cfg.Thresholds.NewestFileAge},
+	} {
+		value, ok := values[t.name]
+		if !ok {
+			continue
+		}
+		if t.threshold.Critical != nil && value > *t.threshold.Critical {
+			status = metrics.ServiceCheckCritical
+			messages = append(messages, fmt.Sprintf("%s %v is above the critical threshold %v", t.name, value, *t.threshold.Critical))
+		} else if t.threshold.Warning != nil && value > *t.threshold.Warning {
+			if status == metrics.ServiceCheckOK {
+				status = metrics.ServiceCheckWarning
+			}
+			messages = append(messages, fmt.Sprintf("%s %v is above the warning threshold %v", t.name, value, *t.threshold.Warning))
+		}
+	}
+	return status, messages
+}
+
+func directoryFactory() check.Check {
+	return &DirectoryCheck{
+		CheckBase: core.NewCheckBase(directoryCheckName),
+	}
+}
+
+func init() {
+	core.RegisterCheck(directoryCheckName, directoryFactory)
+}
@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// createDirectory creates a directory holding:
+//   a.log         10 bytes, modified 1 hour ago
+//   b.txt         20 bytes, modified 2 hours ago
+//   sub/c.log     30 bytes, modified 3 hours ago
+//   sub/deep/d.log 40 bytes, modified 4 hours ago
+//   linked.log    symlink to a.log
+//   broken.log    symlink to a missing file
+func createDirectory(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "directory-check")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "deep"), 0755))
+
+	now := time.Now()
+	for i, name := range []string{"a.log", "b.txt", filepath.Join("sub", "c.log"), filepath.Join("sub", "deep", "d.log")} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, make([]byte, 10*(i+1)), 0644))
+		modTime := now.Add(-time.Duration(i+1) * time.Hour)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	if runtime.GOOS != "windows" {
+		require.NoError(t, os.Symlink(filepath.Join(dir, "a.log"), filepath.Join(dir, "linked.log")))
+		require.NoError(t, os.Symlink(filepath.Join(dir, "missing.log"), filepath.Join(dir, "broken.log")))
+	}
+	return dir
+}
+
+func runDirectoryCheck(t *testing.T, instance string) (*DirectoryCheck, *mocksender.MockSender, error) {
+	c := directoryFactory().(*DirectoryCheck)
+	m := mocksender.NewConfiguredMockSender(t, c, instance, "")
+	return c, m, c.Run()
+}
+
+func TestDirectoryCheck(t *testing.T) {
+	dir := createDirectory(t)
+	defer os.RemoveAll(dir)
+
+	_, m, err := runDirectoryCheck(t, fmt.Sprintf("directory: %s", dir))
+	require.NoError(t, err)
+
+	tags := []string{"name:" + dir}
+	m.AssertMetric(t, "Gauge", "system.disk.directory.files", 2, "", tags)
+	m.AssertMetric(t, "Gauge", "system.disk.directory.bytes", 30, "", tags)
+	m.AssertMetric(t, "Gauge", "system.disk.directory.unreadable_entries", 0, "", tags)
+	m.AssertMetricInRange(t, "Gauge", "system.disk.directory.oldest_file_age", 7200, 7260, "", tags)
+	m.AssertMetricInRange(t, "Gauge", "system.disk.directory.newest_file_age", 3600, 3660, "", tags)
+	m.AssertNotCalled(t, "Gauge", "system.disk.directory.file.bytes", mock.Anything, mock.Anything, mock.Anything)
+	m.AssertServiceCheck(t, "system.disk.directory.status", metrics.ServiceCheckOK, "", tags, "")
+}
+
+func TestDirectoryCheckFilters(t *testing.T) {
+	dir := createDirectory(t)
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		options string
+		files   float64
+		bytes   float64
+	}{
+		{"recursive: true", 4, 100},
+		{"recursive: true\nmax_depth: 2", 3, 60},
+		{"recursive: true\npattern: '*.log'", 3, 80},
+		{"recursive: true\nregex: ^sub/", 2, 70},
+		{"recursive: true\nregex: ^sub/\npattern: 'd.*'", 1, 40},
+		{"max_depth: 3", 2, 30},
+	} {
+		t.Run(tc.options, func(t *testing.T) {
+			_, m, err := runDirectoryCheck(t, fmt.Sprintf("directory: %s\nname: logs\n%s", dir, tc.options))
+			require.NoError(t, err)
+			m.AssertMetric(t, "Gauge", "system.disk.directory.files", tc.files, "", []string{"name:logs"})
+			m.AssertMetric(t, "Gauge", "system.disk.directory.bytes", tc.bytes, "", []string{"name:logs"})
+		})
+	}
+}
+
+func TestDirectoryCheckSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on Windows")
+	}
+	dir := createDirectory(t)
+	defer os.RemoveAll(dir)
+	link := dir + "-link"
+	require.NoError(t, os.Symlink(dir, link))
+	defer os.Remove(link)
+
+	c, m, err := runDirectoryCheck(t, fmt.Sprintf("directory: %s\nfollow_symlinks: true\ndirtagname: directory", link))
+	require.NoError(t, err)
+
+	// the broken symlink is unreadable, it doesn't fail the run
+	tags := []string{"directory:" + link}
+	m.AssertMetric(t, "Gauge", "system.disk.directory.files", 3, "", tags)
+	m.AssertMetric(t, "Gauge", "system.disk.directory.bytes", 40, "", tags)
+	m.AssertMetric(t, "Gauge", "system.disk.directory.unreadable_entries", 1, "", tags)
+	m.AssertServiceCheck(t, "system.disk.directory.status", metrics.ServiceCheckWarning, "", tags, "1 entries could not be read")
+	warnings := c.GetWarnings()
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0].Error(), "broken.log")
+}
+
+func TestDirectoryCheckFileGauges(t *testing.T) {
+	dir := createDirectory(t)
+	defer os.RemoveAll(dir)
+
+	c, m, err := runDirectoryCheck(t, fmt.Sprintf("directory: %s\nname: logs\nfiletagname: file\nfilegauges: true\nmax_filegauge_count: 1", dir))
+	require.NoError(t, err)
+
+	tags := []string{"name:logs", "file:" + filepath.Join(dir, "a.log")}
+	m.AssertMetric(t, "Gauge", "system.disk.directory.file.bytes", 10, "", tags)
+	m.AssertMetricInRange(t, "Gauge", "system.disk.directory.file.modified_sec_ago", 3600, 3660, "", tags)
+	m.AssertNumberOfCalls(t, "Gauge", 7)
+	assert.Len(t, c.GetWarnings(), 1)
+}
+
+func TestDirectoryCheckThresholds(t *testing.T) {
+	dir := createDirectory(t)
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		thresholds string
+		status     metrics.ServiceCheckStatus
+	}{
+		{"file_count: {warning: 2}", metrics.ServiceCheckOK},
+		{"file_count: {warning: 1, critical: 3}", metrics.ServiceCheckWarning},
+		{"file_count: {warning: 1}\noldest_file_age: {critical: 3600}", metrics.ServiceCheckCritical},
+		{"total_bytes: {critical: 20}", metrics.ServiceCheckCritical},
+		{"newest_file_age: {warning: 60}", metrics.ServiceCheckWarning},
+	} {
+		t.Run(tc.thresholds, func(t *testing.T) {
+			instance := fmt.Sprintf("directory: %s\nname: logs\nthresholds:\n  %s", dir, strings.Replace(tc.thresholds, "\n", "\n  ", -1))
+			_, m, err := runDirectoryCheck(t, instance)
+			require.NoError(t, err)
+			m.AssertCalled(t, "ServiceCheck", "system.disk.directory.status", tc.status, "", []string{"name:logs"}, mock.Anything)
+		})
+	}
+}
+
+func TestDirectoryCheckMissingDirectory(t *testing.T) {
+	dir := createDirectory(t)
+	os.RemoveAll(dir)
+
+	_, m, err := runDirectoryCheck(t, fmt.Sprintf("directory: %s\nname: logs", dir))
+	assert.Error(t, err)
+	m.AssertServiceCheck(t, "system.disk.directory.status", metrics.ServiceCheckCritical, "", []string{"name:logs"}, err.Error())
+	m.AssertNotCalled(t, "Gauge", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDirectoryCheckConfigErrors(t *testing.T) {
+	for _, instance := range []string{
+		"name: logs",
+		"directory: /tmp\npattern: '['",
+		"directory: /tmp\nregex: '('",
+		"directory: /tmp\nmax_depth: -1",
+	} {
+		c := directoryFactory()
+		assert.Error(t, c.Configure([]byte(instance), nil), instance)
+	}
+}
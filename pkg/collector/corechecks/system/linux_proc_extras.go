@@ -0,0 +1,281 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+// +build linux
+
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// The check is not named "linux_proc_extras" so as not to be shadowed by the Python
+// check of the same name, which is loaded first.
+const linuxProcExtrasCheckName = "linux_proc_extras_core"
+
+// vmstatCounters are the counters of /proc/vmstat submitted by the check
+var vmstatCounters = []string{"pgfault", "pgmajfault", "pswpin", "pswpout", "oom_kill"}
+
+// LinuxProcExtrasCheck reports kernel statistics: pressure stall information,
+// memory management counters, softirqs and interrupts per CPU, conntrack and
+// socket usage.
+type LinuxProcExtrasCheck struct {
+	core.CheckBase
+	procPath string
+}
+
+// Run executes the check
+func (c *LinuxProcExtrasCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	for _, collect := range []func(aggregator.Sender) error{
+		c.collectPressure,
+		c.collectVMStat,
+		c.collectSoftirqs,
+		c.collectInterrupts,
+		c.collectConntrack,
+		c.collectSockstat,
+	} {
+		if err := collect(sender); os.IsNotExist(err) {
+			// Not supported by the kernel, or the module isn't loaded
+			log.Debugf("system.LinuxProcExtrasCheck: %s", err)
+		} else if err != nil {
+			c.Warnf("system.LinuxProcExtrasCheck: %s", err)
+		}
+	}
+	sender.Commit()
+	return nil
+}
+
+func (c *LinuxProcExtrasCheck) path(elem ...string) string {
+	return filepath.Join(append([]string{c.procPath}, elem...)...)
+}
+
+// collectPressure submits the pressure stall information of /proc/pressure, see
+// https://www.kernel.org/doc/html/latest/accounting/psi.html
+//
+// some avg10=0.04 avg60=0.01 avg300=0.00 total=12093
+// full avg10=0.00 avg60=0.00 avg300=0.00 total=5043
+func (c *LinuxProcExtrasCheck) collectPressure(sender aggregator.Sender) error {
+	for _, resource := range []string{"cpu", "memory", "io"} {
+		lines, err := readProcLines(c.path("pressure", resource))
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			prefix := "system.pressure." + resource + "." + fields[0]
+			for _, field := range fields[1:] {
+				kv := strings.SplitN(field, "=", 2)
+				if len(kv) != 2 {
+					return fmt.Errorf("invalid field %q in %s pressure", field, resource)
+				}
+				value, err := strconv.ParseFloat(kv[1], 64)
+				if err != nil {
+					return fmt.Errorf("invalid field %q in %s pressure: %s", field, resource, err)
+				}
+				if kv[0] == "total" {
+					// The total stall time, in microseconds
+					sender.MonotonicCount(prefix+".total", value, "", nil)
+				} else {
+					sender.Gauge(prefix+"."+kv[0], value, "", nil)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// collectVMStat submits the counters of /proc/vmstat listed in vmstatCounters.
+func (c *LinuxProcExtrasCheck) collectVMStat(sender aggregator.Sender) error {
+	lines, err := readProcLines(c.path("vmstat"))
+	if err != nil {
+		return err
+	}
+	values := make(map[string]string, len(lines))
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			values[fields[0]] = fields[1]
+		}
+	}
+	for _, name := range vmstatCounters {
+		v, ok := values[name]
+		if !ok {
+			// oom_kill appeared in Linux 4.13
+			continue
+		}
+		value, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s value in vmstat: %s", name, err)
+		}
+		sender.MonotonicCount("system.linux.vmstat."+name, value, "", nil)
+	}
+	return nil
+}
+
+// collectSoftirqs submits the number of softirqs of each type per CPU.
+//
+//                     CPU0       CPU1
+//           HI:          0          0
+//        TIMER:     123413     116424
+func (c *LinuxProcExtrasCheck) collectSoftirqs(sender aggregator.Sender) error {
+	return c.parsePerCPUTable("softirqs", func(name string, cpu int, value float64) {
+		tags := []string{"softirq:" + strings.ToLower(name), "cpu:" + strconv.Itoa(cpu)}
+		sender.MonotonicCount("system.linux.cpu.softirqs", value, "", tags)
+	})
+}
+
+// collectInterrupts submits the number of interrupts per CPU. The interrupts of all
+// the IRQs are summed up, as there are usually too many of them to tag by IRQ.
+//
+//            CPU0       CPU1
+//   0:         22          0   IO-APIC   2-edge      timer
+// ERR:          0
+func (c *LinuxProcExtrasCheck) collectInterrupts(sender aggregator.Sender) error {
+	var totals []float64
+	err := c.parsePerCPUTable("interrupts", func(name string, cpu int, value float64) {
+		for len(totals) <= cpu {
+			totals = append(totals, 0)
+		}
+		totals[cpu] += value
+	})
+	if err != nil {
+		return err
+	}
+	for cpu, total := range totals {
+		sender.MonotonicCount("system.linux.cpu.interrupts", total, "", []string{"cpu:" + strconv.Itoa(cpu)})
+	}
+	return nil
+}
+
+// parsePerCPUTable parses a file of /proc with a CPU header line and a line of
+// per-CPU counters per row, and calls fn for every counter.
+func (c *LinuxProcExtrasCheck) parsePerCPUTable(file string, fn func(name string, cpu int, value float64)) error {
+	lines, err := readProcLines(c.path(file))
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("%s is empty", file)
+	}
+	cpus := len(strings.Fields(lines[0]))
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.TrimSuffix(fields[0], ":")
+		// Rows end with a description of the IRQ in interrupts, and some of them,
+		// like ERR and MIS, only have a global counter and are skipped
+		values := make([]float64, 0, cpus)
+		for _, field := range fields[1:] {
+			if len(values) == cpus {
+				break
+			}
+			value, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				break
+			}
+			values = append(values, value)
+		}
+		if len(values) < cpus {
+			continue
+		}
+		for cpu, value := range values {
+			fn(name, cpu, value)
+		}
+	}
+	return nil
+}
+
+// collectConntrack submits the number of connections tracked by netfilter, and the
+// maximum.
+func (c *LinuxProcExtrasCheck) collectConntrack(sender aggregator.Sender) error {
+	for _, name := range []string{"count", "max"} {
+		data, err := ioutil.ReadFile(c.path("sys", "net", "netfilter", "nf_conntrack_"+name))
+		if err != nil {
+			return err
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+		if err != nil {
+			return fmt.Errorf("invalid nf_conntrack_%s value: %s", name, err)
+		}
+		sender.Gauge("system.net.conntrack."+name, value, "", nil)
+	}
+	return nil
+}
+
+// collectSockstat submits the socket usage per protocol of /proc/net/sockstat.
+// The mem values are in pages.
+//
+// sockets: used 290
+// TCP: inuse 27 orphan 1 tw 0 alloc 30 mem 3
+func (c *LinuxProcExtrasCheck) collectSockstat(sender aggregator.Sender) error {
+	lines, err := readProcLines(c.path("net", "sockstat"))
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || len(fields)%2 == 0 {
+			continue
+		}
+		prefix := "system.net.sockstat." + strings.ToLower(strings.TrimSuffix(fields[0], ":"))
+		for i := 1; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i+1], 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s value in sockstat: %s", fields[i], err)
+			}
+			sender.Gauge(prefix+"."+fields[i], value, "", nil)
+		}
+	}
+	return nil
+}
+
+func readProcLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+func linuxProcExtrasFactory() check.Check {
+	procPath := "/proc"
+	if config.Datadog.IsSet("procfs_path") {
+		procPath = config.Datadog.GetString("procfs_path")
+	}
+	return &LinuxProcExtrasCheck{
+		CheckBase: core.NewCheckBase(linuxProcExtrasCheckName),
+		procPath:  procPath,
+	}
+}
+
+func init() {
+	core.RegisterCheck(linuxProcExtrasCheckName, linuxProcExtrasFactory)
+}
@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+// +build linux
+
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+)
+
+func TestLinuxProcExtrasCheck(t *testing.T) {
+	c := linuxProcExtrasFactory().(*LinuxProcExtrasCheck)
+	c.procPath = "./testfiles/linux_proc_extras"
+	c.Configure(nil, nil)
+
+	mock := mocksender.NewMockSender(c.ID())
+	mock.SetupAcceptAll()
+	require.NoError(t, c.Run())
+
+	mock.AssertMetric(t, "Gauge", "system.pressure.cpu.some.avg10", 1.5, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.pressure.cpu.some.avg60", 0.75, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.pressure.cpu.some.avg300", 0.2, "", nil)
+	mock.AssertMetric(t, "MonotonicCount", "system.pressure.cpu.some.total", 123456, "", nil)
+	mock.AssertMetric(t, "MonotonicCount", "system.pressure.memory.full.total", 5043, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.pressure.io.full.avg10", 2, "", nil)
+
+	mock.AssertMetric(t, "MonotonicCount", "system.linux.vmstat.pgfault", 52360938, "", nil)
+	mock.AssertMetric(t, "MonotonicCount", "system.linux.vmstat.pgmajfault", 4270, "", nil)
+	mock.AssertMetric(t, "MonotonicCount", "system.linux.vmstat.pswpin", 12, "", nil)
+	mock.AssertMetric(t, "MonotonicCount", "system.linux.vmstat.pswpout", 34, "", nil)
+	mock.AssertMetric(t, "MonotonicCount", "system.linux.vmstat.oom_kill", 2, "", nil)
+	mock.AssertNotCalled(t, "MonotonicCount", "system.linux.vmstat.pgpgin", mocksender.AnythingBut(nil), "", mocksender.AnythingBut(nil))
+
+	mock.AssertMetric(t, "MonotonicCount", "system.linux.cpu.softirqs", 123413, "", []string{"softirq:timer", "cpu:0"})
+	mock.AssertMetric(t, "MonotonicCount", "system.linux.cpu.softirqs", 4522, "", []string{"softirq:net_rx", "cpu:1"})
+	mock.AssertNumberOfCalls(t, "MonotonicCount", 6+5+20+2)
+
+	// The IRQs are summed up per CPU, ERR and MIS are global counters
+	mock.AssertMetric(t, "MonotonicCount", "system.linux.cpu.interrupts", 501022, "", []string{"cpu:0"})
+	mock.AssertMetric(t, "MonotonicCount", "system.linux.cpu.interrupts", 402009, "", []string{"cpu:1"})
+
+	mock.AssertMetric(t, "Gauge", "system.net.conntrack.count", 1234, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.net.conntrack.max", 262144, "", nil)
+
+	mock.AssertMetric(t, "Gauge", "system.net.sockstat.sockets.used", 290, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.net.sockstat.tcp.inuse", 27, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.net.sockstat.tcp.tw", 4, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.net.sockstat.tcp.mem", 3, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.net.sockstat.udp.inuse", 3, "", nil)
+	mock.AssertMetric(t, "Gauge", "system.net.sockstat.frag.memory", 0, "", nil)
+
+	mock.AssertNumberOfCalls(t, "Commit", 1)
+	assert.Empty(t, c.GetWarnings())
+}
+
+func TestLinuxProcExtrasCheckMissingFiles(t *testing.T) {
+	procPath, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procPath)
+	require.NoError(t, ioutil.WriteFile(procPath+"/vmstat", []byte("pgfault abc\n"), 0644))
+
+	c := linuxProcExtrasFactory().(*LinuxProcExtrasCheck)
+	c.procPath = procPath
+	c.Configure(nil, nil)
+
+	mock := mocksender.NewMockSender(c.ID())
+	mock.SetupAcceptAll()
+	require.NoError(t, c.Run())
+
+	// Missing files are skipped, invalid ones reported as warnings
+	mock.AssertNotCalled(t, "Gauge", mocksender.AnythingBut(""), mocksender.AnythingBut(nil), "", mocksender.AnythingBut(nil))
+	mock.AssertNotCalled(t, "MonotonicCount", mocksender.AnythingBut(""), mocksender.AnythingBut(nil), "", mocksender.AnythingBut(nil))
+	mock.AssertNumberOfCalls(t, "Commit", 1)
+	assert.Len(t, c.GetWarnings(), 1)
+}
@@ -0,0 +1,234 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package external
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/util"
+)
+
+const (
+	protocolJSON   = "json"
+	protocolNagios = "nagios"
+
+	defaultTimeout = 15
+	// maxStderrSize is the maximum number of bytes of stderr reported in the warnings
+	maxStderrSize = 4096
+)
+
+// ExternalCheck runs an executable, and submits the metrics, service checks and
+// events it writes to its standard output.
+type ExternalCheck struct {
+	core.CheckBase
+	cfg   *externalConfig
+	input []byte // the configuration written to the standard input of the executable
+
+	m    sync.Mutex
+	stop func() // kills the running executable, nil if it isn't running
+}
+
+type externalInstanceConfig struct {
+	Command          string            `yaml:"command"`
+	Args             []string          `yaml:"args"`
+	Env              map[string]string `yaml:"env"`
+	Timeout          int               `yaml:"timeout"`
+	Protocol         string            `yaml:"protocol"`
+	ServiceCheckName string            `yaml:"service_check_name"`
+	MetricPrefix     string            `yaml:"metric_prefix"`
+}
+
+type externalConfig struct {
+	externalInstanceConfig
+	timeout time.Duration
+	env     []string
+}
+
+func (c *externalConfig) parse(name string, data []byte) error {
+	var instance externalInstanceConfig
+	if err := yaml.Unmarshal(data, &instance); err != nil {
+		return err
+	}
+	if instance.Command == "" {
+		return errors.New("command is required")
+	}
+	switch instance.Protocol {
+	case "":
+		instance.Protocol = protocolJSON
+	case protocolJSON, protocolNagios:
+	default:
+		return fmt.Errorf("invalid protocol %s, expected %s or %s", instance.Protocol, protocolJSON, protocolNagios)
+	}
+	if instance.ServiceCheckName == "" {
+		instance.ServiceCheckName = name
+	}
+	if instance.MetricPrefix == "" {
+		instance.MetricPrefix = name
+	}
+	c.externalInstanceConfig = instance
+
+	c.timeout = time.Duration(instance.Timeout) * time.Second
+	if instance.Timeout <= 0 {
+		c.timeout = defaultTimeout * time.Second
+	}
+	c.env = os.Environ()
+	for k, v := range instance.Env {
+		c.env = append(c.env, k+"="+v)
+	}
+	return nil
+}
+
+func newExternalCheck(name string) *ExternalCheck {
+	return &ExternalCheck{
+		CheckBase: core.NewCheckBase(name),
+	}
+}
+
+// Configure parses the check configuration
+func (c *ExternalCheck) Configure(data integration.Data, initConfig integration.Data) error {
+	c.BuildID(data, initConfig)
+	if err := c.CommonConfigure(data); err != nil {
+		return err
+	}
+	cfg := new(externalConfig)
+	if err := cfg.parse(c.String(), data); err != nil {
+		return err
+	}
+	input, err := buildInput(c.String(), data, initConfig)
+	if err != nil {
+		return err
+	}
+	c.cfg, c.input = cfg, input
+	return nil
+}
+
+// buildInput returns the JSON document written to the standard input of the
+// executable: {"name": ..., "init_config": {...}, "instance": {...}}
+func buildInput(name string, data integration.Data, initConfig integration.Data) ([]byte, error) {
+	var instance, init interface{}
+	if err := yaml.Unmarshal(data, &instance); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(initConfig, &init); err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{
+		"name":        name,
+		"init_config": util.GetJSONSerializableMap(init),
+		"instance":    util.GetJSONSerializableMap(instance),
+	})
+}
+
+// Run runs the executable and submits its output
+func (c *ExternalCheck) Run() error {
+	sender, err := aggregator.GetSender(c.ID())
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(c.cfg.Command, c.cfg.Args...)
+	cmd.Env = c.cfg.env
+	stderr := &limitedBuffer{limit: maxStderrSize}
+	cmd.Stderr = stderr
+	setProcessGroup(cmd)
+
+	switch c.cfg.Protocol {
+	case protocolNagios:
+		err = c.runNagios(cmd, sender)
+	default:
+		cmd.Stdin = bytes.NewReader(c.input)
+		err = c.runJSON(cmd, sender)
+	}
+	if s := strings.TrimSpace(stderr.String()); s != "" {
+		c.Warnf("%s wrote to stderr: %s", c.cfg.Command, s)
+	}
+	sender.Commit()
+	return err
+}
+
+// start starts the command, which is killed when it times out or the check is stopped.
+// The returned function must be called once the command completed, and returns
+// an error if it was killed.
+func (c *ExternalCheck) start(cmd *exec.Cmd) (func() error, error) {
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan error, 1)
+	stop := func(reason error) {
+		select {
+		case stopped <- reason:
+			killProcessGroup(cmd)
+		default:
+		}
+	}
+	c.m.Lock()
+	c.stop = func() { stop(fmt.Errorf("%s was stopped", c.cfg.Command)) }
+	c.m.Unlock()
+
+	go func() {
+		timer := time.NewTimer(c.cfg.timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			stop(fmt.Errorf("%s timed out after %s", c.cfg.Command, c.cfg.timeout))
+		case <-done:
+		}
+	}()
+
+	return func() error {
+		close(done)
+		c.m.Lock()
+		c.stop = nil
+		c.m.Unlock()
+		select {
+		case err := <-stopped:
+			return err
+		default:
+			return nil
+		}
+	}, nil
+}
+
+// Stop kills the executable if it's running
+func (c *ExternalCheck) Stop() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.stop != nil {
+		c.stop()
+	}
+}
+
+// limitedBuffer keeps the first limit bytes written to it.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if n := b.limit - b.Len(); n > 0 {
+		if len(p) > n {
+			b.Buffer.Write(p[:n])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,256 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+// +build !windows
+
+package external
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// writeScript writes an executable shell script to dir.
+func writeScript(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+content), 0755))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	loader, err := NewExternalCheckLoader()
+	require.NoError(t, err)
+
+	config := integration.Config{
+		Name:      "test",
+		Instances: []integration.Data{integration.Data("command: /bin/true"), integration.Data("{command: /bin/false, timeout: 5}")},
+		Provider:  providers.File,
+	}
+	checks, err := loader.Load(config)
+	require.NoError(t, err)
+	require.Len(t, checks, 2)
+	assert.Equal(t, "test", checks[0].String())
+	assert.NotEqual(t, checks[0].ID(), checks[1].ID())
+
+	config.Instances = append(config.Instances, integration.Data("{command: /bin/true, protocol: xml}"))
+	checks, err = loader.Load(config)
+	assert.Error(t, err)
+	assert.Len(t, checks, 2)
+
+	config.Provider = providers.Docker
+	_, err = loader.Load(config)
+	assert.Error(t, err)
+
+	_, err = loader.Load(integration.Config{
+		Name:      "redisdb",
+		Instances: []integration.Data{integration.Data("host: localhost")},
+		Provider:  providers.File,
+	})
+	assert.Error(t, err)
+}
+
+func TestRunJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "input.json")
+	script := writeScript(t, dir, "check.sh", `
+cat > "$INPUT_FILE"
+cat <<EOF
+{"type": "gauge", "name": "test.gauge", "value": 1.5, "tags": ["env:test"]}
+{"type": "monotonic_count", "name": "test.count", "value": 10, "hostname": "other"}
+{"type": "service_check", "name": "test.can_connect", "status": 2, "message": "connection refused"}
+{"type": "event", "title": "Deployed", "text": "version 2", "alert_type": "success", "timestamp": 1500000000}
+{"type": "warning", "message": "deprecated"}
+{"type": "gauge", "name": "test.invalid"}
+{"type": "distribution", "name": "test.distribution", "value": 1}
+EOF
+echo "something went wrong" >&2
+`)
+
+	c := newExternalCheck("test")
+	sender := mocksender.NewConfiguredMockSender(t, c, fmt.Sprintf(`
+command: %s
+env:
+  INPUT_FILE: %s
+labels:
+  - team:a
+`, script, input), `{foo: bar}`)
+	require.NoError(t, c.Run())
+
+	sender.AssertMetric(t, "Gauge", "test.gauge", 1.5, "", []string{"env:test"})
+	sender.AssertMetric(t, "MonotonicCount", "test.count", 10, "other", nil)
+	sender.AssertServiceCheck(t, "test.can_connect", metrics.ServiceCheckCritical, "", nil, "connection refused")
+	sender.AssertEvent(t, metrics.Event{
+		Title:     "Deployed",
+		Text:      "version 2",
+		AlertType: metrics.EventAlertTypeSuccess,
+		Ts:        1500000000,
+	}, 0)
+	sender.AssertNumberOfCalls(t, "Gauge", 1)
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+
+	warnings := c.GetWarnings()
+	require.Len(t, warnings, 4)
+	assert.Equal(t, script+": deprecated", warnings[0].Error())
+	assert.Equal(t, "Invalid payload from "+script+": gauge needs a name and a value", warnings[1].Error())
+	assert.Equal(t, "Invalid payload from "+script+`: unknown type "distribution"`, warnings[2].Error())
+	assert.Equal(t, script+" wrote to stderr: something went wrong", warnings[3].Error())
+
+	data, err := ioutil.ReadFile(input)
+	require.NoError(t, err)
+	assert.JSONEq(t, fmt.Sprintf(`{
+		"name": "test",
+		"init_config": {"foo": "bar"},
+		"instance": {"command": "%s", "env": {"INPUT_FILE": "%s"}, "labels": ["team:a"]}
+	}`, script, input), string(data))
+}
+
+func TestRunJSONErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	script := writeScript(t, dir, "invalid.sh", `
+echo '{"type": "gauge", "name": "test.gauge", "value": 1}'
+echo 'not json'
+`)
+	c := newExternalCheck("test")
+	sender := mocksender.NewConfiguredMockSender(t, c, "command: "+script, "")
+	err = c.Run()
+	assert.Contains(t, fmt.Sprint(err), "could not decode the output of "+script)
+	sender.AssertMetric(t, "Gauge", "test.gauge", 1, "", nil)
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+
+	script = writeScript(t, dir, "fail.sh", `
+echo "no such file" >&2
+exit 3
+`)
+	c = newExternalCheck("test")
+	mocksender.NewConfiguredMockSender(t, c, "command: "+script, "")
+	err = c.Run()
+	assert.EqualError(t, err, script+" failed: exit status 3")
+	warnings := c.GetWarnings()
+	require.Len(t, warnings, 1)
+	assert.Equal(t, script+" wrote to stderr: no such file", warnings[0].Error())
+
+	c = newExternalCheck("test")
+	mocksender.NewConfiguredMockSender(t, c, "command: "+filepath.Join(dir, "missing.sh"), "")
+	assert.Error(t, c.Run())
+}
+
+func TestRunTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The child of the script keeps its standard output open
+	script := writeScript(t, dir, "slow.sh", `
+echo '{"type": "gauge", "name": "test.gauge", "value": 1}'
+sleep 30 &
+sleep 30
+`)
+	c := newExternalCheck("test")
+	sender := mocksender.NewConfiguredMockSender(t, c, fmt.Sprintf(`{command: %s, timeout: 1}`, script), "")
+	start := time.Now()
+	err = c.Run()
+	assert.EqualError(t, err, script+" timed out after 1s")
+	assert.True(t, time.Since(start) < 10*time.Second)
+	sender.AssertMetric(t, "Gauge", "test.gauge", 1, "", nil)
+}
+
+func TestStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	script := writeScript(t, dir, "slow.sh", "sleep 30\n")
+	c := newExternalCheck("test")
+	mocksender.NewConfiguredMockSender(t, c, "command: "+script, "")
+	errs := make(chan error)
+	go func() { errs <- c.Run() }()
+
+	// Wait for the script to start
+	for i := 0; i < 100; i++ {
+		c.m.Lock()
+		running := c.stop != nil
+		c.m.Unlock()
+		if running {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Stop()
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, script+" was stopped")
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "the check wasn't stopped")
+	}
+}
+
+func TestRunNagios(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		exitCode int
+		status   metrics.ServiceCheckStatus
+	}{
+		{0, metrics.ServiceCheckOK},
+		{1, metrics.ServiceCheckWarning},
+		{2, metrics.ServiceCheckCritical},
+		{3, metrics.ServiceCheckUnknown},
+		{127, metrics.ServiceCheckUnknown},
+	} {
+		t.Run(fmt.Sprint(tc.exitCode), func(t *testing.T) {
+			script := writeScript(t, dir, fmt.Sprintf("check_disk_%d", tc.exitCode), fmt.Sprintf(`
+echo "DISK OK - free space: / 3326 MB (56%%) | /=2643MB;5948;5958;0;5968"
+echo "/ 15272 MB (77%%);"
+echo "/boot 68 MB (69%%); | /boot=68MB;88;93;0;98"
+echo "'free space'=56%%;;;0;100 reads=123c"
+exit %d
+`, tc.exitCode))
+			c := newExternalCheck("test")
+			sender := mocksender.NewConfiguredMockSender(t, c, fmt.Sprintf(`{command: %s, protocol: nagios, args: [-w, "10%%"]}`, script), "")
+			require.NoError(t, c.Run())
+
+			sender.AssertServiceCheck(t, "test", tc.status, "", nil,
+				"DISK OK - free space: / 3326 MB (56%)\n/ 15272 MB (77%);\n/boot 68 MB (69%);")
+			sender.AssertMetric(t, "Gauge", "test.boot", 68, "", nil)
+			sender.AssertMetric(t, "Gauge", "test.free_space", 56, "", nil)
+			sender.AssertMetric(t, "MonotonicCount", "test.reads", 123, "", nil)
+			sender.AssertNotCalled(t, "Gauge", "test.", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	c := newExternalCheck("test")
+	sender := mocksender.NewConfiguredMockSender(t, c, fmt.Sprintf(`{command: %s, protocol: nagios, service_check_name: disk}`, filepath.Join(dir, "missing")), "")
+	assert.Error(t, c.Run())
+	sender.AssertCalled(t, "ServiceCheck", "disk", metrics.ServiceCheckUnknown, "", []string(nil), mocksender.AnythingBut(""))
+}
+
+func TestParsePerfData(t *testing.T) {
+	assert.Equal(t, []perfData{
+		{label: "time", value: 0.0123, unit: "s"},
+		{label: "size", value: 1024, unit: "B"},
+		{label: "free space", value: -1.5e3, unit: ""},
+		{label: "rta", value: 0.08, unit: "ms"},
+	}, parsePerfData(" time=0.0123s;1.0;2.0;0.0 size=1024B 'free space'=-1.5e3 invalid=U rta=0.08ms;100.000;500.000;0; "))
+	assert.Empty(t, parsePerfData("no perfdata"))
+}
@@ -0,0 +1,165 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// payload is an element of the JSON stream written by an executable to its standard
+// output, one of:
+//
+// {"type": "gauge", "name": "app.users", "value": 12, "tags": ["env:prod"], "hostname": "db1"}
+// {"type": "service_check", "name": "app.can_connect", "status": 2, "message": "connection refused"}
+// {"type": "event", "title": "Deployed", "text": "version 2", "alert_type": "info"}
+// {"type": "warning", "message": "app.users is deprecated"}
+//
+// Metrics types are gauge, rate, count, monotonic_count, counter, histogram and historate.
+type payload struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name"`
+	Value    *float64 `json:"value"`
+	Tags     []string `json:"tags"`
+	Hostname string   `json:"hostname"`
+	Status   *int     `json:"status"`
+	Message  string   `json:"message"`
+
+	Title          string `json:"title"`
+	Text           string `json:"text"`
+	Timestamp      int64  `json:"timestamp"`
+	Priority       string `json:"priority"`
+	AlertType      string `json:"alert_type"`
+	AggregationKey string `json:"aggregation_key"`
+	SourceTypeName string `json:"source_type_name"`
+}
+
+// runJSON runs a command writing a stream of JSON payloads, and submits them as
+// they are read.
+func (c *ExternalCheck) runJSON(cmd *exec.Cmd, sender aggregator.Sender) error {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	finish, err := c.start(cmd)
+	if err != nil {
+		return err
+	}
+
+	decodeErr := c.submitPayloads(stdout, sender)
+	// Read what is left so that the command doesn't block on its writes
+	io.Copy(ioutil.Discard, stdout)
+	waitErr := cmd.Wait()
+
+	if err := finish(); err != nil {
+		return err
+	}
+	if waitErr != nil {
+		return fmt.Errorf("%s failed: %s", c.cfg.Command, waitErr)
+	}
+	return decodeErr
+}
+
+// submitPayloads decodes and submits the payloads read from r, until its end or
+// until a payload can't be decoded. Invalid payloads are reported as warnings.
+func (c *ExternalCheck) submitPayloads(r io.Reader, sender aggregator.Sender) error {
+	dec := json.NewDecoder(r)
+	for {
+		var p payload
+		err := dec.Decode(&p)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not decode the output of %s: %s", c.cfg.Command, err)
+		}
+		if err := c.submitPayload(&p, sender); err != nil {
+			c.Warnf("Invalid payload from %s: %s", c.cfg.Command, err)
+		}
+	}
+}
+
+func (c *ExternalCheck) submitPayload(p *payload, sender aggregator.Sender) error {
+	switch p.Type {
+	case "service_check":
+		if p.Name == "" || p.Status == nil {
+			return fmt.Errorf("service_check needs a name and a status")
+		}
+		status, err := metrics.GetServiceCheckStatus(*p.Status)
+		if err != nil {
+			return err
+		}
+		sender.ServiceCheck(p.Name, status, p.Hostname, p.Tags, p.Message)
+	case "event":
+		if p.Title == "" {
+			return fmt.Errorf("event needs a title")
+		}
+		e := metrics.Event{
+			Title:          p.Title,
+			Text:           p.Text,
+			Ts:             p.Timestamp,
+			Host:           p.Hostname,
+			Tags:           p.Tags,
+			AggregationKey: p.AggregationKey,
+			SourceTypeName: p.SourceTypeName,
+		}
+		if e.Ts == 0 {
+			e.Ts = time.Now().Unix()
+		}
+		var err error
+		if p.Priority != "" {
+			if e.Priority, err = metrics.GetEventPriorityFromString(p.Priority); err != nil {
+				return err
+			}
+		}
+		if p.AlertType != "" {
+			if e.AlertType, err = metrics.GetAlertTypeFromString(p.AlertType); err != nil {
+				return err
+			}
+		}
+		sender.Event(e)
+	case "warning":
+		c.Warnf("%s: %s", c.cfg.Command, p.Message)
+	default:
+		submit, ok := metricSubmitter(sender, p.Type)
+		if !ok {
+			return fmt.Errorf("unknown type %q", p.Type)
+		}
+		if p.Name == "" || p.Value == nil {
+			return fmt.Errorf("%s needs a name and a value", p.Type)
+		}
+		submit(p.Name, *p.Value, p.Hostname, p.Tags)
+	}
+	return nil
+}
+
+// metricSubmitter returns the sender method submitting metrics of the given type.
+func metricSubmitter(sender aggregator.Sender, metricType string) (func(string, float64, string, []string), bool) {
+	switch metricType {
+	case "gauge":
+		return sender.Gauge, true
+	case "rate":
+		return sender.Rate, true
+	case "count":
+		return sender.Count, true
+	case "monotonic_count":
+		return sender.MonotonicCount, true
+	case "counter":
+		return sender.Counter, true
+	case "histogram":
+		return sender.Histogram, true
+	case "historate":
+		return sender.Historate, true
+	}
+	return nil, false
+}
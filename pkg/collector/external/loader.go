@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package external
+
+import (
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/providers"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/collector/loaders"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ExternalCheckLoader is a specific loader for checks running an executable
+type ExternalCheckLoader struct{}
+
+// NewExternalCheckLoader creates a loader for external checks
+func NewExternalCheckLoader() (*ExternalCheckLoader, error) {
+	return &ExternalCheckLoader{}, nil
+}
+
+// isExternalInstance returns whether an instance configures an executable to run.
+func isExternalInstance(instance integration.Data) bool {
+	var cfg struct {
+		Command string `yaml:"command"`
+	}
+	return yaml.Unmarshal(instance, &cfg) == nil && cfg.Command != ""
+}
+
+// Load returns a list of checks, one for every configuration instance found in `config`
+func (l *ExternalCheckLoader) Load(config integration.Config) ([]check.Check, error) {
+	checks := []check.Check{}
+
+	external := false
+	for _, instance := range config.Instances {
+		if isExternalInstance(instance) {
+			external = true
+			break
+		}
+	}
+	if !external {
+		return checks, fmt.Errorf("check %s doesn't set a command to run, not an external check", config.Name)
+	}
+	// Only the configuration files can run executables, not the container labels or
+	// the key-value stores
+	if config.Provider != providers.File {
+		return checks, fmt.Errorf("external check %s must be configured in a configuration file, not by the %s provider", config.Name, config.Provider)
+	}
+
+	errors := []string{}
+	for _, instance := range config.Instances {
+		newCheck := newExternalCheck(config.Name)
+		if err := newCheck.Configure(instance, config.InitConfig); err != nil {
+			errors = append(errors, fmt.Sprintf("Could not configure check %s: %s", newCheck, err))
+			log.Errorf("external.loader: could not configure check %s: %s", newCheck, err)
+			continue
+		}
+		checks = append(checks, newCheck)
+	}
+
+	if len(errors) != 0 {
+		return checks, fmt.Errorf("%s", strings.Join(errors, "\n"))
+	}
+
+	return checks, nil
+}
+
+func (l *ExternalCheckLoader) String() string {
+	return "External Check Loader"
+}
+
+func init() {
+	factory := func() (check.Loader, error) {
+		return NewExternalCheckLoader()
+	}
+
+	loaders.RegisterLoader(40, factory)
+}
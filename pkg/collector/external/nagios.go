@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package external
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// maxNagiosOutputSize is the maximum number of bytes read from the output of a
+// Nagios plugin
+const maxNagiosOutputSize = 64 * 1024
+
+var (
+	perfValueRegexp   = regexp.MustCompile(`^([-+]?[0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?)([a-zA-Z%]*)$`)
+	invalidNameRegexp = regexp.MustCompile(`[^a-z0-9_.]+`)
+)
+
+// perfData is a value of the performance data of a Nagios plugin.
+type perfData struct {
+	label string
+	value float64
+	unit  string
+}
+
+// runNagios runs a Nagios plugin, submits its status as a service check and its
+// performance data as metrics. See https://nagios-plugins.org/doc/guidelines.html
+func (c *ExternalCheck) runNagios(cmd *exec.Cmd, sender aggregator.Sender) error {
+	stdout := &limitedBuffer{limit: maxNagiosOutputSize}
+	cmd.Stdout = stdout
+	finish, err := c.start(cmd)
+	if err != nil {
+		sender.ServiceCheck(c.cfg.ServiceCheckName, metrics.ServiceCheckUnknown, "", nil, err.Error())
+		return err
+	}
+	waitErr := cmd.Wait()
+	if err := finish(); err != nil {
+		sender.ServiceCheck(c.cfg.ServiceCheckName, metrics.ServiceCheckUnknown, "", nil, err.Error())
+		return err
+	}
+
+	status := metrics.ServiceCheckOK
+	if waitErr != nil {
+		exitErr, ok := waitErr.(*exec.ExitError)
+		if !ok {
+			sender.ServiceCheck(c.cfg.ServiceCheckName, metrics.ServiceCheckUnknown, "", nil, waitErr.Error())
+			return waitErr
+		}
+		status = nagiosStatus(exitErr.Sys().(syscall.WaitStatus).ExitStatus())
+	}
+
+	message, perf := parseNagiosOutput(stdout.String())
+	for _, p := range perf {
+		label := strings.Trim(invalidNameRegexp.ReplaceAllString(strings.ToLower(p.label), "_"), "_")
+		if label == "" {
+			// e.g. the "/" mount point of check_disk
+			continue
+		}
+		name := c.cfg.MetricPrefix + "." + label
+		if p.unit == "c" {
+			sender.MonotonicCount(name, p.value, "", nil)
+		} else {
+			sender.Gauge(name, p.value, "", nil)
+		}
+	}
+	sender.ServiceCheck(c.cfg.ServiceCheckName, status, "", nil, message)
+	return nil
+}
+
+// nagiosStatus maps the exit code of a Nagios plugin to a service check status.
+func nagiosStatus(code int) metrics.ServiceCheckStatus {
+	switch code {
+	case 0:
+		return metrics.ServiceCheckOK
+	case 1:
+		return metrics.ServiceCheckWarning
+	case 2:
+		return metrics.ServiceCheckCritical
+	}
+	return metrics.ServiceCheckUnknown
+}
+
+// parseNagiosOutput returns the text and the performance data of the output of
+// a Nagios plugin:
+//
+// TEXT OUTPUT | OPTIONAL PERFDATA
+// LONG TEXT LINE 1
+// LONG TEXT LINE 2 | PERFDATA LINE 2
+// PERFDATA LINE 3
+func parseNagiosOutput(output string) (string, []perfData) {
+	var text, perf []string
+	inPerf := false
+	for i, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if inPerf {
+			perf = append(perf, line)
+			continue
+		}
+		parts := strings.SplitN(line, "|", 2)
+		text = append(text, strings.TrimSpace(parts[0]))
+		if len(parts) == 2 {
+			perf = append(perf, parts[1])
+			// Only the perfdata of the long text continues on the next lines
+			inPerf = i > 0
+		}
+	}
+	return strings.TrimSpace(strings.Join(text, "\n")), parsePerfData(strings.Join(perf, " "))
+}
+
+// parsePerfData parses space separated 'label'=value[UOM];[warn];[crit];[min];[max]
+// values. The values that can't be parsed are skipped.
+func parsePerfData(s string) []perfData {
+	var values []perfData
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return values
+		}
+
+		var label string
+		if s[0] == '\'' {
+			end := strings.Index(s[1:], "'=")
+			if end < 0 {
+				return values
+			}
+			label, s = s[1:end+1], s[end+3:]
+		} else {
+			end := strings.Index(s, "=")
+			if end < 0 {
+				return values
+			}
+			label, s = s[:end], s[end+1:]
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		value := strings.SplitN(s[:end], ";", 2)[0]
+		s = s[end:]
+
+		m := perfValueRegexp.FindStringSubmatch(value)
+		if m == nil || label == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			continue
+		}
+		values = append(values, perfData{label: label, value: v, unit: m[2]})
+	}
+}
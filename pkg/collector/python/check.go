@@ -40,6 +40,7 @@ type PythonCheck struct {
 	class        *C.six_pyobject_t
 	ModuleName   string
 	interval     time.Duration
+	runTimeout   time.Duration
 	lastWarnings []error
 }
 
@@ -168,6 +169,11 @@ func (c *PythonCheck) Configure(data integration.Data, initConfig integration.Da
 		c.interval = time.Duration(commonOptions.MinCollectionInterval) * time.Second
 	}
 
+	// See if a run timeout was specified
+	if commonOptions.RunTimeout > 0 {
+		c.runTimeout = time.Duration(commonOptions.RunTimeout) * time.Second
+	}
+
 	// Disable default hostname if specified
 	if commonOptions.EmptyDefaultHostname {
 		s, err := aggregator.GetSender(c.id)
@@ -229,6 +235,12 @@ func (c *PythonCheck) Interval() time.Duration {
 	return c.interval
 }
 
+// RunTimeout returns the time after which the runner stops waiting for a run
+// to complete, 0 to use the agent's default
+func (c *PythonCheck) RunTimeout() time.Duration {
+	return c.runTimeout
+}
+
 // ID returns the ID of the check
 func (c *PythonCheck) ID() check.ID {
 	return c.id
@@ -266,13 +266,15 @@ func (r *Runner) work() {
 		}
 
 		// run the check
-		var err error
 		t0 := time.Now()
 
-		err = check.Run()
+		completed, err := r.runCheck(check, t0)
 		longRunning := check.Interval() == 0
 
-		warnings := check.GetWarnings()
+		var warnings []error
+		if completed {
+			warnings = check.GetWarnings()
+		}
 
 		// use the default sender for the service checks
 		sender, e := aggregator.GetDefaultSender()
@@ -301,13 +303,16 @@ func (r *Runner) work() {
 			sender.Commit()
 		}
 
-		// remove the check from the running list
-		r.m.Lock()
-		delete(r.runningChecks, check.ID())
-		r.m.Unlock()
+		// remove the check from the running list, a run that timed out is
+		// removed once it returns
+		if completed {
+			r.m.Lock()
+			delete(r.runningChecks, check.ID())
+			r.m.Unlock()
+			runnerStats.Add("RunningChecks", -1)
+		}
 
 		// publish statistics about this run
-		runnerStats.Add("RunningChecks", -1)
 		runnerStats.Add("Runs", 1)
 
 		r.m.Lock()
@@ -315,8 +320,12 @@ func (r *Runner) work() {
 			// If the scheduler isn't assigned (it should), just add stats
 			// otherwise only do so if the check is in the scheduler
 			if r.scheduler == nil || r.scheduler.IsCheckScheduled(check.ID()) {
-				mStats, _ := check.GetMetricStats()
-				addWorkStats(check, time.Since(t0), err, warnings, mStats)
+				if completed {
+					mStats, _ := check.GetMetricStats()
+					addWorkStats(check, time.Since(t0), err, warnings, mStats)
+				} else {
+					getWorkStats(check).AddTimeout(time.Since(t0), err)
+				}
 			}
 		}
 		r.m.Unlock()
@@ -340,6 +349,63 @@ func (r *Runner) work() {
 	log.Debug("Finished processing checks.")
 }
 
+// runCheck runs the check and returns whether its run completed. If the run
+// doesn't complete within the run timeout, the check is stopped and runCheck
+// returns without waiting for it: the check stays in the running list, so that
+// its next runs are skipped until it returns.
+func (r *Runner) runCheck(c check.Check, t0 time.Time) (bool, error) {
+	timeout := runTimeout(c)
+	// long-running checks never time out
+	if timeout <= 0 || c.Interval() == 0 {
+		return true, c.Run()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return true, err
+	case <-timer.C:
+	}
+
+	log.Warnf("Check %s did not complete within its run timeout of %v, stopping it and skipping its next runs until it returns", c, timeout)
+	runnerStats.Add("Timeouts", 1)
+	runnerStats.Add("LongRunningChecks", 1)
+	// the check might not stop, or even block in Stop
+	go c.Stop()
+
+	go func() {
+		err := <-done
+		log.Infof("Check %s returned %v after it timed out, error: %v", c, time.Since(t0), err)
+
+		if s := findWorkStats(c); s != nil {
+			s.SetReturned()
+		}
+
+		r.m.Lock()
+		delete(r.runningChecks, c.ID())
+		r.m.Unlock()
+		runnerStats.Add("RunningChecks", -1)
+		runnerStats.Add("LongRunningChecks", -1)
+	}()
+
+	return false, fmt.Errorf("check run timed out after %v", timeout)
+}
+
+// runTimeout returns the run timeout of the check, or the default one if it
+// doesn't configure it.
+func runTimeout(c check.Check) time.Duration {
+	if t, ok := c.(check.RunTimeouter); ok && t.RunTimeout() > 0 {
+		return t.RunTimeout()
+	}
+	return time.Duration(config.Datadog.GetInt("check_run_timeout")) * time.Second
+}
+
 func shouldLog(id check.ID) (doLog bool, lastLog bool) {
 	checkStats.M.RLock()
 	defer checkStats.M.RUnlock()
@@ -369,10 +435,16 @@ func shouldLog(id check.ID) (doLog bool, lastLog bool) {
 }
 
 func addWorkStats(c check.Check, execTime time.Duration, err error, warnings []error, mStats map[string]int64) {
+	getWorkStats(c).Add(execTime, err, warnings, mStats)
+}
+
+// getWorkStats returns the stats of the check, created if they don't exist
+func getWorkStats(c check.Check) *check.Stats {
 	var s *check.Stats
 	var found bool
 
 	checkStats.M.Lock()
+	defer checkStats.M.Unlock()
 	log.Tracef("Add stats for %s", string(c.ID()))
 	stats, found := checkStats.Stats[c.String()]
 	if !found {
@@ -384,9 +456,15 @@ func addWorkStats(c check.Check, execTime time.Duration, err error, warnings []e
 		s = check.NewStats(c)
 		stats[c.ID()] = s
 	}
-	checkStats.M.Unlock()
+	return s
+}
+
+// findWorkStats returns the stats of the check, nil if they don't exist
+func findWorkStats(c check.Check) *check.Stats {
+	checkStats.M.RLock()
+	defer checkStats.M.RUnlock()
 
-	s.Add(execTime, err, warnings, mStats)
+	return checkStats.Stats[c.String()][c.ID()]
 }
 
 func expCheckStats() interface{} {
@@ -404,6 +482,25 @@ func GetCheckStats() map[string]map[check.ID]*check.Stats {
 	return checkStats.Stats
 }
 
+// GetChecksHistory returns the recent runs of the instances of the check named
+// name, or of every check if name is empty, by check name and instance ID
+func GetChecksHistory(name string) map[string]map[check.ID][]check.Run {
+	checkStats.M.RLock()
+	defer checkStats.M.RUnlock()
+
+	history := make(map[string]map[check.ID][]check.Run)
+	for checkName, stats := range checkStats.Stats {
+		if name != "" && checkName != name {
+			continue
+		}
+		history[checkName] = make(map[check.ID][]check.Run)
+		for id, s := range stats {
+			history[checkName][id] = s.History()
+		}
+	}
+	return history
+}
+
 // RemoveCheckStats removes a check from the check stats map
 func RemoveCheckStats(checkID check.ID) {
 	checkStats.M.Lock()
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -173,3 +174,91 @@ func TestStopCheck(t *testing.T) {
 	err = r.StopCheck(c2.ID())
 	assert.Equal(t, "timeout during stop operation on check id TestCheck:2", err.Error())
 }
+
+type HangingCheck struct {
+	TestCheck
+	runs    int32
+	release chan struct{}
+	stopped chan struct{}
+}
+
+func newHangingCheck() *HangingCheck {
+	return &HangingCheck{
+		TestCheck: TestCheck{id: "1"},
+		release:   make(chan struct{}),
+		stopped:   make(chan struct{}, 1),
+	}
+}
+
+func (c *HangingCheck) Run() error {
+	atomic.AddInt32(&c.runs, 1)
+	<-c.release
+	return nil
+}
+func (c *HangingCheck) Stop() {
+	select {
+	case c.stopped <- struct{}{}:
+	default:
+	}
+}
+func (c *HangingCheck) String() string            { return "HangingCheck" }
+func (c *HangingCheck) ID() check.ID              { return check.ID("HangingCheck:1") }
+func (c *HangingCheck) RunTimeout() time.Duration { return 50 * time.Millisecond }
+
+func TestRunTimeout(t *testing.T) {
+	r := NewRunner()
+	defer r.Stop()
+	c := newHangingCheck()
+	defer RemoveCheckStats(c.ID())
+
+	r.pending <- c
+	select {
+	case <-c.stopped:
+	case <-time.After(time.Second):
+		require.Fail(t, "Check hasn't been stopped 1 second after its run timeout")
+	}
+
+	var s *check.Stats
+	for i := 0; i < 100 && s == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		// the stats are added with the runner locked
+		r.m.Lock()
+		s = findWorkStats(c)
+		r.m.Unlock()
+	}
+	require.NotNil(t, s)
+	assert.Equal(t, "check run timed out after 50ms", s.LastError)
+	assert.Equal(t, uint64(1), s.TotalRuns)
+	assert.Equal(t, uint64(1), s.TotalErrors)
+	assert.Equal(t, uint64(1), s.TotalTimeouts)
+	assert.True(t, s.LongRunning)
+	assert.Equal(t, "1", runnerStats.Get("LongRunningChecks").String())
+
+	// the next runs are skipped until the check returns
+	r.pending <- c
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&c.runs))
+
+	close(c.release)
+	running := true
+	for i := 0; i < 100 && running; i++ {
+		time.Sleep(10 * time.Millisecond)
+		r.m.Lock()
+		_, running = r.runningChecks[c.ID()]
+		r.m.Unlock()
+	}
+	require.False(t, running)
+	assert.False(t, s.LongRunning)
+	history := GetChecksHistory("HangingCheck")
+	require.Len(t, history["HangingCheck"][c.ID()], 1)
+	assert.True(t, history["HangingCheck"][c.ID()][0].TimedOut)
+	assert.Empty(t, GetChecksHistory("foo"))
+	assert.Equal(t, uint64(1), s.TotalRuns)
+	assert.Equal(t, "0", runnerStats.Get("LongRunningChecks").String())
+
+	r.pending <- c
+	for i := 0; i < 100 && atomic.LoadInt32(&c.runs) != 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&c.runs))
+}
@@ -43,6 +43,7 @@ type jobQueue struct {
 	buckets             []*jobBucket
 	bucketTicker        *time.Ticker
 	lastTick            time.Time
+	lastWait            time.Duration // time taken to hand off the checks of the last bucket to the runners
 	sparseStep          uint
 	currentBucketIdx    uint
 	schedulingBucketIdx uint
@@ -93,14 +94,26 @@ func newJobQueue(interval time.Duration) *jobQueue {
 	return jq
 }
 
-// addJob is a convenience method to add a check to a queue
+// addJob adds a check to the least loaded bucket of the queue, so that the checks
+// are spread evenly over the interval
 func (jq *jobQueue) addJob(c check.Check) {
 	jq.mu.Lock()
 	defer jq.mu.Unlock()
 
-	// Checks scheduled to buckets scheduled with sparse round-robin
-	jq.buckets[jq.schedulingBucketIdx].addJob(c)
-	jq.schedulingBucketIdx = (jq.schedulingBucketIdx + jq.sparseStep) % uint(len(jq.buckets))
+	// Among the least loaded buckets, pick the first one from the sparse
+	// round-robin offset
+	nb := uint(len(jq.buckets))
+	idx := jq.schedulingBucketIdx
+	minSize := jq.buckets[idx].size()
+	for i := uint(1); i < nb; i++ {
+		candidate := (jq.schedulingBucketIdx + i) % nb
+		if size := jq.buckets[candidate].size(); size < minSize {
+			idx, minSize = candidate, size
+		}
+	}
+
+	jq.buckets[idx].addJob(c)
+	jq.schedulingBucketIdx = (idx + jq.sparseStep) % nb
 }
 
 func (jq *jobQueue) removeJob(id check.ID) error {
@@ -128,15 +141,21 @@ func (jq *jobQueue) stats() map[string]interface{} {
 
 	nJobs := 0
 	nBuckets := 0
+	// number of checks in each bucket, in the order they're scheduled
+	distribution := make([]int, 0, len(jq.buckets))
 	for _, bucket := range jq.buckets {
-		nJobs += bucket.size()
+		size := bucket.size()
+		nJobs += size
 		nBuckets++
+		distribution = append(distribution, size)
 	}
 
 	return map[string]interface{}{
-		"Interval": jq.interval / time.Second,
-		"Buckets":  nBuckets,
-		"Size":     nJobs,
+		"Interval":     jq.interval / time.Second,
+		"Buckets":      nBuckets,
+		"Size":         nJobs,
+		"Distribution": distribution,
+		"LastWaitTime": jq.lastWait.Nanoseconds() / 1e6,
 	}
 }
 
@@ -189,12 +208,14 @@ func (jq *jobQueue) process(s *Scheduler) bool {
 			select {
 			// blocking, we'll be here as long as it takes
 			case s.checksPipe <- check:
+				schedulerChecksWaitTime.Add(time.Since(t).Nanoseconds() / 1e6)
 			case <-jq.stop:
 				jq.health.Deregister()
 				return false
 			}
 		}
 		jq.mu.Lock()
+		jq.lastWait = time.Since(t)
 		jq.currentBucketIdx = (jq.currentBucketIdx + 1) % uint(len(jq.buckets))
 		jq.mu.Unlock()
 	case <-jq.health.C:
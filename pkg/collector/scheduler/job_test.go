@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+)
+
+type idCheck struct {
+	TestCheck
+	id int
+}
+
+func (c *idCheck) ID() check.ID { return check.ID(fmt.Sprintf("TestCheck:%d", c.id)) }
+
+// assertEvenSpread asserts that the sizes of the buckets differ by one at most
+func assertEvenSpread(t *testing.T, jq *jobQueue) {
+	distribution := jq.stats()["Distribution"].([]int)
+	require.Len(t, distribution, len(jq.buckets))
+	min, max := distribution[0], distribution[0]
+	for _, size := range distribution {
+		if size < min {
+			min = size
+		}
+		if size > max {
+			max = size
+		}
+	}
+	assert.True(t, max-min <= 1, "uneven distribution: %v", distribution)
+}
+
+func TestAddJobSpread(t *testing.T) {
+	jq := newJobQueue(15 * time.Second)
+	defer jq.health.Deregister()
+
+	for i := 0; i < 100; i++ {
+		jq.addJob(&idCheck{TestCheck: TestCheck{intl: 15 * time.Second}, id: i})
+		assertEvenSpread(t, jq)
+	}
+	assert.Equal(t, 100, jq.stats()["Size"])
+	assert.Equal(t, 15, jq.stats()["Buckets"])
+
+	// the first checks are spread over the interval
+	assert.Equal(t, check.ID("TestCheck:0"), jq.buckets[0].jobs[0].ID())
+	assert.Equal(t, check.ID("TestCheck:1"), jq.buckets[jq.sparseStep].jobs[0].ID())
+
+	// empty some buckets, the new checks fill them first
+	removed := 0
+	for i := 0; i < 3; i++ {
+		for len(jq.buckets[i].jobs) > 0 {
+			require.NoError(t, jq.removeJob(jq.buckets[i].jobs[0].ID()))
+			removed++
+		}
+	}
+	assert.Equal(t, []int{0, 0, 0}, jq.stats()["Distribution"].([]int)[:3])
+	for i := 0; i < removed; i++ {
+		jq.addJob(&idCheck{TestCheck: TestCheck{intl: 15 * time.Second}, id: 100 + i})
+	}
+	assertEvenSpread(t, jq)
+	assert.Equal(t, 100, jq.stats()["Size"])
+}
+
+func TestAddJobLeastLoadedFromOffset(t *testing.T) {
+	jq := newJobQueue(5 * time.Second)
+	defer jq.health.Deregister()
+
+	for i := 0; i < 5; i++ {
+		jq.addJob(&idCheck{TestCheck: TestCheck{intl: 5 * time.Second}, id: i})
+	}
+	require.NoError(t, jq.removeJob(jq.buckets[1].jobs[0].ID()))
+	require.NoError(t, jq.removeJob(jq.buckets[3].jobs[0].ID()))
+
+	// the first least loaded bucket from the offset is picked, wrapping around
+	jq.schedulingBucketIdx = 4
+	jq.addJob(&idCheck{TestCheck: TestCheck{intl: 5 * time.Second}, id: 5})
+	assert.Equal(t, []int{1, 1, 1, 0, 1}, jq.stats()["Distribution"])
+	assert.Equal(t, (1+jq.sparseStep)%5, jq.schedulingBucketIdx)
+
+	jq.addJob(&idCheck{TestCheck: TestCheck{intl: 5 * time.Second}, id: 6})
+	assert.Equal(t, []int{1, 1, 1, 1, 1}, jq.stats()["Distribution"])
+}
@@ -22,12 +22,16 @@ var (
 	schedulerExpvars       *expvar.Map
 	schedulerQueuesCount   = expvar.Int{}
 	schedulerChecksEntered = expvar.Int{}
+	// cumulative time in milliseconds checks waited for a check runner, since
+	// their bucket ticked
+	schedulerChecksWaitTime = expvar.Int{}
 )
 
 func init() {
 	schedulerExpvars = expvar.NewMap("scheduler")
 	schedulerExpvars.Set("QueuesCount", &schedulerQueuesCount)
 	schedulerExpvars.Set("ChecksEntered", &schedulerChecksEntered)
+	schedulerExpvars.Set("ChecksWaitTime", &schedulerChecksWaitTime)
 }
 
 // Scheduler keeps things rolling.
@@ -119,6 +119,7 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("enable_metadata_collection", true)
 	config.BindEnvAndSetDefault("enable_gohai", true)
 	config.BindEnvAndSetDefault("check_runners", int64(4))
+	config.BindEnvAndSetDefault("check_run_timeout", 0) // in seconds, 0 disables the run timeout
 	config.BindEnvAndSetDefault("auth_token_file_path", "")
 	config.BindEnvAndSetDefault("bind_host", "localhost")
 	config.BindEnvAndSetDefault("health_port", int64(0))
@@ -136,6 +137,20 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("secret_backend_arguments", []string{})
 	config.BindEnvAndSetDefault("secret_backend_output_max_size", 1024)
 	config.BindEnvAndSetDefault("secret_backend_timeout", 5)
+	// built-in secret backends
+	config.BindEnvAndSetDefault("secret_backend_file_directory", "")
+	config.BindEnvAndSetDefault("secret_backend_file_ttl", 60)
+	config.BindEnvAndSetDefault("secret_backend_env_enabled", false)
+	config.BindEnvAndSetDefault("secret_backend_env_ttl", 0)
+	config.BindEnvAndSetDefault("secret_backend_vault_address", "")
+	config.BindEnvAndSetDefault("secret_backend_vault_ca_file", "")
+	config.BindEnvAndSetDefault("secret_backend_vault_auth_method", "token")
+	config.BindEnvAndSetDefault("secret_backend_vault_token", "")
+	config.BindEnvAndSetDefault("secret_backend_vault_kubernetes_role", "")
+	config.BindEnvAndSetDefault("secret_backend_vault_kubernetes_mount", "kubernetes")
+	config.BindEnvAndSetDefault("secret_backend_vault_kubernetes_token_file", "/var/run/secrets/kubernetes.io/serviceaccount/token")
+	config.BindEnvAndSetDefault("secret_backend_vault_kv_version", 2)
+	config.BindEnvAndSetDefault("secret_backend_vault_ttl", 300)
 
 	// Retry settings
 	config.BindEnvAndSetDefault("forwarder_backoff_factor", 2)
@@ -231,7 +246,12 @@ func initConfig(config Config) {
 	config.BindEnvAndSetDefault("ac_exclude", []string{})
 	config.BindEnvAndSetDefault("ad_config_poll_interval", int64(10)) // in seconds
 	config.BindEnvAndSetDefault("extra_listeners", []string{})
+	config.BindEnvAndSetDefault("process_listener_polling_interval", 10) // in seconds
+	config.BindEnvAndSetDefault("cri_listener_polling_interval", 5)      // in seconds
 	config.BindEnvAndSetDefault("extra_config_providers", []string{})
+	config.BindEnvAndSetDefault("snmp_autodiscovery.discovery_interval", 3600) // in seconds
+	config.BindEnvAndSetDefault("snmp_autodiscovery.workers", 5)
+	config.BindEnvAndSetDefault("snmp_autodiscovery.allowed_failures", 3)
 
 	// Docker
 	config.BindEnvAndSetDefault("docker_query_timeout", int64(5))
@@ -396,6 +416,7 @@ func initConfig(config Config) {
 	// Mostly, keys we use IsSet() on, because IsSet always returns true if a key has a default.
 	config.SetKnown("metadata_providers")
 	config.SetKnown("config_providers")
+	config.SetKnown("snmp_autodiscovery.configs")
 	config.SetKnown("clustername")
 	config.SetKnown("listeners")
 	config.SetKnown("additional_endpoints")
@@ -434,9 +455,14 @@ func initConfig(config Config) {
 	config.SetKnown("apm_config.apm_dd_url")
 	config.SetKnown("apm_config.max_cpu_percent")
 	config.SetKnown("apm_config.receiver_port")
+	config.SetKnown("apm_config.receiver_socket")
+	config.SetKnown("apm_config.container_tags")
+	config.SetKnown("apm_config.container_tags_cardinality")
+	config.SetKnown("apm_config.container_tags_aggregators")
 	config.SetKnown("apm_config.connection_limit")
 	config.SetKnown("apm_config.ignore_resources")
 	config.SetKnown("apm_config.replace_tags")
+	config.SetKnown("apm_config.filter_rules")
 	config.SetKnown("apm_config.obfuscation.elasticsearch.enabled")
 	config.SetKnown("apm_config.obfuscation.elasticsearch.keep_values")
 	config.SetKnown("apm_config.obfuscation.mongodb.enabled")
@@ -446,9 +472,19 @@ func initConfig(config Config) {
 	config.SetKnown("apm_config.obfuscation.remove_stack_traces")
 	config.SetKnown("apm_config.obfuscation.redis.enabled")
 	config.SetKnown("apm_config.obfuscation.memcached.enabled")
+	config.SetKnown("apm_config.obfuscation.graphql.enabled")
+	config.SetKnown("apm_config.obfuscation.tag_rules")
 	config.SetKnown("apm_config.extra_sample_rate")
 	config.SetKnown("apm_config.dd_agent_bin")
 	config.SetKnown("apm_config.max_events_per_second")
+	config.SetKnown("apm_config.sampling_rules")
+	config.SetKnown("apm_config.tail_sampling.enabled")
+	config.SetKnown("apm_config.tail_sampling.decision_wait_seconds")
+	config.SetKnown("apm_config.tail_sampling.max_buffered_spans")
+	config.SetKnown("apm_config.tail_sampling.keep_errors")
+	config.SetKnown("apm_config.tail_sampling.duration_thresholds")
+	config.SetKnown("apm_config.tail_sampling.tags")
+	config.SetKnown("apm_config.tail_sampling.baseline_traces_per_second")
 	config.SetKnown("apm_config.trace_writer.flush_period_seconds")
 	config.SetKnown("apm_config.trace_writer.update_info_period_seconds")
 	config.SetKnown("apm_config.trace_writer.queue.max_age_seconds")
@@ -631,25 +667,45 @@ func ResolveSecrets(config Config, origin string) error {
 		config.GetInt("secret_backend_timeout"),
 		config.GetInt("secret_backend_output_max_size"),
 	)
+	secrets.InitBackends(secrets.BackendsConfig{
+		FileDirectory: config.GetString("secret_backend_file_directory"),
+		FileTTL:       config.GetInt("secret_backend_file_ttl"),
+		EnvEnabled:    config.GetBool("secret_backend_env_enabled"),
+		EnvTTL:        config.GetInt("secret_backend_env_ttl"),
+		Vault: secrets.VaultConfig{
+			Address:             config.GetString("secret_backend_vault_address"),
+			CAFile:              config.GetString("secret_backend_vault_ca_file"),
+			AuthMethod:          config.GetString("secret_backend_vault_auth_method"),
+			Token:               config.GetString("secret_backend_vault_token"),
+			KubernetesRole:      config.GetString("secret_backend_vault_kubernetes_role"),
+			KubernetesMount:     config.GetString("secret_backend_vault_kubernetes_mount"),
+			KubernetesTokenFile: config.GetString("secret_backend_vault_kubernetes_token_file"),
+			KVVersion:           config.GetInt("secret_backend_vault_kv_version"),
+			TTL:                 config.GetInt("secret_backend_vault_ttl"),
+		},
+	})
 
-	if config.GetString("secret_backend_command") != "" {
-		// Viper doesn't expose the final location of the file it
-		// loads. Since we are searching for 'datadog.yaml' in multiple
-		// locations we let viper determine the one to use before
-		// updating it.
-		yamlConf, err := yaml.Marshal(config.AllSettings())
-		if err != nil {
-			return fmt.Errorf("unable to marshal configuration to YAML to decrypt secrets: %v", err)
-		}
+	// Viper doesn't expose the final location of the file it
+	// loads. Since we are searching for 'datadog.yaml' in multiple
+	// locations we let viper determine the one to use before
+	// updating it.
+	yamlConf, err := yaml.Marshal(config.AllSettings())
+	if err != nil {
+		return fmt.Errorf("unable to marshal configuration to YAML to decrypt secrets: %v", err)
+	}
 
-		finalYamlConf, err := secrets.Decrypt(yamlConf, origin)
-		if err != nil {
-			return fmt.Errorf("unable to decrypt secret from datadog.yaml: %v", err)
-		}
-		r := bytes.NewReader(finalYamlConf)
-		if err = config.MergeConfigOverride(r); err != nil {
-			return fmt.Errorf("could not update main configuration after decrypting secrets: %v", err)
-		}
+	// the built-in backends need no secret_backend_command, the configuration
+	// is only updated if it had secrets
+	finalYamlConf, err := secrets.Decrypt(yamlConf, origin)
+	if err != nil {
+		return fmt.Errorf("unable to decrypt secret from datadog.yaml: %v", err)
+	}
+	if bytes.Equal(finalYamlConf, yamlConf) {
+		return nil
+	}
+	r := bytes.NewReader(finalYamlConf)
+	if err = config.MergeConfigOverride(r); err != nil {
+		return fmt.Errorf("could not update main configuration after decrypting secrets: %v", err)
 	}
 	return nil
 }
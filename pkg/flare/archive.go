@@ -157,6 +157,11 @@ func createArchive(zipFilePath string, local bool, confSearchPaths SearchPaths,
 		if err != nil {
 			log.Errorf("Could not zip config check: %s", err)
 		}
+
+		err = zipCheckHistory(tempDir, hostname)
+		if err != nil {
+			log.Errorf("Could not zip check history: %s", err)
+		}
 	}
 
 	// auth token permissions info (only if existing)
@@ -468,6 +473,32 @@ func writeConfigCheck(tempDir, hostname string, data []byte) error {
 	return err
 }
 
+func zipCheckHistory(tempDir, hostname string) error {
+	var b bytes.Buffer
+
+	writer := bufio.NewWriter(&b)
+	err := GetCheckHistory(writer, "")
+	if err != nil {
+		fmt.Fprintf(writer, "%s", err)
+	}
+	writer.Flush()
+
+	f := filepath.Join(tempDir, hostname, "check-history.log")
+	err = ensureParentDirsExist(f)
+	if err != nil {
+		return err
+	}
+
+	w, err := newRedactingWriter(f, os.ModePerm, true)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
 func zipHealth(tempDir, hostname string) error {
 	s := health.GetStatus()
 	sort.Strings(s.Healthy)
@@ -22,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/cmd/agent/api/response"
 	"github.com/DataDog/datadog-agent/cmd/agent/common"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/stretchr/testify/assert"
 )
@@ -145,6 +146,44 @@ func TestZipConfigCheck(t *testing.T) {
 	assert.NotContains(t, string(content), "MySecurePass")
 }
 
+func TestZipCheckHistory(t *testing.T) {
+	hr := response.CheckHistoryResponse{
+		Checks: map[string]map[check.ID][]check.Run{
+			"TestCheck": {
+				"TestCheck:123": {
+					{Start: 1500000010000, Duration: 150, Error: "connection refused", MetricSamples: 1},
+					{Start: 1500000000000, Duration: 12, Warnings: []string{"api_key: aaaaaaaaaaaaaaaaaaaaaaaaaaaabbbb"}, MetricSamples: 3},
+				},
+			},
+		},
+	}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out, _ := json.Marshal(hr)
+		w.Write(out)
+	}))
+	defer ts.Close()
+	checkHistoryURL = ts.URL
+
+	dir, err := ioutil.TempDir("", "TestZipCheckHistory")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	zipCheckHistory(dir, "")
+	content, err := ioutil.ReadFile(filepath.Join(dir, "check-history.log"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Contains(t, string(content), "Instance ID: TestCheck:123")
+	assert.Contains(t, string(content), "[ERROR] duration: 150ms, metric samples: 1, events: 0, service checks: 0")
+	assert.Contains(t, string(content), "Error: connection refused")
+	assert.Contains(t, string(content), "[WARNING] duration: 12ms, metric samples: 3")
+	assert.NotContains(t, string(content), "aaaaaaaaaaaaaaaaaaaaaaaaaaaabbbb")
+}
+
 func TestIncludeConfigFiles(t *testing.T) {
 	assert := assert.New(t)
 
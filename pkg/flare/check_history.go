@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package flare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/api/response"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/status"
+)
+
+// checkHistoryURL contains the Agent API endpoint URL exposing the recent runs of the checks
+var checkHistoryURL string
+
+// GetCheckHistory dumps the recent runs of the instances of the check named name,
+// or of every check if name is empty, to the writer
+func GetCheckHistory(w io.Writer, name string) error {
+	if w != color.Output {
+		color.NoColor = true
+	}
+
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+
+	// Set session token
+	err := util.SetAuthToken()
+	if err != nil {
+		return err
+	}
+
+	if checkHistoryURL == "" {
+		checkHistoryURL = fmt.Sprintf("https://localhost:%v/agent/check-history", config.Datadog.GetInt("cmd_port"))
+	}
+	endpoint := checkHistoryURL
+	if name != "" {
+		endpoint += "/" + url.PathEscape(name)
+	}
+	r, err := util.DoGet(c, endpoint)
+	if err != nil {
+		if r != nil && string(r) != "" {
+			return fmt.Errorf("the agent ran into an error while getting the check history: %s", string(r))
+		}
+		return fmt.Errorf("failed to query the agent (running?): %s", err)
+	}
+
+	hr := response.CheckHistoryResponse{}
+	err = json.Unmarshal(r, &hr)
+	if err != nil {
+		return err
+	}
+
+	PrintCheckHistory(w, hr.Checks)
+	return nil
+}
+
+// PrintCheckHistory prints the recent runs of check instances, by check name
+// and instance ID
+func PrintCheckHistory(w io.Writer, history map[string]map[check.ID][]check.Run) {
+	names := make([]string, 0, len(history))
+	for name := range history {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintln(w, fmt.Sprintf("\n=== %s check ===", color.GreenString(name)))
+
+		ids := make([]string, 0, len(history[name]))
+		for id := range history[name] {
+			ids = append(ids, string(id))
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			fmt.Fprintln(w, fmt.Sprintf("\n%s: %s", color.BlueString("Instance ID"), id))
+			for _, run := range history[name][check.ID(id)] {
+				printRun(w, run)
+			}
+		}
+	}
+}
+
+func printRun(w io.Writer, run check.Run) {
+	result := color.GreenString("OK")
+	switch {
+	case run.TimedOut:
+		result = color.RedString("TIMEOUT")
+	case run.Error != "":
+		result = color.RedString("ERROR")
+	case len(run.Warnings) > 0:
+		result = color.YellowString("WARNING")
+	}
+
+	start := time.Unix(0, run.Start*int64(time.Millisecond))
+	fmt.Fprintln(w, fmt.Sprintf("  %s [%s] duration: %v, metric samples: %d, events: %d, service checks: %d",
+		start.Format(time.RFC3339), result, time.Duration(run.Duration)*time.Millisecond,
+		run.MetricSamples, run.Events, run.ServiceChecks))
+	if run.Error != "" {
+		fmt.Fprintln(w, fmt.Sprintf("    %s: %s", color.RedString("Error"), status.CheckErrorMessage(run.Error)))
+	}
+	for _, warning := range run.Warnings {
+		fmt.Fprintln(w, fmt.Sprintf("    %s: %s", color.YellowString("Warning"), warning))
+	}
+}
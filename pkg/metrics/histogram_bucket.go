@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package metrics
+
+// HistogramBucket represents the count of the values between two bounds of a
+// histogram, like a Prometheus histogram bucket. It is aggregated into a
+// distribution.
+type HistogramBucket struct {
+	Name       string
+	Value      int64
+	LowerBound float64
+	UpperBound float64
+	Monotonic  bool // the value is a raw counter, of which the increase is counted
+	Tags       []string
+	Host       string
+	Timestamp  float64
+}
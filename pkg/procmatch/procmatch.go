@@ -12,6 +12,7 @@ type Integration struct {
 	MetricPrefix string // Metric prefix of the integration
 	Name         string // Name of the integration
 	DisplayName  string // DisplayName of the integration
+	Signature    string // Signature of the catalog that matched the command line
 }
 
 // IntegrationEntry represents an integration entry in the catalog
@@ -77,3 +77,22 @@ func TestDefaultCatalogOnGraph(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchSignature(t *testing.T) {
+	cases := []struct {
+		cmdline   string
+		signature string
+	}{
+		{"/usr/bin/redis-server 127.0.0.1:6379", "redis-server"},
+		{"/usr/lib/postgresql/10/bin/postgres -D /var/lib/postgresql/10/main", "postgres -D"},
+		{"java -Xmx4000m -Xms4000m -port 9999 kafka.Kafka", "java kafka.kafka"},
+		{"/bin/bash", ""},
+	}
+
+	for _, c := range cases {
+		matched := testMatcher.Match(c.cmdline)
+		if matched.Signature != c.signature {
+			t.Errorf("%s failed, wrong signature for '%s', expected '%s' but got '%s'", t.Name(), c.cmdline, c.signature, matched.Signature)
+		}
+	}
+}
@@ -11,7 +11,7 @@ func (i IntegrationEntry) getSignatures() []signature {
 
 	for _, rawSig := range i.Signatures {
 		sigs = append(sigs, signature{
-			integration: Integration{DisplayName: i.DisplayName, MetricPrefix: i.MetricPrefix, Name: i.Name},
+			integration: Integration{DisplayName: i.DisplayName, MetricPrefix: i.MetricPrefix, Name: i.Name, Signature: rawSig},
 			words:       strings.FieldsFunc(strings.ToLower(rawSig), splitCmdline),
 		})
 	}
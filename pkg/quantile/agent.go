@@ -1,5 +1,7 @@
 package quantile
 
+import "math"
+
 const (
 	agentBufCap = 512
 )
@@ -56,3 +58,47 @@ func (a *Agent) Insert(v float64) {
 
 	a.flush()
 }
+
+// InsertInterpolate inserts count values spread evenly between lower and upper,
+// like the values counted by a histogram bucket. All the values are inserted at
+// upper if the bounds don't make an interval of positive values.
+func (a *Agent) InsertInterpolate(lower, upper float64, count uint) {
+	if count == 0 {
+		return
+	}
+	if lower < 0 || lower >= upper {
+		a.Sketch.Basic.InsertN(upper, count)
+		a.Sketch.merge(agentConfig, &sparseStore{
+			bins:  appendSafe(nil, agentConfig.key(upper), int(count)),
+			count: int(count),
+		})
+		return
+	}
+
+	var (
+		o        sparseStore
+		done     int
+		distance = upper - lower
+	)
+	for k := agentConfig.key(lower); done < int(count); k++ {
+		low, high := math.Max(lower, agentConfig.binLow(k)), upper
+		if !k.IsInf() {
+			high = math.Min(upper, agentConfig.binLow(k+1))
+		}
+
+		// the values are assigned to the keys by rounding the running total, so
+		// that they all end up in the sketch
+		n := int(count) - done
+		if high < upper {
+			n = int(math.Round(float64(count)*(high-lower)/distance)) - done
+		}
+		if n <= 0 {
+			continue
+		}
+		a.Sketch.Basic.InsertN((low+high)/2, uint(n))
+		o.bins = appendSafe(o.bins, k, n)
+		o.count += n
+		done += n
+	}
+	a.Sketch.merge(agentConfig, &o)
+}
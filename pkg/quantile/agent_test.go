@@ -110,3 +110,31 @@ func TestAgentFinish(t *testing.T) {
 		require.Nil(t, a.Finish())
 	})
 }
+
+func TestAgentInsertInterpolate(t *testing.T) {
+	for _, tt := range []struct {
+		lower, upper float64
+		count        uint
+		median       float64
+	}{
+		{lower: 0, upper: 10, count: 1000, median: 5},
+		{lower: 1, upper: 2, count: 200000, median: 1.5},
+		{lower: 0.5, upper: 0.5, count: 3, median: 0.5},
+		{lower: -1, upper: 4, count: 7, median: 4},
+	} {
+		a := &Agent{}
+		a.InsertInterpolate(tt.lower, tt.upper, tt.count)
+		s := a.Finish()
+		require.NotNil(t, s)
+
+		require.EqualValues(t, tt.count, s.Basic.Cnt)
+		require.Equal(t, int(tt.count), s.count)
+		require.Equal(t, int(tt.count), s.bins.nSum())
+		require.InDelta(t, tt.median, s.Quantile(agentConfig, 0.5), tt.median*0.02)
+		require.True(t, s.Basic.Min >= tt.lower && s.Basic.Max <= tt.upper, "%s", s.Basic.String())
+	}
+
+	a := &Agent{}
+	a.InsertInterpolate(0, 10, 0)
+	require.True(t, a.IsEmpty())
+}
@@ -0,0 +1,32 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package secrets
+
+// BackendsConfig holds the options of the built-in secret backends, selected
+// by the prefix of a handle: ENC[file:<path>], ENC[env:<name>] and
+// ENC[vault:<path>#<key>]. A backend is only enabled once configured, until
+// then its handles are decrypted by the secret_backend_command. The TTLs are
+// in seconds, 0 caches the secrets until the agent restarts.
+type BackendsConfig struct {
+	FileDirectory string // enables the file backend
+	FileTTL       int
+	EnvEnabled    bool
+	EnvTTL        int
+	Vault         VaultConfig // its Address enables the Vault backend
+}
+
+// VaultConfig holds the options of the Vault secret backend
+type VaultConfig struct {
+	Address             string
+	CAFile              string
+	AuthMethod          string // token or kubernetes
+	Token               string
+	KubernetesRole      string
+	KubernetesMount     string
+	KubernetesTokenFile string
+	KVVersion           int
+	TTL                 int
+}
@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/common"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// commandBackendName is reported for the secrets decrypted by the secret_backend_command
+const commandBackendName = "secret_backend_command"
+
+// secretBackend fetches the secrets of a built-in backend
+type secretBackend interface {
+	// fetch returns the secret of a handle, stripped of the backend prefix
+	fetch(key string) (string, error)
+}
+
+// builtinBackend is a built-in backend and the TTL of the secrets it fetches
+type builtinBackend struct {
+	name    string
+	backend secretBackend
+	ttl     time.Duration
+}
+
+type cachedSecret struct {
+	value  string
+	expiry time.Time // zero if the secret never expires
+}
+
+var (
+	builtinBackends map[string]*builtinBackend
+	builtinCache    map[string]cachedSecret
+	// name of the backend which decrypted each handle
+	secretBackendName map[string]string
+
+	// for testing purpose
+	timeNow = time.Now
+)
+
+func init() {
+	builtinCache = make(map[string]cachedSecret)
+	secretBackendName = make(map[string]string)
+	InitBackends(BackendsConfig{})
+}
+
+// InitBackends initializes the built-in secret backends. Only the configured
+// ones are enabled: the handles prefixed with the name of a disabled backend
+// keep being decrypted by the secret_backend_command, as existing scripts may
+// use such handles.
+func InitBackends(cfg BackendsConfig) {
+	builtinBackends = map[string]*builtinBackend{}
+	if cfg.FileDirectory != "" {
+		builtinBackends["file"] = &builtinBackend{
+			name:    "file",
+			backend: &fileBackend{directory: cfg.FileDirectory},
+			ttl:     time.Duration(cfg.FileTTL) * time.Second,
+		}
+	}
+	if cfg.EnvEnabled {
+		builtinBackends["env"] = &builtinBackend{
+			name:    "env",
+			backend: &envBackend{},
+			ttl:     time.Duration(cfg.EnvTTL) * time.Second,
+		}
+	}
+	if cfg.Vault.Address != "" {
+		builtinBackends["vault"] = &builtinBackend{
+			name:    "vault",
+			backend: newVaultBackend(cfg.Vault),
+			ttl:     time.Duration(cfg.Vault.TTL) * time.Second,
+		}
+	}
+}
+
+// getBuiltinBackend returns the enabled built-in backend selected by the prefix
+// of a handle, like file:db_password, and the handle without its prefix
+func getBuiltinBackend(handle string) (*builtinBackend, string, bool) {
+	idx := strings.Index(handle, ":")
+	if idx == -1 {
+		return nil, "", false
+	}
+	backend, found := builtinBackends[handle[:idx]]
+	if !found {
+		return nil, "", false
+	}
+	return backend, handle[idx+1:], true
+}
+
+// resolveBuiltinSecret returns the secret of a handle of a built-in backend,
+// from the cache until it expires. A secret failing to refresh keeps its
+// cached value until the backend answers again. Secrets are only refreshed
+// when decrypting a configuration, the ones already loaded are left as is.
+func resolveBuiltinSecret(handle string, b *builtinBackend, key string, origin string) (string, error) {
+	cached, found := builtinCache[handle]
+	if found && (cached.expiry.IsZero() || timeNow().Before(cached.expiry)) {
+		log.Debugf("Secret '%s' was retrieved from cache", handle)
+		addSecretOrigin(handle, origin)
+		return cached.value, nil
+	}
+
+	value, err := b.backend.fetch(key)
+	if err == nil && value == "" {
+		err = fmt.Errorf("decrypted secret is empty")
+	}
+	if err != nil {
+		if found {
+			log.Warnf("Could not refresh secret '%s' from the %s backend, using the cached value: %s", handle, b.name, err)
+			addSecretOrigin(handle, origin)
+			return cached.value, nil
+		}
+		return "", fmt.Errorf("an error occurred while decrypting '%s' with the %s backend: %s", handle, b.name, err)
+	}
+	log.Debugf("Secret '%s' was retrieved from the %s backend", handle, b.name)
+
+	cached = cachedSecret{value: value}
+	if b.ttl > 0 {
+		cached.expiry = timeNow().Add(b.ttl)
+	}
+	builtinCache[handle] = cached
+	secretBackendName[handle] = b.name
+	addSecretOrigin(handle, origin)
+	return value, nil
+}
+
+// addSecretOrigin keeps track of a place where a handle was found
+func addSecretOrigin(handle string, origin string) {
+	if origins, found := secretOrigin[handle]; found {
+		origins.Add(origin)
+	} else {
+		secretOrigin[handle] = common.NewStringSet(origin)
+	}
+}
+
+// fileBackend reads the secrets from the files of a directory, like the
+// secrets mounted in a Kubernetes pod. The handle is the path of the file
+// relative to the directory.
+type fileBackend struct {
+	directory string
+}
+
+func (b *fileBackend) fetch(key string) (string, error) {
+	if b.directory == "" {
+		return "", fmt.Errorf("secret_backend_file_directory is not set")
+	}
+	path := filepath.Join(b.directory, key)
+	if rel, err := filepath.Rel(b.directory, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("'%s' is outside of the secrets directory %s", key, b.directory)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > int64(secretBackendOutputMaxSize) {
+		return "", fmt.Errorf("secret file %s is too large: exceeded %d bytes", path, secretBackendOutputMaxSize)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// the files written by hand usually end with a newline
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// envBackend reads the secrets from the environment variables of the agent,
+// the handle is the name of the variable
+type envBackend struct{}
+
+func (b *envBackend) fetch(key string) (string, error) {
+	value, found := os.LookupEnv(key)
+	if !found {
+		return "", fmt.Errorf("environment variable %s is not set", key)
+	}
+	return value, nil
+}
@@ -0,0 +1,186 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/common"
+)
+
+type fakeBackend struct {
+	secrets map[string]string
+	calls   int
+}
+
+func (b *fakeBackend) fetch(key string) (string, error) {
+	b.calls++
+	value, found := b.secrets[key]
+	if !found {
+		return "", fmt.Errorf("secret %s not found", key)
+	}
+	return value, nil
+}
+
+func resetBuiltinBackends() {
+	InitBackends(BackendsConfig{})
+	builtinCache = map[string]cachedSecret{}
+	secretBackendName = map[string]string{}
+	secretOrigin = map[string]common.StringSet{}
+	timeNow = time.Now
+}
+
+func TestGetBuiltinBackend(t *testing.T) {
+	defer resetBuiltinBackends()
+
+	// the backends are disabled until configured
+	for _, handle := range []string{"file:db_password", "env:DB_PASSWORD", "vault:secret/data/db#password"} {
+		_, _, found := getBuiltinBackend(handle)
+		assert.False(t, found, handle)
+	}
+
+	InitBackends(BackendsConfig{
+		FileDirectory: "/etc/secrets",
+		EnvEnabled:    true,
+		Vault:         VaultConfig{Address: "https://vault:8200"},
+	})
+	backend, key, found := getBuiltinBackend("vault:secret/data/db#password")
+	require.True(t, found)
+	assert.Equal(t, "vault", backend.name)
+	assert.Equal(t, "secret/data/db#password", key)
+
+	backend, key, found = getBuiltinBackend("env:DB_PASSWORD")
+	require.True(t, found)
+	assert.Equal(t, "env", backend.name)
+	assert.Equal(t, "DB_PASSWORD", key)
+
+	backend, key, found = getBuiltinBackend("file:postgres/password")
+	require.True(t, found)
+	assert.Equal(t, "file", backend.name)
+	assert.Equal(t, "postgres/password", key)
+
+	// the other handles are decrypted by the secret_backend_command
+	for _, handle := range []string{"db_password", "AES256_GCM,data:v8jQ=", "unknown:db_password", ":file"} {
+		_, _, found = getBuiltinBackend(handle)
+		assert.False(t, found, handle)
+	}
+}
+
+func TestResolveBuiltinSecretCache(t *testing.T) {
+	defer resetBuiltinBackends()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	fake := &fakeBackend{secrets: map[string]string{"db": "password1"}}
+	backend := &builtinBackend{name: "fake", backend: fake, ttl: time.Minute}
+
+	value, err := resolveBuiltinSecret("fake:db", backend, "db", "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "password1", value)
+	assert.Equal(t, "fake", secretBackendName["fake:db"])
+
+	// cached until the TTL expires
+	fake.secrets["db"] = "password2"
+	value, err = resolveBuiltinSecret("fake:db", backend, "db", "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, "password1", value)
+	assert.Equal(t, 1, fake.calls)
+	assert.ElementsMatch(t, []string{"postgres", "mysql"}, secretOrigin["fake:db"].GetAll())
+
+	// refreshed once expired
+	now = now.Add(2 * time.Minute)
+	value, err = resolveBuiltinSecret("fake:db", backend, "db", "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "password2", value)
+	assert.Equal(t, 2, fake.calls)
+
+	// the cached value is kept when the refresh fails
+	now = now.Add(2 * time.Minute)
+	delete(fake.secrets, "db")
+	value, err = resolveBuiltinSecret("fake:db", backend, "db", "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "password2", value)
+	assert.Equal(t, 3, fake.calls)
+
+	_, err = resolveBuiltinSecret("fake:unknown", backend, "unknown", "postgres")
+	assert.EqualError(t, err, "an error occurred while decrypting 'fake:unknown' with the fake backend: secret unknown not found")
+
+	fake.secrets["empty"] = ""
+	_, err = resolveBuiltinSecret("fake:empty", backend, "empty", "postgres")
+	assert.EqualError(t, err, "an error occurred while decrypting 'fake:empty' with the fake backend: decrypted secret is empty")
+}
+
+func TestResolveBuiltinSecretNoTTL(t *testing.T) {
+	defer resetBuiltinBackends()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	fake := &fakeBackend{secrets: map[string]string{"db": "password1"}}
+	backend := &builtinBackend{name: "fake", backend: fake}
+
+	_, err := resolveBuiltinSecret("fake:db", backend, "db", "postgres")
+	require.NoError(t, err)
+	now = now.Add(24 * time.Hour)
+	value, err := resolveBuiltinSecret("fake:db", backend, "db", "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "password1", value)
+	assert.Equal(t, 1, fake.calls)
+}
+
+func TestFileBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "postgres"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "password"), []byte("password1"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "postgres", "password"), []byte("password2\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "large"), []byte(strings.Repeat("a", secretBackendOutputMaxSize+1)), 0600))
+
+	backend := &fileBackend{directory: dir}
+	value, err := backend.fetch("password")
+	require.NoError(t, err)
+	assert.Equal(t, "password1", value)
+
+	value, err = backend.fetch("postgres/password")
+	require.NoError(t, err)
+	assert.Equal(t, "password2", value)
+
+	_, err = backend.fetch("missing")
+	assert.Error(t, err)
+	_, err = backend.fetch("large")
+	assert.Error(t, err)
+	_, err = backend.fetch("../password")
+	assert.EqualError(t, err, fmt.Sprintf("'../password' is outside of the secrets directory %s", dir))
+
+	_, err = (&fileBackend{}).fetch("password")
+	assert.EqualError(t, err, "secret_backend_file_directory is not set")
+}
+
+func TestEnvBackend(t *testing.T) {
+	require.NoError(t, os.Setenv("TEST_SECRET_DB_PASSWORD", "password1"))
+	defer os.Unsetenv("TEST_SECRET_DB_PASSWORD")
+	os.Unsetenv("TEST_SECRET_NOT_SET")
+
+	backend := &envBackend{}
+	value, err := backend.fetch("TEST_SECRET_DB_PASSWORD")
+	require.NoError(t, err)
+	assert.Equal(t, "password1", value)
+
+	_, err = backend.fetch("TEST_SECRET_NOT_SET")
+	assert.EqualError(t, err, "environment variable TEST_SECRET_NOT_SET is not set")
+}
@@ -119,6 +119,7 @@ func fetchSecret(secretsHandle []string, origin string) (map[string]string, erro
 		secretCache[sec] = v.Value
 		// keep track of place where a handle was found
 		secretOrigin[sec] = common.NewStringSet(origin)
+		secretBackendName[sec] = commandBackendName
 		res[sec] = v.Value
 	}
 	return res, nil
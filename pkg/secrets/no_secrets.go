@@ -16,6 +16,9 @@ import (
 // Init placeholder when compiled without the 'secrets' build tag
 func Init(command string, arguments []string, timeout int, maxSize int) {}
 
+// InitBackends placeholder when compiled without the 'secrets' build tag
+func InitBackends(cfg BackendsConfig) {}
+
 // Decrypt encrypted secrets are not available on windows
 func Decrypt(data []byte, origin string) ([]byte, error) {
 	return data, nil
@@ -8,8 +8,10 @@
 package secrets
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
+	"sync"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -18,6 +20,9 @@ import (
 )
 
 var (
+	// guards the caches, Decrypt is called from the config and the autodiscovery
+	secretsMutex sync.Mutex
+
 	secretCache map[string]string
 	// list of handles and where they were found
 	secretOrigin map[string]common.StringSet
@@ -119,13 +124,18 @@ func isEnc(str string) (bool, string) {
 // testing purpose
 var secretFetcher = fetchSecret
 
-// Decrypt replaces all encrypted secrets in data by executing
+// Decrypt replaces all encrypted secrets in data. The handles prefixed with the
+// name of an enabled built-in backend, like ENC[vault:secret/data/db#password],
+// are fetched from it. The other ones are decrypted by executing
 // "secret_backend_command" once if all secrets aren't present in the cache.
 func Decrypt(data []byte, origin string) ([]byte, error) {
-	if data == nil || secretBackendCommand == "" {
+	if data == nil || !bytes.Contains(data, []byte("ENC[")) {
 		return data, nil
 	}
 
+	secretsMutex.Lock()
+	defer secretsMutex.Unlock()
+
 	var config interface{}
 	err := yaml.Unmarshal(data, &config)
 	if err != nil {
@@ -137,6 +147,14 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 	haveSecret := false
 	err = walk(&config, func(str string) (string, error) {
 		if ok, handle := isEnc(str); ok {
+			if backend, key, found := getBuiltinBackend(handle); found {
+				haveSecret = true
+				return resolveBuiltinSecret(handle, backend, key, origin)
+			}
+			if secretBackendCommand == "" {
+				// only the secret_backend_command could decrypt this handle
+				return str, nil
+			}
 			haveSecret = true
 			// Check if we already know this secret
 			if secret, ok := secretCache[handle]; ok {
@@ -192,15 +210,22 @@ func Decrypt(data []byte, origin string) ([]byte, error) {
 
 // GetDebugInfo exposes debug informations about secrets to be included in a flare
 func GetDebugInfo() (*SecretInfo, error) {
-	if secretBackendCommand == "" {
-		return nil, fmt.Errorf("No secret_backend_command set: secrets feature is not enabled")
+	secretsMutex.Lock()
+	defer secretsMutex.Unlock()
+
+	if secretBackendCommand == "" && len(secretBackendName) == 0 {
+		return nil, fmt.Errorf("No secret_backend_command set and no secret decrypted by a built-in backend: secrets feature is not enabled")
 	}
 	info := &SecretInfo{ExecutablePath: secretBackendCommand}
-	info.populateRights()
+	if secretBackendCommand != "" {
+		info.populateRights()
+	}
 
 	info.SecretsHandles = map[string][]string{}
+	info.SecretsBackends = map[string]string{}
 	for handle, originNames := range secretOrigin {
 		info.SecretsHandles[handle] = originNames.GetAll()
+		info.SecretsBackends[handle] = secretBackendName[handle]
 	}
 	return info, nil
 }
@@ -9,6 +9,7 @@ package secrets
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"testing"
 
@@ -61,6 +62,14 @@ instances:
 - password: ENC[pass2]
 `)
 
+	testConfBuiltin = []byte(`---
+instances:
+- password: ENC[env:TEST_SECRET_PASSWORD]
+  user: test
+- password: ENC[pass2]
+  user: test2
+`)
+
 	testConfDecrypted = []byte(`instances:
 - password: password1
   user: test
@@ -300,3 +309,115 @@ func TestDebugInfo(t *testing.T) {
 		"pass3": {"test2"},
 	}, handles)
 }
+
+func TestDecryptBuiltinBackendNoCommand(t *testing.T) {
+	require.NoError(t, os.Setenv("TEST_SECRET_PASSWORD", "password1"))
+	defer os.Unsetenv("TEST_SECRET_PASSWORD")
+	InitBackends(BackendsConfig{EnvEnabled: true})
+	defer func() {
+		resetBuiltinBackends()
+		secretFetcher = fetchSecret
+	}()
+
+	secretFetcher = func(secrets []string, origin string) (map[string]string, error) {
+		require.Fail(t, "secret_backend_command called without being set")
+		return nil, nil
+	}
+
+	// the handles of the secret_backend_command are left as is
+	newConf, err := Decrypt(testConfBuiltin, "test")
+	require.Nil(t, err)
+	assert.Equal(t, `instances:
+- password: password1
+  user: test
+- password: ENC[pass2]
+  user: test2
+`, string(newConf))
+
+	_, err = Decrypt([]byte("password: ENC[env:TEST_SECRET_NOT_SET]"), "test")
+	assert.EqualError(t, err, "an error occurred while decrypting 'env:TEST_SECRET_NOT_SET' with the env backend: environment variable TEST_SECRET_NOT_SET is not set")
+}
+
+func TestDecryptBuiltinBackendAndCommand(t *testing.T) {
+	require.NoError(t, os.Setenv("TEST_SECRET_PASSWORD", "password1"))
+	defer os.Unsetenv("TEST_SECRET_PASSWORD")
+	InitBackends(BackendsConfig{EnvEnabled: true})
+	secretBackendCommand = "some_command"
+	defer func() {
+		secretBackendCommand = ""
+		secretCache = map[string]string{}
+		resetBuiltinBackends()
+		runCommand = execCommand
+	}()
+
+	runCommand = func(payload string) ([]byte, error) {
+		assert.Equal(t, `{"secrets":["pass2"],"version":"1.0"}`, payload)
+		return []byte(`{"pass2":{"value":"password2"}}`), nil
+	}
+
+	newConf, err := Decrypt(testConfBuiltin, "test")
+	require.Nil(t, err)
+	assert.Equal(t, string(testConfDecrypted), string(newConf))
+
+	info, err := GetDebugInfo()
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"env:TEST_SECRET_PASSWORD": "env",
+		"pass2":                    commandBackendName,
+	}, info.SecretsBackends)
+}
+
+func TestDecryptPrefixedHandleWithCommand(t *testing.T) {
+	require.NoError(t, os.Setenv("TEST_SECRET_PASSWORD", "password1"))
+	defer os.Unsetenv("TEST_SECRET_PASSWORD")
+	InitBackends(BackendsConfig{EnvEnabled: true})
+	secretBackendCommand = "some_command"
+	defer func() {
+		secretBackendCommand = ""
+		secretCache = map[string]string{}
+		resetBuiltinBackends()
+		runCommand = execCommand
+	}()
+
+	// the Vault backend isn't enabled, its handles are left to the command
+	runCommand = func(payload string) ([]byte, error) {
+		assert.Equal(t, `{"secrets":["vault:secret/db#password"],"version":"1.0"}`, payload)
+		return []byte(`{"vault:secret/db#password":{"value":"password2"}}`), nil
+	}
+
+	newConf, err := Decrypt([]byte(`---
+instances:
+- password: ENC[env:TEST_SECRET_PASSWORD]
+  user: test
+- password: ENC[vault:secret/db#password]
+  user: test2
+`), "test")
+	require.Nil(t, err)
+	assert.Equal(t, string(testConfDecrypted), string(newConf))
+
+	info, err := GetDebugInfo()
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"env:TEST_SECRET_PASSWORD": "env",
+		"vault:secret/db#password": commandBackendName,
+	}, info.SecretsBackends)
+}
+
+func TestDebugInfoBuiltinBackendOnly(t *testing.T) {
+	require.NoError(t, os.Setenv("TEST_SECRET_PASSWORD", "password1"))
+	defer os.Unsetenv("TEST_SECRET_PASSWORD")
+	InitBackends(BackendsConfig{EnvEnabled: true})
+	defer resetBuiltinBackends()
+
+	_, err := GetDebugInfo()
+	require.NotNil(t, err)
+
+	_, err = Decrypt([]byte("password: ENC[env:TEST_SECRET_PASSWORD]"), "test")
+	require.Nil(t, err)
+
+	info, err := GetDebugInfo()
+	require.Nil(t, err)
+	assert.Equal(t, "", info.ExecutablePath)
+	assert.Equal(t, map[string][]string{"env:TEST_SECRET_PASSWORD": {"test"}}, info.SecretsHandles)
+	assert.Equal(t, map[string]string{"env:TEST_SECRET_PASSWORD": "env"}, info.SecretsBackends)
+}
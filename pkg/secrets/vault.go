@@ -0,0 +1,240 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultVaultKubernetesMount     = "kubernetes"
+	defaultVaultKubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	vaultMaxResponseSize            = 1024 * 1024
+)
+
+var errVaultPermissionDenied = errors.New("permission denied")
+
+// vaultBackend reads the secrets from a KV secrets engine of HashiCorp Vault,
+// version 1 or 2. The handle is the API path of the secret and the key to
+// read, like secret/data/datadog#api_key for the KV version 2 engine mounted
+// at secret/. It authenticates with a token, or logs in with the service
+// account token of the pod with the kubernetes auth method.
+type vaultBackend struct {
+	cfg         VaultConfig
+	client      *http.Client
+	token       string
+	tokenExpiry time.Time // zero if the token never expires
+}
+
+func newVaultBackend(cfg VaultConfig) *vaultBackend {
+	if cfg.AuthMethod == "" {
+		cfg.AuthMethod = "token"
+	}
+	if cfg.KubernetesMount == "" {
+		cfg.KubernetesMount = defaultVaultKubernetesMount
+	}
+	if cfg.KubernetesTokenFile == "" {
+		cfg.KubernetesTokenFile = defaultVaultKubernetesTokenFile
+	}
+	if cfg.KVVersion == 0 {
+		cfg.KVVersion = 2
+	}
+	return &vaultBackend{cfg: cfg}
+}
+
+func (b *vaultBackend) fetch(key string) (string, error) {
+	if b.cfg.Address == "" {
+		return "", fmt.Errorf("secret_backend_vault_address is not set")
+	}
+	idx := strings.LastIndex(key, "#")
+	if idx == -1 {
+		return "", fmt.Errorf("'%s' is not a <path>#<key> handle", key)
+	}
+	path, field := key[:idx], key[idx+1:]
+
+	if err := b.setupClient(); err != nil {
+		return "", err
+	}
+	if err := b.authenticate(false); err != nil {
+		return "", err
+	}
+	data, err := b.read(path)
+	if err == errVaultPermissionDenied && b.cfg.AuthMethod == "kubernetes" {
+		// the token may have been revoked before the end of its lease
+		if err = b.authenticate(true); err != nil {
+			return "", err
+		}
+		data, err = b.read(path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	value, found := data[field]
+	if !found {
+		return "", fmt.Errorf("key %s not found in %s", field, path)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key %s of %s is not a string", field, path)
+	}
+	return str, nil
+}
+
+func (b *vaultBackend) setupClient() error {
+	if b.client != nil {
+		return nil
+	}
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if b.cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(b.cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("could not read the Vault CA file: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in the Vault CA file %s", b.cfg.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	b.client = &http.Client{Transport: transport}
+	return nil
+}
+
+// authenticate sets the token of the requests, logging in again with the
+// kubernetes auth method when the token expires or when forced to
+func (b *vaultBackend) authenticate(force bool) error {
+	switch b.cfg.AuthMethod {
+	case "token":
+		if b.cfg.Token == "" {
+			return fmt.Errorf("secret_backend_vault_token is not set")
+		}
+		b.token = b.cfg.Token
+		return nil
+	case "kubernetes":
+		if !force && b.token != "" && (b.tokenExpiry.IsZero() || timeNow().Before(b.tokenExpiry)) {
+			return nil
+		}
+		return b.kubernetesLogin()
+	default:
+		return fmt.Errorf("unknown Vault auth method '%s', use token or kubernetes", b.cfg.AuthMethod)
+	}
+}
+
+func (b *vaultBackend) kubernetesLogin() error {
+	if b.cfg.KubernetesRole == "" {
+		return fmt.Errorf("secret_backend_vault_kubernetes_role is not set")
+	}
+	jwt, err := ioutil.ReadFile(b.cfg.KubernetesTokenFile)
+	if err != nil {
+		return fmt.Errorf("could not read the service account token: %s", err)
+	}
+	body, err := json.Marshal(map[string]string{
+		"role": b.cfg.KubernetesRole,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	b.token = ""
+	if err := b.do("POST", "auth/"+b.cfg.KubernetesMount+"/login", body, &resp); err != nil {
+		return fmt.Errorf("could not log in to Vault with the kubernetes auth method: %s", err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return fmt.Errorf("could not log in to Vault with the kubernetes auth method: no token returned")
+	}
+	b.token = resp.Auth.ClientToken
+	b.tokenExpiry = time.Time{}
+	if resp.Auth.LeaseDuration > 0 {
+		// log in again before the token expires
+		b.tokenExpiry = timeNow().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second * 9 / 10)
+	}
+	return nil
+}
+
+// read returns the data of a secret
+func (b *vaultBackend) read(path string) (map[string]interface{}, error) {
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := b.do("GET", path, nil, &resp); err != nil {
+		return nil, err
+	}
+	if b.cfg.KVVersion == 1 {
+		return resp.Data, nil
+	}
+	// the KV version 2 engine nests the data of the secret with its metadata
+	data, ok := resp.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not a secret of a KV version 2 engine", path)
+	}
+	return data, nil
+}
+
+// do sends a request to the Vault API and decodes its JSON answer
+func (b *vaultBackend) do(method string, path string, body []byte, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		time.Duration(secretBackendTimeout)*time.Second)
+	defer cancel()
+
+	url := strings.TrimRight(b.cfg.Address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if b.token != "" {
+		req.Header.Set("X-Vault-Token", b.token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, vaultMaxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusForbidden {
+		return errVaultPermissionDenied
+	}
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(content, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("the Vault API returned %s: %s", resp.Status, strings.Join(vaultErr.Errors, ", "))
+		}
+		return fmt.Errorf("the Vault API returned %s", resp.Status)
+	}
+	return json.Unmarshal(content, out)
+}
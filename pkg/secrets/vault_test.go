@@ -0,0 +1,164 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build secrets
+
+package secrets
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVault serves a KV version 1 engine at kv/ and a version 2 one at
+// secret/, and the login endpoint of the kubernetes auth method
+type fakeVault struct {
+	tokens map[string]bool // the valid tokens
+	logins int
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v1/auth/kubernetes/login" {
+		var login map[string]string
+		json.NewDecoder(r.Body).Decode(&login)
+		if login["role"] != "datadog" || login["jwt"] != "service-account-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		v.logins++
+		v.tokens["k8s-token"] = true
+		w.Write([]byte(`{"auth":{"client_token":"k8s-token","lease_duration":3600}}`))
+		return
+	}
+
+	if !v.tokens[r.Header.Get("X-Vault-Token")] {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+	switch r.URL.Path {
+	case "/v1/kv/datadog":
+		w.Write([]byte(`{"data":{"api_key":"kv1-key","port":5432}}`))
+	case "/v1/secret/data/datadog":
+		w.Write([]byte(`{"data":{"data":{"api_key":"kv2-key"},"metadata":{"version":3}}}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[]}`))
+	}
+}
+
+func setVaultTestTimeout() func() {
+	timeout := secretBackendTimeout
+	secretBackendTimeout = 5
+	return func() { secretBackendTimeout = timeout }
+}
+
+func TestVaultBackendToken(t *testing.T) {
+	defer setVaultTestTimeout()()
+	vault := &fakeVault{tokens: map[string]bool{"root-token": true}}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	backend := newVaultBackend(VaultConfig{Address: server.URL, Token: "root-token"})
+	value, err := backend.fetch("secret/data/datadog#api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "kv2-key", value)
+
+	_, err = backend.fetch("secret/data/datadog#app_key")
+	assert.EqualError(t, err, "key app_key not found in secret/data/datadog")
+	_, err = backend.fetch("secret/data/missing#api_key")
+	assert.EqualError(t, err, "the Vault API returned 404 Not Found")
+	_, err = backend.fetch("secret/data/datadog")
+	assert.EqualError(t, err, "'secret/data/datadog' is not a <path>#<key> handle")
+	_, err = backend.fetch("kv/datadog#api_key")
+	assert.EqualError(t, err, "kv/datadog is not a secret of a KV version 2 engine")
+
+	backend = newVaultBackend(VaultConfig{Address: server.URL, Token: "root-token", KVVersion: 1})
+	value, err = backend.fetch("kv/datadog#api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "kv1-key", value)
+	_, err = backend.fetch("kv/datadog#port")
+	assert.EqualError(t, err, "key port of kv/datadog is not a string")
+
+	backend = newVaultBackend(VaultConfig{Address: server.URL, Token: "revoked-token"})
+	_, err = backend.fetch("secret/data/datadog#api_key")
+	assert.Equal(t, errVaultPermissionDenied, err)
+
+	_, err = newVaultBackend(VaultConfig{Address: server.URL}).fetch("secret/data/datadog#api_key")
+	assert.EqualError(t, err, "secret_backend_vault_token is not set")
+	_, err = newVaultBackend(VaultConfig{}).fetch("secret/data/datadog#api_key")
+	assert.EqualError(t, err, "secret_backend_vault_address is not set")
+}
+
+func TestVaultBackendKubernetes(t *testing.T) {
+	defer setVaultTestTimeout()()
+	defer func() { timeNow = time.Now }()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	tokenFile, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(tokenFile.Name())
+	_, err = tokenFile.WriteString("service-account-token\n")
+	require.NoError(t, err)
+	tokenFile.Close()
+
+	vault := &fakeVault{tokens: map[string]bool{}}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	backend := newVaultBackend(VaultConfig{
+		Address:             server.URL,
+		AuthMethod:          "kubernetes",
+		KubernetesRole:      "datadog",
+		KubernetesTokenFile: tokenFile.Name(),
+	})
+	value, err := backend.fetch("secret/data/datadog#api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "kv2-key", value)
+	assert.Equal(t, 1, vault.logins)
+
+	// the token is reused until it expires
+	_, err = backend.fetch("secret/data/datadog#api_key")
+	require.NoError(t, err)
+	assert.Equal(t, 1, vault.logins)
+
+	now = now.Add(time.Hour)
+	_, err = backend.fetch("secret/data/datadog#api_key")
+	require.NoError(t, err)
+	assert.Equal(t, 2, vault.logins)
+
+	// a revoked token is replaced
+	delete(vault.tokens, "k8s-token")
+	_, err = backend.fetch("secret/data/datadog#api_key")
+	require.NoError(t, err)
+	assert.Equal(t, 3, vault.logins)
+
+	backend = newVaultBackend(VaultConfig{
+		Address:             server.URL,
+		AuthMethod:          "kubernetes",
+		KubernetesRole:      "other",
+		KubernetesTokenFile: tokenFile.Name(),
+	})
+	_, err = backend.fetch("secret/data/datadog#api_key")
+	assert.EqualError(t, err, "could not log in to Vault with the kubernetes auth method: permission denied")
+
+	backend = newVaultBackend(VaultConfig{Address: server.URL, AuthMethod: "kubernetes"})
+	_, err = backend.fetch("secret/data/datadog#api_key")
+	assert.EqualError(t, err, "secret_backend_vault_kubernetes_role is not set")
+
+	backend = newVaultBackend(VaultConfig{Address: server.URL, AuthMethod: "approle"})
+	_, err = backend.fetch("secret/data/datadog#api_key")
+	assert.EqualError(t, err, "unknown Vault auth method 'approle', use token or kubernetes")
+}
@@ -70,14 +70,20 @@ func lastErrorTraceback(value string) template.HTML {
 
 // lastErrorMessage converts the last error message to html
 func lastErrorMessage(value string) template.HTML {
+	return template.HTML(CheckErrorMessage(value))
+}
+
+// CheckErrorMessage returns the message of the error of a check run. Python
+// checks report their errors as a JSON list of messages and tracebacks.
+func CheckErrorMessage(value string) string {
 	var lastErrorArray []map[string]string
 	err := json.Unmarshal([]byte(value), &lastErrorArray)
 	if err == nil && len(lastErrorArray) > 0 {
 		if msg, ok := lastErrorArray[0]["message"]; ok {
-			return template.HTML(msg)
+			return msg
 		}
 	}
-	return template.HTML(value)
+	return value
 }
 
 // formatUnixTime formats the unix time to make it more readable
@@ -17,3 +17,9 @@ func TestNtpWarning(t *testing.T) {
 	require.True(t, ntpWarning(3601))
 	require.True(t, ntpWarning(-601))
 }
+
+func TestCheckErrorMessage(t *testing.T) {
+	require.Equal(t, "no connection", CheckErrorMessage("no connection"))
+	require.Equal(t, "no connection", CheckErrorMessage(`[{"message": "no connection", "traceback": "Traceback..."}]`))
+	require.Equal(t, `[{"traceback": "Traceback..."}]`, CheckErrorMessage(`[{"traceback": "Traceback..."}]`))
+}
@@ -29,10 +29,13 @@ type Agent struct {
 	Receiver           *api.HTTPReceiver
 	Concentrator       *stats.Concentrator
 	Blacklister        *filters.Blacklister
+	SpanFilter         *filters.SpanFilter
 	Replacer           *filters.Replacer
 	ScoreSampler       *Sampler
 	ErrorsScoreSampler *Sampler
 	PrioritySampler    *Sampler
+	RulesSampler       *sampler.RulesSampler // nil when no sampling rules are configured
+	TailSampler        *sampler.TailSampler  // nil when tail-based sampling is disabled
 	EventProcessor     *event.Processor
 	TraceWriter        *writer.TraceWriter
 	ServiceWriter      *writer.ServiceWriter
@@ -45,6 +48,7 @@ type Agent struct {
 	obfuscator *obfuscate.Obfuscator
 
 	tracePkgChan chan *writer.TracePackage
+	tailChan     chan pb.Trace // traces kept by the tail sampler
 
 	// config
 	conf    *config.AgentConfig
@@ -70,6 +74,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 	r := api.NewHTTPReceiver(conf, dynConf, rawTraceChan, serviceChan)
 	c := stats.NewConcentrator(
 		conf.ExtraAggregators,
+		conf.ContainerTagsAggregators,
 		conf.BucketInterval.Nanoseconds(),
 		statsChan,
 	)
@@ -85,10 +90,11 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 	sw := writer.NewStatsWriter(conf, statsChan)
 	svcW := writer.NewServiceWriter(conf, filteredServiceChan)
 
-	return &Agent{
+	agnt := &Agent{
 		Receiver:           r,
 		Concentrator:       c,
 		Blacklister:        filters.NewBlacklister(conf.Ignore["resource"]),
+		SpanFilter:         filters.NewSpanFilter(conf.FilterRules),
 		Replacer:           filters.NewReplacer(conf.ReplaceTags),
 		ScoreSampler:       ss,
 		ErrorsScoreSampler: ess,
@@ -105,6 +111,14 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		dynConf:            dynConf,
 		ctx:                ctx,
 	}
+	if len(conf.SamplingRules) > 0 {
+		agnt.RulesSampler = sampler.NewRulesSampler(conf.SamplingRules)
+	}
+	if conf.TailSampling != nil && conf.TailSampling.Enabled {
+		agnt.tailChan = make(chan pb.Trace, 1000)
+		agnt.TailSampler = sampler.NewTailSampler(conf.TailSampling, agnt.tailChan)
+	}
+	return agnt
 }
 
 // Run starts routers routines and individual pieces then stop them when the exit order is received
@@ -125,6 +139,14 @@ func (a *Agent) Run() {
 	} {
 		starter.Start()
 	}
+	if a.RulesSampler != nil {
+		a.RulesSampler.Start()
+		go a.reportSamplingRules()
+	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+		go a.forwardTailSampled()
+	}
 
 	n := 1
 	if config.HasFeature("parallel_process") {
@@ -150,6 +172,31 @@ func (a *Agent) work() {
 
 }
 
+// forwardTailSampled sends the traces kept by the tail sampler to the trace writer.
+func (a *Agent) forwardTailSampled() {
+	defer watchdog.LogOnPanic()
+	for t := range a.tailChan {
+		a.tracePkgChan <- &writer.TracePackage{Trace: t}
+	}
+}
+
+// reportSamplingRules periodically publishes the counters of the sampling rules.
+func (a *Agent) reportSamplingRules() {
+	defer watchdog.LogOnPanic()
+
+	t := time.NewTicker(10 * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			info.UpdateSamplingRules(a.RulesSampler.Stats())
+		case <-a.ctx.Done():
+			return
+		}
+	}
+}
+
 func (a *Agent) loop() {
 	for {
 		select {
@@ -158,6 +205,10 @@ func (a *Agent) loop() {
 			if err := a.Receiver.Stop(); err != nil {
 				log.Error(err)
 			}
+			if a.TailSampler != nil {
+				a.TailSampler.Stop()
+				close(a.tailChan)
+			}
 			a.Concentrator.Stop()
 			a.TraceWriter.Stop()
 			a.StatsWriter.Stop()
@@ -166,6 +217,9 @@ func (a *Agent) loop() {
 			a.ScoreSampler.Stop()
 			a.ErrorsScoreSampler.Stop()
 			a.PrioritySampler.Stop()
+			if a.RulesSampler != nil {
+				a.RulesSampler.Stop()
+			}
 			a.EventProcessor.Stop()
 			return
 		}
@@ -207,16 +261,41 @@ func (a *Agent) Process(t pb.Trace) {
 	}
 	atomic.AddInt64(stat, 1)
 
+	// rec records the decision taken on the trace for the /debug/traces endpoint. The
+	// resource is only recorded once obfuscated.
+	rec := info.TraceRecord{
+		TraceID: root.TraceID,
+		Service: root.Service,
+		Spans:   len(t),
+	}
+	if hasPriority {
+		p := int(priority)
+		rec.Priority = &p
+	}
+
 	if !a.Blacklister.Allows(root) {
 		log.Debugf("Trace rejected by blacklister. root: %v", root)
 		atomic.AddInt64(&ts.TracesFiltered, 1)
 		atomic.AddInt64(&ts.SpansFiltered, int64(len(t)))
+		rec.Decision, rec.Reason = info.DecisionDropped, "blacklist"
+		info.RecordTrace(rec)
+		return
+	}
+	if rule, ok := a.SpanFilter.Match(t, root); ok {
+		log.Debugf("Trace rejected by filter rule %q. root: %v", rule, root)
+		atomic.AddInt64(&ts.TracesFiltered, 1)
+		atomic.AddInt64(&ts.SpansFiltered, int64(len(t)))
+		ts.TracesFilteredByRule.Add(rule, 1)
+		rec.Decision, rec.Reason = info.DecisionDropped, "filter_rule:"+rule
+		info.RecordTrace(rec)
 		return
 	}
 
 	// Extra sanitization steps of the trace.
 	for _, span := range t {
-		a.obfuscator.Obfuscate(span)
+		if a.obfuscator.Obfuscate(span) {
+			rec.Obfuscated = true
+		}
 		Truncate(span)
 	}
 	a.Replacer.Replace(&t)
@@ -254,24 +333,36 @@ func (a *Agent) Process(t pb.Trace) {
 		pt.Env = tenv
 	}
 
+	// Sampling rules record their decision on the root span, so they are applied before
+	// the trace is shared with the goroutines below.
+	if priority >= 0 && a.RulesSampler != nil {
+		pt.rule.name, pt.rule.sampled, pt.rule.rate = a.RulesSampler.Sample(t, root, pt.Env)
+	}
+
 	go func() {
 		defer watchdog.LogOnPanic()
 		a.ServiceExtractor.Process(pt.WeightedTrace)
 	}()
 
+	// read before the samplers get a chance to modify the root span concurrently
+	ctags := root.Meta[traceutil.ContainerTagsKey]
+	rec.Resource, rec.Env = root.Resource, pt.Env
 	go func(pt ProcessedTrace) {
 		defer watchdog.LogOnPanic()
 		defer timing.Since("datadog.trace_agent.internal.concentrator_ms", time.Now())
 		// Everything is sent to concentrator for stats, regardless of sampling.
 		a.Concentrator.Add(&stats.Input{
-			Trace:     pt.WeightedTrace,
-			Sublayers: pt.Sublayers,
-			Env:       pt.Env,
+			Trace:         pt.WeightedTrace,
+			Sublayers:     pt.Sublayers,
+			Env:           pt.Env,
+			ContainerTags: ctags,
 		})
 	}(pt)
 
 	// Don't go through sampling for < 0 priority traces
 	if priority < 0 {
+		rec.Decision, rec.Reason = info.DecisionDropped, "priority"
+		info.RecordTrace(rec)
 		return
 	}
 	// Run both full trace sampling and transaction extraction in another goroutine.
@@ -281,17 +372,26 @@ func (a *Agent) Process(t pb.Trace) {
 
 		tracePkg := writer.TracePackage{}
 
-		sampled, rate := a.sample(pt)
-
-		if sampled {
+		// The samplers see all traces, even with tail-based sampling, to keep their
+		// rates and the rates by service reported to the tracers up to date.
+		sampled, rate, reason := a.sample(pt)
+		switch {
+		case a.TailSampler != nil:
+			rec.Decision, rec.Reason = info.DecisionDeferred, "tail"
+		case sampled:
 			pt.Sampled = sampled
 			sampler.AddGlobalRate(pt.Root, rate)
 			tracePkg.Trace = pt.Trace
+			rec.Decision, rec.Reason, rec.Rate = info.DecisionKept, reason, rate
+		default:
+			rec.Decision, rec.Reason, rec.Rate = info.DecisionDropped, reason, rate
 		}
 
 		// NOTE: Events can be processed on non-sampled traces.
 		events, numExtracted := a.EventProcessor.Process(pt.Root, pt.Trace)
 		tracePkg.Events = events
+		rec.Events = len(events)
+		info.RecordTrace(rec)
 
 		atomic.AddInt64(&ts.EventsExtracted, int64(numExtracted))
 		atomic.AddInt64(&ts.EventsSampled, int64(len(tracePkg.Events)))
@@ -299,24 +399,48 @@ func (a *Agent) Process(t pb.Trace) {
 		if !tracePkg.Empty() {
 			a.tracePkgChan <- &tracePkg
 		}
+		if a.TailSampler != nil {
+			// the sampling decision is deferred until the whole trace was received
+			a.TailSampler.Add(pt.Trace, sampler.HeadDecision{
+				Sampled: sampled,
+				Rate:    rate,
+				Rule:    pt.rule.name != "",
+			})
+		}
 	}(pt)
 }
 
-func (a *Agent) sample(pt ProcessedTrace) (sampled bool, rate float64) {
+// sample returns whether the trace should be kept, the rate applied and the
+// name of the sampler which took the decision.
+func (a *Agent) sample(pt ProcessedTrace) (sampled bool, rate float64, reason string) {
+	if pt.rule.name != "" {
+		// user-defined rules take precedence over all other samplers
+		return pt.rule.sampled, pt.rule.rate, "sampling_rule:" + pt.rule.name
+	}
+
 	var sampledPriority, sampledScore bool
 	var ratePriority, rateScore float64
 
-	if _, ok := pt.GetSamplingPriority(); ok {
+	_, hasPriority := pt.GetSamplingPriority()
+	if hasPriority {
 		sampledPriority, ratePriority = a.PrioritySampler.Add(pt)
 	}
 
+	scoreReason := "score"
 	if traceContainsError(pt.Trace) {
+		scoreReason = "errors"
 		sampledScore, rateScore = a.ErrorsScoreSampler.Add(pt)
 	} else {
 		sampledScore, rateScore = a.ScoreSampler.Add(pt)
 	}
 
-	return sampledScore || sampledPriority, sampler.CombineRates(ratePriority, rateScore)
+	switch {
+	case sampledPriority, !sampledScore && hasPriority:
+		reason = "priority"
+	default:
+		reason = scoreReason
+	}
+	return sampledScore || sampledPriority, sampler.CombineRates(ratePriority, rateScore), reason
 }
 
 func traceContainsError(trace pb.Trace) bool {
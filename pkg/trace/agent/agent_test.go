@@ -15,6 +15,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/event"
+	"github.com/DataDog/datadog-agent/pkg/trace/filters/expr"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/obfuscate"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
@@ -129,6 +130,89 @@ func TestProcess(t *testing.T) {
 		assert.EqualValues(2, stats.SpansFiltered)
 	})
 
+	t.Run("SpanFilter", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.FilterRules = []*config.FilterRule{
+			{Name: "health", Matcher: expr.MustCompile(`meta["http.url"] =~ "/health$"`)},
+			{Name: "fast-cache", Matcher: expr.MustCompile(`service == "cache" && duration < 1ms`), AnySpan: true},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		newSpan := func(id uint64, service, url string, duration time.Duration) *pb.Span {
+			return &pb.Span{
+				TraceID:  1,
+				SpanID:   id,
+				Service:  service,
+				Resource: "GET",
+				Meta:     map[string]string{"http.url": url},
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: duration.Nanoseconds(),
+			}
+		}
+
+		stats := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		assert := assert.New(t)
+
+		agnt.Process(pb.Trace{newSpan(1, "web", "/users", time.Second)})
+		assert.EqualValues(0, stats.TracesFiltered)
+
+		agnt.Process(pb.Trace{newSpan(1, "web", "/health", time.Second)})
+		assert.EqualValues(1, stats.TracesFiltered)
+		assert.EqualValues(1, stats.SpansFiltered)
+
+		fast := newSpan(2, "cache", "", time.Microsecond)
+		fast.ParentID = 1
+		agnt.Process(pb.Trace{newSpan(1, "web", "/users", time.Second), fast})
+		assert.EqualValues(2, stats.TracesFiltered)
+		assert.EqualValues(3, stats.SpansFiltered)
+
+		assert.Equal(map[string]int64{"health": 1, "fast-cache": 1}, stats.TracesFilteredByRule.Counts())
+
+		recent := info.RecentTraces("web")
+		if assert.NotEmpty(recent) {
+			assert.Equal(info.DecisionDropped, recent[0].Decision)
+			assert.Equal("filter_rule:fast-cache", recent[0].Reason)
+			assert.Equal(2, recent[0].Spans)
+		}
+	})
+
+	t.Run("SamplingRules", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.SamplingRules = []*config.SamplingRule{
+			{Name: "health", Rate: 0, ResourceRe: regexp.MustCompile("^GET /health$")},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		health := &pb.Span{
+			Service:  "web",
+			Resource: "GET /health",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+		}
+		other := &pb.Span{
+			Service:  "web",
+			Resource: "GET /users",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+		}
+		agnt.Process(pb.Trace{health})
+		agnt.Process(pb.Trace{other})
+
+		// the rule is recorded on the root span before Process returns
+		assert := assert.New(t)
+		assert.Equal("health", health.Meta[sampler.KeySamplingRule])
+		assert.Equal(0., health.Metrics[sampler.KeySamplingRateRule])
+		assert.NotContains(other.Meta, sampler.KeySamplingRule)
+	})
+
 	t.Run("Stats/Priority", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -302,13 +386,43 @@ func TestSampling(t *testing.T) {
 				sampler.SetSamplingPriority(pt.Root, 1)
 			}
 
-			sampled, rate := a.sample(pt)
+			sampled, rate, _ := a.sample(pt)
 			assert.EqualValues(t, tt.wantRate, rate)
 			assert.EqualValues(t, tt.wantSampled, sampled)
 		})
 	}
 }
 
+func TestSampleReason(t *testing.T) {
+	for _, tt := range []struct {
+		hasPriority, prioritySampled, hasErrors, scoreSampled bool
+		reason                                                string
+	}{
+		{scoreSampled: true, reason: "score"},
+		{scoreSampled: false, reason: "score"},
+		{hasErrors: true, reason: "errors"},
+		{hasPriority: true, prioritySampled: true, reason: "priority"},
+		{hasPriority: true, scoreSampled: true, reason: "score"},
+		{hasPriority: true, hasErrors: true, reason: "priority"},
+	} {
+		a := &Agent{
+			ScoreSampler:       newMockSampler(tt.scoreSampled, 1),
+			ErrorsScoreSampler: newMockSampler(tt.scoreSampled, 1),
+			PrioritySampler:    newMockSampler(tt.prioritySampled, 1),
+		}
+		root := &pb.Span{Service: "serv1", Metrics: map[string]float64{}}
+		if tt.hasErrors {
+			root.Error = 1
+		}
+		pt := ProcessedTrace{Trace: pb.Trace{root}, Root: root}
+		if tt.hasPriority {
+			sampler.SetSamplingPriority(pt.Root, 1)
+		}
+		_, _, reason := a.sample(pt)
+		assert.Equal(t, tt.reason, reason, "%+v", tt)
+	}
+}
+
 func TestEventProcessorFromConf(t *testing.T) {
 	if _, ok := os.LookupEnv("INTEGRATION"); !ok {
 		t.Skip("set INTEGRATION environment variable to run")
@@ -14,6 +14,16 @@ type ProcessedTrace struct {
 	Env           string
 	Sublayers     stats.SublayerMap
 	Sampled       bool
+
+	// rule holds the decision of the sampling rule which matched the trace, if any.
+	rule ruleDecision
+}
+
+// ruleDecision is the decision taken on a trace by a user-defined sampling rule.
+type ruleDecision struct {
+	name    string // empty if no rule matched
+	sampled bool
+	rate    float64
 }
 
 // Weight returns the weight at the root span.
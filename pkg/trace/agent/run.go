@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/pidfile"
+	"github.com/DataDog/datadog-agent/pkg/tagger"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/flags"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
@@ -46,6 +47,13 @@ func Run(ctx context.Context) {
 		return
 	}
 
+	if flags.DebugTraces {
+		if err := info.DebugTraces(os.Stdout, cfg, flags.Service); err != nil {
+			osutil.Exitf("failed to print recent traces: %s\n", err)
+		}
+		return
+	}
+
 	if err := setupLogger(cfg); err != nil {
 		osutil.Exitf("cannot create logger: %v", err)
 	}
@@ -90,6 +98,12 @@ func Run(ctx context.Context) {
 	}
 	metrics.Count("datadog.trace_agent.started", 1, nil, 1)
 
+	if cfg.ContainerTags || cfg.ReceiverSocket != "" {
+		// the tagger resolves the tags of the containers sending traces
+		tagger.Init()
+		defer tagger.Stop()
+	}
+
 	// Seed rand
 	rand.Seed(time.Now().UTC().UnixNano())
 
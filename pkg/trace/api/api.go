@@ -21,6 +21,7 @@ import (
 
 	"github.com/tinylib/msgp/msgp"
 
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
@@ -28,6 +29,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/osutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
@@ -58,6 +60,10 @@ const (
 	// Traces: msgpack/JSON (Content-Type) slice of traces + returns service sampling ratios
 	// Services: msgpack/JSON, map[string]map[string][string]
 	v04 Version = "v0.4"
+	// v05
+	// Traces: msgpack slice of traces with strings encoded in a dictionary (see
+	// pb.Traces.DecodeMsgDictionary) + returns service sampling ratios
+	v05 Version = "v0.5"
 )
 
 // HTTPReceiver is a collector that uses HTTP protocol and just holds
@@ -74,7 +80,8 @@ type HTTPReceiver struct {
 
 	maxRequestBodyLength int64
 	debug                bool
-	presamplerResponse   int // HTTP status code when refusing
+	presamplerResponse   int                       // HTTP status code when refusing
+	tagCardinality       collectors.TagCardinality // cardinality of the container tags
 
 	wg   sync.WaitGroup // waits for all requests to be processed
 	exit chan struct{}
@@ -101,6 +108,7 @@ func NewHTTPReceiver(
 		maxRequestBodyLength: maxRequestBodyLength,
 		debug:                strings.ToLower(conf.LogLevel) == "debug",
 		presamplerResponse:   presamplerResponse,
+		tagCardinality:       tagCardinality(conf.ContainerTagsCardinality),
 
 		exit: make(chan struct{}),
 	}
@@ -122,6 +130,7 @@ func (r *HTTPReceiver) Start() {
 	mux.HandleFunc("/v0.3/services", r.httpHandleWithVersion(v03, r.handleServices))
 	mux.HandleFunc("/v0.4/traces", r.httpHandleWithVersion(v04, r.handleTraces))
 	mux.HandleFunc("/v0.4/services", r.httpHandleWithVersion(v04, r.handleServices))
+	mux.HandleFunc("/v0.5/traces", r.httpHandleWithVersion(v05, r.handleTraces))
 
 	timeout := 5 * time.Second
 	if r.conf.ReceiverTimeout > 0 {
@@ -140,6 +149,12 @@ func (r *HTTPReceiver) Start() {
 		log.Criticalf("Error creating listener: %v", err)
 		killProcess(err.Error())
 	}
+	if path := r.conf.ReceiverSocket; path != "" {
+		// origin detection relies on SO_PEERCRED, which is only available on Linux
+		if err := r.ListenUnix(path, runtime.GOOS == "linux"); err != nil {
+			log.Errorf("Error creating unix socket listener: %v", err)
+		}
+	}
 
 	go r.PreSampler.Run()
 
@@ -178,6 +193,35 @@ func (r *HTTPReceiver) attachDebugHandlers(mux *http.ServeMux) {
 		pprof.Handler("block").ServeHTTP(w, r)
 		runtime.SetBlockProfileRate(0)
 	})
+
+	mux.HandleFunc("/debug/traces", handleDebugTraces)
+}
+
+// handleDebugTraces serves the traces recently processed by the agent as JSON, optionally
+// filtered by the "service" query string parameter. It is only available to local clients.
+func handleDebugTraces(w http.ResponseWriter, req *http.Request) {
+	if !isLocalRequest(req) {
+		http.Error(w, "only available on localhost", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(info.RecentTraces(req.URL.Query().Get("service"))); err != nil {
+		log.Errorf("Error writing /debug/traces response: %v", err)
+	}
+}
+
+// isLocalRequest returns true if req was sent from the loopback interface or a unix socket.
+func isLocalRequest(req *http.Request) bool {
+	if _, ok := pidFromRequest(req); ok {
+		return true
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		// not a TCP address, the request was received on the unix socket
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
 // Listen creates a new HTTP server listening on the provided address.
@@ -205,6 +249,24 @@ func (r *HTTPReceiver) Listen(addr, logExtra string) error {
 	return nil
 }
 
+// ListenUnix serves the HTTP API on the unix domain socket found at path. If originDetection
+// is true, traces are tagged with the tags of the container which sent them.
+func (r *HTTPReceiver) ListenUnix(path string, originDetection bool) error {
+	ln, err := newUDSListener(path, originDetection)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Listening for traces at unix://%s", path)
+
+	go func() {
+		defer watchdog.LogOnPanic()
+		r.server.Serve(ln)
+	}()
+
+	return nil
+}
+
 // Stop stops the receiver and shuts down the HTTP server.
 func (r *HTTPReceiver) Stop() error {
 	r.exit <- struct{}{}
@@ -254,7 +316,7 @@ func (r *HTTPReceiver) replyTraces(v Version, w http.ResponseWriter) {
 	case v03:
 		// Simple response, simply acknowledge with "OK"
 		httpOK(w)
-	case v04:
+	case v04, v05:
 		// Return the recommended sampling rate for each service as a JSON.
 		httpRateByService(w, r.dynConf)
 	}
@@ -277,6 +339,8 @@ func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.
 
 	r.replyTraces(v, w)
 
+	ctags := r.containerTags(req)
+
 	ts := r.Stats.GetTagStats(info.Tags{
 		Lang:          req.Header.Get("Datadog-Meta-Lang"),
 		LangVersion:   req.Header.Get("Datadog-Meta-Lang-Version"),
@@ -294,11 +358,13 @@ func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.
 			r.wg.Done()
 			watchdog.LogOnPanic()
 		}()
-		r.processTraces(ts, traces)
+		r.processTraces(ts, traces, ctags)
 	}()
 }
 
-func (r *HTTPReceiver) processTraces(ts *info.TagStats, traces pb.Traces) {
+// processTraces normalizes traces and sends them downstream. If ctags is not empty,
+// it is set as the container tags of each trace.
+func (r *HTTPReceiver) processTraces(ts *info.TagStats, traces pb.Traces, ctags string) {
 	defer timing.Since("datadog.trace_agent.internal.normalize_ms", time.Now())
 	for _, trace := range traces {
 		spans := len(trace)
@@ -318,6 +384,9 @@ func (r *HTTPReceiver) processTraces(ts *info.TagStats, traces pb.Traces) {
 			log.Errorf(msg)
 			continue
 		}
+		if ctags != "" {
+			traceutil.SetMeta(traceutil.GetRoot(trace), traceutil.ContainerTagsKey, ctags)
+		}
 
 		r.Out <- trace
 	}
@@ -510,6 +579,20 @@ func getTraces(v Version, w http.ResponseWriter, req *http.Request) (pb.Traces,
 			httpDecodingError(err, []string{tagTraceHandler, fmt.Sprintf("v:%s", v)}, w)
 			return nil, false
 		}
+	case v05:
+		if mediaType != "application/msgpack" {
+			httpFormatError(w, v, fmt.Errorf("unsupported media type: %q", mediaType))
+			return nil, false
+		}
+		payload, err := ioutil.ReadAll(req.Body)
+		if err == nil {
+			err = traces.DecodeMsgDictionary(payload)
+		}
+		if err != nil {
+			log.Errorf("Cannot decode %s traces payload: %v", v, err)
+			httpDecodingError(err, []string{tagTraceHandler, fmt.Sprintf("v:%s", v)}, w)
+			return nil, false
+		}
 	default:
 		httpEndpointNotSupported([]string{tagTraceHandler, fmt.Sprintf("v:%s", v)}, w)
 		return nil, false
@@ -494,6 +494,34 @@ func TestReceiverServiceMsgpackDecoder(t *testing.T) {
 	}
 }
 
+func TestHandleDebugTraces(t *testing.T) {
+	assert := assert.New(t)
+	info.RecordTrace(info.TraceRecord{TraceID: 1, Service: "debug-web", Decision: info.DecisionKept})
+	info.RecordTrace(info.TraceRecord{TraceID: 2, Service: "debug-db", Decision: info.DecisionDropped})
+
+	for _, addr := range []string{"10.0.0.1:1234", "[2001:db8::1]:1234"} {
+		req := httptest.NewRequest("GET", "/debug/traces", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		handleDebugTraces(rr, req)
+		assert.Equal(http.StatusForbidden, rr.Code, addr)
+	}
+
+	for _, addr := range []string{"127.0.0.1:1234", "[::1]:1234", "@", originAddr(42).String()} {
+		req := httptest.NewRequest("GET", "/debug/traces?service=debug-web", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		handleDebugTraces(rr, req)
+		assert.Equal(http.StatusOK, rr.Code, addr)
+
+		var records []info.TraceRecord
+		assert.NoError(json.NewDecoder(rr.Body).Decode(&records))
+		if assert.Len(records, 1, addr) {
+			assert.EqualValues(1, records[0].TraceID)
+		}
+	}
+}
+
 func TestHandleTraces(t *testing.T) {
 	assert := assert.New(t)
 
@@ -540,6 +568,55 @@ func TestHandleTraces(t *testing.T) {
 	assert.Equal("C#|go|java|python|ruby", receiver.Languages())
 }
 
+func TestHandleTracesV05(t *testing.T) {
+	assert := assert.New(t)
+	receiver := newTestReceiverFromConfig(newTestReceiverConfig())
+	handler := http.HandlerFunc(receiver.httpHandleWithVersion(v05, receiver.handleTraces))
+	payload := testutil.GetTestTrace(1, 1, false).AppendMsgDictionary(nil)
+
+	t.Run("msgpack", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v0.5/traces", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/msgpack")
+		handler.ServeHTTP(rr, req)
+		assert.Equal(200, rr.Code)
+
+		select {
+		case rt := <-receiver.Out:
+			assert.Len(rt, 1)
+			span := rt[0]
+			assert.Equal(uint64(42), span.TraceID)
+			assert.Equal(uint64(52), span.SpanID)
+			assert.Equal("fennel_is_amazing", span.Service)
+			assert.Equal("something_that_should_be_a_metric", span.Name)
+			assert.Equal("NOT touched because it is going to be hashed", span.Resource)
+			assert.Equal("192.168.0.1", span.Meta["http.host"])
+			assert.Equal(41.99, span.Metrics["http.monitor"])
+		case <-time.After(time.Second):
+			t.Fatalf("no data received")
+		}
+
+		var tr traceResponse
+		assert.Nil(json.Unmarshal(rr.Body.Bytes(), &tr), "the answer should be a valid JSON")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v0.5/traces", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(rr, req)
+		assert.Equal(415, rr.Code)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/v0.5/traces", bytes.NewReader(payload[:len(payload)/2]))
+		req.Header.Set("Content-Type", "application/msgpack")
+		handler.ServeHTTP(rr, req)
+		assert.Equal(400, rr.Code)
+	})
+}
+
 // chunkedReader is a reader which forces partial reads, this is required
 // to trigger some network related bugs, such as body not being read fully by server.
 // Without this, all the data could be read/written at once, not triggering the issue.
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger"
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// headerContainerID is the header tracers set with the ID of the container they run in.
+	headerContainerID = "Datadog-Container-ID"
+
+	containerIDToEntityCacheKeyPrefix = "trace_container_id_to_entity"
+	containerIDToEntityCacheDuration  = 10 * time.Minute
+	// containerIDMissCacheDuration is how long a container ID without tags isn't looked up again.
+	containerIDMissCacheDuration = 30 * time.Second
+)
+
+// containerRuntimes are the runtimes a container ID received in a header may belong to.
+var containerRuntimes = []string{
+	containers.RuntimeNameDocker,
+	containers.RuntimeNameContainerd,
+	containers.RuntimeNameCRIO,
+}
+
+// containerTagsForPID returns the tags of the container running the process
+// identified by pid; replaced in tests.
+var containerTagsForPID = func(pid int32, cardinality collectors.TagCardinality) ([]string, error) {
+	entity, err := entityForPID(pid)
+	if err != nil || entity == "" {
+		return nil, err
+	}
+	return tagger.Tag(entity, cardinality)
+}
+
+// containerTagsForID returns the tags of the container identified by id; replaced in tests.
+var containerTagsForID = func(id string, cardinality collectors.TagCardinality) ([]string, error) {
+	return tagsForContainerID(id, cardinality, tagger.Tag)
+}
+
+// tagsForContainerID returns the tags found by tag for the container identified by id.
+// The runtime of the container is unknown, so the entity of each runtime is looked up
+// until one has tags. The entity found is cached, and so is the absence of one for a
+// shorter time, so that unknown container IDs don't cost a lookup per payload.
+func tagsForContainerID(id string, cardinality collectors.TagCardinality, tag func(string, collectors.TagCardinality) ([]string, error)) ([]string, error) {
+	key := cache.BuildAgentKey(containerIDToEntityCacheKeyPrefix, id)
+	if x, found := cache.Cache.Get(key); found {
+		if x.(string) == "" {
+			return nil, nil
+		}
+		return tag(x.(string), cardinality)
+	}
+	var lastErr error
+	for _, runtime := range containerRuntimes {
+		entity := containers.BuildEntityName(runtime, id)
+		tags, err := tag(entity, cardinality)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(tags) > 0 {
+			cache.Cache.Set(key, entity, containerIDToEntityCacheDuration)
+			return tags, nil
+		}
+	}
+	cache.Cache.Set(key, "", containerIDMissCacheDuration)
+	return nil, lastErr
+}
+
+// tagCardinality converts the cardinality name found in the configuration.
+func tagCardinality(name string) collectors.TagCardinality {
+	switch name {
+	case "low":
+		return collectors.LowCardinality
+	case "high":
+		return collectors.HighCardinality
+	default:
+		return collectors.OrchestratorCardinality
+	}
+}
+
+// containerTags returns the comma-separated tags of the container which sent req,
+// or an empty string if they can not be determined. The container is identified by
+// the PID of the sender when req was received on a unix socket with origin detection,
+// which can't be spoofed, and by the Datadog-Container-ID header otherwise, if enabled.
+func (r *HTTPReceiver) containerTags(req *http.Request) string {
+	var (
+		tags []string
+		err  error
+	)
+	if pid, ok := pidFromRequest(req); ok {
+		tags, err = containerTagsForPID(pid, r.tagCardinality)
+		if err != nil {
+			log.Debugf("Unable to get container tags for PID %d: %v", pid, err)
+			return ""
+		}
+	} else if cid := req.Header.Get(headerContainerID); cid != "" && r.conf.ContainerTags {
+		tags, err = containerTagsForID(cid, r.tagCardinality)
+		if err != nil {
+			log.Debugf("Unable to get container tags for container %s: %v", cid, err)
+			return ""
+		}
+	}
+	return strings.Join(tags, ",")
+}
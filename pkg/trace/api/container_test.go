@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerTags(t *testing.T) {
+	defer func(old func(int32, collectors.TagCardinality) ([]string, error)) { containerTagsForPID = old }(containerTagsForPID)
+	defer func(old func(string, collectors.TagCardinality) ([]string, error)) { containerTagsForID = old }(containerTagsForID)
+	var gotCardinality collectors.TagCardinality
+	containerTagsForPID = func(pid int32, cardinality collectors.TagCardinality) ([]string, error) {
+		gotCardinality = cardinality
+		return []string{"source:pid"}, nil
+	}
+	containerTagsForID = func(id string, cardinality collectors.TagCardinality) ([]string, error) {
+		gotCardinality = cardinality
+		return []string{"container_id:" + id, "image_tag:1.0"}, nil
+	}
+
+	conf := newTestReceiverConfig()
+	conf.ContainerTags = true
+	conf.ContainerTagsCardinality = "high"
+	r := newTestReceiverFromConfig(conf)
+
+	t.Run("none", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/v0.4/traces", nil)
+		assert.Equal(t, "", r.containerTags(req))
+	})
+
+	t.Run("header", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/v0.4/traces", nil)
+		req.Header.Set("Datadog-Container-ID", "abc123")
+		assert.Equal(t, "container_id:abc123,image_tag:1.0", r.containerTags(req))
+		assert.Equal(t, collectors.HighCardinality, gotCardinality)
+	})
+
+	t.Run("header-disabled", func(t *testing.T) {
+		conf := newTestReceiverConfig()
+		conf.ContainerTags = false
+		r := newTestReceiverFromConfig(conf)
+		req, _ := http.NewRequest("POST", "/v0.4/traces", nil)
+		req.Header.Set("Datadog-Container-ID", "abc123")
+		assert.Equal(t, "", r.containerTags(req))
+	})
+
+	t.Run("pid-over-header", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/v0.4/traces", nil)
+		req.Header.Set("Datadog-Container-ID", "abc123")
+		req.RemoteAddr = originAddr(42).String()
+		assert.Equal(t, "source:pid", r.containerTags(req))
+	})
+}
+
+func TestTagsForContainerID(t *testing.T) {
+	assert := assert.New(t)
+	var looked []string
+	tag := func(entity string, cardinality collectors.TagCardinality) ([]string, error) {
+		looked = append(looked, entity)
+		if entity == "containerd://4f2a" {
+			return []string{"image_tag:1.0"}, nil
+		}
+		return nil, nil
+	}
+
+	tags, err := tagsForContainerID("4f2a", collectors.LowCardinality, tag)
+	assert.NoError(err)
+	assert.Equal([]string{"image_tag:1.0"}, tags)
+	assert.Equal([]string{"docker://4f2a", "containerd://4f2a"}, looked)
+
+	// the entity found is reused
+	looked = nil
+	tags, err = tagsForContainerID("4f2a", collectors.LowCardinality, tag)
+	assert.NoError(err)
+	assert.Equal([]string{"image_tag:1.0"}, tags)
+	assert.Equal([]string{"containerd://4f2a"}, looked)
+
+	looked = nil
+	tags, err = tagsForContainerID("unknown", collectors.LowCardinality, tag)
+	assert.NoError(err)
+	assert.Empty(tags)
+	assert.Equal([]string{"docker://unknown", "containerd://unknown", "cri-o://unknown"}, looked)
+
+	// the absence of an entity is cached too
+	looked = nil
+	tags, err = tagsForContainerID("unknown", collectors.LowCardinality, tag)
+	assert.NoError(err)
+	assert.Empty(tags)
+	assert.Empty(looked)
+}
+
+func TestTagCardinality(t *testing.T) {
+	assert.Equal(t, collectors.LowCardinality, tagCardinality("low"))
+	assert.Equal(t, collectors.OrchestratorCardinality, tagCardinality("orchestrator"))
+	assert.Equal(t, collectors.HighCardinality, tagCardinality("high"))
+	assert.Equal(t, collectors.OrchestratorCardinality, tagCardinality(""))
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// originAddrPrefix prefixes the remote address of connections accepted on the
+// unix socket for which the PID of the peer process could be obtained.
+const originAddrPrefix = "pid:"
+
+// udsListener wraps a unix socket listener. When origin detection is enabled,
+// every accepted connection reports the PID of the peer process as its remote
+// address, allowing HTTP handlers to identify the sender of a request.
+type udsListener struct {
+	*net.UnixListener
+
+	originDetection bool
+}
+
+// newUDSListener creates a unix socket listener at path. A stale socket left over
+// at that path is removed, but any other type of file will cause an error.
+func newUDSListener(path string, originDetection bool) (*udsListener, error) {
+	fileInfo, err := os.Stat(path)
+	if err == nil {
+		// socket file already exists
+		if fileInfo.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("cannot reuse %s socket path: path already exists and is not a UNIX socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("cannot remove stale UNIX socket: %v", err)
+		}
+	}
+	addr, err := net.ResolveUnixAddr("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve unix address %s: %v", path, err)
+	}
+	ln, err := net.ListenUnix("unix", addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %v", path, err)
+	}
+	if err := os.Chmod(path, 0722); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("cannot set permissions on socket %s: %v", path, err)
+	}
+	return &udsListener{
+		UnixListener:    ln,
+		originDetection: originDetection,
+	}, nil
+}
+
+// Accept implements net.Listener.
+func (l *udsListener) Accept() (net.Conn, error) {
+	conn, err := l.AcceptUnix()
+	if err != nil {
+		return nil, err
+	}
+	if !l.originDetection {
+		return conn, nil
+	}
+	pid, err := getPeerPID(conn)
+	if err != nil {
+		log.Debugf("Unable to obtain peer credentials on unix socket, traces will not be tagged: %v", err)
+		metrics.Count("datadog.trace_agent.receiver.origin_detection_error", 1, nil, 1)
+		return conn, nil
+	}
+	return &udsConn{UnixConn: conn, pid: pid}, nil
+}
+
+// udsConn is a unix socket connection which knows the PID of its peer process.
+type udsConn struct {
+	*net.UnixConn
+	pid int32
+}
+
+// RemoteAddr implements net.Conn.
+func (c *udsConn) RemoteAddr() net.Addr { return originAddr(c.pid) }
+
+// originAddr is the net.Addr of a peer process connected via unix socket.
+type originAddr int32
+
+// Network implements net.Addr.
+func (a originAddr) Network() string { return "unix" }
+
+// String implements net.Addr.
+func (a originAddr) String() string { return originAddrPrefix + strconv.Itoa(int(a)) }
+
+// pidFromRequest returns the PID of the process which sent req, if it was
+// received on a unix socket with origin detection.
+func pidFromRequest(req *http.Request) (int32, bool) {
+	if !strings.HasPrefix(req.RemoteAddr, originAddrPrefix) {
+		return 0, false
+	}
+	pid, err := strconv.ParseInt(req.RemoteAddr[len(originAddrPrefix):], 10, 32)
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return int32(pid), true
+}
@@ -0,0 +1,72 @@
+// +build linux
+
+package api
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/containers"
+)
+
+const (
+	pidToEntityCacheKeyPrefix = "trace_pid_to_entity"
+	pidToEntityCacheDuration  = time.Minute
+)
+
+// errPIDOutOfNamespace is returned when the peer process lives in another PID namespace.
+var errPIDOutOfNamespace = errors.New("peer PID is 0, it probably belongs to another namespace; is the agent in host PID mode?")
+
+// getPeerPID returns the PID of the process at the other end of conn, as
+// reported by the kernel through SO_PEERCRED.
+func getPeerPID(conn *net.UnixConn) (int32, error) {
+	rawconn, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		cred    *unix.Ucred
+		credErr error
+	)
+	err = rawconn.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if credErr != nil {
+		return 0, credErr
+	}
+	if cred.Pid == 0 {
+		return 0, errPIDOutOfNamespace
+	}
+	return cred.Pid, nil
+}
+
+// entityForPID returns the container entity name of the process identified by pid,
+// or an empty string if it is not running in a container. Results are cached.
+func entityForPID(pid int32) (string, error) {
+	key := cache.BuildAgentKey(pidToEntityCacheKeyPrefix, strconv.Itoa(int(pid)))
+	if x, found := cache.Cache.Get(key); found {
+		return x.(string), nil
+	}
+
+	entity, err := containers.EntityForPID(pid)
+	switch err {
+	case nil:
+		cache.Cache.Set(key, entity, pidToEntityCacheDuration)
+		return entity, nil
+	case containers.ErrNoRuntimeMatch, containers.ErrNoContainerMatch:
+		// not a container, cache the empty result
+		cache.Cache.Set(key, "", pidToEntityCacheDuration)
+		return "", nil
+	default:
+		// other lookup error, retry next time
+		return "", err
+	}
+}
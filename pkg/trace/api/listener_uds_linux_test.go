@@ -0,0 +1,68 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/tagger/collectors"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/test/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestReceiverUDSOriginDetection(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "dd-test-")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "apm.socket")
+
+	defer func(old func(int32, collectors.TagCardinality) ([]string, error)) { containerTagsForPID = old }(containerTagsForPID)
+	var gotPID int32
+	containerTagsForPID = func(pid int32, _ collectors.TagCardinality) ([]string, error) {
+		gotPID = pid
+		return []string{"kube_deployment:web", "image_tag:1.0"}, nil
+	}
+
+	conf := newTestReceiverConfig()
+	conf.ReceiverSocket = path
+	receiver := newTestReceiverFromConfig(conf)
+	receiver.Start()
+	defer receiver.Stop()
+
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", path)
+			},
+		},
+	}
+	var buf bytes.Buffer
+	assert.NoError(msgp.Encode(&buf, pb.Traces{testutil.GetTestTrace(1, 3, true)[0]}))
+	req, err := http.NewRequest("POST", "http://unix/v0.4/traces", &buf)
+	assert.NoError(err)
+	req.Header.Set("Content-Type", "application/msgpack")
+	resp, err := client.Do(req)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	select {
+	case trace := <-receiver.Out:
+		root := traceutil.GetRoot(trace)
+		assert.Equal("kube_deployment:web,image_tag:1.0", root.Meta[traceutil.ContainerTagsKey])
+		assert.EqualValues(os.Getpid(), gotPID)
+	case <-time.After(time.Second):
+		t.Fatal("no trace received")
+	}
+}
@@ -0,0 +1,21 @@
+// +build !linux
+
+package api
+
+import (
+	"errors"
+	"net"
+)
+
+// errLinuxOnly is returned by origin detection on non-linux platforms.
+var errLinuxOnly = errors.New("only implemented on Linux hosts")
+
+// getPeerPID returns a "not implemented" error on non-linux hosts.
+func getPeerPID(conn *net.UnixConn) (int32, error) {
+	return 0, errLinuxOnly
+}
+
+// entityForPID returns a "not implemented" error on non-linux hosts.
+func entityForPID(pid int32) (string, error) {
+	return "", errLinuxOnly
+}
@@ -0,0 +1,73 @@
+// +build !windows
+
+package api
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUDSListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dd-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "apm.socket")
+
+	t.Run("file-exists", func(t *testing.T) {
+		f, err := os.Create(path)
+		assert.NoError(t, err)
+		f.Close()
+		defer os.Remove(path)
+
+		_, err = newUDSListener(path, false)
+		assert.Error(t, err)
+	})
+
+	t.Run("stale-socket", func(t *testing.T) {
+		addr, err := net.ResolveUnixAddr("unix", path)
+		assert.NoError(t, err)
+		stale, err := net.ListenUnix("unix", addr)
+		assert.NoError(t, err)
+		stale.SetUnlinkOnClose(false)
+		stale.Close()
+
+		ln, err := newUDSListener(path, false)
+		assert.NoError(t, err)
+		ln.Close()
+	})
+
+	t.Run("permissions", func(t *testing.T) {
+		ln, err := newUDSListener(path, false)
+		assert.NoError(t, err)
+		defer ln.Close()
+
+		fi, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "Srwx-w--w-", fi.Mode().String())
+	})
+}
+
+func TestPIDFromRequest(t *testing.T) {
+	for _, tt := range []struct {
+		addr string
+		pid  int32
+		ok   bool
+	}{
+		{addr: "127.0.0.1:1234"},
+		{addr: "@"},
+		{addr: ""},
+		{addr: "pid:abc"},
+		{addr: "pid:0"},
+		{addr: originAddr(42).String(), pid: 42, ok: true},
+	} {
+		pid, ok := pidFromRequest(&http.Request{RemoteAddr: tt.addr})
+		assert.Equal(t, tt.ok, ok, tt.addr)
+		assert.Equal(t, tt.pid, pid, tt.addr)
+	}
+}
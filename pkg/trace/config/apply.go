@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/filters/expr"
 	"github.com/DataDog/datadog-agent/pkg/trace/osutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/writer/backoff"
 	writerconfig "github.com/DataDog/datadog-agent/pkg/trace/writer/config"
@@ -43,6 +44,41 @@ type ObfuscationConfig struct {
 	// Memcached holds the configuration for obfuscating the "memcached.command" tag
 	// for spans of type "memcached".
 	Memcached Enablable `mapstructure:"memcached"`
+
+	// GraphQL holds the configuration for obfuscating the "graphql.query" tag and
+	// the resource of spans of type "graphql".
+	GraphQL Enablable `mapstructure:"graphql"`
+
+	// TagRules holds rules obfuscating the values of arbitrary tags, for any span type.
+	TagRules []*TagObfuscationRule `mapstructure:"tag_rules"`
+}
+
+// TagObfuscationRule specifies how to obfuscate the tags matching it.
+type TagObfuscationRule struct {
+	// Tag is a glob matching the keys of the tags to obfuscate, where "*" matches
+	// any sequence of characters (e.g. "kafka.*" or "user.email").
+	Tag string `mapstructure:"tag"`
+
+	// Type and Service are globs restricting the rule to the spans of the given type
+	// and service. They match any span when empty.
+	Type    string `mapstructure:"type"`
+	Service string `mapstructure:"service"`
+
+	// Action specifies what to do with matching tags: "redact" (the default) replaces
+	// the value, or the parts of it matched by Pattern, with Repl; "drop" removes the
+	// tag; "hash" replaces the value by its hash; "json" obfuscates the values in the
+	// JSON document it holds, except those of KeepValues.
+	Action string `mapstructure:"action"`
+
+	// Pattern is the regular expression matching the parts of the value to redact.
+	// The whole value is redacted when empty.
+	Pattern string `mapstructure:"pattern"`
+
+	// Repl is the replacement of redacted values. It defaults to "?".
+	Repl string `mapstructure:"repl"`
+
+	// KeepValues specifies the JSON keys whose values are kept by the "json" action.
+	KeepValues []string `mapstructure:"keep_values"`
 }
 
 // HTTPObfuscationConfig holds the configuration settings for HTTP obfuscation.
@@ -70,6 +106,50 @@ type JSONObfuscationConfig struct {
 	KeepValues []string `mapstructure:"keep_values"`
 }
 
+// TailSamplingConfig holds the configuration of the tail-based sampler, which buffers
+// the spans of a trace until it is complete before deciding whether to keep it.
+type TailSamplingConfig struct {
+	// Enabled specifies whether the per-payload sampling decisions are deferred to the tail-based sampler.
+	Enabled bool `mapstructure:"enabled"`
+
+	// DecisionWait specifies the maximum time (in seconds) to wait for the spans
+	// of a trace before taking a sampling decision.
+	DecisionWait float64 `mapstructure:"decision_wait_seconds"`
+
+	// MaxSpans is the maximum number of spans held in the buffer. When it is reached,
+	// a decision is taken for the oldest traces to free up memory.
+	MaxSpans int `mapstructure:"max_buffered_spans"`
+
+	// KeepErrors keeps all traces containing at least one error.
+	KeepErrors bool `mapstructure:"keep_errors"`
+
+	// Durations keeps traces whose root span duration is above the threshold
+	// configured for its service.
+	Durations []*DurationThreshold `mapstructure:"duration_thresholds"`
+
+	// Tags keeps traces containing a span with any of the given tag values.
+	Tags []*TagValue `mapstructure:"tags"`
+
+	// BaselineTPS is the maximum number of traces per second kept out of those
+	// which are not matched by any other policy. They are all dropped if it is 0.
+	BaselineTPS float64 `mapstructure:"baseline_traces_per_second"`
+}
+
+// DurationThreshold specifies a root span duration threshold for a service.
+type DurationThreshold struct {
+	// Service specifies the service of the root span.
+	Service string `mapstructure:"service"`
+
+	// Threshold specifies the duration of the root span, in milliseconds.
+	Threshold float64 `mapstructure:"threshold_ms"`
+}
+
+// TagValue specifies a tag key and value pair.
+type TagValue struct {
+	Key   string `mapstructure:"key"`
+	Value string `mapstructure:"value"`
+}
+
 // ReplaceRule specifies a replace rule.
 type ReplaceRule struct {
 	// Name specifies the name of the tag that the replace rule addresses. However,
@@ -88,6 +168,59 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// FilterRule specifies a rule dropping the traces matched by a filter expression.
+type FilterRule struct {
+	// Name identifies the rule in the receiver stats. It defaults to its position in the list.
+	Name string `mapstructure:"name"`
+
+	// Expr specifies the boolean expression evaluated against the spans of the trace,
+	// e.g. `meta["http.url"] =~ "/health$" || duration < 1ms`.
+	Expr string `mapstructure:"expression"`
+
+	// Scope specifies which spans the expression is evaluated against: "root" (the default)
+	// matches the root span only and "any" matches traces containing any matching span.
+	Scope string `mapstructure:"scope"`
+
+	// Matcher holds the compiled Expr and AnySpan is true if the Scope is "any". They
+	// are only used internally.
+	Matcher expr.Node `mapstructure:"-"`
+	AnySpan bool      `mapstructure:"-"`
+}
+
+// SamplingRule specifies a sampling rule applied to traces whose root span matches it.
+// Patterns are globs, where "*" matches any sequence of characters and "?" matches a
+// single one, unless enclosed in slashes, in which case they are regular expressions
+// (e.g. "/^GET /api/.*$/"). Empty patterns match everything.
+type SamplingRule struct {
+	// Name identifies the rule in the agent's status. It defaults to its position in the list.
+	Name string `mapstructure:"name"`
+
+	// Service, Operation, Resource and Env are the patterns matching the root span's
+	// service, operation name, resource and the trace's environment.
+	Service   string `mapstructure:"service"`
+	Operation string `mapstructure:"operation"`
+	Resource  string `mapstructure:"resource"`
+	Env       string `mapstructure:"env"`
+
+	// Tags maps tag keys to the patterns their value must match on the root span.
+	Tags map[string]string `mapstructure:"tags"`
+
+	// SampleRate is the rate at which matching traces are kept. It defaults to 1.
+	SampleRate *float64 `mapstructure:"sample_rate"`
+
+	// MaxTPS is the maximum number of matching traces kept per second. Zero means no limit.
+	MaxTPS float64 `mapstructure:"max_traces_per_second"`
+
+	// Rate, ServiceRe, OperationRe, ResourceRe, EnvRe and TagsRe hold the compiled rule
+	// and are only used internally. A nil regular expression matches everything.
+	Rate        float64                   `mapstructure:"-"`
+	ServiceRe   *regexp.Regexp            `mapstructure:"-"`
+	OperationRe *regexp.Regexp            `mapstructure:"-"`
+	ResourceRe  *regexp.Regexp            `mapstructure:"-"`
+	EnvRe       *regexp.Regexp            `mapstructure:"-"`
+	TagsRe      map[string]*regexp.Regexp `mapstructure:"-"`
+}
+
 type traceWriter struct {
 	FlushPeriod            float64                `mapstructure:"flush_period_seconds"`
 	UpdateInfoPeriod       int                    `mapstructure:"update_info_period_seconds"`
@@ -189,6 +322,23 @@ func (c *AgentConfig) applyDatadogConfig() error {
 	if config.Datadog.IsSet("apm_config.receiver_port") {
 		c.ReceiverPort = config.Datadog.GetInt("apm_config.receiver_port")
 	}
+	if config.Datadog.IsSet("apm_config.receiver_socket") {
+		c.ReceiverSocket = config.Datadog.GetString("apm_config.receiver_socket")
+	}
+	if config.Datadog.IsSet("apm_config.container_tags") {
+		c.ContainerTags = config.Datadog.GetBool("apm_config.container_tags")
+	}
+	if config.Datadog.IsSet("apm_config.container_tags_cardinality") {
+		switch v := strings.ToLower(config.Datadog.GetString("apm_config.container_tags_cardinality")); v {
+		case "low", "orchestrator", "high":
+			c.ContainerTagsCardinality = v
+		default:
+			log.Errorf("Invalid container tags cardinality %q, using %q: must be one of low, orchestrator or high", v, c.ContainerTagsCardinality)
+		}
+	}
+	if config.Datadog.IsSet("apm_config.container_tags_aggregators") {
+		c.ContainerTagsAggregators = config.Datadog.GetStringSlice("apm_config.container_tags_aggregators")
+	}
 	if config.Datadog.IsSet("apm_config.connection_limit") {
 		c.ConnectionLimit = config.Datadog.GetInt("apm_config.connection_limit")
 	}
@@ -217,6 +367,32 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	if config.Datadog.IsSet("apm_config.filter_rules") {
+		rules := make([]*FilterRule, 0)
+		err := config.Datadog.UnmarshalKey("apm_config.filter_rules", &rules)
+		if err == nil {
+			if err := compileFilterRules(rules); err != nil {
+				osutil.Exitf("filter_rules: %s", err)
+			}
+			c.FilterRules = rules
+		} else {
+			log.Errorf("Error reading filter rules: %v", err)
+		}
+	}
+
+	if config.Datadog.IsSet("apm_config.sampling_rules") {
+		rules := make([]*SamplingRule, 0)
+		err := config.Datadog.UnmarshalKey("apm_config.sampling_rules", &rules)
+		if err == nil {
+			if err := compileSamplingRules(rules); err != nil {
+				osutil.Exitf("sampling_rules: %s", err)
+			}
+			c.SamplingRules = rules
+		} else {
+			log.Errorf("Error reading sampling rules: %v", err)
+		}
+	}
+
 	if config.Datadog.IsSet("bind_host") {
 		host := config.Datadog.GetString("bind_host")
 		c.StatsdHost = host
@@ -239,6 +415,15 @@ func (c *AgentConfig) applyDatadogConfig() error {
 		}
 	}
 
+	if config.Datadog.IsSet("apm_config.tail_sampling") {
+		ts := *c.TailSampling
+		if err := config.Datadog.UnmarshalKey("apm_config.tail_sampling", &ts); err != nil {
+			log.Errorf("Error reading tail sampling configuration: %v", err)
+		} else {
+			c.TailSampling = &ts
+		}
+	}
+
 	// undocumented
 	if config.Datadog.IsSet("apm_config.max_cpu_percent") {
 		c.MaxCPU = config.Datadog.GetFloat64("apm_config.max_cpu_percent") / 100
@@ -461,6 +646,84 @@ func compileReplaceRules(rules []*ReplaceRule) error {
 	return nil
 }
 
+// compileFilterRules validates the filter rules and compiles their expressions.
+// If it fails it returns the first error.
+func compileFilterRules(rules []*FilterRule) error {
+	for i, r := range rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("filter_%d", i)
+		}
+		switch r.Scope {
+		case "", "root":
+		case "any":
+			r.AnySpan = true
+		default:
+			return fmt.Errorf("rule %q: unknown scope %q", r.Name, r.Scope)
+		}
+		var err error
+		if r.Matcher, err = expr.Compile(r.Expr); err != nil {
+			return fmt.Errorf("rule %q: %s", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// compileSamplingRules validates the sampling rules and compiles their patterns.
+// If it fails it returns the first error.
+func compileSamplingRules(rules []*SamplingRule) error {
+	for i, r := range rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule_%d", i)
+		}
+		r.Rate = 1
+		if r.SampleRate != nil {
+			r.Rate = *r.SampleRate
+		}
+		if r.Rate < 0 || r.Rate > 1 {
+			return fmt.Errorf("rule %q: sample_rate must be between 0 and 1", r.Name)
+		}
+		if r.MaxTPS < 0 {
+			return fmt.Errorf("rule %q: max_traces_per_second must not be negative", r.Name)
+		}
+		var err error
+		for _, p := range []struct {
+			pattern string
+			re      **regexp.Regexp
+		}{
+			{r.Service, &r.ServiceRe},
+			{r.Operation, &r.OperationRe},
+			{r.Resource, &r.ResourceRe},
+			{r.Env, &r.EnvRe},
+		} {
+			if *p.re, err = compilePattern(p.pattern); err != nil {
+				return fmt.Errorf("rule %q: %s", r.Name, err)
+			}
+		}
+		r.TagsRe = make(map[string]*regexp.Regexp, len(r.Tags))
+		for k, v := range r.Tags {
+			if r.TagsRe[k], err = compilePattern(v); err != nil {
+				return fmt.Errorf("rule %q, tag %q: %s", r.Name, k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// compilePattern compiles a sampling rule pattern. Patterns enclosed in slashes are
+// regular expressions, others are globs. An empty pattern returns a nil expression.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return regexp.Compile(pattern[1 : len(pattern)-1])
+	}
+	glob := regexp.QuoteMeta(pattern)
+	glob = strings.Replace(glob, `\*`, ".*", -1)
+	glob = strings.Replace(glob, `\?`, ".", -1)
+	return regexp.Compile("^" + glob + "$")
+}
+
 // getDuration returns the duration of the provided value in seconds
 func getDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
@@ -3,6 +3,7 @@ package config
 import (
 	"testing"
 
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,3 +23,62 @@ func TestParseRepaceRules(t *testing.T) {
 		assert.Equal(r.Pattern, r.Re.String())
 	}
 }
+
+// TestCompileFilterRules tests the compileFilterRules helper function.
+func TestCompileFilterRules(t *testing.T) {
+	assert := assert.New(t)
+
+	rules := []*FilterRule{
+		{Name: "health", Expr: `resource == "GET /health"`},
+		{Expr: `error == 1`, Scope: "any"},
+	}
+	assert.NoError(compileFilterRules(rules))
+
+	assert.Equal("health", rules[0].Name)
+	assert.True(rules[0].Matcher.Eval(&pb.Span{Resource: "GET /health"}))
+	assert.False(rules[0].AnySpan)
+	assert.Equal("filter_1", rules[1].Name)
+	assert.True(rules[1].Matcher.Eval(&pb.Span{Error: 1}))
+	assert.True(rules[1].AnySpan)
+
+	for name, rule := range map[string]*FilterRule{
+		"expression": {Expr: `service ==`},
+		"scope":      {Expr: `service == "web"`, Scope: "child"},
+	} {
+		assert.Error(compileFilterRules([]*FilterRule{rule}), name)
+	}
+}
+
+// TestCompileSamplingRules tests the compileSamplingRules helper function.
+func TestCompileSamplingRules(t *testing.T) {
+	assert := assert.New(t)
+
+	rate := 0.2
+	rules := []*SamplingRule{
+		{Service: "web-?", Operation: "http.*", SampleRate: &rate},
+		{Name: "checkout", Resource: "/^POST /(cart|checkout)$/", Tags: map[string]string{"region": "eu-*"}},
+	}
+	assert.NoError(compileSamplingRules(rules))
+
+	assert.Equal("rule_0", rules[0].Name)
+	assert.Equal(0.2, rules[0].Rate)
+	assert.True(rules[0].ServiceRe.MatchString("web-1"))
+	assert.False(rules[0].ServiceRe.MatchString("web-12"))
+	assert.True(rules[0].OperationRe.MatchString("http.request"))
+	assert.False(rules[0].OperationRe.MatchString("httpXrequest"))
+	assert.Nil(rules[0].ResourceRe)
+
+	assert.Equal("checkout", rules[1].Name)
+	assert.Equal(1.0, rules[1].Rate)
+	assert.True(rules[1].ResourceRe.MatchString("POST /checkout"))
+	assert.False(rules[1].ResourceRe.MatchString("GET /checkout"))
+	assert.True(rules[1].TagsRe["region"].MatchString("eu-west-1"))
+
+	for name, rule := range map[string]*SamplingRule{
+		"rate":  {SampleRate: func() *float64 { r := 1.5; return &r }()},
+		"tps":   {MaxTPS: -1},
+		"regex": {Service: "/web(/"},
+	} {
+		assert.Error(compileSamplingRules([]*SamplingRule{rule}), name)
+	}
+}
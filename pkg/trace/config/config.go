@@ -59,15 +59,27 @@ type AgentConfig struct {
 	BucketInterval   time.Duration // the size of our pre-aggregation per bucket
 	ExtraAggregators []string
 
+	// Container tags
+	ContainerTags            bool     // whether traces are tagged with the tags of the container found in their Datadog-Container-ID header
+	ContainerTagsCardinality string   // cardinality of the container tags attached to traces: "low", "orchestrator" or "high"
+	ContainerTagsAggregators []string // names of the container tags used as extra stats aggregation dimensions
+
 	// Sampler configuration
 	ExtraSampleRate float64
 	MaxTPS          float64
 	MaxEPS          float64
 
+	// SamplingRules are applied in order to the traces, before any other sampler.
+	SamplingRules []*SamplingRule
+
+	// TailSampling holds the tail-based sampler's configuration.
+	TailSampling *TailSamplingConfig
+
 	// Receiver
 	ReceiverHost    string
 	ReceiverPort    int
-	ConnectionLimit int // for rate-limiting, how many unique connections to allow in a lease period (30s)
+	ReceiverSocket  string // if set, the receiver also serves its API on this unix domain socket
+	ConnectionLimit int    // for rate-limiting, how many unique connections to allow in a lease period (30s)
 	ReceiverTimeout int
 
 	// Writers
@@ -96,6 +108,9 @@ type AgentConfig struct {
 	// filtering
 	Ignore map[string][]string
 
+	// FilterRules are used to drop the traces matching any of their expressions.
+	FilterRules []*FilterRule
+
 	// ReplaceTags is used to filter out sensitive information from tag values.
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
@@ -121,10 +136,19 @@ func New() *AgentConfig {
 		BucketInterval:   time.Duration(10) * time.Second,
 		ExtraAggregators: []string{"http.status_code"},
 
+		ContainerTagsCardinality: "orchestrator",
+
 		ExtraSampleRate: 1.0,
 		MaxTPS:          10,
 		MaxEPS:          200,
 
+		TailSampling: &TailSamplingConfig{
+			DecisionWait: 10,
+			MaxSpans:     100000,
+			KeepErrors:   true,
+			BaselineTPS:  10,
+		},
+
 		ReceiverHost:    "localhost",
 		ReceiverPort:    8126,
 		ConnectionLimit: 2000,
@@ -111,6 +111,7 @@ func TestDefaultConfig(t *testing.T) {
 
 	assert.Equal("INFO", c.LogLevel)
 	assert.Equal(true, c.Enabled)
+	assert.False(c.ContainerTags)
 
 }
 
@@ -268,9 +269,45 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.Equal("test", c.DefaultEnv)
 	assert.Equal(123, c.ConnectionLimit)
 	assert.Equal(18126, c.ReceiverPort)
+	assert.Equal("/var/run/datadog/apm.socket", c.ReceiverSocket)
+	assert.True(c.ContainerTags)
+	assert.Equal("high", c.ContainerTagsCardinality)
+	assert.Equal([]string{"kube_deployment", "image_tag"}, c.ContainerTagsAggregators)
 	assert.Equal(0.5, c.ExtraSampleRate)
 	assert.Equal(5.0, c.MaxTPS)
 	assert.Equal(50.0, c.MaxEPS)
+	if assert.Len(c.FilterRules, 2) {
+		health, cache := c.FilterRules[0], c.FilterRules[1]
+		assert.Equal("health-checks", health.Name)
+		assert.Equal(`meta["http.url"] =~ "/health$" || meta["http.user_agent"] =~ "^kube-probe/"`, health.Expr)
+		assert.NotNil(health.Matcher)
+		assert.False(health.AnySpan)
+		assert.Equal("filter_1", cache.Name)
+		assert.NotNil(cache.Matcher)
+		assert.True(cache.AnySpan)
+	}
+	if assert.Len(c.SamplingRules, 2) {
+		health, web := c.SamplingRules[0], c.SamplingRules[1]
+		assert.Equal("health-checks", health.Name)
+		assert.Equal(0.0, health.Rate)
+		assert.Equal("^GET /health.*$", health.ResourceRe.String())
+		assert.Nil(health.ServiceRe)
+		assert.Equal("rule_1", web.Name)
+		assert.Equal(1.0, web.Rate)
+		assert.Equal(50.0, web.MaxTPS)
+		assert.Equal("^web$", web.ServiceRe.String())
+		assert.Equal("^(prod|staging)$", web.EnvRe.String())
+		assert.Equal("^acme$", web.TagsRe["customer"].String())
+	}
+	assert.Equal(&TailSamplingConfig{
+		Enabled:      true,
+		DecisionWait: 5,
+		MaxSpans:     5000,
+		KeepErrors:   false,
+		Durations:    []*DurationThreshold{{Service: "web", Threshold: 500}},
+		Tags:         []*TagValue{{Key: "http.status_code", Value: "429"}},
+		BaselineTPS:  2,
+	}, c.TailSampling)
 	assert.Equal(0.5, c.MaxCPU)
 	assert.EqualValues(123.4, c.MaxMemory)
 	assert.Equal("0.0.0.0", c.ReceiverHost)
@@ -325,6 +362,12 @@ func TestFullYamlConfig(t *testing.T) {
 	assert.True(o.RemoveStackTraces)
 	assert.True(c.Obfuscation.Redis.Enabled)
 	assert.True(c.Obfuscation.Memcached.Enabled)
+	assert.True(c.Obfuscation.GraphQL.Enabled)
+	assert.Equal([]*TagObfuscationRule{
+		{Tag: "user.email", Pattern: "^[^@]+", Repl: "***"},
+		{Tag: "kafka.*", Type: "queue", Action: "hash"},
+		{Tag: "graphql.variables", Action: "json", KeepValues: []string{"first"}},
+	}, c.Obfuscation.TagRules)
 }
 
 func TestUndocumentedYamlConfig(t *testing.T) {
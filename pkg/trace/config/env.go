@@ -34,6 +34,7 @@ func loadEnv() {
 		{"DD_APM_DD_URL", "apm_config.apm_dd_url"},
 		{"DD_RECEIVER_PORT", "apm_config.receiver_port"}, // deprecated
 		{"DD_APM_RECEIVER_PORT", "apm_config.receiver_port"},
+		{"DD_APM_RECEIVER_SOCKET", "apm_config.receiver_socket"},
 		{"DD_MAX_EPS", "apm_config.max_events_per_second"}, // deprecated
 		{"DD_APM_MAX_EPS", "apm_config.max_events_per_second"},
 		{"DD_MAX_TPS", "apm_config.max_traces_per_second"}, // deprecated
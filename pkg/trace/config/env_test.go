@@ -149,6 +149,17 @@ func TestLoadEnv(t *testing.T) {
 				})
 			}
 
+			env = "DD_APM_RECEIVER_SOCKET"
+			t.Run(env, func(t *testing.T) {
+				assert := assert.New(t)
+				err := os.Setenv(env, "/tmp/apm.sock")
+				assert.NoError(err)
+				defer os.Unsetenv(env)
+				cfg, err := Load("./testdata/full." + ext)
+				assert.NoError(err)
+				assert.Equal("/tmp/apm.sock", cfg.ReceiverSocket)
+			})
+
 			env = "DD_DOGSTATSD_PORT"
 			t.Run(env, func(t *testing.T) {
 				assert := assert.New(t)
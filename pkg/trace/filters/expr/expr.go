@@ -0,0 +1,413 @@
+// Package expr implements the expressions matching spans used by the trace filter rules.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// Node is a compiled filter expression.
+type Node interface {
+	// Eval returns true if the span matches the expression.
+	Eval(s *pb.Span) bool
+}
+
+// Compile compiles a filter expression. An expression is made of comparisons
+// between a span field and a literal value, combined with the "&&", "||" and "!"
+// operators and grouped using parentheses, e.g.:
+//
+//	service == "web" && (meta["http.url"] =~ "/health" || duration < 5ms)
+//
+// String fields are service, name, resource, type and meta["key"]. They support
+// the "==", "!=", "=~" and "!~" operators, the last two taking a regular expression.
+// Numeric fields are duration, start, error and metrics["key"]. They support the
+// "==", "!=", "<", "<=", ">" and ">=" operators. Numbers may have a duration unit
+// (ns, us, ms, s, m or h), in which case they are converted to nanoseconds.
+// A comparison against a missing meta or metrics key never matches.
+func Compile(input string) (Node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return node, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be compiled.
+func MustCompile(input string) Node {
+	node, err := Compile(input)
+	if err != nil {
+		panic(fmt.Sprintf("expr: Compile(%q): %v", input, err))
+	}
+	return node
+}
+
+type orNode struct{ left, right Node }
+
+func (n *orNode) Eval(s *pb.Span) bool { return n.left.Eval(s) || n.right.Eval(s) }
+
+type andNode struct{ left, right Node }
+
+func (n *andNode) Eval(s *pb.Span) bool { return n.left.Eval(s) && n.right.Eval(s) }
+
+type notNode struct{ node Node }
+
+func (n *notNode) Eval(s *pb.Span) bool { return !n.node.Eval(s) }
+
+// stringCmp compares a string field of the span to a value.
+type stringCmp struct {
+	get func(s *pb.Span) (string, bool)
+	op  string
+	val string
+	re  *regexp.Regexp // set for the "=~" and "!~" operators
+}
+
+func (n *stringCmp) Eval(s *pb.Span) bool {
+	v, ok := n.get(s)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "==":
+		return v == n.val
+	case "!=":
+		return v != n.val
+	case "=~":
+		return n.re.MatchString(v)
+	case "!~":
+		return !n.re.MatchString(v)
+	}
+	return false
+}
+
+// numberCmp compares a numeric field of the span to a value.
+type numberCmp struct {
+	get func(s *pb.Span) (float64, bool)
+	op  string
+	val float64
+}
+
+func (n *numberCmp) Eval(s *pb.Span) bool {
+	v, ok := n.get(s)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "==":
+		return v == n.val
+	case "!=":
+		return v != n.val
+	case "<":
+		return v < n.val
+	case "<=":
+		return v <= n.val
+	case ">":
+		return v > n.val
+	case ">=":
+		return v >= n.val
+	}
+	return false
+}
+
+var stringFields = map[string]func(s *pb.Span) (string, bool){
+	"service":  func(s *pb.Span) (string, bool) { return s.Service, true },
+	"name":     func(s *pb.Span) (string, bool) { return s.Name, true },
+	"resource": func(s *pb.Span) (string, bool) { return s.Resource, true },
+	"type":     func(s *pb.Span) (string, bool) { return s.Type, true },
+}
+
+var numberFields = map[string]func(s *pb.Span) (float64, bool){
+	"duration": func(s *pb.Span) (float64, bool) { return float64(s.Duration), true },
+	"start":    func(s *pb.Span) (float64, bool) { return float64(s.Start), true },
+	"error":    func(s *pb.Span) (float64, bool) { return float64(s.Error), true },
+}
+
+// durationUnits maps the units allowed on numbers to their value in nanoseconds.
+var durationUnits = map[string]float64{
+	"ns": 1,
+	"us": 1e3,
+	"ms": 1e6,
+	"s":  1e9,
+	"m":  60e9,
+	"h":  3600e9,
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string // for strings, the unquoted value
+	pos  int
+}
+
+// operators lists the operators of the language, longest first.
+var operators = []string{"==", "!=", "=~", "!~", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]"}
+
+// lex splits the input into tokens.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+outer:
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '`':
+			end := i + 1
+			for end < len(input) && input[end] != c {
+				if c == '"' && input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			s, err := strconv.Unquote(input[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %v", i, err)
+			}
+			tokens = append(tokens, token{kind: tokString, text: s, pos: i})
+			i = end + 1
+		case isDigit(c) || (c == '-' && i+1 < len(input) && isDigit(input[i+1])):
+			end := i + 1
+			for end < len(input) && (isDigit(input[end]) || input[end] == '.' || unicode.IsLetter(rune(input[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: input[i:end], pos: i})
+			i = end
+		case c == '_' || unicode.IsLetter(rune(c)):
+			end := i + 1
+			for end < len(input) && (input[end] == '_' || input[end] == '.' || isDigit(input[end]) || unicode.IsLetter(rune(input[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: input[i:end], pos: i})
+			i = end
+		default:
+			for _, op := range operators {
+				if strings.HasPrefix(input[i:], op) {
+					tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+					i += len(op)
+					continue outer
+				}
+			}
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// parser is a recursive descent parser of filter expressions.
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token { return p.tokens[p.i] }
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is the given operator.
+func (p *parser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at position %d", op, tok.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.accept("!") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node}, nil
+	}
+	if p.accept("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	field := p.next()
+	if field.kind != tokIdent {
+		return nil, fmt.Errorf("expected a field at position %d", field.pos)
+	}
+	var (
+		getString func(s *pb.Span) (string, bool)
+		getNumber func(s *pb.Span) (float64, bool)
+	)
+	switch field.text {
+	case "meta", "metrics":
+		if err := p.expect("["); err != nil {
+			return nil, err
+		}
+		key := p.next()
+		if key.kind != tokString {
+			return nil, fmt.Errorf("expected a quoted key at position %d", key.pos)
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		k := key.text
+		if field.text == "meta" {
+			getString = func(s *pb.Span) (string, bool) {
+				v, ok := s.Meta[k]
+				return v, ok
+			}
+		} else {
+			getNumber = func(s *pb.Span) (float64, bool) {
+				v, ok := s.Metrics[k]
+				return v, ok
+			}
+		}
+	default:
+		var ok bool
+		if getString, ok = stringFields[field.text]; !ok {
+			if getNumber, ok = numberFields[field.text]; !ok {
+				return nil, fmt.Errorf("unknown field %q at position %d", field.text, field.pos)
+			}
+		}
+	}
+
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("expected an operator at position %d", op.pos)
+	}
+	val := p.next()
+	if getString != nil {
+		return newStringCmp(getString, op, val)
+	}
+	return newNumberCmp(getNumber, op, val)
+}
+
+func newStringCmp(get func(s *pb.Span) (string, bool), op, val token) (Node, error) {
+	if val.kind != tokString {
+		return nil, fmt.Errorf("expected a string at position %d", val.pos)
+	}
+	n := &stringCmp{get: get, op: op.text, val: val.text}
+	switch op.text {
+	case "==", "!=":
+	case "=~", "!~":
+		re, err := regexp.Compile(val.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %v", val.pos, err)
+		}
+		n.re = re
+	default:
+		return nil, fmt.Errorf("operator %q at position %d is not supported on strings", op.text, op.pos)
+	}
+	return n, nil
+}
+
+func newNumberCmp(get func(s *pb.Span) (float64, bool), op, val token) (Node, error) {
+	if val.kind != tokNumber {
+		return nil, fmt.Errorf("expected a number at position %d", val.pos)
+	}
+	switch op.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return nil, fmt.Errorf("operator %q at position %d is not supported on numbers", op.text, op.pos)
+	}
+	f, err := parseNumber(val.text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number at position %d: %v", val.pos, err)
+	}
+	return &numberCmp{get: get, op: op.text, val: f}, nil
+}
+
+// parseNumber parses a number with an optional duration unit.
+func parseNumber(s string) (float64, error) {
+	end := len(s)
+	for end > 0 && unicode.IsLetter(rune(s[end-1])) {
+		end--
+	}
+	f, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil {
+		return 0, err
+	}
+	if unit := s[end:]; unit != "" {
+		mult, ok := durationUnits[unit]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit %q", unit)
+		}
+		f *= mult
+	}
+	return f, nil
+}
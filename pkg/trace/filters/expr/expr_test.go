@@ -0,0 +1,87 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	span := &pb.Span{
+		Service:  "web",
+		Name:     "http.request",
+		Resource: "GET /health",
+		Type:     "http",
+		Duration: 1500000, // 1.5ms
+		Error:    0,
+		Meta: map[string]string{
+			"http.url":        "http://localhost/health",
+			"http.user_agent": "kube-probe/1.14",
+		},
+		Metrics: map[string]float64{"http.status_code": 200},
+	}
+
+	for _, tt := range []struct {
+		expr  string
+		match bool
+	}{
+		{`service == "web"`, true},
+		{`service != "web"`, false},
+		{`name == "http.request" && type == "http"`, true},
+		{`resource =~ "^GET /health"`, true},
+		{"resource !~ `^GET /health`", false},
+		{`meta["http.user_agent"] =~ "^kube-probe/"`, true},
+		{`meta["http.method"] == "GET"`, false},
+		{`meta["http.method"] != "GET"`, false},
+		{`metrics["http.status_code"] >= 200 && metrics["http.status_code"] < 300`, true},
+		{`metrics["missing"] < 1`, false},
+		{`duration < 2ms`, true},
+		{`duration < 1ms`, false},
+		{`duration > 1500us`, false},
+		{`duration >= 1500000`, true},
+		{`error == 0`, true},
+		{`error > -1`, true},
+		{`service == "api" || duration < 2ms`, true},
+		{`service == "api" || service == "db" && duration < 2ms`, false},
+		{`(service == "api" || service == "web") && duration < 2ms`, true},
+		{`!(service == "web")`, false},
+		{`!service == "api" && !!(error == 0)`, true},
+		{`meta["http.url"] == "http://localhost/health"`, true},
+		{`meta["http.url"] == "http://localhost/\x68ealth"`, true},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Compile(tt.expr)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.match, expr.Eval(span))
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`service`,
+		`service ==`,
+		`service == web`,
+		`service == 1`,
+		`service < "web"`,
+		`duration == "1s"`,
+		`duration =~ "1"`,
+		`duration < 1y`,
+		`unknown == "x"`,
+		`meta[http.url] == "x"`,
+		`meta["http.url" == "x"`,
+		`resource =~ "("`,
+		`(service == "web"`,
+		`service == "web")`,
+		`service == "web" &&`,
+		`service == "web" & error == 1`,
+		`service == "web`,
+	} {
+		_, err := Compile(expr)
+		assert.Error(t, err, expr)
+	}
+}
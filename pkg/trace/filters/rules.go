@@ -0,0 +1,36 @@
+package filters
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// SpanFilter holds a list of filter rules, matching traces that should be dropped.
+type SpanFilter struct {
+	rules []*config.FilterRule
+}
+
+// NewSpanFilter creates a new SpanFilter from the given rules, compiled when
+// loading the configuration.
+func NewSpanFilter(rules []*config.FilterRule) *SpanFilter {
+	return &SpanFilter{rules: rules}
+}
+
+// Match returns the name of the first rule matching the trace and true, or false
+// if the trace is not matched by any rule.
+func (f *SpanFilter) Match(t pb.Trace, root *pb.Span) (string, bool) {
+	for _, r := range f.rules {
+		if !r.AnySpan {
+			if r.Matcher.Eval(root) {
+				return r.Name, true
+			}
+			continue
+		}
+		for _, span := range t {
+			if r.Matcher.Eval(span) {
+				return r.Name, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,32 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/filters/expr"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanFilter(t *testing.T) {
+	assert := assert.New(t)
+	f := NewSpanFilter([]*config.FilterRule{
+		{Name: "health", Matcher: expr.MustCompile(`resource == "GET /health"`)},
+		{Name: "errors", Matcher: expr.MustCompile(`error == 1`), AnySpan: true},
+	})
+
+	root := &pb.Span{SpanID: 1, Resource: "GET /health"}
+	rule, ok := f.Match(pb.Trace{root}, root)
+	assert.True(ok)
+	assert.Equal("health", rule)
+
+	root = &pb.Span{SpanID: 1, Resource: "GET /users"}
+	child := &pb.Span{SpanID: 2, ParentID: 1, Error: 1}
+	rule, ok = f.Match(pb.Trace{root, child}, root)
+	assert.True(ok)
+	assert.Equal("errors", rule)
+
+	_, ok = f.Match(pb.Trace{root}, root)
+	assert.False(ok)
+}
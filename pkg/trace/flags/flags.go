@@ -18,6 +18,12 @@ var (
 	// Info will display information about a running agent.
 	Info bool
 
+	// DebugTraces will display the traces recently processed by a running agent.
+	DebugTraces bool
+
+	// Service restricts the traces displayed by DebugTraces to the given service.
+	Service string
+
 	// CPUProfile specifies the path to output CPU profiling information to.
 	// When empty, CPU profiling is disabled.
 	CPUProfile string
@@ -40,6 +46,8 @@ func init() {
 	flag.StringVar(&PIDFilePath, "pid", "", "Path to set pidfile for process")
 	flag.BoolVar(&Version, "version", false, "Show version information and exit")
 	flag.BoolVar(&Info, "info", false, "Show info about running trace agent process and exit")
+	flag.BoolVar(&DebugTraces, "debug-traces", false, "Show the traces recently processed by the running trace agent and the sampling decisions taken on them, and exit")
+	flag.StringVar(&Service, "service", "", "Only show the traces of this service with -debug-traces")
 
 	// profiling
 	flag.StringVar(&CPUProfile, "cpuprofile", "", "Write cpu profile to file")
@@ -36,6 +36,7 @@ var (
 	prioritySamplerInfo SamplerInfo
 	errorsSamplerInfo   SamplerInfo
 	rateByService       map[string]float64
+	samplingRules       []sampler.RuleStats
 	preSamplerStats     sampler.PreSamplerStats
 	start               = time.Now()
 	once                sync.Once
@@ -73,11 +74,17 @@ const (
     {{if gt $ts.Stats.SpansDropped 0}}
     WARNING: Spans dropped: {{ $ts.Stats.SpansDropped }}
     {{end}}
+    {{ range $rule, $n := $ts.Stats.TracesFilteredByRule.Counts }}
+    Traces filtered by rule '{{ $rule }}': {{ $n }}
+    {{end}}
 
   {{end}}
   {{ range $key, $value := .Status.RateByService }}
   Priority sampling rate for '{{ $key }}': {{percent $value}} %
   {{ end }}
+  {{ range $i, $r := .Status.SamplingRules }}
+  Sampling rule '{{ $r.Name }}': {{ $r.Kept }} traces kept, {{ $r.Dropped }} dropped
+  {{ end }}
   {{if lt .Status.PreSampler.Rate 1.0}}
   WARNING: Pre-sampling traces: {{percent .Status.PreSampler.Rate}} %
   {{end}}
@@ -123,7 +130,7 @@ func UpdateReceiverStats(rs *ReceiverStats) {
 	s := make([]TagStats, 0, len(rs.Stats))
 	for _, tagStats := range rs.Stats {
 		if !tagStats.isEmpty() {
-			s = append(s, *tagStats)
+			s = append(s, tagStats.snapshot())
 		}
 	}
 
@@ -200,6 +207,19 @@ func publishRateByService() interface{} {
 	return rateByService
 }
 
+// UpdateSamplingRules updates the counters of the user-defined sampling rules.
+func UpdateSamplingRules(rs []sampler.RuleStats) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	samplingRules = rs
+}
+
+func publishSamplingRules() interface{} {
+	infoMu.RLock()
+	defer infoMu.RUnlock()
+	return samplingRules
+}
+
 // UpdateWatchdogInfo updates internal stats about the watchdog.
 func UpdateWatchdogInfo(wi watchdog.Info) {
 	infoMu.Lock()
@@ -259,6 +279,7 @@ func InitInfo(conf *config.AgentConfig) error {
 		expvar.Publish("prioritysampler", expvar.Func(publishPrioritySamplerInfo))
 		expvar.Publish("errorssampler", expvar.Func(publishErrorsSamplerInfo))
 		expvar.Publish("ratebyservice", expvar.Func(publishRateByService))
+		expvar.Publish("sampling_rules", expvar.Func(publishSamplingRules))
 		expvar.Publish("watchdog", expvar.Func(publishWatchdogInfo))
 		expvar.Publish("presampler", expvar.Func(publishPreSamplerStats))
 
@@ -314,6 +335,7 @@ type StatusInfo struct {
 	Version       infoVersion             `json:"version"`
 	Receiver      []TagStats              `json:"receiver"`
 	RateByService map[string]float64      `json:"ratebyservice"`
+	SamplingRules []sampler.RuleStats     `json:"sampling_rules"`
 	TraceWriter   TraceWriterInfo         `json:"trace_writer"`
 	StatsWriter   StatsWriterInfo         `json:"stats_writer"`
 	ServiceWriter ServiceWriterInfo       `json:"service_writer"`
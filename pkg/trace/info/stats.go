@@ -1,6 +1,7 @@
 package info
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"sync"
@@ -111,7 +112,14 @@ type TagStats struct {
 }
 
 func newTagStats(tags Tags) *TagStats {
-	return &TagStats{tags, Stats{}}
+	return &TagStats{tags, Stats{TracesFilteredByRule: NewRuleCounts()}}
+}
+
+// snapshot returns a copy of the tag stats which is not updated anymore.
+func (ts *TagStats) snapshot() TagStats {
+	s := *ts
+	s.TracesFilteredByRule = ts.TracesFilteredByRule.clone()
+	return s
 }
 
 func (ts *TagStats) publish() {
@@ -155,6 +163,9 @@ func (ts *TagStats) publish() {
 	metrics.Count("datadog.trace_agent.receiver.events_extracted", eventsExtracted, tags, 1)
 	metrics.Count("datadog.trace_agent.receiver.events_sampled", eventsSampled, tags, 1)
 	metrics.Count("datadog.trace_agent.receiver.payload_accepted", requestsMade, tags, 1)
+	for rule, n := range ts.TracesFilteredByRule.Counts() {
+		metrics.Count("datadog.trace_agent.receiver.traces_filtered_by_rule", n, append(tags, "filter_rule:"+rule), 1)
+	}
 }
 
 // Stats holds the metrics that will be reported every 10s by the agent.
@@ -184,6 +195,8 @@ type Stats struct {
 	SpansDropped int64
 	// SpansFiltered is the number of spans filtered.
 	SpansFiltered int64
+	// TracesFilteredByRule is the number of traces filtered by each filter rule.
+	TracesFilteredByRule *RuleCounts
 	// ServicesReceived is the number of services received.
 	ServicesReceived int64
 	// ServicesBytes is the amount of data received on the services endpoint (raw data, encoded, compressed).
@@ -214,6 +227,12 @@ func (s *Stats) update(recent *Stats) {
 	atomic.AddInt64(&s.EventsExtracted, atomic.LoadInt64(&recent.EventsExtracted))
 	atomic.AddInt64(&s.EventsSampled, atomic.LoadInt64(&recent.EventsSampled))
 	atomic.AddInt64(&s.PayloadAccepted, atomic.LoadInt64(&recent.PayloadAccepted))
+	if s.TracesFilteredByRule == nil {
+		s.TracesFilteredByRule = NewRuleCounts()
+	}
+	for rule, n := range recent.TracesFilteredByRule.Counts() {
+		s.TracesFilteredByRule.Add(rule, n)
+	}
 }
 
 func (s *Stats) reset() {
@@ -234,6 +253,7 @@ func (s *Stats) reset() {
 	atomic.StoreInt64(&s.EventsExtracted, 0)
 	atomic.StoreInt64(&s.EventsSampled, 0)
 	atomic.StoreInt64(&s.PayloadAccepted, 0)
+	s.TracesFilteredByRule.reset()
 }
 
 func (s *Stats) isEmpty() bool {
@@ -263,6 +283,74 @@ func (s *Stats) String() string {
 		eventsExtracted, eventsSampled)
 }
 
+// RuleCounts holds counts by rule name. It is safe for concurrent use.
+type RuleCounts struct {
+	mu     sync.RWMutex
+	counts map[string]int64
+}
+
+// NewRuleCounts returns a new, empty RuleCounts.
+func NewRuleCounts() *RuleCounts {
+	return &RuleCounts{counts: make(map[string]int64)}
+}
+
+// Add adds n to the count of rule.
+func (c *RuleCounts) Add(rule string, n int64) {
+	c.mu.Lock()
+	c.counts[rule] += n
+	c.mu.Unlock()
+}
+
+// Counts returns a copy of the counts by rule name. It returns nil if c is nil.
+func (c *RuleCounts) Counts() map[string]int64 {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	counts := make(map[string]int64, len(c.counts))
+	for rule, n := range c.counts {
+		counts[rule] = n
+	}
+	return counts
+}
+
+func (c *RuleCounts) clone() *RuleCounts {
+	if c == nil {
+		return nil
+	}
+	return &RuleCounts{counts: c.Counts()}
+}
+
+func (c *RuleCounts) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	for rule := range c.counts {
+		delete(c.counts, rule)
+	}
+	c.mu.Unlock()
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *RuleCounts) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Counts())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *RuleCounts) UnmarshalJSON(b []byte) error {
+	var counts map[string]int64
+	if err := json.Unmarshal(b, &counts); err != nil {
+		return err
+	}
+	if counts == nil {
+		counts = make(map[string]int64)
+	}
+	c.counts = counts
+	return nil
+}
+
 // Tags holds the tags we parse when we handle the header of the payload.
 type Tags struct {
 	Lang, LangVersion, Interpreter, TracerVersion string
@@ -0,0 +1,157 @@
+package info
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+// recentTracesSize is the number of traces kept by RecordTrace.
+const recentTracesSize = 500
+
+// Decisions taken on a trace.
+const (
+	DecisionKept     = "kept"
+	DecisionDropped  = "dropped"
+	DecisionDeferred = "deferred" // the decision is taken later by the tail sampler
+)
+
+// TraceRecord describes a trace recently processed by the agent and the decision
+// taken on it.
+type TraceRecord struct {
+	Time     time.Time `json:"time"`
+	TraceID  uint64    `json:"trace_id"`
+	Service  string    `json:"service"`
+	Resource string    `json:"resource"`
+	Env      string    `json:"env"`
+	Spans    int       `json:"spans"`
+
+	// Priority is the sampling priority of the trace, nil if it has none.
+	Priority *int `json:"priority,omitempty"`
+
+	// Decision is one of DecisionKept, DecisionDropped or DecisionDeferred.
+	Decision string `json:"decision"`
+
+	// Reason names the component which took the decision: "blacklist", "filter_rule:<name>",
+	// "sampling_rule:<name>", "priority", "score", "errors" or "tail".
+	Reason string `json:"reason"`
+
+	// Rate is the sampling rate applied to the trace.
+	Rate float64 `json:"rate"`
+
+	// Events is the number of events extracted from the trace.
+	Events int `json:"events"`
+
+	// Obfuscated is true if the obfuscation modified any of the spans of the trace.
+	Obfuscated bool `json:"obfuscated"`
+}
+
+// traceRing is a ring buffer of the most recent trace records.
+type traceRing struct {
+	mu      sync.Mutex
+	records []TraceRecord
+	next    int // index of the next record to write
+}
+
+var recentTraces = newTraceRing(recentTracesSize)
+
+func newTraceRing(size int) *traceRing {
+	return &traceRing{records: make([]TraceRecord, 0, size)}
+}
+
+func (r *traceRing) add(rec TraceRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) < cap(r.records) {
+		r.records = append(r.records, rec)
+	} else {
+		r.records[r.next] = rec
+	}
+	r.next = (r.next + 1) % cap(r.records)
+}
+
+// list returns the records of the given service, or of all services if empty,
+// the most recent first.
+func (r *traceRing) list(service string) []TraceRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]TraceRecord, 0, len(r.records))
+	for i := 1; i <= len(r.records); i++ {
+		rec := r.records[(r.next-i+len(r.records))%len(r.records)]
+		if service == "" || rec.Service == service {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// RecordTrace records the decision taken on a trace. Only the most recent
+// records are kept.
+func RecordTrace(rec TraceRecord) {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	recentTraces.add(rec)
+}
+
+// RecentTraces returns the most recently recorded traces of the given service,
+// or of all services if empty, the most recent first.
+func RecentTraces(service string) []TraceRecord {
+	return recentTraces.list(service)
+}
+
+// DebugTraces writes the traces recently processed by the running agent, along with
+// the decisions taken on them. Only the traces of the given service are shown, unless
+// it is empty.
+func DebugTraces(w io.Writer, conf *config.AgentConfig, service string) error {
+	u := fmt.Sprintf("http://%s:%d/debug/traces", conf.ReceiverHost, conf.ReceiverPort)
+	if service != "" {
+		u += "?service=" + url.QueryEscape(service)
+	}
+	client := http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(u)
+	if err != nil {
+		return fmt.Errorf("could not reach the trace-agent on port %d, is it running? %v", conf.ReceiverPort, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", u, resp.Status)
+	}
+	var records []TraceRecord
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return fmt.Errorf("could not decode the response of %s: %v", u, err)
+	}
+	writeTraceRecords(w, records)
+	return nil
+}
+
+// writeTraceRecords writes records as a table.
+func writeTraceRecords(w io.Writer, records []TraceRecord) {
+	if len(records) == 0 {
+		fmt.Fprintln(w, "No traces received recently.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tTRACE ID\tSERVICE\tRESOURCE\tSPANS\tPRIORITY\tDECISION\tREASON\tRATE\tEVENTS\tOBFUSCATED")
+	for _, rec := range records {
+		priority := "-"
+		if rec.Priority != nil {
+			priority = fmt.Sprint(*rec.Priority)
+		}
+		resource := rec.Resource
+		if len(resource) > 60 {
+			resource = resource[:57] + "..."
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%d\t%s\t%s\t%s\t%.3g\t%d\t%t\n",
+			rec.Time.Format("15:04:05.000"), rec.TraceID, rec.Service, resource, rec.Spans,
+			priority, rec.Decision, rec.Reason, rec.Rate, rec.Events, rec.Obfuscated)
+	}
+	tw.Flush()
+}
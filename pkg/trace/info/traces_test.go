@@ -0,0 +1,52 @@
+package info
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceRing(t *testing.T) {
+	assert := assert.New(t)
+	r := newTraceRing(3)
+	assert.Empty(r.list(""))
+
+	for i, service := range []string{"web", "db", "web", "cache", "web"} {
+		r.add(TraceRecord{TraceID: uint64(i), Service: service})
+	}
+
+	ids := func(records []TraceRecord) []uint64 {
+		var ids []uint64
+		for _, rec := range records {
+			ids = append(ids, rec.TraceID)
+		}
+		return ids
+	}
+	assert.Equal([]uint64{4, 3, 2}, ids(r.list("")))
+	assert.Equal([]uint64{4, 2}, ids(r.list("web")))
+	assert.Empty(r.list("db"))
+}
+
+func TestWriteTraceRecords(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	writeTraceRecords(&buf, nil)
+	assert.Equal("No traces received recently.\n", buf.String())
+
+	buf.Reset()
+	priority := 1
+	writeTraceRecords(&buf, []TraceRecord{
+		{TraceID: 42, Service: "web", Resource: "GET /users", Spans: 3, Priority: &priority, Decision: DecisionKept, Reason: "priority", Rate: 1, Obfuscated: true},
+		{TraceID: 43, Service: "web", Resource: strings.Repeat("a", 100), Spans: 1, Decision: DecisionDropped, Reason: "blacklist"},
+	})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(lines, 3) {
+		assert.Equal([]string{"TIME", "TRACE", "ID", "SERVICE", "RESOURCE", "SPANS", "PRIORITY", "DECISION", "REASON", "RATE", "EVENTS", "OBFUSCATED"}, strings.Fields(lines[0]))
+		assert.Equal([]string{"42", "web", "GET", "/users", "3", "1", "kept", "priority", "1", "0", "true"}, strings.Fields(lines[1])[1:])
+		assert.Contains(lines[2], strings.Repeat("a", 57)+"...")
+		assert.Contains(lines[2], "blacklist")
+	}
+}
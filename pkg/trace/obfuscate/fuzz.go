@@ -0,0 +1,100 @@
+// +build gofuzz
+
+// The functions in this file are entry points for go-fuzz (github.com/dvyukov/go-fuzz).
+// Build and run one of them with:
+//
+//	go-fuzz-build -func FuzzTagRules github.com/DataDog/datadog-agent/pkg/trace/obfuscate
+//	go-fuzz -bin obfuscate-fuzz.zip -func FuzzTagRules
+//
+// They return 1 when the input was parsed successfully, so as to give it priority
+// in the corpus, and panic when an invariant is broken.
+
+package obfuscate
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// FuzzSQL fuzzes the SQL tokenizer and obfuscator.
+func FuzzSQL(data []byte) int {
+	if _, err := obfuscateSQLString(string(data)); err != nil {
+		return 0
+	}
+	return 1
+}
+
+// FuzzRedis fuzzes the Redis tokenizer and obfuscator.
+func FuzzRedis(data []byte) int {
+	span := &pb.Span{
+		Type:     "redis",
+		Resource: string(data),
+		Meta:     map[string]string{"redis.raw_command": string(data)},
+	}
+	NewObfuscator(&config.ObfuscationConfig{Redis: config.Enablable{Enabled: true}}).Obfuscate(span)
+	return 1
+}
+
+// fuzzTagObfuscator obfuscates tags using one rule of each action.
+var fuzzTagObfuscator = NewObfuscator(&config.ObfuscationConfig{
+	TagRules: []*config.TagObfuscationRule{
+		{Tag: "redact.pattern", Pattern: `\d+`, Repl: "N"},
+		{Tag: "redact.*"},
+		{Tag: "drop.*", Action: "drop"},
+		{Tag: "hash.*", Action: "hash"},
+		{Tag: "json.*", Action: "json", KeepValues: []string{"id"}},
+	},
+})
+
+// FuzzTagRules fuzzes the tag obfuscation rules. The input is split on its first
+// newline into a tag key and a tag value. TestObfuscateTagsRandom and
+// TestTagRulesMalformed check the same invariants in the regular test suite.
+func FuzzTagRules(data []byte) int {
+	key, val := string(data), ""
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		key, val = string(data[:i]), string(data[i+1:])
+	}
+	span := &pb.Span{Meta: map[string]string{key: val}}
+	fuzzTagObfuscator.Obfuscate(span)
+
+	out, ok := span.Meta[key]
+	switch {
+	case key == "redact.pattern":
+		if strings.ContainsAny(out, "0123456789") {
+			panic("digits were not redacted: " + out)
+		}
+	case strings.HasPrefix(key, "redact."):
+		if out != "?" {
+			panic("value was not redacted: " + out)
+		}
+	case strings.HasPrefix(key, "drop."):
+		if ok {
+			panic("tag was not dropped: " + key)
+		}
+	case strings.HasPrefix(key, "hash."):
+		if len(out) != 16 {
+			panic("unexpected hash: " + out)
+		}
+	case strings.HasPrefix(key, "json."):
+		if !ok {
+			panic("tag was removed: " + key)
+		}
+	default:
+		if out != val {
+			panic("tag matched by no rule was modified: " + key)
+		}
+		return 0
+	}
+	return 1
+}
+
+// FuzzGraphQL fuzzes the GraphQL tokenizer and obfuscator.
+func FuzzGraphQL(data []byte) int {
+	if _, err := obfuscateGraphQLString(string(data)); err != nil {
+		return 0
+	}
+	return 1
+}
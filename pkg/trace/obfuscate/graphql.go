@@ -0,0 +1,307 @@
+package obfuscate
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const graphQLQueryTag = "graphql.query"
+
+// obfuscateGraphQL obfuscates the query found in the "graphql.query" tag of the span
+// and its resource, when the latter holds a query too.
+func (*Obfuscator) obfuscateGraphQL(span *pb.Span) {
+	if span.Meta != nil && span.Meta[graphQLQueryTag] != "" {
+		if out, err := obfuscateGraphQLString(span.Meta[graphQLQueryTag]); err != nil {
+			log.Debugf("Error parsing GraphQL query: %v", err)
+		} else {
+			span.Meta[graphQLQueryTag] = out
+		}
+	}
+	if !strings.Contains(span.Resource, "{") {
+		// the resource is an operation name, not a query
+		return
+	}
+	out, err := obfuscateGraphQLString(span.Resource)
+	if err != nil {
+		log.Debugf("Error parsing GraphQL query: %v", err)
+		span.Resource = "Non-parsable GraphQL query"
+		return
+	}
+	span.Resource = out
+}
+
+// obfuscateGraphQLString obfuscates a GraphQL document: the literal values of arguments,
+// of variable defaults and of input object fields are replaced by "?", lists of values
+// are collapsed into a single "?", aliases are removed and whitespace, commas and
+// comments are normalized. Variables, enum values and null are kept.
+func obfuscateGraphQLString(in string) (string, error) {
+	tokens, err := tokenizeGraphQL(in)
+	if err != nil {
+		return "", err
+	}
+	o := &graphQLObfuscator{tokens: tokens}
+	if err := o.obfuscate(); err != nil {
+		return "", err
+	}
+	return o.out.String(), nil
+}
+
+// graphQLTokenType is the type of a GraphQL token.
+type graphQLTokenType int
+
+const (
+	graphQLPunctuator graphQLTokenType = iota
+	graphQLName
+	graphQLInt
+	graphQLFloat
+	graphQLString
+)
+
+type graphQLToken struct {
+	typ graphQLTokenType
+	val string
+}
+
+// tokenizeGraphQL splits a GraphQL document into tokens, skipping whitespace, commas
+// and comments, which are insignificant in GraphQL.
+func tokenizeGraphQL(in string) ([]graphQLToken, error) {
+	var tokens []graphQLToken
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(in) && in[i] != '\n' && in[i] != '\r' {
+				i++
+			}
+		case c == '.':
+			if !strings.HasPrefix(in[i:], "...") {
+				return nil, errors.New("unexpected '.'")
+			}
+			tokens = append(tokens, graphQLToken{graphQLPunctuator, "..."})
+			i += 3
+		case strings.IndexByte("!$&()/:=@[]{}|", c) >= 0:
+			tokens = append(tokens, graphQLToken{graphQLPunctuator, string(c)})
+			i++
+		case c == '_' || isAlpha(c):
+			j := i + 1
+			for j < len(in) && (in[j] == '_' || isAlpha(in[j]) || isDigit(uint16(in[j]))) {
+				j++
+			}
+			tokens = append(tokens, graphQLToken{graphQLName, in[i:j]})
+			i = j
+		case c == '-' || isDigit(uint16(c)):
+			typ := graphQLInt
+			j := i + 1
+			for j < len(in) && (isDigit(uint16(in[j])) || strings.IndexByte(".eE+-", in[j]) >= 0) {
+				if !isDigit(uint16(in[j])) {
+					typ = graphQLFloat
+				}
+				j++
+			}
+			tokens = append(tokens, graphQLToken{typ, in[i:j]})
+			i = j
+		case c == '"':
+			j, err := scanGraphQLString(in, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, graphQLToken{graphQLString, in[i:j]})
+			i = j
+		default:
+			return nil, errors.New("unexpected character " + string(c))
+		}
+	}
+	return tokens, nil
+}
+
+// scanGraphQLString returns the end offset of the string or block string starting at i.
+func scanGraphQLString(in string, i int) (int, error) {
+	if strings.HasPrefix(in[i:], `"""`) {
+		for j := i + 3; j < len(in); j++ {
+			if in[j] == '\\' && strings.HasPrefix(in[j:], `\"""`) {
+				j += 3
+				continue
+			}
+			if strings.HasPrefix(in[j:], `"""`) {
+				return j + 3, nil
+			}
+		}
+		return 0, errors.New("unterminated block string")
+	}
+	for j := i + 1; j < len(in); j++ {
+		switch in[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		case '\n', '\r':
+			return 0, errors.New("unterminated string")
+		}
+	}
+	return 0, errors.New("unterminated string")
+}
+
+func isAlpha(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+
+// graphQLObfuscator writes an obfuscated GraphQL document from its tokens.
+type graphQLObfuscator struct {
+	tokens []graphQLToken
+	i      int
+	out    strings.Builder
+	last   string // last token written
+}
+
+func (o *graphQLObfuscator) peek(n int) (graphQLToken, bool) {
+	if o.i+n >= len(o.tokens) {
+		return graphQLToken{}, false
+	}
+	return o.tokens[o.i+n], true
+}
+
+// write writes s to the output, separated from the previous token by a space when needed.
+func (o *graphQLObfuscator) write(s string) {
+	if o.out.Len() > 0 {
+		switch {
+		case s == "(" || s == ")" || s == "]" || s == ":" || s == "!":
+		case o.last == "(" || o.last == "[" || o.last == "$" || o.last == "@":
+		case o.last == "..." && s != "on":
+		default:
+			o.out.WriteByte(' ')
+		}
+	}
+	o.out.WriteString(s)
+	o.last = s
+}
+
+func (o *graphQLObfuscator) obfuscate() error {
+	var (
+		depth  int    // selection set depth
+		parens []bool // the open parentheses, true for variable definitions, false for arguments
+	)
+	for o.i < len(o.tokens) {
+		tok := o.tokens[o.i]
+		o.i++
+		varDefs := len(parens) > 0 && parens[len(parens)-1]
+		args := len(parens) > 0 && !parens[len(parens)-1]
+		switch {
+		case tok.typ == graphQLPunctuator && tok.val == "{":
+			depth++
+		case tok.typ == graphQLPunctuator && tok.val == "}":
+			depth--
+		case tok.typ == graphQLPunctuator && tok.val == "(":
+			// variable definitions follow the operation type and name at the top level,
+			// the parentheses of fields and directives hold arguments
+			parens = append(parens, depth == 0 && len(parens) == 0 && !o.afterDirective())
+		case tok.typ == graphQLPunctuator && tok.val == ")":
+			if len(parens) > 0 {
+				parens = parens[:len(parens)-1]
+			}
+		case tok.typ == graphQLPunctuator && tok.val == "=" && varDefs:
+			o.write(tok.val)
+			if err := o.value(); err != nil {
+				return err
+			}
+			continue
+		case tok.typ == graphQLPunctuator && tok.val == ":" && args:
+			o.write(tok.val)
+			if err := o.value(); err != nil {
+				return err
+			}
+			continue
+		case tok.typ == graphQLName && depth > 0 && !args:
+			if next, ok := o.peek(0); ok && next.typ == graphQLPunctuator && next.val == ":" {
+				// alias: skip it along with the colon
+				o.i++
+				continue
+			}
+		}
+		o.write(tok.val)
+	}
+	if depth != 0 {
+		return errors.New("unbalanced braces")
+	}
+	return nil
+}
+
+// afterDirective reports whether the token before the current one is the name of
+// a directive, like "include" in "@include(".
+func (o *graphQLObfuscator) afterDirective() bool {
+	return o.i >= 3 && o.tokens[o.i-2].typ == graphQLName && o.tokens[o.i-3].val == "@"
+}
+
+// value writes the obfuscated value starting at the current token.
+func (o *graphQLObfuscator) value() error {
+	tok, ok := o.peek(0)
+	if !ok {
+		return errors.New("missing value")
+	}
+	o.i++
+	switch tok.typ {
+	case graphQLInt, graphQLFloat, graphQLString:
+		o.write("?")
+	case graphQLName:
+		if tok.val == "true" || tok.val == "false" {
+			o.write("?")
+		} else {
+			// null or enum value
+			o.write(tok.val)
+		}
+	case graphQLPunctuator:
+		switch tok.val {
+		case "$":
+			name, ok := o.peek(0)
+			if !ok || name.typ != graphQLName {
+				return errors.New("invalid variable")
+			}
+			o.i++
+			o.write("$")
+			o.write(name.val)
+		case "[":
+			// lists are collapsed
+			for depth := 1; depth > 0; o.i++ {
+				tok, ok := o.peek(0)
+				if !ok {
+					return errors.New("unterminated list")
+				}
+				switch tok.val {
+				case "[", "{":
+					depth++
+				case "]", "}":
+					depth--
+				}
+			}
+			o.write("?")
+		case "{":
+			o.write("{")
+			for {
+				name, ok := o.peek(0)
+				if !ok {
+					return errors.New("unterminated object")
+				}
+				o.i++
+				if name.typ == graphQLPunctuator && name.val == "}" {
+					break
+				}
+				colon, ok := o.peek(0)
+				if name.typ != graphQLName || !ok || colon.val != ":" {
+					return errors.New("invalid object field")
+				}
+				o.i++
+				o.write(name.val)
+				o.write(":")
+				if err := o.value(); err != nil {
+					return err
+				}
+			}
+			o.write("}")
+		default:
+			return errors.New("unexpected " + tok.val)
+		}
+	}
+	return nil
+}
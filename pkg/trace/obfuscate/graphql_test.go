@@ -0,0 +1,112 @@
+package obfuscate
+
+import (
+	"encoding/xml"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+// loadQueryTests loads the XML tests found at path, trimming the whitespace
+// around their input and output.
+func loadQueryTests(path string) ([]*xmlObfuscateTest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var suite xmlObfuscateTests
+	if err := xml.NewDecoder(f).Decode(&suite); err != nil {
+		return nil, err
+	}
+	for _, test := range suite.Tests {
+		test.In = strings.TrimSpace(test.In)
+		test.Out = strings.TrimSpace(test.Out)
+	}
+	return suite.Tests, nil
+}
+
+func TestObfuscateGraphQLString(t *testing.T) {
+	tests, err := loadQueryTests("./testdata/graphql_tests.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		out, err := obfuscateGraphQLString(tt.In)
+		if assert.NoError(t, err, i) {
+			assert.Equal(t, tt.Out, out, i)
+		}
+	}
+}
+
+func TestObfuscateGraphQLStringErrors(t *testing.T) {
+	for _, in := range []string{
+		`query { user(id: "42) { name } }`,
+		`query { user(id: 42) { name }`,
+		`query { user(id: ) { name } }`,
+		`query { user(id: [1, 2) { name } }`,
+		`query { user(input: {id 1}) { name } }`,
+		`query { user(id: 4.2.) { name } } %`,
+		`query { user(id: $) { name } }`,
+		`query { user.name }`,
+	} {
+		_, err := obfuscateGraphQLString(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestObfuscateGraphQL(t *testing.T) {
+	query := `query GetUser { user(id: 42) { name } }`
+	newSpan := func(resource string) *pb.Span {
+		return &pb.Span{
+			Type:     "graphql",
+			Resource: resource,
+			Meta:     map[string]string{"graphql.query": query},
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		span := newSpan(query)
+		NewObfuscator(nil).Obfuscate(span)
+		assert.Equal(t, query, span.Resource)
+		assert.Equal(t, query, span.Meta["graphql.query"])
+	})
+
+	o := NewObfuscator(&config.ObfuscationConfig{GraphQL: config.Enablable{Enabled: true}})
+
+	t.Run("query", func(t *testing.T) {
+		span := newSpan(query)
+		o.Obfuscate(span)
+		assert.Equal(t, "query GetUser { user(id: ?) { name } }", span.Resource)
+		assert.Equal(t, "query GetUser { user(id: ?) { name } }", span.Meta["graphql.query"])
+	})
+
+	t.Run("operation-name", func(t *testing.T) {
+		span := newSpan("GetUser")
+		o.Obfuscate(span)
+		assert.Equal(t, "GetUser", span.Resource)
+		assert.Equal(t, "query GetUser { user(id: ?) { name } }", span.Meta["graphql.query"])
+	})
+
+	t.Run("non-parsable", func(t *testing.T) {
+		bad := `query { user(id: "42) { name } }`
+		span := &pb.Span{Type: "graphql", Resource: bad, Meta: map[string]string{"graphql.query": bad}}
+		o.Obfuscate(span)
+		assert.Equal(t, "Non-parsable GraphQL query", span.Resource)
+		assert.Equal(t, bad, span.Meta["graphql.query"])
+	})
+}
+
+func BenchmarkObfuscateGraphQLString(b *testing.B) {
+	query := `query GetUser($id: ID!) { user(id: $id) { name friends(first: 10, after: "abc") { edges { node { name } } } } }`
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := obfuscateGraphQLString(query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
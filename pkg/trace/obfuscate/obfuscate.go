@@ -12,9 +12,10 @@ import (
 // Obfuscator quantizes and obfuscates spans. The obfuscator is not safe for
 // concurrent use.
 type Obfuscator struct {
-	opts  *config.ObfuscationConfig
-	es    *jsonObfuscator // nil if disabled
-	mongo *jsonObfuscator // nil if disabled
+	opts     *config.ObfuscationConfig
+	es       *jsonObfuscator // nil if disabled
+	mongo    *jsonObfuscator // nil if disabled
+	tagRules []*tagRule
 }
 
 // NewObfuscator creates a new Obfuscator.
@@ -29,12 +30,30 @@ func NewObfuscator(cfg *config.ObfuscationConfig) *Obfuscator {
 	if cfg.Mongo.Enabled {
 		o.mongo = newJSONObfuscator(&cfg.Mongo)
 	}
+	o.tagRules = newTagRules(cfg.TagRules)
 	return &o
 }
 
+// typeTags maps span types to the tag obfuscated for them, if any.
+var typeTags = map[string]string{
+	"redis":         "redis.raw_command",
+	"memcached":     "memcached.command",
+	"web":           "http.url",
+	"http":          "http.url",
+	"mongodb":       "mongodb.query",
+	"elasticsearch": "elasticsearch.body",
+	"graphql":       graphQLQueryTag,
+}
+
 // Obfuscate may obfuscate span's properties based on its type and on the Obfuscator's
-// configuration.
-func (o *Obfuscator) Obfuscate(span *pb.Span) {
+// configuration. It returns true if the resource or the tags of the span were modified.
+func (o *Obfuscator) Obfuscate(span *pb.Span) bool {
+	resource := span.Resource
+	tag, hasTag := typeTags[span.Type]
+	var tagValue string
+	if hasTag {
+		tagValue = span.Meta[tag]
+	}
 	switch span.Type {
 	case "sql", "cassandra":
 		o.obfuscateSQL(span)
@@ -47,6 +66,10 @@ func (o *Obfuscator) Obfuscate(span *pb.Span) {
 		if o.opts.Memcached.Enabled {
 			o.obfuscateMemcached(span)
 		}
+	case "graphql":
+		if o.opts.GraphQL.Enabled {
+			o.obfuscateGraphQL(span)
+		}
 	case "web", "http":
 		o.obfuscateHTTP(span)
 	case "mongodb":
@@ -54,6 +77,8 @@ func (o *Obfuscator) Obfuscate(span *pb.Span) {
 	case "elasticsearch":
 		o.obfuscateJSON(span, "elasticsearch.body", o.es)
 	}
+	changed := span.Resource != resource || (hasTag && span.Meta[tag] != tagValue)
+	return o.obfuscateTags(span) || changed
 }
 
 // compactWhitespaces compacts all whitespaces in t.
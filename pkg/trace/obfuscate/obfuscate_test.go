@@ -231,3 +231,22 @@ func BenchmarkCompactWhitespaces(b *testing.B) {
 		compactWhitespaces(str)
 	}
 }
+
+func TestObfuscateChanged(t *testing.T) {
+	o := NewObfuscator(&config.ObfuscationConfig{
+		TagRules: []*config.TagObfuscationRule{{Tag: "user.email"}},
+	})
+	for _, tt := range []struct {
+		span    *pb.Span
+		changed bool
+	}{
+		{&pb.Span{Type: "sql", Resource: "SELECT 1"}, true},
+		{&pb.Span{Type: "sql", Resource: "SELECT a FROM b"}, false},
+		{&pb.Span{Type: "http", Resource: "GET", Meta: map[string]string{"http.url": "/users?id=1"}}, false},
+		{&pb.Span{Type: "web", Meta: map[string]string{"user.email": "jane@example.com"}}, true},
+		{&pb.Span{Type: "web", Meta: map[string]string{"user.email": "?"}}, false},
+		{&pb.Span{Type: "custom", Resource: "SELECT 1"}, false},
+	} {
+		assert.Equal(t, tt.changed, o.Obfuscate(tt.span), "%v", tt.span)
+	}
+}
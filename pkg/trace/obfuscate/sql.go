@@ -78,6 +78,9 @@ func (f *replaceFilter) Filter(token, lastToken int, buffer []byte) (int, []byte
 	switch token {
 	case String, Number, Null, Variable, PreparedStatement, BooleanLiteral, EscapeSequence:
 		return Filtered, []byte("?")
+	case ValueList:
+		// lists of any size are collapsed to keep the cardinality of resources low
+		return Filtered, []byte("( ? )")
 	default:
 		return token, buffer
 	}
@@ -493,3 +493,33 @@ func TestCassQuantizer(t *testing.T) {
 		assert.Equal(testCase.expected, s.Resource)
 	}
 }
+
+func TestSQLTestdata(t *testing.T) {
+	tests, err := loadQueryTests("./testdata/sql_tests.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		out, err := obfuscateSQLString(tt.In)
+		if assert.NoError(t, err, tt.In) {
+			assert.Equal(t, tt.Out, out, tt.In)
+		}
+	}
+}
+
+func TestSQLTokenizerValueListMultibyte(t *testing.T) {
+	assert := assert.New(t)
+	tkn := NewStringTokenizer("SELECT * FROM t WHERE name IN ('café', 'naïve', '日本') AND id = 1")
+	var tokens []string
+	for {
+		token, buff := tkn.Scan()
+		if token == EOFChar {
+			break
+		}
+		if token == ValueList {
+			assert.Equal("('café', 'naïve', '日本')", string(buff))
+		}
+		tokens = append(tokens, string(buff))
+	}
+	assert.Equal([]string{"SELECT", "*", "FROM", "t", "WHERE", "name", "IN", "('café', 'naïve', '日本')", "AND", "id", "=", "1"}, tokens)
+}
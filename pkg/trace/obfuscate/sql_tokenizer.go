@@ -2,6 +2,7 @@ package obfuscate
 
 import (
 	"bytes"
+	"io"
 	"strings"
 	"unicode"
 )
@@ -50,14 +51,20 @@ const (
 	// a bracketed identifier (MSSQL).
 	// See issue https://github.com/DataDog/datadog-trace-agent/issues/475.
 	FilteredBracketedIdentifier = 57367
+
+	// ValueList specifies a parenthesized list of values following the IN keyword,
+	// e.g. (1, 2, 3) or ((1, 'a'), (2, 'b')).
+	ValueList = 57368
 )
 
 // Tokenizer is the struct used to generate SQL
 // tokens for the parser.
 type Tokenizer struct {
-	InStream *strings.Reader
-	Position int
-	lastChar uint16
+	InStream   *strings.Reader
+	Position   int
+	lastChar   uint16
+	lastOffset int64 // the offset in the input of lastChar
+	afterIn    bool  // true if the last token scanned was the IN keyword
 }
 
 // NewStringTokenizer creates a new Tokenizer for the
@@ -71,6 +78,8 @@ func (tkn *Tokenizer) Reset(in string) {
 	tkn.InStream.Reset(in)
 	tkn.Position = 0
 	tkn.lastChar = 0
+	tkn.lastOffset = 0
+	tkn.afterIn = false
 }
 
 // keywords used to recognize string tokens
@@ -89,6 +98,12 @@ var keywords = map[string]int{
 // for each Scan(). An improvement to reduce the overhead of
 // the Scan() is to return slices instead of buffers.
 func (tkn *Tokenizer) Scan() (int, []byte) {
+	token, buff := tkn.scan()
+	tkn.afterIn = token == ID && bytes.EqualFold(buff, []byte("IN"))
+	return token, buff
+}
+
+func (tkn *Tokenizer) scan() (int, []byte) {
 	if tkn.lastChar == 0 {
 		tkn.next()
 	}
@@ -109,7 +124,14 @@ func (tkn *Tokenizer) Scan() (int, []byte) {
 				return tkn.scanBindVar()
 			}
 			fallthrough
-		case '=', ',', ';', '(', ')', '+', '*', '&', '|', '^', '~', '[', ']', '?':
+		case '(':
+			if tkn.afterIn {
+				if buff, ok := tkn.scanValueList(); ok {
+					return ValueList, buff
+				}
+			}
+			return int(ch), []byte{byte(ch)}
+		case '=', ',', ';', ')', '+', '*', '&', '|', '^', '~', '[', ']', '?':
 			return int(ch), []byte{byte(ch)}
 		case '.':
 			if isDigit(tkn.lastChar) {
@@ -182,6 +204,9 @@ func (tkn *Tokenizer) Scan() (int, []byte) {
 			// modulo operator (e.g. 'id % 8')
 			return int(ch), []byte{byte(ch)}
 		case '$':
+			if tkn.lastChar == '$' || isLeadingLetter(tkn.lastChar) {
+				return tkn.scanDollarQuotedString()
+			}
 			return tkn.scanPreparedStatement('$')
 		case '{':
 			return tkn.scanEscapeSequence('{')
@@ -283,6 +308,63 @@ func (tkn *Tokenizer) scanPreparedStatement(prefix rune) (int, []byte) {
 	return PreparedStatement, buffer.Bytes()
 }
 
+// scanDollarQuotedString scans a PostgreSQL dollar-quoted string constant, such as
+// $$text$$ or $tag$text$tag$. The opening dollar sign is already consumed.
+func (tkn *Tokenizer) scanDollarQuotedString() (int, []byte) {
+	delim := bytes.NewBufferString("$")
+	for tkn.lastChar != '$' {
+		if !isLetter(tkn.lastChar) && !isDigit(tkn.lastChar) {
+			return LexError, delim.Bytes()
+		}
+		tkn.consumeNext(delim)
+	}
+	tkn.consumeNext(delim)
+
+	buffer := &bytes.Buffer{}
+	for {
+		if tkn.lastChar == EOFChar {
+			return LexError, buffer.Bytes()
+		}
+		tkn.consumeNext(buffer)
+		if bytes.HasSuffix(buffer.Bytes(), delim.Bytes()) {
+			return String, buffer.Bytes()[:buffer.Len()-delim.Len()]
+		}
+	}
+}
+
+// scanValueList scans a list of values following the IN keyword, the opening parenthesis
+// being already consumed. If the list holds anything else than literals, placeholders and
+// nested lists (e.g. a subquery), the tokenizer is rewound and false is returned.
+func (tkn *Tokenizer) scanValueList() ([]byte, bool) {
+	start := tkn.offset() - 1
+	pos, lastChar, lastOffset := tkn.Position, tkn.lastChar, tkn.lastOffset
+	readPos := tkn.InStream.Size() - int64(tkn.InStream.Len())
+	tkn.afterIn = false
+
+	for depth := 1; depth > 0; {
+		switch token, _ := tkn.scan(); token {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case String, Number, Null, BooleanLiteral, Variable, PreparedStatement, ValueArg, ListArg, '?', ',', '-', '+':
+		default:
+			tkn.InStream.Seek(readPos, io.SeekStart)
+			tkn.Position, tkn.lastChar, tkn.lastOffset = pos, lastChar, lastOffset
+			return nil, false
+		}
+	}
+	buffer := make([]byte, tkn.offset()-start)
+	tkn.InStream.ReadAt(buffer, start)
+	return buffer, true
+}
+
+// offset returns the offset in the input of the current character, or the size of
+// the input once it is consumed.
+func (tkn *Tokenizer) offset() int64 {
+	return tkn.lastOffset
+}
+
 func (tkn *Tokenizer) scanEscapeSequence(braces rune) (int, []byte) {
 	buffer := &bytes.Buffer{}
 	buffer.WriteByte(byte(braces))
@@ -462,6 +544,7 @@ func (tkn *Tokenizer) consumeNext(buffer *bytes.Buffer) {
 }
 
 func (tkn *Tokenizer) next() {
+	tkn.lastOffset = tkn.InStream.Size() - int64(tkn.InStream.Len())
 	if ch, err := tkn.InStream.ReadByte(); err != nil {
 		// Only EOF is possible.
 		tkn.lastChar = EOFChar
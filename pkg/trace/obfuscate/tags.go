@@ -0,0 +1,133 @@
+package obfuscate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// tagRule is a compiled tag obfuscation rule.
+type tagRule struct {
+	tag      *regexp.Regexp
+	spanType *regexp.Regexp // nil matches any type
+	service  *regexp.Regexp // nil matches any service
+	action   string
+
+	re   *regexp.Regexp  // set for the "redact" action, nil redacts the whole value
+	repl string          // set for the "redact" action
+	json *jsonObfuscator // set for the "json" action
+}
+
+// newTagRules compiles the given tag obfuscation rules. Invalid rules are logged and ignored.
+func newTagRules(cfgs []*config.TagObfuscationRule) []*tagRule {
+	rules := make([]*tagRule, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		r, err := newTagRule(cfg)
+		if err != nil {
+			log.Errorf("Invalid tag obfuscation rule for %q: %v", cfg.Tag, err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+func newTagRule(cfg *config.TagObfuscationRule) (*tagRule, error) {
+	if cfg.Tag == "" {
+		return nil, fmt.Errorf(`missing "tag"`)
+	}
+	r := &tagRule{
+		tag:      compileGlob(cfg.Tag),
+		spanType: compileGlob(cfg.Type),
+		service:  compileGlob(cfg.Service),
+		action:   cfg.Action,
+	}
+	switch cfg.Action {
+	case "", "redact":
+		r.action = "redact"
+		r.repl = cfg.Repl
+		if r.repl == "" {
+			r.repl = "?"
+		}
+		if cfg.Pattern != "" {
+			re, err := regexp.Compile(cfg.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			r.re = re
+		}
+	case "drop", "hash":
+	case "json":
+		r.json = newJSONObfuscator(&config.JSONObfuscationConfig{Enabled: true, KeepValues: cfg.KeepValues})
+	default:
+		return nil, fmt.Errorf("unknown action %q", cfg.Action)
+	}
+	return r, nil
+}
+
+// compileGlob compiles a glob where "*" matches any sequence of characters into
+// a regular expression. It returns nil for an empty glob.
+func compileGlob(glob string) *regexp.Regexp {
+	if glob == "" {
+		return nil
+	}
+	expr := strings.Replace(regexp.QuoteMeta(glob), `\*`, ".*", -1)
+	return regexp.MustCompile("^" + expr + "$")
+}
+
+// obfuscateTags applies the first matching tag rule to each of the span's tags.
+// It returns true if any tag was modified.
+func (o *Obfuscator) obfuscateTags(span *pb.Span) bool {
+	if len(o.tagRules) == 0 || len(span.Meta) == 0 {
+		return false
+	}
+	var changed bool
+	for k, v := range span.Meta {
+		for _, r := range o.tagRules {
+			if !r.matches(span, k) {
+				continue
+			}
+			if obfuscated, keep := r.apply(v); !keep {
+				delete(span.Meta, k)
+				changed = true
+			} else if obfuscated != v {
+				span.Meta[k] = obfuscated
+				changed = true
+			}
+			break
+		}
+	}
+	return changed
+}
+
+// matches returns true if the rule applies to the tag key of span.
+func (r *tagRule) matches(span *pb.Span, key string) bool {
+	return r.tag.MatchString(key) &&
+		(r.spanType == nil || r.spanType.MatchString(span.Type)) &&
+		(r.service == nil || r.service.MatchString(span.Service))
+}
+
+// apply returns the obfuscated value and false if the tag should be removed.
+func (r *tagRule) apply(v string) (string, bool) {
+	switch r.action {
+	case "drop":
+		return "", false
+	case "hash":
+		sum := sha256.Sum256([]byte(v))
+		return hex.EncodeToString(sum[:8]), true
+	case "json":
+		// accept the output even on error, see obfuscateJSON
+		out, _ := r.json.obfuscate([]byte(v))
+		return out, true
+	}
+	if r.re == nil {
+		return r.repl, true
+	}
+	return r.re.ReplaceAllString(v, r.repl), true
+}
@@ -0,0 +1,180 @@
+package obfuscate
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateTags(t *testing.T) {
+	o := NewObfuscator(&config.ObfuscationConfig{
+		TagRules: []*config.TagObfuscationRule{
+			{Tag: "user.email", Pattern: `^[^@]+`, Repl: "***"},
+			{Tag: "kafka.key", Type: "queue", Action: "hash"},
+			{Tag: "amqp.*", Service: "billing-*", Action: "drop"},
+			{Tag: "graphql.variables", Action: "json", KeepValues: []string{"first"}},
+			{Tag: "*.token"},
+			{Tag: "invalid", Pattern: "("},
+			{Tag: "unknown", Action: "encrypt"},
+			{Action: "drop"},
+		},
+	})
+	assert.Len(t, o.tagRules, 5)
+
+	for name, tt := range map[string]struct {
+		span *pb.Span
+		want map[string]string
+	}{
+		"redact-pattern": {
+			span: &pb.Span{Type: "web", Meta: map[string]string{"user.email": "jane.doe@example.com"}},
+			want: map[string]string{"user.email": "***@example.com"},
+		},
+		"redact-all": {
+			span: &pb.Span{Meta: map[string]string{"auth.token": "s3cr3t", "user.id": "42"}},
+			want: map[string]string{"auth.token": "?", "user.id": "42"},
+		},
+		"hash": {
+			span: &pb.Span{Type: "queue", Meta: map[string]string{"kafka.key": "customer-1234"}},
+			want: map[string]string{"kafka.key": "26f17c383554e75c"},
+		},
+		"hash-other-type": {
+			span: &pb.Span{Type: "web", Meta: map[string]string{"kafka.key": "customer-1234"}},
+			want: map[string]string{"kafka.key": "customer-1234"},
+		},
+		"drop": {
+			span: &pb.Span{Service: "billing-worker", Meta: map[string]string{"amqp.routing_key": "card.4242", "amqp": "x"}},
+			want: map[string]string{"amqp": "x"},
+		},
+		"drop-other-service": {
+			span: &pb.Span{Service: "web", Meta: map[string]string{"amqp.routing_key": "card.4242"}},
+			want: map[string]string{"amqp.routing_key": "card.4242"},
+		},
+		"json": {
+			span: &pb.Span{Meta: map[string]string{"graphql.variables": `{"first": 10, "email": "jane@example.com"}`}},
+			want: map[string]string{"graphql.variables": `{"first":10,"email":"?"}`},
+		},
+		"no-meta": {
+			span: &pb.Span{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			o.Obfuscate(tt.span)
+			assert.Equal(t, tt.want, tt.span.Meta)
+		})
+	}
+}
+
+func TestObfuscateTagsHashIsStable(t *testing.T) {
+	r := &tagRule{action: "hash"}
+	a, _ := r.apply("value")
+	b, _ := r.apply("value")
+	c, _ := r.apply("other")
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+	assert.Len(t, a, 16)
+}
+
+// TestObfuscateTagsRandom checks the tag rules' invariants on random inputs. See
+// FuzzTagRules for a coverage-guided version of this test.
+func TestObfuscateTagsRandom(t *testing.T) {
+	o := NewObfuscator(&config.ObfuscationConfig{
+		TagRules: []*config.TagObfuscationRule{
+			{Tag: "redact.pattern", Pattern: `\d+`, Repl: "N"},
+			{Tag: "redact.*"},
+			{Tag: "drop.*", Action: "drop"},
+			{Tag: "hash.*", Action: "hash"},
+			{Tag: "json.*", Action: "json"},
+		},
+	})
+	keys := []string{"redact.pattern", "redact.x", "drop.x", "hash.x", "json.x", "other"}
+	alphabet := []byte(`{}[]":,0123456789 abc\` + "\x00\xff")
+	rnd := rand.New(rand.NewSource(42))
+
+	for i := 0; i < 10000; i++ {
+		val := make([]byte, rnd.Intn(64))
+		for j := range val {
+			val[j] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		key := keys[rnd.Intn(len(keys))]
+		span := &pb.Span{Meta: map[string]string{key: string(val)}}
+		o.Obfuscate(span)
+
+		out, ok := span.Meta[key]
+		switch key {
+		case "redact.pattern":
+			assert.False(t, strings.ContainsAny(out, "0123456789"), out)
+		case "redact.x":
+			assert.Equal(t, "?", out)
+		case "drop.x":
+			assert.False(t, ok)
+		case "hash.x":
+			assert.Len(t, out, 16)
+		case "json.x":
+			assert.True(t, ok)
+		default:
+			assert.Equal(t, string(val), out)
+		}
+	}
+}
+
+// TestTagRulesMalformed runs the tag rules on malformed values, checking that they
+// don't panic and that the secret they hold never appears in the obfuscated tags.
+// See FuzzTagRules for a coverage-guided version of this test.
+func TestTagRulesMalformed(t *testing.T) {
+	const secret = "s3cr3t"
+	o := NewObfuscator(&config.ObfuscationConfig{
+		TagRules: []*config.TagObfuscationRule{
+			{Tag: "redact.pattern", Pattern: `\d+`, Repl: "N"},
+			{Tag: "redact.*"},
+			{Tag: "drop.*", Action: "drop"},
+			{Tag: "hash.*", Action: "hash"},
+			{Tag: "json.*", Action: "json"},
+		},
+	})
+
+	for _, val := range []string{
+		secret,
+		"\x00" + secret + "\xff",
+		secret + "\xe2\x82",
+		`"` + secret,
+		`"` + secret + `"`,
+		`{"a": "` + secret + `"`,
+		`{"a": "` + secret + `"}}}`,
+		`{"a": "` + secret + `\`,
+		`{"a": "\ud800` + secret + `"}`,
+		`{"a": "` + secret + "\xff\xfe" + `"}`,
+		`{"a": ` + secret + `}`,
+		`{"a" "` + secret + `"}`,
+		`{"a": 1, "b": "` + secret + `",}`,
+		`{"a": {"b": ["` + secret + `", {"c": "` + secret,
+		`[[[[["` + secret + `"`,
+		strings.Repeat("[", 10000) + `"` + secret + `"`,
+		strings.Repeat(`{"a":`, 1000) + `"` + secret + `"` + strings.Repeat("]", 1000),
+		`]]]` + secret,
+		`{"a": "` + secret + `"} {"b": "` + secret + `"}`,
+		`{"a": "ok"}` + secret,
+	} {
+		for _, key := range []string{"redact.pattern", "redact.x", "drop.x", "hash.x", "json.x"} {
+			span := &pb.Span{Meta: map[string]string{key: val}}
+			if !assert.NotPanics(t, func() { o.Obfuscate(span) }, "%s: %q", key, val) {
+				continue
+			}
+			out, ok := span.Meta[key]
+			if key == "drop.x" {
+				assert.False(t, ok, "%s: %q", key, val)
+				continue
+			}
+			assert.True(t, ok, "%s: %q", key, val)
+			assert.NotContains(t, out, secret, "%s: %q", key, val)
+		}
+	}
+
+	// the rules are not affected by the malformed values they obfuscated
+	span := &pb.Span{Meta: map[string]string{"json.x": `{"a": "` + secret + `", "b": [1]}`}}
+	o.Obfuscate(span)
+	assert.Equal(t, `{"a":"?","b":["?"]}`, span.Meta["json.x"])
+}
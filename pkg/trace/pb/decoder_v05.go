@@ -0,0 +1,306 @@
+package pb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// dictionarySpanFields is the number of fields of a span in a dictionary encoded payload.
+const dictionarySpanFields = 12
+
+// DecodeMsgDictionary decodes a payload where strings are encoded once in a dictionary
+// and referenced by their index everywhere else, instead of being repeated in every span.
+// The payload is an array of two elements: the dictionary, an array of strings, and the
+// traces, an array of arrays of spans. Each span is an array of 12 elements:
+//
+//	0:  Service   (uint32, string index)
+//	1:  Name      (uint32, string index)
+//	2:  Resource  (uint32, string index)
+//	3:  TraceID   (uint64)
+//	4:  SpanID    (uint64)
+//	5:  ParentID  (uint64)
+//	6:  Start     (int64)
+//	7:  Duration  (int64)
+//	8:  Error     (int32)
+//	9:  Meta      (map of string index to string index)
+//	10: Metrics   (map of string index to float64)
+//	11: Type      (uint32, string index)
+//
+// All the spans share the strings of the dictionary, which are only allocated once.
+// The sizes of the arrays and maps are checked against the length of the payload
+// before they're allocated, so that a small payload can't force a huge allocation.
+func (z *Traces) DecodeMsgDictionary(payload []byte) error {
+	r := bytes.NewReader(payload)
+	dc := &dictionaryReader{Reader: msgp.NewReader(r), payload: r}
+	sz, err := dc.readArrayHeader()
+	if err != nil {
+		return err
+	}
+	if sz != 2 {
+		return errors.New("encoded payload: expected an array of 2 elements")
+	}
+	dict, err := decodeDictionary(dc)
+	if err != nil {
+		return err
+	}
+	sz, err = dc.readArrayHeader()
+	if err != nil {
+		return err
+	}
+	if cap(*z) >= int(sz) {
+		*z = (*z)[:sz]
+	} else {
+		*z = make(Traces, sz)
+	}
+	for i := range *z {
+		sz, err := dc.readArrayHeader()
+		if err != nil {
+			return err
+		}
+		if cap((*z)[i]) >= int(sz) {
+			(*z)[i] = (*z)[i][:sz]
+		} else {
+			(*z)[i] = make(Trace, sz)
+		}
+		for j := range (*z)[i] {
+			if (*z)[i][j] == nil {
+				(*z)[i][j] = new(Span)
+			}
+			if err := (*z)[i][j].decodeMsgDictionary(dc, dict); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dictionaryReader reads a dictionary encoded payload, and checks the sizes read in
+// its headers against the number of bytes left to decode.
+type dictionaryReader struct {
+	*msgp.Reader
+	payload *bytes.Reader
+}
+
+// remaining returns the number of bytes of the payload left to decode.
+func (dc *dictionaryReader) remaining() int {
+	return dc.payload.Len() + dc.Buffered()
+}
+
+// readArrayHeader reads an array header, whose elements take at least a byte each.
+func (dc *dictionaryReader) readArrayHeader() (uint32, error) {
+	sz, err := dc.ReadArrayHeader()
+	if err != nil {
+		return 0, err
+	}
+	if int64(sz) > int64(dc.remaining()) {
+		return 0, fmt.Errorf("encoded array of %d elements, only %d bytes left in the payload", sz, dc.remaining())
+	}
+	return sz, nil
+}
+
+// readMapHeader reads a map header, whose entries take at least two bytes each.
+func (dc *dictionaryReader) readMapHeader() (uint32, error) {
+	sz, err := dc.ReadMapHeader()
+	if err != nil {
+		return 0, err
+	}
+	if 2*int64(sz) > int64(dc.remaining()) {
+		return 0, fmt.Errorf("encoded map of %d entries, only %d bytes left in the payload", sz, dc.remaining())
+	}
+	return sz, nil
+}
+
+// readString reads a string, encoded as a string or as binary, whose length is
+// checked against the number of bytes left to decode.
+func (dc *dictionaryReader) readString() (string, error) {
+	t, err := dc.NextType()
+	if err != nil {
+		return "", err
+	}
+	var sz uint32
+	switch t {
+	case msgp.StrType:
+		sz, err = dc.ReadStringHeader()
+	case msgp.BinType:
+		sz, err = dc.ReadBytesHeader()
+	default:
+		return "", msgp.TypeError{Encoded: t, Method: msgp.StrType}
+	}
+	if err != nil {
+		return "", err
+	}
+	if int64(sz) > int64(dc.remaining()) {
+		return "", fmt.Errorf("encoded string of %d bytes, only %d bytes left in the payload", sz, dc.remaining())
+	}
+	b := make([]byte, sz)
+	if _, err := dc.ReadFull(b); err != nil {
+		return "", err
+	}
+	return msgp.UnsafeString(b), nil
+}
+
+// decodeDictionary decodes the array of strings referenced by a payload.
+func decodeDictionary(dc *dictionaryReader) ([]string, error) {
+	sz, err := dc.readArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	dict := make([]string, sz)
+	for i := range dict {
+		dict[i], err = dc.readString()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dict, nil
+}
+
+// decodeMsgDictionary decodes a span whose strings are indexes in dict.
+func (z *Span) decodeMsgDictionary(dc *dictionaryReader, dict []string) error {
+	sz, err := dc.readArrayHeader()
+	if err != nil {
+		return err
+	}
+	if sz != dictionarySpanFields {
+		return fmt.Errorf("encoded span: expected an array of %d elements, got %d", dictionarySpanFields, sz)
+	}
+	if z.Service, err = parseStringIndex(dc.Reader, dict); err != nil {
+		return err
+	}
+	if z.Name, err = parseStringIndex(dc.Reader, dict); err != nil {
+		return err
+	}
+	if z.Resource, err = parseStringIndex(dc.Reader, dict); err != nil {
+		return err
+	}
+	if z.TraceID, err = parseUint64(dc.Reader); err != nil {
+		return err
+	}
+	if z.SpanID, err = parseUint64(dc.Reader); err != nil {
+		return err
+	}
+	if z.ParentID, err = parseUint64(dc.Reader); err != nil {
+		return err
+	}
+	if z.Start, err = parseInt64(dc.Reader); err != nil {
+		return err
+	}
+	if z.Duration, err = parseInt64(dc.Reader); err != nil {
+		return err
+	}
+	if z.Error, err = parseInt32(dc.Reader); err != nil {
+		return err
+	}
+
+	msz, err := dc.readMapHeader()
+	if err != nil {
+		return err
+	}
+	if msz > 0 {
+		z.Meta = make(map[string]string, msz)
+	} else {
+		z.Meta = nil
+	}
+	for ; msz > 0; msz-- {
+		k, err := parseStringIndex(dc.Reader, dict)
+		if err != nil {
+			return err
+		}
+		v, err := parseStringIndex(dc.Reader, dict)
+		if err != nil {
+			return err
+		}
+		z.Meta[k] = v
+	}
+
+	msz, err = dc.readMapHeader()
+	if err != nil {
+		return err
+	}
+	if msz > 0 {
+		z.Metrics = make(map[string]float64, msz)
+	} else {
+		z.Metrics = nil
+	}
+	for ; msz > 0; msz-- {
+		k, err := parseStringIndex(dc.Reader, dict)
+		if err != nil {
+			return err
+		}
+		v, err := parseFloat64(dc.Reader)
+		if err != nil {
+			return err
+		}
+		z.Metrics[k] = v
+	}
+
+	z.Type, err = parseStringIndex(dc.Reader, dict)
+	return err
+}
+
+// parseStringIndex reads a string index and returns the string of dict it references.
+func parseStringIndex(dc *msgp.Reader, dict []string) (string, error) {
+	i, err := parseUint64(dc)
+	if err != nil {
+		return "", err
+	}
+	if i >= uint64(len(dict)) {
+		return "", fmt.Errorf("encoded string index %d out of range, the dictionary holds %d strings", i, len(dict))
+	}
+	return dict[i], nil
+}
+
+// AppendMsgDictionary appends the traces to b, encoded with a string dictionary as
+// expected by DecodeMsgDictionary.
+func (z Traces) AppendMsgDictionary(b []byte) []byte {
+	var dict []string
+	indexes := make(map[string]uint32)
+	index := func(s string) uint32 {
+		i, ok := indexes[s]
+		if !ok {
+			i = uint32(len(dict))
+			indexes[s] = i
+			dict = append(dict, s)
+		}
+		return i
+	}
+
+	var traces []byte
+	traces = msgp.AppendArrayHeader(traces, uint32(len(z)))
+	for _, t := range z {
+		traces = msgp.AppendArrayHeader(traces, uint32(len(t)))
+		for _, s := range t {
+			traces = msgp.AppendArrayHeader(traces, dictionarySpanFields)
+			traces = msgp.AppendUint32(traces, index(s.Service))
+			traces = msgp.AppendUint32(traces, index(s.Name))
+			traces = msgp.AppendUint32(traces, index(s.Resource))
+			traces = msgp.AppendUint64(traces, s.TraceID)
+			traces = msgp.AppendUint64(traces, s.SpanID)
+			traces = msgp.AppendUint64(traces, s.ParentID)
+			traces = msgp.AppendInt64(traces, s.Start)
+			traces = msgp.AppendInt64(traces, s.Duration)
+			traces = msgp.AppendInt32(traces, s.Error)
+			traces = msgp.AppendMapHeader(traces, uint32(len(s.Meta)))
+			for k, v := range s.Meta {
+				traces = msgp.AppendUint32(traces, index(k))
+				traces = msgp.AppendUint32(traces, index(v))
+			}
+			traces = msgp.AppendMapHeader(traces, uint32(len(s.Metrics)))
+			for k, v := range s.Metrics {
+				traces = msgp.AppendUint32(traces, index(k))
+				traces = msgp.AppendFloat64(traces, v)
+			}
+			traces = msgp.AppendUint32(traces, index(s.Type))
+		}
+	}
+
+	b = msgp.AppendArrayHeader(b, 2)
+	b = msgp.AppendArrayHeader(b, uint32(len(dict)))
+	for _, s := range dict {
+		b = msgp.AppendString(b, s)
+	}
+	return append(b, traces...)
+}
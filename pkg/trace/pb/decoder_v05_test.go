@@ -0,0 +1,180 @@
+package pb
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// encodeV04 encodes traces as a v0.4 payload.
+func encodeV04(tb testing.TB, traces Traces) []byte {
+	var buf bytes.Buffer
+	if err := msgp.Encode(&buf, traces); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newDictionaryTestTraces returns traces with the repetitive strings of a typical service.
+func newDictionaryTestTraces(n int) Traces {
+	traces := make(Traces, n)
+	for i := range traces {
+		root := &Span{
+			Service:  "web-store",
+			Name:     "http.request",
+			Resource: "GET /api/v1/products/:id",
+			TraceID:  uint64(i + 1),
+			SpanID:   1,
+			Start:    1548931840954169000,
+			Duration: 100000000,
+			Type:     "web",
+			Meta: map[string]string{
+				"http.method":      "GET",
+				"http.url":         "/api/v1/products/:id",
+				"http.status_code": "200",
+				"env":              "prod",
+			},
+			Metrics: map[string]float64{"_sampling_priority_v1": 1, "_top_level": 1},
+		}
+		child := &Span{
+			Service:  "web-store-db",
+			Name:     "postgres.query",
+			Resource: "SELECT * FROM products WHERE id = ?",
+			TraceID:  uint64(i + 1),
+			SpanID:   2,
+			ParentID: 1,
+			Start:    1548931840954169100,
+			Duration: 50000000,
+			Error:    int32(i % 2),
+			Type:     "sql",
+			Meta:     map[string]string{"db.name": "store", "db.user": "web", "env": "prod"},
+			Metrics:  map[string]float64{"db.rows": 1},
+		}
+		traces[i] = Trace{root, child}
+	}
+	return traces
+}
+
+func TestDecodeMsgDictionary(t *testing.T) {
+	assert := assert.New(t)
+	want := newDictionaryTestTraces(3)
+	want[0][0].Meta = nil
+	want[0][0].Metrics = nil
+
+	var got Traces
+	err := got.DecodeMsgDictionary(want.AppendMsgDictionary(nil))
+	assert.NoError(err)
+	assert.Equal(want, got)
+
+	// strings are shared between spans
+	assert.Equal(got[1][0].Service, got[2][0].Service)
+}
+
+func TestDecodeMsgDictionaryErrors(t *testing.T) {
+	valid := newDictionaryTestTraces(1).AppendMsgDictionary(nil)
+
+	for name, payload := range map[string][]byte{
+		"empty":     {},
+		"not-array": msgp.AppendMapHeader(nil, 2),
+		"one-elem":  msgp.AppendArrayHeader(nil, 1),
+		"truncated": valid[:len(valid)-1],
+		"span-fields": func() []byte {
+			b := msgp.AppendArrayHeader(nil, 2)
+			b = msgp.AppendArrayHeader(b, 0)
+			b = msgp.AppendArrayHeader(b, 1)
+			b = msgp.AppendArrayHeader(b, 1)
+			return msgp.AppendArrayHeader(b, 11)
+		}(),
+		"out-of-range": func() []byte {
+			b := msgp.AppendArrayHeader(nil, 2)
+			b = msgp.AppendArrayHeader(b, 1)
+			b = msgp.AppendString(b, "web")
+			b = msgp.AppendArrayHeader(b, 1)
+			b = msgp.AppendArrayHeader(b, 1)
+			b = msgp.AppendArrayHeader(b, dictionarySpanFields)
+			return msgp.AppendUint32(b, 1)
+		}(),
+		// the sizes of the headers are larger than the payload
+		"huge-dictionary": func() []byte {
+			b := msgp.AppendArrayHeader(nil, 2)
+			return msgp.AppendArrayHeader(b, math.MaxUint32)
+		}(),
+		"huge-string": func() []byte {
+			b := msgp.AppendArrayHeader(nil, 2)
+			b = msgp.AppendArrayHeader(b, 1)
+			return append(b, 0xdb, 0xff, 0xff, 0xff, 0xff) // str32 header
+		}(),
+		"huge-traces": func() []byte {
+			b := msgp.AppendArrayHeader(nil, 2)
+			b = msgp.AppendArrayHeader(b, 0)
+			return msgp.AppendArrayHeader(b, math.MaxUint32)
+		}(),
+		"huge-trace": func() []byte {
+			b := msgp.AppendArrayHeader(nil, 2)
+			b = msgp.AppendArrayHeader(b, 0)
+			b = msgp.AppendArrayHeader(b, 1)
+			return msgp.AppendArrayHeader(b, math.MaxUint32)
+		}(),
+		"huge-meta": func() []byte {
+			b := msgp.AppendArrayHeader(nil, 2)
+			b = msgp.AppendArrayHeader(b, 1)
+			b = msgp.AppendString(b, "web")
+			b = msgp.AppendArrayHeader(b, 1)
+			b = msgp.AppendArrayHeader(b, 1)
+			b = msgp.AppendArrayHeader(b, dictionarySpanFields)
+			for i := 0; i < 3; i++ {
+				b = msgp.AppendUint32(b, 0)
+			}
+			for i := 0; i < 5; i++ {
+				b = msgp.AppendUint64(b, 1)
+			}
+			b = msgp.AppendInt32(b, 0)
+			return msgp.AppendMapHeader(b, math.MaxUint32)
+		}(),
+	} {
+		var traces Traces
+		err := traces.DecodeMsgDictionary(payload)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestDictionaryPayloadSize(t *testing.T) {
+	traces := newDictionaryTestTraces(100)
+	v04 := encodeV04(t, traces)
+	v05 := traces.AppendMsgDictionary(nil)
+	assert.True(t, len(v05) < len(v04)/2, "dictionary payload: %d bytes, v0.4 payload: %d bytes", len(v05), len(v04))
+}
+
+func benchmarkDecode(b *testing.B, decode func(*Traces, []byte) error, encode func(Traces) []byte) {
+	for _, n := range []int{1, 10, 100} {
+		payload := encode(newDictionaryTestTraces(n))
+		b.Run(fmt.Sprintf("%d-traces", n), func(b *testing.B) {
+			b.SetBytes(int64(len(payload)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var traces Traces
+				if err := decode(&traces, payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeV04(b *testing.B) {
+	benchmarkDecode(b,
+		func(t *Traces, payload []byte) error { return t.DecodeMsg(msgp.NewReader(bytes.NewReader(payload))) },
+		func(t Traces) []byte { return encodeV04(b, t) },
+	)
+}
+
+func BenchmarkDecodeDictionary(b *testing.B) {
+	benchmarkDecode(b,
+		func(t *Traces, payload []byte) error { return t.DecodeMsgDictionary(payload) },
+		func(t Traces) []byte { return t.AppendMsgDictionary(nil) },
+	)
+}
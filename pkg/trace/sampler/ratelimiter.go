@@ -0,0 +1,47 @@
+package sampler
+
+import "time"
+
+// rateLimiter samples traces so as to keep at most maxTPS traces per second.
+type rateLimiter struct {
+	maxTPS  float64
+	backend *MemoryBackend
+}
+
+// newRateLimiter returns a rateLimiter keeping at most maxTPS traces per second.
+// A negative or zero maxTPS disables the limit.
+func newRateLimiter(maxTPS float64) *rateLimiter {
+	return &rateLimiter{
+		maxTPS:  maxTPS,
+		backend: NewMemoryBackend(1*time.Second, 1.125),
+	}
+}
+
+// Start starts the decaying of the underlying counters.
+func (l *rateLimiter) Start() {
+	go l.backend.Run()
+}
+
+// Stop stops the decaying of the underlying counters.
+func (l *rateLimiter) Stop() {
+	l.backend.Stop()
+}
+
+// Sample counts the trace identified by traceID and returns whether it should be
+// kept, along with the rate applied to take that decision.
+func (l *rateLimiter) Sample(traceID uint64) (sampled bool, rate float64) {
+	l.backend.CountSample()
+	rate = l.Rate()
+	return SampleByRate(traceID, rate), rate
+}
+
+// Rate returns the sample rate currently applied by the limiter.
+func (l *rateLimiter) Rate() float64 {
+	if l.maxTPS <= 0 {
+		return 1
+	}
+	if tps := l.backend.GetUpperSampledScore(); tps > l.maxTPS {
+		return l.maxTPS / tps
+	}
+	return 1
+}
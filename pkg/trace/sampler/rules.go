@@ -0,0 +1,137 @@
+package sampler
+
+import (
+	"regexp"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+const (
+	// KeySamplingRule is the key of the meta holding the name of the sampling rule
+	// which matched a trace on its root span.
+	KeySamplingRule = "_dd.sampling_rule"
+
+	// KeySamplingRateRule is the key of the metric holding the rate effectively applied
+	// by the sampling rule which matched a trace, including its rate limit.
+	KeySamplingRateRule = "_dd.rule_psr"
+)
+
+// RuleStats holds the number of traces kept and dropped by a sampling rule.
+type RuleStats struct {
+	Name    string
+	Kept    uint64
+	Dropped uint64
+}
+
+// RulesSampler samples traces using the first user-defined sampling rule which
+// matches their root span.
+type RulesSampler struct {
+	rules []*samplingRule
+}
+
+// samplingRule is a sampling rule along with its rate limiter and counters.
+type samplingRule struct {
+	*config.SamplingRule
+
+	limiter *rateLimiter // nil when the rule has no rate limit
+	kept    uint64
+	dropped uint64
+}
+
+// NewRulesSampler returns a sampler applying the given compiled rules.
+func NewRulesSampler(rules []*config.SamplingRule) *RulesSampler {
+	s := &RulesSampler{rules: make([]*samplingRule, len(rules))}
+	for i, r := range rules {
+		s.rules[i] = &samplingRule{SamplingRule: r}
+		if r.MaxTPS > 0 {
+			s.rules[i].limiter = newRateLimiter(r.MaxTPS)
+		}
+	}
+	return s
+}
+
+// Start starts the rate limiters of the rules.
+func (s *RulesSampler) Start() {
+	for _, r := range s.rules {
+		if r.limiter != nil {
+			r.limiter.Start()
+		}
+	}
+}
+
+// Stop stops the rate limiters of the rules.
+func (s *RulesSampler) Stop() {
+	for _, r := range s.rules {
+		if r.limiter != nil {
+			r.limiter.Stop()
+		}
+	}
+}
+
+// Sample applies the first rule matching the trace. It returns the name of the rule which
+// matched, or an empty string if none did, and if so whether the trace should be kept and
+// the rate applied. The name of the rule and the rate are recorded on the root span, so it
+// must be called before the trace is shared with other goroutines.
+func (s *RulesSampler) Sample(t pb.Trace, root *pb.Span, env string) (rule string, sampled bool, rate float64) {
+	for _, r := range s.rules {
+		if !r.match(root, env) {
+			continue
+		}
+		rate = r.Rate
+		sampled = SampleByRate(root.TraceID, rate)
+		if sampled && r.limiter != nil {
+			var limitRate float64
+			sampled, limitRate = r.limiter.Sample(root.TraceID)
+			rate *= limitRate
+		}
+		if sampled {
+			atomic.AddUint64(&r.kept, 1)
+		} else {
+			atomic.AddUint64(&r.dropped, 1)
+		}
+		if root.Meta == nil {
+			root.Meta = make(map[string]string)
+		}
+		root.Meta[KeySamplingRule] = r.Name
+		setMetric(root, KeySamplingRateRule, rate)
+		return r.Name, sampled, rate
+	}
+	return "", false, 0
+}
+
+// Stats returns the number of traces kept and dropped by each rule since the start.
+func (s *RulesSampler) Stats() []RuleStats {
+	stats := make([]RuleStats, len(s.rules))
+	for i, r := range s.rules {
+		stats[i] = RuleStats{
+			Name:    r.Name,
+			Kept:    atomic.LoadUint64(&r.kept),
+			Dropped: atomic.LoadUint64(&r.dropped),
+		}
+	}
+	return stats
+}
+
+// match returns true if the rule matches the root span of a trace from env.
+func (r *samplingRule) match(root *pb.Span, env string) bool {
+	if !matchPattern(r.ServiceRe, root.Service) ||
+		!matchPattern(r.OperationRe, root.Name) ||
+		!matchPattern(r.ResourceRe, root.Resource) ||
+		!matchPattern(r.EnvRe, env) {
+		return false
+	}
+	for k, re := range r.TagsRe {
+		v, ok := root.Meta[k]
+		if !ok || !matchPattern(re, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPattern returns true if re matches s. A nil re matches everything.
+func matchPattern(re *regexp.Regexp, s string) bool {
+	return re == nil || re.MatchString(s)
+}
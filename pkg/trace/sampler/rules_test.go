@@ -0,0 +1,113 @@
+package sampler
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRule(name string, rate float64) *config.SamplingRule {
+	return &config.SamplingRule{Name: name, Rate: rate}
+}
+
+func TestRulesSamplerMatch(t *testing.T) {
+	health := newTestRule("health", 0)
+	health.ResourceRe = regexp.MustCompile("^GET /health.*$")
+	prod := newTestRule("prod-web", 1)
+	prod.ServiceRe = regexp.MustCompile("^web$")
+	prod.EnvRe = regexp.MustCompile("^prod$")
+	tagged := newTestRule("tagged", 1)
+	tagged.TagsRe = map[string]*regexp.Regexp{"customer": regexp.MustCompile("^acme$")}
+
+	s := NewRulesSampler([]*config.SamplingRule{health, prod, tagged})
+
+	for name, tt := range map[string]struct {
+		root    *pb.Span
+		env     string
+		rule    string
+		sampled bool
+	}{
+		"health": {
+			root: &pb.Span{TraceID: 1, Service: "web", Resource: "GET /health/ready"},
+			env:  "prod",
+			rule: "health",
+		},
+		"prod": {
+			root:    &pb.Span{TraceID: 2, Service: "web", Resource: "GET /users"},
+			env:     "prod",
+			rule:    "prod-web",
+			sampled: true,
+		},
+		"staging": {
+			root: &pb.Span{TraceID: 3, Service: "web", Resource: "GET /users"},
+			env:  "staging",
+		},
+		"tagged": {
+			root:    &pb.Span{TraceID: 4, Service: "api", Meta: map[string]string{"customer": "acme"}},
+			rule:    "tagged",
+			sampled: true,
+		},
+		"other-tag": {
+			root: &pb.Span{TraceID: 5, Service: "api", Meta: map[string]string{"customer": "other"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			rule, sampled, rate := s.Sample(pb.Trace{tt.root}, tt.root, tt.env)
+			assert.Equal(tt.rule, rule)
+			if tt.rule == "" {
+				assert.NotContains(tt.root.Meta, KeySamplingRule)
+				assert.NotContains(tt.root.Metrics, KeySamplingRateRule)
+				return
+			}
+			assert.Equal(tt.sampled, sampled)
+			assert.Equal(tt.rule, tt.root.Meta[KeySamplingRule])
+			assert.Equal(rate, tt.root.Metrics[KeySamplingRateRule])
+		})
+	}
+
+	assert.Equal(t, []RuleStats{
+		{Name: "health", Dropped: 1},
+		{Name: "prod-web", Kept: 1},
+		{Name: "tagged", Kept: 1},
+	}, s.Stats())
+}
+
+func TestRulesSamplerRate(t *testing.T) {
+	assert := assert.New(t)
+	s := NewRulesSampler([]*config.SamplingRule{newTestRule("half", 0.5)})
+
+	var kept int
+	for i := uint64(1); i <= 1000; i++ {
+		root := &pb.Span{TraceID: i * samplerHasher}
+		rule, sampled, rate := s.Sample(pb.Trace{root}, root, "")
+		assert.Equal("half", rule)
+		assert.Equal(0.5, rate)
+		if sampled {
+			kept++
+		}
+	}
+	assert.InDelta(500, kept, 100)
+}
+
+func TestRulesSamplerMaxTPS(t *testing.T) {
+	assert := assert.New(t)
+	rule := newTestRule("limited", 1)
+	rule.MaxTPS = 5
+	s := NewRulesSampler([]*config.SamplingRule{rule})
+
+	// simulate a traffic of 100 traces per second for the rule
+	for i := 0; i < 100; i++ {
+		s.rules[0].limiter.backend.CountSample()
+	}
+	s.rules[0].limiter.backend.decayScore()
+
+	root := &pb.Span{TraceID: 42}
+	name, _, rate := s.Sample(pb.Trace{root}, root, "")
+	assert.Equal("limited", name)
+	assert.True(rate < 1, "rate %f must be limited", rate)
+	assert.Equal(rate, root.Metrics[KeySamplingRateRule])
+}
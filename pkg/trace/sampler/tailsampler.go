@@ -0,0 +1,387 @@
+package sampler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// tailFlushInterval is the interval at which the tail sampler looks for traces
+// to take a decision on. A complete trace must also have been idle for at least
+// this long, leaving time for its late spans to arrive.
+const tailFlushInterval = time.Second
+
+// tailDecisionTTLFactor is the number of decision waits during which a decision is
+// remembered and applied to the late spans of a trace, instead of buffering them as
+// a new trace.
+const tailDecisionTTLFactor = 5
+
+// TailSampler buffers the spans of incoming traces by trace ID and takes a sampling
+// decision once a trace is complete or after a configured delay. Decisions are based
+// on the decisions of the regular samplers and on policies evaluated over the whole
+// trace. Kept traces are sent to the output channel.
+type TailSampler struct {
+	out      chan<- pb.Trace
+	wait     time.Duration
+	maxSpans int
+	policies []tailPolicy
+	baseline *rateLimiter // nil if the traces matched by no policy are all dropped
+
+	mu      sync.Mutex
+	traces  map[uint64]*tailTrace   // pending traces by trace ID
+	queue   []*tailTrace            // pending traces in order of arrival
+	decided map[uint64]tailDecision // recent decisions by trace ID, applied to late spans
+	nspans  int                     // number of buffered spans
+
+	// closeMu guards the output channel: it is held for reading while traces are
+	// added and for writing once the sampler is stopped.
+	closeMu sync.RWMutex
+	stopped bool
+
+	exit chan struct{}
+	wg   sync.WaitGroup // waits for the flush loop to return
+}
+
+// HeadDecision is the decision taken by the regular samplers on the spans of a trace
+// received in a payload.
+type HeadDecision struct {
+	// Sampled is true if the spans were kept.
+	Sampled bool
+	// Rate is the rate applied to take the decision.
+	Rate float64
+	// Rule is true if the decision was taken by a user-defined sampling rule. Such a
+	// decision is final and the tail policies don't apply to the trace.
+	Rule bool
+}
+
+// tailTrace holds the spans of a trace pending a decision.
+type tailTrace struct {
+	id       uint64
+	spans    pb.Trace
+	seen     time.Time // time of the first span's arrival
+	lastSeen time.Time // time of the last span's arrival
+	complete bool      // true when the root and all parents were received
+	head     HeadDecision
+	done     bool      // true once removed from the buffer
+}
+
+// tailDecision is a sampling decision taken by the tail sampler.
+type tailDecision struct {
+	keep    bool
+	expires time.Time
+}
+
+// NewTailSampler returns a new TailSampler sending kept traces to out.
+func NewTailSampler(conf *config.TailSamplingConfig, out chan<- pb.Trace) *TailSampler {
+	wait := time.Duration(conf.DecisionWait * float64(time.Second))
+	if wait <= 0 {
+		wait = tailFlushInterval
+	}
+	s := &TailSampler{
+		out:      out,
+		wait:     wait,
+		maxSpans: conf.MaxSpans,
+		policies: newTailPolicies(conf),
+		traces:   make(map[uint64]*tailTrace),
+		decided:  make(map[uint64]tailDecision),
+		exit:     make(chan struct{}),
+	}
+	if conf.BaselineTPS > 0 {
+		s.baseline = newRateLimiter(conf.BaselineTPS)
+	}
+	return s
+}
+
+// Start starts the tail sampler.
+func (s *TailSampler) Start() {
+	if s.baseline != nil {
+		s.baseline.Start()
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		t := time.NewTicker(tailFlushInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case now := <-t.C:
+				s.flush(now)
+			case <-s.exit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the tail sampler, taking a decision on all the buffered traces. The
+// traces added afterwards are dropped, so that the output channel can be closed
+// once it returns.
+func (s *TailSampler) Stop() {
+	close(s.exit)
+	s.wg.Wait()
+	if s.baseline != nil {
+		s.baseline.Stop()
+	}
+
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
+	s.stopped = true
+
+	s.mu.Lock()
+	ready := make([]*tailTrace, 0, len(s.traces))
+	for _, tt := range s.traces {
+		ready = append(ready, s.remove(tt))
+	}
+	s.mu.Unlock()
+	s.decide(ready, time.Now())
+}
+
+// Add adds the spans of a trace, or part of a trace, to the buffer along with the
+// decision taken on them by the regular samplers.
+func (s *TailSampler) Add(t pb.Trace, head HeadDecision) {
+	if len(t) == 0 {
+		return
+	}
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.stopped {
+		return
+	}
+	now := time.Now()
+	id := t[0].TraceID
+
+	s.mu.Lock()
+	if d, ok := s.decided[id]; ok {
+		// late spans of a trace which was already decided on
+		s.mu.Unlock()
+		if d.keep {
+			s.out <- t
+		}
+		return
+	}
+	tt, ok := s.traces[id]
+	if !ok {
+		tt = &tailTrace{id: id, seen: now}
+		s.traces[id] = tt
+		s.queue = append(s.queue, tt)
+	}
+	tt.spans = append(tt.spans, t...)
+	switch {
+	case tt.head.Rule:
+		// the first rule decision is final
+	case head.Rule, head.Sampled && !tt.head.Sampled:
+		tt.head = head
+	}
+	tt.lastSeen = now
+	tt.complete = isComplete(tt.spans)
+	s.nspans += len(t)
+
+	var ready []*tailTrace
+	for s.nspans > s.maxSpans && s.maxSpans > 0 && len(s.queue) > 0 {
+		// memory bound reached, decide on the oldest traces now
+		oldest := s.queue[0]
+		s.queue = s.queue[1:]
+		if !oldest.done {
+			ready = append(ready, s.remove(oldest))
+		}
+	}
+	s.mu.Unlock()
+
+	if len(ready) > 0 {
+		metrics.Count("datadog.trace_agent.tail_sampler.evicted", int64(len(ready)), nil, 1)
+		s.decide(ready, now)
+	}
+}
+
+// flush takes a decision on all traces which are complete and idle, or which have
+// been waiting for longer than the decision wait.
+func (s *TailSampler) flush(now time.Time) {
+	var ready []*tailTrace
+
+	s.mu.Lock()
+	for _, tt := range s.traces {
+		if now.Sub(tt.seen) >= s.wait || (tt.complete && now.Sub(tt.lastSeen) >= tailFlushInterval) {
+			ready = append(ready, s.remove(tt))
+		}
+	}
+	queue := s.queue[:0]
+	for _, tt := range s.queue {
+		if !tt.done {
+			queue = append(queue, tt)
+		}
+	}
+	s.queue = queue
+	for id, d := range s.decided {
+		if now.After(d.expires) {
+			delete(s.decided, id)
+		}
+	}
+	ntraces, nspans := len(s.traces), s.nspans
+	s.mu.Unlock()
+
+	metrics.Gauge("datadog.trace_agent.tail_sampler.buffered_traces", float64(ntraces), nil, 1)
+	metrics.Gauge("datadog.trace_agent.tail_sampler.buffered_spans", float64(nspans), nil, 1)
+
+	s.decide(ready, now)
+}
+
+// remove removes tt from the buffer. It must be called with the lock held.
+func (s *TailSampler) remove(tt *tailTrace) *tailTrace {
+	delete(s.traces, tt.id)
+	s.nspans -= len(tt.spans)
+	tt.done = true
+	return tt
+}
+
+// decide takes a sampling decision on each of the given traces and sends the kept
+// ones downstream.
+func (s *TailSampler) decide(traces []*tailTrace, now time.Time) {
+	for _, tt := range traces {
+		root := traceutil.GetRoot(tt.spans)
+		keep, rate, policy := s.sample(tt.spans, root, tt.head)
+
+		s.mu.Lock()
+		s.decided[tt.id] = tailDecision{keep: keep, expires: now.Add(tailDecisionTTLFactor * s.wait)}
+		s.mu.Unlock()
+
+		tags := []string{"policy:" + policy}
+		if !keep {
+			metrics.Count("datadog.trace_agent.tail_sampler.dropped", 1, tags, 1)
+			continue
+		}
+		metrics.Count("datadog.trace_agent.tail_sampler.kept", 1, tags, 1)
+		AddGlobalRate(root, rate)
+		s.out <- tt.spans
+	}
+}
+
+// sample returns whether the trace should be kept, the rate applied to take that
+// decision and the name of the policy which took it. Traces kept by the regular
+// samplers are always kept, the policies only pick among the others.
+func (s *TailSampler) sample(t pb.Trace, root *pb.Span, head HeadDecision) (keep bool, rate float64, policy string) {
+	if head.Rule {
+		return head.Sampled, head.Rate, "sampling_rule"
+	}
+	if priority, ok := GetSamplingPriority(root); ok && priority == PriorityUserKeep {
+		return true, 1, "user_keep"
+	}
+	if head.Sampled {
+		return true, head.Rate, "head"
+	}
+	for _, p := range s.policies {
+		if p.keep(t, root) {
+			return true, 1, p.name()
+		}
+	}
+	if s.baseline == nil {
+		return false, 0, "baseline"
+	}
+	keep, rate = s.baseline.Sample(root.TraceID)
+	return keep, rate, "baseline"
+}
+
+// isComplete returns true if the trace contains its root span and the parents of all
+// its other spans.
+func isComplete(t pb.Trace) bool {
+	ids := make(map[uint64]struct{}, len(t))
+	hasRoot := false
+	for _, span := range t {
+		ids[span.SpanID] = struct{}{}
+		if span.ParentID == 0 {
+			hasRoot = true
+		}
+	}
+	if !hasRoot {
+		return false
+	}
+	for _, span := range t {
+		if span.ParentID == 0 {
+			continue
+		}
+		if _, ok := ids[span.ParentID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// tailPolicy decides whether a whole trace should be kept.
+type tailPolicy interface {
+	name() string
+	keep(t pb.Trace, root *pb.Span) bool
+}
+
+// newTailPolicies returns the policies enabled by the given configuration.
+func newTailPolicies(conf *config.TailSamplingConfig) []tailPolicy {
+	var policies []tailPolicy
+	if conf.KeepErrors {
+		policies = append(policies, errorsPolicy{})
+	}
+	if len(conf.Durations) > 0 {
+		p := make(durationPolicy, len(conf.Durations))
+		for _, d := range conf.Durations {
+			if d.Service == "" || d.Threshold <= 0 {
+				log.Warnf("Ignoring invalid tail sampling duration threshold %q: %.0fms", d.Service, d.Threshold)
+				continue
+			}
+			p[d.Service] = int64(d.Threshold * float64(time.Millisecond))
+		}
+		policies = append(policies, p)
+	}
+	if len(conf.Tags) > 0 {
+		p := make(tagPolicy, 0, len(conf.Tags))
+		for _, tv := range conf.Tags {
+			p = append(p, *tv)
+		}
+		policies = append(policies, p)
+	}
+	return policies
+}
+
+// errorsPolicy keeps traces containing at least one error.
+type errorsPolicy struct{}
+
+func (errorsPolicy) name() string { return "errors" }
+
+func (errorsPolicy) keep(t pb.Trace, _ *pb.Span) bool {
+	for _, span := range t {
+		if span.Error != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// durationPolicy keeps traces whose root span lasts longer than the threshold (in
+// nanoseconds) of its service.
+type durationPolicy map[string]int64
+
+func (durationPolicy) name() string { return "duration" }
+
+func (p durationPolicy) keep(_ pb.Trace, root *pb.Span) bool {
+	threshold, ok := p[root.Service]
+	return ok && root.Duration >= threshold
+}
+
+// tagPolicy keeps traces containing a span with any of the given tag values.
+type tagPolicy []config.TagValue
+
+func (tagPolicy) name() string { return "tags" }
+
+func (p tagPolicy) keep(t pb.Trace, _ *pb.Span) bool {
+	for _, span := range t {
+		for _, tv := range p {
+			if v, ok := span.Meta[tv.Key]; ok && v == tv.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTailSampler(conf *config.TailSamplingConfig) (*TailSampler, chan pb.Trace) {
+	out := make(chan pb.Trace, 100)
+	return NewTailSampler(conf, out), out
+}
+
+// tailTestTrace returns a trace made of a root span for service and a child span.
+func tailTestTrace(id uint64, service string, duration int64) pb.Trace {
+	return pb.Trace{
+		&pb.Span{TraceID: id, SpanID: 1, Service: service, Name: "web.request", Duration: duration},
+		&pb.Span{TraceID: id, SpanID: 2, ParentID: 1, Service: "db", Name: "db.query", Duration: duration / 2},
+	}
+}
+
+func receiveTrace(t *testing.T, out chan pb.Trace) pb.Trace {
+	select {
+	case tr := <-out:
+		return tr
+	default:
+		t.Fatal("expected a kept trace")
+	}
+	return nil
+}
+
+func assertNoTrace(t *testing.T, out chan pb.Trace) {
+	select {
+	case tr := <-out:
+		t.Fatalf("unexpected kept trace: %v", tr)
+	default:
+	}
+}
+
+func TestTailSamplerPolicies(t *testing.T) {
+	conf := &config.TailSamplingConfig{
+		DecisionWait: 10,
+		KeepErrors:   true,
+		Durations:    []*config.DurationThreshold{{Service: "web", Threshold: 500}},
+		Tags:         []*config.TagValue{{Key: "http.status_code", Value: "429"}},
+		BaselineTPS:  1e9,
+	}
+	s, _ := newTestTailSampler(conf)
+
+	for name, tt := range map[string]struct {
+		trace  pb.Trace
+		policy string
+	}{
+		"error": {
+			trace: func() pb.Trace {
+				tr := tailTestTrace(1, "api", 1e6)
+				tr[1].Error = 1
+				return tr
+			}(),
+			policy: "errors",
+		},
+		"slow": {
+			trace:  tailTestTrace(2, "web", 600*1e6),
+			policy: "duration",
+		},
+		"fast": {
+			trace:  tailTestTrace(3, "web", 400*1e6),
+			policy: "baseline",
+		},
+		"other-service": {
+			trace:  tailTestTrace(4, "api", 600*1e6),
+			policy: "baseline",
+		},
+		"tag": {
+			trace: func() pb.Trace {
+				tr := tailTestTrace(5, "api", 1e6)
+				tr[1].Meta = map[string]string{"http.status_code": "429"}
+				return tr
+			}(),
+			policy: "tags",
+		},
+		"user-keep": {
+			trace: func() pb.Trace {
+				tr := tailTestTrace(6, "api", 1e6)
+				SetSamplingPriority(tr[0], PriorityUserKeep)
+				return tr
+			}(),
+			policy: "user_keep",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			keep, rate, policy := s.sample(tt.trace, tt.trace[0], HeadDecision{})
+			assert.True(t, keep)
+			assert.Equal(t, 1., rate)
+			assert.Equal(t, tt.policy, policy)
+		})
+	}
+}
+
+func TestTailSamplerWaitsForLateSpans(t *testing.T) {
+	assert := assert.New(t)
+	s, out := newTestTailSampler(&config.TailSamplingConfig{DecisionWait: 10, KeepErrors: true})
+
+	tr := tailTestTrace(42, "web", 1e6)
+	late := &pb.Span{TraceID: 42, SpanID: 3, ParentID: 2, Service: "cache", Error: 1}
+
+	s.Add(pb.Trace{tr[1]}, HeadDecision{})
+	s.flush(time.Now())
+	assertNoTrace(t, out)
+
+	s.Add(pb.Trace{tr[0]}, HeadDecision{})
+	s.flush(time.Now())
+	assertNoTrace(t, out) // complete, but not idle for long enough yet
+
+	s.Add(pb.Trace{late}, HeadDecision{})
+	s.flush(time.Now().Add(2 * tailFlushInterval))
+	kept := receiveTrace(t, out)
+	assert.Len(kept, 3)
+	assert.Equal(0, s.nspans)
+	assert.Len(s.traces, 0)
+
+	// spans arriving after the decision follow it, even after the decision wait
+	s.flush(time.Now().Add(20 * time.Second))
+	s.Add(pb.Trace{&pb.Span{TraceID: 42, SpanID: 4, ParentID: 1}}, HeadDecision{})
+	assert.Len(receiveTrace(t, out), 1)
+	assert.Len(s.traces, 0)
+}
+
+func TestTailSamplerDecisionWait(t *testing.T) {
+	assert := assert.New(t)
+	s, out := newTestTailSampler(&config.TailSamplingConfig{DecisionWait: 5, KeepErrors: true})
+
+	orphan := &pb.Span{TraceID: 7, SpanID: 2, ParentID: 1, Error: 1}
+	s.Add(pb.Trace{orphan}, HeadDecision{})
+	s.flush(time.Now().Add(4 * time.Second))
+	assertNoTrace(t, out)
+
+	s.flush(time.Now().Add(5 * time.Second))
+	assert.Equal(pb.Trace{orphan}, receiveTrace(t, out))
+}
+
+func TestTailSamplerMaxSpans(t *testing.T) {
+	assert := assert.New(t)
+	s, out := newTestTailSampler(&config.TailSamplingConfig{DecisionWait: 10, MaxSpans: 3, KeepErrors: true})
+
+	first := &pb.Span{TraceID: 1, SpanID: 2, ParentID: 1, Error: 1}
+	s.Add(pb.Trace{first}, HeadDecision{})
+	s.Add(pb.Trace{&pb.Span{TraceID: 2, SpanID: 2, ParentID: 1}}, HeadDecision{})
+	s.Add(pb.Trace{&pb.Span{TraceID: 3, SpanID: 2, ParentID: 1}}, HeadDecision{})
+	assertNoTrace(t, out)
+
+	// going over the limit evicts the oldest trace
+	s.Add(pb.Trace{&pb.Span{TraceID: 4, SpanID: 2, ParentID: 1}}, HeadDecision{})
+	assert.Equal(pb.Trace{first}, receiveTrace(t, out))
+	assert.Equal(3, s.nspans)
+	assert.Len(s.traces, 3)
+}
+
+func TestTailSamplerBaselineRate(t *testing.T) {
+	assert := assert.New(t)
+	s, out := newTestTailSampler(&config.TailSamplingConfig{DecisionWait: 10, BaselineTPS: 1})
+
+	var kept int
+	for i := uint64(1); i <= 1000; i++ {
+		s.Add(tailTestTrace(i*samplerHasher, "web", 1e6), HeadDecision{})
+	}
+	s.flush(time.Now().Add(20 * time.Second))
+	for len(out) > 0 {
+		tr := <-out
+		rate := GetGlobalRate(tr[0])
+		assert.True(rate > 0 && rate <= 1, "kept rate %f must be recorded on the root span", rate)
+		kept++
+	}
+	assert.True(kept > 0 && kept < 1000, "baseline kept %d traces", kept)
+}
+
+func TestTailSamplerNoBaseline(t *testing.T) {
+	assert := assert.New(t)
+	s, out := newTestTailSampler(&config.TailSamplingConfig{DecisionWait: 10, KeepErrors: true})
+
+	tr := tailTestTrace(1, "web", 1e6)
+	keep, rate, policy := s.sample(tr, tr[0], HeadDecision{})
+	assert.False(keep)
+	assert.Equal(0., rate)
+	assert.Equal("baseline", policy)
+
+	s.Add(tr, HeadDecision{})
+	s.flush(time.Now().Add(2 * tailFlushInterval))
+	assertNoTrace(t, out)
+}
+
+func TestTailSamplerHeadDecision(t *testing.T) {
+	assert := assert.New(t)
+	s, out := newTestTailSampler(&config.TailSamplingConfig{DecisionWait: 10, KeepErrors: true, BaselineTPS: 1e9})
+
+	// a part of the trace kept by the regular samplers keeps the whole trace
+	tr := tailTestTrace(1, "web", 1e6)
+	s.Add(pb.Trace{tr[0]}, HeadDecision{Sampled: true, Rate: 0.5})
+	s.Add(pb.Trace{tr[1]}, HeadDecision{Rate: 0.5})
+	s.flush(time.Now().Add(2 * tailFlushInterval))
+	kept := receiveTrace(t, out)
+	assert.Len(kept, 2)
+	assert.Equal(0.5, GetGlobalRate(kept[0]))
+
+	// the traces dropped by a sampling rule are not kept by the policies
+	tr = tailTestTrace(2, "web", 1e6)
+	tr[1].Error = 1
+	s.Add(pb.Trace{tr[0]}, HeadDecision{Rule: true})
+	s.Add(pb.Trace{tr[1]}, HeadDecision{Sampled: true, Rate: 1})
+	s.flush(time.Now().Add(2 * tailFlushInterval))
+	assertNoTrace(t, out)
+
+	// the policies pick among the traces dropped by the regular samplers
+	tr = tailTestTrace(3, "web", 1e6)
+	tr[1].Error = 1
+	s.Add(tr, HeadDecision{Rate: 0.1})
+	s.flush(time.Now().Add(2 * tailFlushInterval))
+	assert.Len(receiveTrace(t, out), 2)
+}
+
+func TestTailSamplerStop(t *testing.T) {
+	assert := assert.New(t)
+	s, out := newTestTailSampler(&config.TailSamplingConfig{DecisionWait: 10, KeepErrors: true})
+	s.Start()
+
+	tr := tailTestTrace(1, "web", 1e6)
+	tr[1].Error = 1
+	s.Add(tr, HeadDecision{})
+	s.Stop()
+	assert.Len(receiveTrace(t, out), 2)
+
+	// traces added once stopped are dropped, the output channel can be closed
+	close(out)
+	s.Add(tailTestTrace(2, "web", 1e6), HeadDecision{Sampled: true})
+	assert.Len(s.traces, 0)
+}
+
+func TestTailSamplerStopNotStarted(t *testing.T) {
+	s, out := newTestTailSampler(&config.TailSamplingConfig{DecisionWait: 10, BaselineTPS: 10})
+
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stopping a tail sampler which was not started blocked")
+	}
+	assertNoTrace(t, out)
+}
+
+func TestIsComplete(t *testing.T) {
+	assert := assert.New(t)
+
+	tr := tailTestTrace(1, "web", 1e6)
+	assert.True(isComplete(tr))
+	assert.False(isComplete(tr[1:]), "missing root")
+	assert.False(isComplete(append(tr, &pb.Span{TraceID: 1, SpanID: 5, ParentID: 4})), "missing parent")
+}
@@ -2,6 +2,7 @@ package stats
 
 import (
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,6 +22,8 @@ const defaultBufferLen = 2
 type Concentrator struct {
 	// list of attributes to use for extra aggregation
 	aggregators []string
+	// list of container tag names to use for extra aggregation
+	containerAggregators []string
 	// bucket duration in nanoseconds
 	bsize int64
 	// Timestamp of the oldest time bucket for which we allow data.
@@ -41,12 +44,14 @@ type Concentrator struct {
 	mu      sync.Mutex
 }
 
-// NewConcentrator initializes a new concentrator ready to be started
-func NewConcentrator(aggregators []string, bsize int64, out chan []Bucket) *Concentrator {
+// NewConcentrator initializes a new concentrator ready to be started. Stats are aggregated
+// by the span tags named in aggregators and by the container tags named in containerAggregators.
+func NewConcentrator(aggregators, containerAggregators []string, bsize int64, out chan []Bucket) *Concentrator {
 	c := Concentrator{
-		aggregators: aggregators,
-		bsize:       bsize,
-		buckets:     make(map[int64]*RawBucket),
+		aggregators:          aggregators,
+		containerAggregators: containerAggregators,
+		bsize:                bsize,
+		buckets:              make(map[int64]*RawBucket),
 		// At start, only allow stats for the current time bucket. Ensure we don't
 		// override buckets which could have been sent before an Agent restart.
 		oldestTs: alignTs(time.Now().UnixNano(), bsize),
@@ -108,6 +113,9 @@ type Input struct {
 	Trace     WeightedTrace
 	Sublayers SublayerMap
 	Env       string
+
+	// ContainerTags holds the comma-separated tags of the container which sent the trace.
+	ContainerTags string
 }
 
 // Add appends to the proper stats bucket this trace's statistics
@@ -116,6 +124,8 @@ func (c *Concentrator) Add(i *Input) {
 }
 
 func (c *Concentrator) addNow(i *Input, now int64) {
+	ctags := containerTagValues(i.ContainerTags, c.containerAggregators)
+
 	c.mu.Lock()
 
 	for _, s := range i.Trace {
@@ -138,7 +148,7 @@ func (c *Concentrator) addNow(i *Input, now int64) {
 		}
 
 		subs, _ := i.Sublayers[s.Span]
-		b.HandleSpan(s, i.Env, c.aggregators, subs)
+		b.handleSpan(s, i.Env, c.aggregators, ctags, subs)
 	}
 
 	c.mu.Unlock()
@@ -178,6 +188,29 @@ func (c *Concentrator) flushNow(now int64) []Bucket {
 	return sb
 }
 
+// containerTagValues returns the values of the container tags named in aggregators,
+// found in the comma-separated list of tags ctags.
+func containerTagValues(ctags string, aggregators []string) map[string]string {
+	if ctags == "" || len(aggregators) == 0 {
+		return nil
+	}
+	var m map[string]string
+	for _, tag := range strings.Split(ctags, ",") {
+		name, value := SplitTag(tag)
+		for _, agg := range aggregators {
+			if name != agg {
+				continue
+			}
+			if m == nil {
+				m = make(map[string]string, len(aggregators))
+			}
+			m[name] = value
+			break
+		}
+	}
+	return m
+}
+
 // alignTs returns the provided timestamp truncated to the bucket size.
 // It gives us the start time of the time bucket in which such timestamp falls.
 func alignTs(ts int64, bsize int64) int64 {
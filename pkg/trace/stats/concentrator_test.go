@@ -16,7 +16,7 @@ var testBucketInterval = time.Duration(2 * time.Second).Nanoseconds()
 
 func NewTestConcentrator() *Concentrator {
 	statsChan := make(chan []Bucket)
-	return NewConcentrator([]string{}, time.Second.Nanoseconds(), statsChan)
+	return NewConcentrator([]string{}, nil, time.Second.Nanoseconds(), statsChan)
 }
 
 // getTsInBucket gives a timestamp in ns which is `offset` buckets late
@@ -72,7 +72,7 @@ func TestConcentratorOldestTs(t *testing.T) {
 	t.Run("cold", func(t *testing.T) {
 		// Running cold, all spans in the past should end up in the current time bucket.
 		flushTime := now
-		c := NewConcentrator([]string{}, testBucketInterval, statsChan)
+		c := NewConcentrator([]string{}, nil, testBucketInterval, statsChan)
 		c.Add(testTrace)
 
 		for i := 0; i < c.bufferLen; i++ {
@@ -102,7 +102,7 @@ func TestConcentratorOldestTs(t *testing.T) {
 
 	t.Run("hot", func(t *testing.T) {
 		flushTime := now
-		c := NewConcentrator([]string{}, testBucketInterval, statsChan)
+		c := NewConcentrator([]string{}, nil, testBucketInterval, statsChan)
 		c.oldestTs = alignTs(now, c.bsize) - int64(c.bufferLen-1)*c.bsize
 		c.Add(testTrace)
 
@@ -152,7 +152,7 @@ func TestConcentratorOldestTs(t *testing.T) {
 func TestConcentratorStatsTotals(t *testing.T) {
 	assert := assert.New(t)
 	statsChan := make(chan []Bucket)
-	c := NewConcentrator([]string{}, testBucketInterval, statsChan)
+	c := NewConcentrator([]string{}, nil, testBucketInterval, statsChan)
 
 	now := time.Now().UnixNano()
 	alignedNow := alignTs(now, c.bsize)
@@ -210,7 +210,7 @@ func TestConcentratorStatsTotals(t *testing.T) {
 func TestConcentratorStatsCounts(t *testing.T) {
 	assert := assert.New(t)
 	statsChan := make(chan []Bucket)
-	c := NewConcentrator([]string{}, testBucketInterval, statsChan)
+	c := NewConcentrator([]string{}, nil, testBucketInterval, statsChan)
 
 	now := time.Now().UnixNano()
 	alignedNow := alignTs(now, c.bsize)
@@ -333,7 +333,7 @@ func TestConcentratorStatsCounts(t *testing.T) {
 func TestConcentratorSublayersStatsCounts(t *testing.T) {
 	assert := assert.New(t)
 	statsChan := make(chan []Bucket)
-	c := NewConcentrator([]string{}, testBucketInterval, statsChan)
+	c := NewConcentrator([]string{}, nil, testBucketInterval, statsChan)
 
 	now := time.Now().UnixNano()
 	alignedNow := now - now%c.bsize
@@ -415,3 +415,38 @@ func TestConcentratorSublayersStatsCounts(t *testing.T) {
 		assert.Equal(val, int64(count.Value), "Wrong value for count %s", key)
 	}
 }
+
+// TestConcentratorContainerTags tests that the configured container tags are added to the
+// aggregation dimensions.
+func TestConcentratorContainerTags(t *testing.T) {
+	assert := assert.New(t)
+	statsChan := make(chan []Bucket)
+	c := NewConcentrator([]string{"http.status_code"}, []string{"kube_deployment", "image_tag"}, testBucketInterval, statsChan)
+
+	now := time.Now().UnixNano()
+	span := testSpan(1, 0, 50, 5, "A1", "resource1", 0)
+	span.Meta = map[string]string{"http.status_code": "200"}
+	trace := pb.Trace{span}
+	traceutil.ComputeTopLevel(trace)
+
+	c.addNow(&Input{
+		Env:           "none",
+		Trace:         NewWeightedTrace(trace, span),
+		ContainerTags: "kube_deployment:web,kube_namespace:default,image_tag:1.0",
+	}, now)
+
+	stats := c.flushNow(now + int64(c.bufferLen)*c.bsize)
+	if !assert.Len(stats, 1) {
+		return
+	}
+	_, ok := stats[0].Counts["query|hits|env:none,resource:resource1,service:A1,http.status_code:200,image_tag:1.0,kube_deployment:web"]
+	assert.True(ok, "GOT %v", stats[0].Counts)
+}
+
+func TestContainerTagValues(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(containerTagValues("", []string{"image_tag"}))
+	assert.Nil(containerTagValues("image_tag:1.0", nil))
+	assert.Nil(containerTagValues("image_tag:1.0", []string{"kube_deployment"}))
+	assert.Equal(map[string]string{"image_tag": "1.0"}, containerTagValues("kube_namespace:default,image_tag:1.0", []string{"kube_deployment", "image_tag"}))
+}
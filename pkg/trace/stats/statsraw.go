@@ -185,11 +185,20 @@ func assembleGrain(b *bytes.Buffer, env, resource, service string, m map[string]
 
 // HandleSpan adds the span to this bucket stats, aggregated with the finest grain matching given aggregators
 func (sb *RawBucket) HandleSpan(s *WeightedSpan, env string, aggregators []string, sublayers []SublayerValue) {
+	sb.handleSpan(s, env, aggregators, nil, sublayers)
+}
+
+// handleSpan is like HandleSpan, with the extra dimensions of extraTags added to the grain
+// unless the span has a tag of the same name amongst the aggregators.
+func (sb *RawBucket) handleSpan(s *WeightedSpan, env string, aggregators []string, extraTags map[string]string, sublayers []SublayerValue) {
 	if env == "" {
 		panic("env should never be empty")
 	}
 
-	m := make(map[string]string)
+	m := make(map[string]string, len(extraTags))
+	for k, v := range extraTags {
+		m[k] = v
+	}
 
 	for _, agg := range aggregators {
 		if agg != "env" && agg != "resource" && agg != "service" {
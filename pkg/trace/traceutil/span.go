@@ -8,6 +8,10 @@ const (
 	// [FIXME] *not implemented yet*
 	TraceMetricsKey = "datadog.trace_metrics"
 
+	// ContainerTagsKey is the meta key holding the comma-separated tags of the
+	// container which emitted the trace. It is set on the root span.
+	ContainerTagsKey = "_dd.tags.container"
+
 	// This is a special metric, it's 1 if the span is top-level, 0 if not.
 	topLevelKey = "_top_level"
 )
@@ -36,6 +40,14 @@ func SetTopLevel(s *pb.Span, topLevel bool) {
 	setMetric(s, topLevelKey, 1)
 }
 
+// SetMeta sets the metadata key of the span to val.
+func SetMeta(s *pb.Span, key, val string) {
+	if s.Meta == nil {
+		s.Meta = make(map[string]string)
+	}
+	s.Meta[key] = val
+}
+
 func setMetric(s *pb.Span, key string, val float64) {
 	if s.Metrics == nil {
 		s.Metrics = make(map[string]float64)
@@ -166,3 +166,16 @@ func TestForceMetrics(t *testing.T) {
 	span.Meta = map[string]string{"env": "dev"}
 	assert.False(HasForceMetrics(span), "there's a tag, but metrics should not be enforced anyway")
 }
+
+func TestSetMeta(t *testing.T) {
+	assert := assert.New(t)
+
+	span := &pb.Span{}
+	SetMeta(span, "container_name", "redis")
+	assert.Equal("redis", span.Meta["container_name"])
+
+	span.Meta = map[string]string{"env": "dev"}
+	SetMeta(span, "container_name", "web")
+	assert.Equal("web", span.Meta["container_name"])
+	assert.Equal("dev", span.Meta["env"], "former meta should still be here")
+}
@@ -9,6 +9,8 @@ package containerd
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"sync"
 	"time"
 
@@ -18,7 +20,9 @@ import (
 	"github.com/containerd/containerd"
 	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/content"
 	"github.com/containerd/containerd/namespaces"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 const (
@@ -37,10 +41,12 @@ type ContainerdItf interface {
 	Containers() ([]containerd.Container, error)
 	GetEvents() containerd.EventService
 	Info(ctn containerd.Container) (containers.Container, error)
+	ImageConfig(ctn containerd.Container) (ocispec.ImageConfig, error)
 	ImageSize(ctn containerd.Container) (int64, error)
 	Metadata() (containerd.Version, error)
 	Namespace() string
 	TaskMetrics(ctn containerd.Container) (*types.Metric, error)
+	TaskPid(ctn containerd.Container) (uint32, error)
 }
 
 // ContainerdUtil is the util used to interact with the Containerd api.
@@ -152,6 +158,32 @@ func (c *ContainerdUtil) ImageSize(ctn containerd.Container) (int64, error) {
 	return img.Size(ctxNamespace)
 }
 
+// ImageConfig interfaces with the containerd api to get the configuration of the image of a container,
+// with its exposed ports
+func (c *ContainerdUtil) ImageConfig(ctn containerd.Container) (ocispec.ImageConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.queryTimeout)
+	defer cancel()
+	ctxNamespace := namespaces.WithNamespace(ctx, c.namespace)
+
+	img, err := ctn.Image(ctxNamespace)
+	if err != nil {
+		return ocispec.ImageConfig{}, err
+	}
+	desc, err := img.Config(ctxNamespace)
+	if err != nil {
+		return ocispec.ImageConfig{}, err
+	}
+	blob, err := content.ReadBlob(ctxNamespace, img.ContentStore(), desc)
+	if err != nil {
+		return ocispec.ImageConfig{}, err
+	}
+	var spec ocispec.Image
+	if err := json.Unmarshal(blob, &spec); err != nil {
+		return ocispec.ImageConfig{}, err
+	}
+	return spec.Config, nil
+}
+
 // Info interfaces with the containerd api to get Container info
 func (c *ContainerdUtil) Info(ctn containerd.Container) (containers.Container, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), c.queryTimeout)
@@ -174,3 +206,34 @@ func (c *ContainerdUtil) TaskMetrics(ctn containerd.Container) (*types.Metric, e
 
 	return t.Metrics(ctxNamespace)
 }
+
+// TaskPid interfaces with the containerd api to get the pid of the task of a container.
+// It returns an error if the container isn't running.
+func (c *ContainerdUtil) TaskPid(ctn containerd.Container) (uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.queryTimeout)
+	defer cancel()
+	ctxNamespace := namespaces.WithNamespace(ctx, c.namespace)
+
+	t, err := ctn.Task(ctxNamespace, nil)
+	if err != nil {
+		return 0, err
+	}
+	status, err := t.Status(ctxNamespace)
+	if err != nil {
+		return 0, err
+	}
+	if status.Status != containerd.Running {
+		return 0, fmt.Errorf("the task is %s", status.Status)
+	}
+	return t.Pid(), nil
+}
+
+// TaskLifecycleFilters returns the event filters of the tasks started and
+// stopped in a namespace, and of the containers deleted
+func TaskLifecycleFilters(namespace string) []string {
+	var filters []string
+	for _, topic := range []string{"/tasks/start", "/tasks/exit", "/tasks/delete", "/containers/delete"} {
+		filters = append(filters, fmt.Sprintf(`topic==%q,namespace==%q`, topic, namespace))
+	}
+	return filters
+}
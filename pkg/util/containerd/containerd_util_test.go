@@ -8,6 +8,7 @@
 package containerd
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -18,8 +19,10 @@ import (
 	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/cio"
 	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/content"
 	"github.com/containerd/typeurl"
 	prototypes "github.com/gogo/protobuf/types"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/require"
 )
 
@@ -54,6 +57,18 @@ func (cs *mockContainer) Info(context.Context) (containers.Container, error) {
 type mockTaskStruct struct {
 	containerd.Task
 	mockMectric func(ctx context.Context) (*types.Metric, error)
+	pid         uint32
+	status      containerd.ProcessStatus
+}
+
+// Pid is from the containerd.Task interface
+func (t *mockTaskStruct) Pid() uint32 {
+	return t.pid
+}
+
+// Status is from the containerd.Task interface
+func (t *mockTaskStruct) Status(ctx context.Context) (containerd.Status, error) {
+	return containerd.Status{Status: t.status}, nil
 }
 
 // Metrics is from the containerd.Task interface
@@ -64,6 +79,7 @@ func (t *mockTaskStruct) Metrics(ctx context.Context) (*types.Metric, error) {
 type mockImage struct {
 	imageName string
 	size      int64
+	config    []byte
 	containerd.Image
 }
 
@@ -72,6 +88,35 @@ func (i *mockImage) Size(ctx context.Context) (int64, error) {
 	return i.size, nil
 }
 
+// Config is from the Image interface
+func (i *mockImage) Config(ctx context.Context) (ocispec.Descriptor, error) {
+	return ocispec.Descriptor{Size: int64(len(i.config))}, nil
+}
+
+// ContentStore is from the Image interface
+func (i *mockImage) ContentStore() content.Store {
+	return &mockContentStore{blob: i.config}
+}
+
+type mockContentStore struct {
+	content.Store
+	blob []byte
+}
+
+// ReaderAt is from the content.Provider interface
+func (s *mockContentStore) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (content.ReaderAt, error) {
+	return &mockReaderAt{bytes.NewReader(s.blob)}, nil
+}
+
+type mockReaderAt struct {
+	*bytes.Reader
+}
+
+// Close is from the content.ReaderAt interface
+func (r *mockReaderAt) Close() error {
+	return nil
+}
+
 func TestInfo(t *testing.T) {
 	mockUtil := ContainerdUtil{}
 	cs := &mockContainer{
@@ -104,6 +149,49 @@ func TestImageSize(t *testing.T) {
 	require.Equal(t, int64(12), c)
 }
 
+func TestImageConfig(t *testing.T) {
+	mockUtil := ContainerdUtil{}
+
+	cs := &mockContainer{
+		mockImage: func() (containerd.Image, error) {
+			return &mockImage{
+				config: []byte(`{"architecture":"amd64","os":"linux","config":{"ExposedPorts":{"6379/tcp":{}}}}`),
+			}, nil
+		},
+	}
+	ctn := containerd.Container(cs)
+	c, err := mockUtil.ImageConfig(ctn)
+	require.NoError(t, err)
+	require.Equal(t, map[string]struct{}{"6379/tcp": {}}, c.ExposedPorts)
+}
+
+func TestTaskPid(t *testing.T) {
+	mockUtil := ContainerdUtil{}
+
+	for _, status := range []containerd.ProcessStatus{containerd.Running, containerd.Stopped} {
+		cs := &mockContainer{
+			mockTask: func() (containerd.Task, error) {
+				return &mockTaskStruct{pid: 42, status: status}, nil
+			},
+		}
+		pid, err := mockUtil.TaskPid(containerd.Container(cs))
+		if status == containerd.Running {
+			require.NoError(t, err)
+			require.Equal(t, uint32(42), pid)
+		} else {
+			require.Error(t, err)
+		}
+	}
+
+	cs := &mockContainer{
+		mockTask: func() (containerd.Task, error) {
+			return nil, fmt.Errorf("no running task found")
+		},
+	}
+	_, err := mockUtil.TaskPid(containerd.Container(cs))
+	require.Error(t, err)
+}
+
 func TestTaskMetrics(t *testing.T) {
 	mockUtil := ContainerdUtil{}
 	typeurl.Register(&cgroups.Metrics{}, "io.containerd.cgroups.v1.Metrics") // Need to register the type to be used in UnmarshalAny later on.
@@ -88,6 +88,10 @@ func (c *Container) FillCgroupMetrics() error {
 	if err != nil {
 		return fmt.Errorf("thread count: %s", err)
 	}
+	c.Pressure, err = c.cgroup.Pressure()
+	if err != nil {
+		return fmt.Errorf("pressure: %s", err)
+	}
 
 	return nil
 }
@@ -4,3 +4,26 @@
 // Copyright 2016-2019 Datadog, Inc.
 
 package cri
+
+import (
+	"encoding/json"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// pidFromVerboseInfo returns the pid of a container from the verbose info of
+// its status, a JSON object with a pid field for containerd and CRI-O, or 0
+func pidFromVerboseInfo(info map[string]string) int {
+	raw, found := info["info"]
+	if !found {
+		return 0
+	}
+	var verbose struct {
+		Pid int `json:"pid"`
+	}
+	if err := json.Unmarshal([]byte(raw), &verbose); err != nil {
+		log.Debugf("Could not parse the verbose info of a container: %s", err)
+		return 0
+	}
+	return verbose.Pid
+}
@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package cri
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPidFromVerboseInfo(t *testing.T) {
+	for _, tc := range []struct {
+		info map[string]string
+		pid  int
+	}{
+		{map[string]string{"info": `{"sandboxID":"abc","pid":4242,"removing":false}`}, 4242},
+		{map[string]string{"info": `{"sandboxID":"abc"}`}, 0},
+		{map[string]string{"info": `not json`}, 0},
+		{map[string]string{}, 0},
+		{nil, 0},
+	} {
+		assert.Equal(t, tc.pid, pidFromVerboseInfo(tc.info), "%v", tc.info)
+	}
+}
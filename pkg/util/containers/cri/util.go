@@ -114,3 +114,41 @@ func (c *CRIUtil) ListContainerStats() (map[string]*pb.ContainerStats, error) {
 	}
 	return stats, nil
 }
+
+// ListContainers sends a ListContainersRequest to the server, and returns the running containers
+func (c *CRIUtil) ListContainers() ([]*pb.Container, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.queryTimeout)
+	defer cancel()
+	filter := &pb.ContainerFilter{State: &pb.ContainerStateValue{State: pb.ContainerState_CONTAINER_RUNNING}}
+	request := &pb.ListContainersRequest{Filter: filter}
+	r, err := c.client.ListContainers(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetContainers(), nil
+}
+
+// GetContainerStatus sends a verbose ContainerStatusRequest to the server, and returns
+// the status of the container with its pid, 0 if the runtime doesn't report it
+func (c *CRIUtil) GetContainerStatus(containerID string) (*pb.ContainerStatus, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.queryTimeout)
+	defer cancel()
+	request := &pb.ContainerStatusRequest{ContainerId: containerID, Verbose: true}
+	r, err := c.client.ContainerStatus(ctx, request)
+	if err != nil {
+		return nil, 0, err
+	}
+	return r.GetStatus(), pidFromVerboseInfo(r.GetInfo()), nil
+}
+
+// GetPodSandboxStatus sends a PodSandboxStatusRequest to the server, and returns the status of the pod
+func (c *CRIUtil) GetPodSandboxStatus(podSandboxID string) (*pb.PodSandboxStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.queryTimeout)
+	defer cancel()
+	request := &pb.PodSandboxStatusRequest{PodSandboxId: podSandboxID}
+	r, err := c.client.PodSandboxStatus(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetStatus(), nil
+}
@@ -50,6 +50,22 @@ func TestCRIUtilListContainerStats(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestCRIUtilListContainers(t *testing.T) {
+	fakeRuntime, endpoint := createAndStartFakeRemoteRuntime(t)
+	defer fakeRuntime.Stop()
+	socketFile := endpoint[7:] // remove unix://
+	util := &CRIUtil{
+		queryTimeout:      1 * time.Second,
+		connectionTimeout: 1 * time.Second,
+		socketPath:        socketFile,
+	}
+	err := util.init()
+	require.NoError(t, err)
+	containers, err := util.ListContainers()
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+}
+
 // createAndStartFakeRemoteRuntime creates and starts fakeremote.RemoteRuntime.
 // It returns the RemoteRuntime, endpoint on success.
 // Users should call fakeRuntime.Stop() to cleanup the server.
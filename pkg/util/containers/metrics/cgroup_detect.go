@@ -32,10 +32,29 @@ var (
 	dindCgroupRe = regexp.MustCompile("^\\/docker\\/[0-9a-f]{64}(\\/docker\\/[0-9a-f]{64})")
 )
 
+// unifiedHierarchy is the target under which the mount point and the paths of the
+// cgroup v2 unified hierarchy are stored in ContainerCgroup.
+const unifiedHierarchy = "unified"
+
+// isCgroupV2 returns true if the stats of the cgroup are to be read from the cgroup v2
+// unified hierarchy. In hybrid setups, where both hierarchies are mounted, the controllers
+// are attached to the v1 hierarchies and those are used.
+func (c ContainerCgroup) isCgroupV2() bool {
+	if _, ok := c.Mounts[unifiedHierarchy]; !ok {
+		return false
+	}
+	_, v1 := c.Mounts["memory"]
+	return !v1
+}
+
 // ContainerStartTime gets the stat for cgroup directory and use the mtime for that dir to determine the start time for the container
 // this should work because the cgroup dir for the container would be created only when it's started
 func (c ContainerCgroup) ContainerStartTime() (int64, error) {
-	cgroupDir := c.cgroupFilePath("cpuacct", "")
+	target := "cpuacct"
+	if c.isCgroupV2() {
+		target = unifiedHierarchy
+	}
+	cgroupDir := c.cgroupFilePath(target, "")
 	if !pathExists(cgroupDir) {
 		return 0, fmt.Errorf("could not get cgroup dir, directory doesn't exist")
 	}
@@ -85,7 +104,11 @@ func (c ContainerCgroup) cgroupFilePath(target, file string) string {
 //	 cgroup /sys/fs/cgroup/perf_event cgroup rw,relatime,perf_event 0 0
 //	 cgroup /sys/fs/cgroup/hugetlb cgroup rw,relatime,hugetlb 0 0
 //
-// Returns a map for every target (cpuset, cpu, cpuacct) => path
+// On hosts using the cgroup v2 unified hierarchy, a single cgroup2 mount holds all
+// the controllers:
+//	 cgroup2 /sys/fs/cgroup cgroup2 rw,nosuid,nodev,noexec,relatime,nsdelegate 0 0
+//
+// Returns a map for every target (cpuset, cpu, cpuacct, unified) => path
 func cgroupMountPoints() (map[string]string, error) {
 	mountsFile := "/proc/mounts"
 	if !pathExists(mountsFile) {
@@ -106,6 +129,13 @@ func parseCgroupMountPoints(r io.Reader) map[string]string {
 	for scanner.Scan() {
 		mount := scanner.Text()
 		tokens := strings.Split(mount, " ")
+		if len(tokens) >= 3 && tokens[2] == "cgroup2" {
+			// the unified hierarchy is usually mounted on the cgroup root itself
+			if strings.HasPrefix(tokens[1]+"/", cgroupRoot) {
+				mountPoints[unifiedHierarchy] = tokens[1]
+			}
+			continue
+		}
 		// Check if the filesystem type is 'cgroup'
 		if len(tokens) >= 3 && tokens[2] == "cgroup" {
 			cgroupPath := tokens[1]
@@ -218,6 +248,11 @@ func ReadCgroupsForPath(pidCgroupPath, prefix string) (string, map[string]string
 // 8:memory:/kubepods/besteffort/pod2baa3444-4d37-11e7-bd2f-080027d2bf10/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e
 // 7:blkio:/kubepods/besteffort/pod2baa3444-4d37-11e7-bd2f-080027d2bf10/47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e
 //
+// The path in the cgroup v2 unified hierarchy is on a line with the hierarchy ID 0
+// and no controller, and is stored as the "unified" target:
+//
+// 0::/system.slice/docker-47fc31db38b4fa0f4db44b99d0cad10e3cd4d5f142135a7721c1c95c1aadfb2e.scope
+//
 // Returns the common containerID and a mapping of target => path
 // If the first line doesn't have a valid container ID we will return an empty string
 func parseCgroupPaths(r io.Reader, prefix string) (string, map[string]string, error) {
@@ -239,6 +274,10 @@ func parseCgroupPaths(r io.Reader, prefix string) (string, map[string]string, er
 		if len(sp) < 3 {
 			continue
 		}
+		if sp[0] == "0" && sp[1] == "" {
+			paths[unifiedHierarchy] = sp[2]
+			continue
+		}
 		// Target can be comma-separate values like cpu,cpuacct
 		tsp := strings.Split(sp[1], ",")
 		for _, target := range tsp {
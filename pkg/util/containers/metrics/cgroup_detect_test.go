@@ -112,6 +112,27 @@ func TestParseCgroupMountPoints(t *testing.T) {
 				"systemd":    "/sys/fs/cgroup/systemd",
 			},
 		},
+		{
+			// cgroup v2 unified hierarchy
+			contents: []string{
+				"sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0",
+				"cgroup2 /sys/fs/cgroup cgroup2 rw,nosuid,nodev,noexec,relatime,nsdelegate 0 0",
+			},
+			expected: map[string]string{
+				"unified": "/sys/fs/cgroup",
+			},
+		},
+		{
+			// hybrid setup, controllers are on the v1 hierarchies
+			contents: []string{
+				"cgroup2 /sys/fs/cgroup/unified cgroup2 rw,nosuid,nodev,noexec,relatime,nsdelegate 0 0",
+				"cgroup /sys/fs/cgroup/memory cgroup rw,nosuid,nodev,noexec,relatime,memory 0 0",
+			},
+			expected: map[string]string{
+				"unified": "/sys/fs/cgroup/unified",
+				"memory":  "/sys/fs/cgroup/memory",
+			},
+		},
 		{
 			contents: []string{
 				"",
@@ -255,6 +276,30 @@ func TestParseCgroupPaths(t *testing.T) {
 				"cpuset":       "/docker/af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841",
 			},
 		},
+		{
+			// cgroup v2 unified hierarchy
+			contents: []string{
+				"0::/system.slice/docker-af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841.scope",
+			},
+			expectedContainer: "af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841",
+			expectedPaths: map[string]string{
+				"unified": "/system.slice/docker-af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841.scope",
+			},
+		},
+		{
+			// hybrid setup
+			contents: []string{
+				"4:memory:/docker/af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841",
+				"1:name=systemd:/docker/af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841",
+				"0::/docker/af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841",
+			},
+			expectedContainer: "af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841",
+			expectedPaths: map[string]string{
+				"memory":       "/docker/af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841",
+				"name=systemd": "/docker/af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841",
+				"unified":      "/docker/af1c1c0b02c6e45e0b6cb6151cd68fd02c7a6d91ad70d9bd72ccec8e83607841",
+			},
+		},
 	} {
 		contents := strings.NewReader(strings.Join(tc.contents, "\n"))
 		c, p, err := parseCgroupPaths(contents, "")
@@ -315,3 +360,12 @@ func TestDindContainer(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, value, uint64(1234))
 }
+
+func TestIsCgroupV2(t *testing.T) {
+	assert.False(t, ContainerCgroup{Mounts: map[string]string{"memory": "/sys/fs/cgroup/memory"}}.isCgroupV2())
+	assert.True(t, ContainerCgroup{Mounts: map[string]string{"unified": "/sys/fs/cgroup"}}.isCgroupV2())
+	assert.False(t, ContainerCgroup{Mounts: map[string]string{
+		"unified": "/sys/fs/cgroup/unified",
+		"memory":  "/sys/fs/cgroup/memory",
+	}}.isCgroupV2())
+}
@@ -26,6 +26,9 @@ const NanoToUserHZDivisor float64 = 1e9 / 100
 // Mem returns the memory statistics for a Cgroup. If the cgroup file is not
 // available then we return an empty stats file.
 func (c ContainerCgroup) Mem() (*CgroupMemStat, error) {
+	if c.isCgroupV2() {
+		return c.memV2()
+	}
 	ret := &CgroupMemStat{ContainerID: c.ContainerID}
 	statfile := c.cgroupFilePath("memory", "memory.stat")
 
@@ -115,6 +118,9 @@ func (c ContainerCgroup) Mem() (*CgroupMemStat, error) {
 // MemLimit returns the memory limit of the cgroup, if it exists. If the file does not
 // exist or there is no limit then this will default to 0.
 func (c ContainerCgroup) MemLimit() (uint64, error) {
+	if c.isCgroupV2() {
+		return c.memLimitV2()
+	}
 	v, err := c.ParseSingleStat("memory", "memory.limit_in_bytes")
 	if os.IsNotExist(err) {
 		log.Debugf("Missing cgroup file: %s",
@@ -134,6 +140,9 @@ func (c ContainerCgroup) MemLimit() (uint64, error) {
 // FailedMemoryCount returns the number of times this cgroup reached its memory limit, if it exists.
 // If the file does not exist or there is no limit, then this will default to 0
 func (c ContainerCgroup) FailedMemoryCount() (uint64, error) {
+	if c.isCgroupV2() {
+		return c.failedMemoryCountV2()
+	}
 	v, err := c.ParseSingleStat("memory", "memory.failcnt")
 	if os.IsNotExist(err) {
 		log.Debugf("Missing cgroup file: %s",
@@ -148,6 +157,9 @@ func (c ContainerCgroup) FailedMemoryCount() (uint64, error) {
 // KernelMemoryUsage returns the number of bytes of kernel memory used by this cgroup, if it exists.
 // If the file does not exist or there is an error, then this will default to 0
 func (c ContainerCgroup) KernelMemoryUsage() (uint64, error) {
+	if c.isCgroupV2() {
+		return c.kernelMemoryUsageV2()
+	}
 	v, err := c.ParseSingleStat("memory", "memory.kmem.usage_in_bytes")
 	if os.IsNotExist(err) {
 		log.Debugf("Missing cgroup file: %s",
@@ -162,6 +174,9 @@ func (c ContainerCgroup) KernelMemoryUsage() (uint64, error) {
 // SoftMemLimit returns the soft memory limit of the cgroup, if it exists. If the file does not
 // exist or there is no limit then this will default to 0.
 func (c ContainerCgroup) SoftMemLimit() (uint64, error) {
+	if c.isCgroupV2() {
+		return c.softMemLimitV2()
+	}
 	v, err := c.ParseSingleStat("memory", "memory.soft_limit_in_bytes")
 	if os.IsNotExist(err) {
 		log.Debugf("Missing cgroup file: %s",
@@ -181,6 +196,9 @@ func (c ContainerCgroup) SoftMemLimit() (uint64, error) {
 // CPU returns the CPU status for this cgroup instance
 // If the cgroup file does not exist then we just log debug return nothing.
 func (c ContainerCgroup) CPU() (*CgroupTimesStat, error) {
+	if c.isCgroupV2() {
+		return c.cpuV2()
+	}
 	ret := &CgroupTimesStat{ContainerID: c.ContainerID}
 	statfile := c.cgroupFilePath("cpuacct", "cpuacct.stat")
 	f, err := os.Open(statfile)
@@ -232,6 +250,9 @@ func (c ContainerCgroup) CPU() (*CgroupTimesStat, error) {
 // throttle/limited because of CPU quota / limit
 // If the cgroup file does not exist then we just log debug and return 0.
 func (c ContainerCgroup) CPUNrThrottled() (uint64, error) {
+	if c.isCgroupV2() {
+		return c.cpuNrThrottledV2()
+	}
 	statfile := c.cgroupFilePath("cpu", "cpu.stat")
 	f, err := os.Open(statfile)
 	if os.IsNotExist(err) {
@@ -266,6 +287,9 @@ func (c ContainerCgroup) CPUNrThrottled() (uint64, error) {
 // If the limits files aren't available (on older version) then
 // we'll return the default value of 100.
 func (c ContainerCgroup) CPULimit() (float64, error) {
+	if c.isCgroupV2() {
+		return c.cpuLimitV2()
+	}
 	periodFile := c.cgroupFilePath("cpu", "cpu.cfs_period_us")
 	quotaFile := c.cgroupFilePath("cpu", "cpu.cfs_quota_us")
 	plines, err := readLines(periodFile)
@@ -314,6 +338,9 @@ func (c ContainerCgroup) CPULimit() (float64, error) {
 // 252:0 Total 58945536
 //
 func (c ContainerCgroup) IO() (*CgroupIOStat, error) {
+	if c.isCgroupV2() {
+		return c.ioV2()
+	}
 	ret := &CgroupIOStat{
 		ContainerID:      c.ContainerID,
 		DeviceReadBytes:  make(map[string]uint64),
@@ -378,6 +405,9 @@ func (c ContainerCgroup) IO() (*CgroupIOStat, error) {
 // Although the metric is called `pid.current`, it also tracks
 // threads, and not only task-group-pids
 func (c ContainerCgroup) ThreadCount() (uint64, error) {
+	if c.isCgroupV2() {
+		return c.threadCountV2()
+	}
 	v, err := c.ParseSingleStat("pids", "pids.current")
 	if os.IsNotExist(err) {
 		log.Debugf("Missing cgroup file: %s",
@@ -395,6 +425,9 @@ func (c ContainerCgroup) ThreadCount() (uint64, error) {
 //
 // If `max` is found, the method returns 0 as-in "no limit"
 func (c ContainerCgroup) ThreadLimit() (uint64, error) {
+	if c.isCgroupV2() {
+		return c.threadLimitV2()
+	}
 	statFile := c.cgroupFilePath("pids", "pids.max")
 	lines, err := readLines(statFile)
 	if os.IsNotExist(err) {
@@ -416,6 +449,19 @@ func (c ContainerCgroup) ThreadLimit() (uint64, error) {
 	return value, nil
 }
 
+// Pressure returns the pressure stall information of the cgroup, which tells the share
+// of time its tasks were stalled waiting for CPU, memory or I/O.
+// ref: https://www.kernel.org/doc/Documentation/accounting/psi.txt
+//
+// It is only available in the cgroup v2 unified hierarchy, an empty stat is returned
+// with v1 cgroups.
+func (c ContainerCgroup) Pressure() (*CgroupPressureStat, error) {
+	if c.isCgroupV2() {
+		return c.pressureV2()
+	}
+	return &CgroupPressureStat{ContainerID: c.ContainerID}, nil
+}
+
 // ParseSingleStat reads and converts a single-value cgroup stat file content to uint64.
 func (c ContainerCgroup) ParseSingleStat(target, file string) (uint64, error) {
 	statFile := c.cgroupFilePath(target, file)
@@ -0,0 +1,418 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build linux
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// This file holds the cgroup v2 versions of the ContainerCgroup stats methods.
+// In the unified hierarchy, all the controller files of a cgroup are in the same
+// directory, and their format differs from their v1 counterparts.
+// ref: https://www.kernel.org/doc/Documentation/cgroup-v2.txt
+
+// unifiedFilePath returns the path of a file of the cgroup in the unified hierarchy.
+func (c ContainerCgroup) unifiedFilePath(file string) string {
+	return c.cgroupFilePath(unifiedHierarchy, file)
+}
+
+// memV2 returns the memory statistics of the cgroup, read from memory.stat,
+// memory.current, memory.max and their swap equivalents.
+// Statistics in the unified hierarchy are always hierarchical, so the Total*
+// fields are set to the same values as their non hierarchical counterparts.
+func (c ContainerCgroup) memV2() (*CgroupMemStat, error) {
+	ret := &CgroupMemStat{ContainerID: c.ContainerID}
+	statfile := c.unifiedFilePath("memory.stat")
+	stats, err := parseFlatKeyedFile(statfile)
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", statfile)
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+	ret.Cache = stats["file"]
+	ret.RSS = stats["anon"]
+	ret.RSSHuge = stats["anon_thp"]
+	ret.MappedFile = stats["file_mapped"]
+	ret.Pgfault = stats["pgfault"]
+	ret.Pgmajfault = stats["pgmajfault"]
+	ret.InactiveAnon = stats["inactive_anon"]
+	ret.ActiveAnon = stats["active_anon"]
+	ret.InactiveFile = stats["inactive_file"]
+	ret.ActiveFile = stats["active_file"]
+	ret.Unevictable = stats["unevictable"]
+
+	ret.TotalCache = ret.Cache
+	ret.TotalRSS = ret.RSS
+	ret.TotalRSSHuge = ret.RSSHuge
+	ret.TotalMappedFile = ret.MappedFile
+	ret.TotalPgFault = ret.Pgfault
+	ret.TotalPgMajFault = ret.Pgmajfault
+	ret.TotalInactiveAnon = ret.InactiveAnon
+	ret.TotalActiveAnon = ret.ActiveAnon
+	ret.TotalInactiveFile = ret.InactiveFile
+	ret.TotalActiveFile = ret.ActiveFile
+	ret.TotalUnevictable = ret.Unevictable
+
+	if v, err := c.ParseSingleStat(unifiedHierarchy, "memory.current"); err == nil {
+		ret.MemUsageInBytes = v
+	} else {
+		log.Debugf("Missing memory usage stat for %s: %s", c.ContainerID, err)
+	}
+	if v, err := c.ParseSingleStat(unifiedHierarchy, "memory.swap.current"); err == nil {
+		ret.Swap = v
+		ret.SwapPresent = true
+	}
+
+	// memory.max and memory.swap.max hold "max" when there is no limit. Unlike
+	// memory.memsw.limit_in_bytes in v1, the swap limit does not include the memory.
+	if limit, err := c.parseLimitStat("memory.max"); err == nil && limit > 0 {
+		ret.HierarchicalMemoryLimit = limit
+		if swapLimit, err := c.parseLimitStat("memory.swap.max"); err == nil && swapLimit > 0 {
+			ret.HierarchicalMemSWLimit = limit + swapLimit
+		}
+	}
+	return ret, nil
+}
+
+// memLimitV2 returns the memory limit of the cgroup, 0 if there is none.
+func (c ContainerCgroup) memLimitV2() (uint64, error) {
+	v, err := c.parseLimitStat("memory.max")
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", c.unifiedFilePath("memory.max"))
+		return 0, nil
+	}
+	return v, err
+}
+
+// softMemLimitV2 returns the memory protection of the cgroup, which is what the
+// memory reservation of a container is mapped to in v2, 0 if there is none.
+func (c ContainerCgroup) softMemLimitV2() (uint64, error) {
+	v, err := c.parseLimitStat("memory.low")
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", c.unifiedFilePath("memory.low"))
+		return 0, nil
+	}
+	return v, err
+}
+
+// failedMemoryCountV2 returns the number of times the memory usage of the cgroup
+// was about to go over its limit, as reported by memory.events.
+func (c ContainerCgroup) failedMemoryCountV2() (uint64, error) {
+	statfile := c.unifiedFilePath("memory.events")
+	events, err := parseFlatKeyedFile(statfile)
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", statfile)
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return events["max"], nil
+}
+
+// kernelMemoryUsageV2 returns the number of bytes of kernel memory used by the cgroup.
+// memory.stat only has a "kernel" entry on recent kernels, in which case it is used,
+// otherwise the kernel stack and slab usages are summed.
+func (c ContainerCgroup) kernelMemoryUsageV2() (uint64, error) {
+	statfile := c.unifiedFilePath("memory.stat")
+	stats, err := parseFlatKeyedFile(statfile)
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", statfile)
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if v, ok := stats["kernel"]; ok {
+		return v, nil
+	}
+	return stats["kernel_stack"] + stats["slab"], nil
+}
+
+// cpuV2 returns the CPU status of the cgroup, read from cpu.stat, where times are
+// in microseconds, and cpu.weight.
+func (c ContainerCgroup) cpuV2() (*CgroupTimesStat, error) {
+	ret := &CgroupTimesStat{ContainerID: c.ContainerID}
+	statfile := c.unifiedFilePath("cpu.stat")
+	stats, err := parseFlatKeyedFile(statfile)
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", statfile)
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+	// convert to USER_HZ, like cpuacct.stat
+	ret.User = stats["user_usec"] / 1e4
+	ret.System = stats["system_usec"] / 1e4
+	ret.UsageTotal = float64(stats["usage_usec"]) * 1e3 / NanoToUserHZDivisor
+
+	weight, err := c.ParseSingleStat(unifiedHierarchy, "cpu.weight")
+	if err == nil && weight > 0 {
+		// inverse of the conversion of cpu.shares [2-262144] into cpu.weight [1-10000]
+		// applied by the container runtimes
+		ret.Shares = 2 + ((weight-1)*262142)/9999
+	} else if err != nil {
+		log.Debugf("Missing cpu weight stat for %s: %s", c.ContainerID, err.Error())
+	}
+	return ret, nil
+}
+
+// cpuNrThrottledV2 returns the number of times the cgroup has been throttled.
+func (c ContainerCgroup) cpuNrThrottledV2() (uint64, error) {
+	statfile := c.unifiedFilePath("cpu.stat")
+	stats, err := parseFlatKeyedFile(statfile)
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", statfile)
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return stats["nr_throttled"], nil
+}
+
+// cpuLimitV2 returns the CPU limit of the cgroup in percent, from cpu.max which
+// holds the quota, or "max", and the period:
+//
+//	50000 100000
+func (c ContainerCgroup) cpuLimitV2() (float64, error) {
+	statfile := c.unifiedFilePath("cpu.max")
+	lines, err := readLines(statfile)
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", statfile)
+		return 100, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(lines) != 1 {
+		return 0, fmt.Errorf("wrong file format: %s", statfile)
+	}
+	fields := strings.Fields(lines[0])
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("wrong file format: %s", statfile)
+	}
+	if fields[0] == "max" {
+		return 100, nil
+	}
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	period, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	limit := 100.0
+	if (period > 0) && (quota > 0) {
+		limit = (quota / period) * 100.0
+	}
+	return limit, nil
+}
+
+// ioV2 returns the disk read and write bytes stats of the cgroup.
+// Format:
+//
+// 8:0 rbytes=49225728 wbytes=9850880 rios=1203 wios=320 dbytes=0 dios=0
+// 252:0 rbytes=49094656 wbytes=9850880 rios=1198 wios=320 dbytes=0 dios=0
+//
+func (c ContainerCgroup) ioV2() (*CgroupIOStat, error) {
+	ret := &CgroupIOStat{
+		ContainerID:      c.ContainerID,
+		DeviceReadBytes:  make(map[string]uint64),
+		DeviceWriteBytes: make(map[string]uint64),
+	}
+
+	statfile := c.unifiedFilePath("io.stat")
+	f, err := os.Open(statfile)
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", statfile)
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var devices map[string]string
+	mapping, err := getDiskDeviceMapping()
+	if err != nil {
+		log.Debugf("Cannot get per-device stats: %s", err)
+	} else {
+		devices = mapping.idToName
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		deviceName := devices[fields[0]]
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				ret.ReadBytes += v
+				if deviceName != "" {
+					ret.DeviceReadBytes[deviceName] = v
+				}
+			case "wbytes":
+				ret.WriteBytes += v
+				if deviceName != "" {
+					ret.DeviceWriteBytes[deviceName] = v
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ret, fmt.Errorf("error reading %s: %s", statfile, err)
+	}
+	return ret, nil
+}
+
+// pressureV2 returns the pressure stall information of the cgroup, read from
+// cpu.pressure, memory.pressure and io.pressure.
+func (c ContainerCgroup) pressureV2() (*CgroupPressureStat, error) {
+	ret := &CgroupPressureStat{ContainerID: c.ContainerID}
+	for file, dest := range map[string]*PressureStat{
+		"cpu.pressure":    &ret.CPU,
+		"memory.pressure": &ret.Memory,
+		"io.pressure":     &ret.IO,
+	} {
+		statfile := c.unifiedFilePath(file)
+		lines, err := readLines(statfile)
+		if os.IsNotExist(err) {
+			log.Debugf("Missing cgroup file: %s", statfile)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if err := parsePressureLines(lines, dest); err != nil {
+			return nil, fmt.Errorf("error reading %s: %s", statfile, err)
+		}
+	}
+	return ret, nil
+}
+
+// parsePressureLines parses the content of a pressure file into stat.
+// Format:
+//
+// some avg10=0.04 avg60=0.01 avg300=0.00 total=12093
+// full avg10=0.00 avg60=0.00 avg300=0.00 total=5043
+//
+func parsePressureLines(lines []string, stat *PressureStat) error {
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		psi := &PSIStat{}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid field %q", field)
+			}
+			var err error
+			switch kv[0] {
+			case "avg10":
+				psi.Avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				psi.Avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				psi.Avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				psi.Total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		switch fields[0] {
+		case "some":
+			stat.Some = psi
+		case "full":
+			stat.Full = psi
+		}
+	}
+	return nil
+}
+
+// threadCountV2 returns the number of threads in the cgroup.
+func (c ContainerCgroup) threadCountV2() (uint64, error) {
+	v, err := c.ParseSingleStat(unifiedHierarchy, "pids.current")
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", c.unifiedFilePath("pids.current"))
+		return 0, nil
+	}
+	return v, err
+}
+
+// threadLimitV2 returns the thread count limit of the cgroup, 0 if there is none.
+func (c ContainerCgroup) threadLimitV2() (uint64, error) {
+	v, err := c.parseLimitStat("pids.max")
+	if os.IsNotExist(err) {
+		log.Debugf("Missing cgroup file: %s", c.unifiedFilePath("pids.max"))
+		return 0, nil
+	}
+	return v, err
+}
+
+// parseLimitStat reads a single-value file of the unified hierarchy holding either
+// a number or "max", in which case 0 is returned, as in "no limit".
+func (c ContainerCgroup) parseLimitStat(file string) (uint64, error) {
+	statfile := c.unifiedFilePath(file)
+	lines, err := readLines(statfile)
+	if err != nil {
+		return 0, err
+	}
+	if len(lines) != 1 {
+		return 0, fmt.Errorf("wrong file format: %s", statfile)
+	}
+	if lines[0] == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(lines[0], 10, 64)
+}
+
+// parseFlatKeyedFile reads a file made of "key value" lines, like memory.stat or
+// cpu.stat. Lines whose value is not an integer are ignored.
+func parseFlatKeyedFile(filename string) (map[string]uint64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ret := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		ret[fields[0]] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", filename, err)
+	}
+	return ret, nil
+}
@@ -0,0 +1,256 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// +build linux
+
+package metrics
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemV2(t *testing.T) {
+	tempFolder, err := newTempFolder("mem-stats-v2")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, "unified")
+
+	// No file
+	memStat, err := cgroup.Mem()
+	assert.Nil(t, err)
+	assert.Equal(t, &CgroupMemStat{ContainerID: "dummy"}, memStat)
+
+	memStats := dummyCgroupStat{
+		"anon":          4653056,
+		"file":          1024000,
+		"kernel_stack":  49152,
+		"slab":          204800,
+		"anon_thp":      2097152,
+		"file_mapped":   811008,
+		"inactive_anon": 4579328,
+		"active_anon":   73728,
+		"inactive_file": 200000,
+		"active_file":   824000,
+		"unevictable":   0,
+		"pgfault":       5379,
+		"pgmajfault":    12,
+	}
+	tempFolder.add("unified/memory.stat", memStats.String())
+	tempFolder.add("unified/memory.current", "5890048")
+	tempFolder.add("unified/memory.swap.current", "4096")
+	tempFolder.add("unified/memory.max", "268435456")
+	tempFolder.add("unified/memory.swap.max", "max")
+
+	memStat, err = cgroup.Mem()
+	assert.Nil(t, err)
+	assert.Equal(t, "dummy", memStat.ContainerID)
+	assert.Equal(t, uint64(4653056), memStat.RSS)
+	assert.Equal(t, uint64(4653056), memStat.TotalRSS)
+	assert.Equal(t, uint64(1024000), memStat.Cache)
+	assert.Equal(t, uint64(2097152), memStat.RSSHuge)
+	assert.Equal(t, uint64(811008), memStat.MappedFile)
+	assert.Equal(t, uint64(5379), memStat.Pgfault)
+	assert.Equal(t, uint64(12), memStat.TotalPgMajFault)
+	assert.Equal(t, uint64(5890048), memStat.MemUsageInBytes)
+	assert.Equal(t, uint64(4096), memStat.Swap)
+	assert.True(t, memStat.SwapPresent)
+	assert.Equal(t, uint64(268435456), memStat.HierarchicalMemoryLimit)
+	assert.Equal(t, uint64(0), memStat.HierarchicalMemSWLimit)
+
+	tempFolder.add("unified/memory.swap.max", "1000")
+	memStat, err = cgroup.Mem()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(268436456), memStat.HierarchicalMemSWLimit)
+
+	kmem, err := cgroup.KernelMemoryUsage()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(49152+204800), kmem)
+
+	memStats["kernel"] = 300000
+	tempFolder.add("unified/memory.stat", memStats.String())
+	kmem, err = cgroup.KernelMemoryUsage()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(300000), kmem)
+}
+
+func TestMemLimitsV2(t *testing.T) {
+	tempFolder, err := newTempFolder("mem-limits-v2")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, "unified")
+
+	// No file
+	value, err := cgroup.MemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+	value, err = cgroup.SoftMemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+	value, err = cgroup.FailedMemoryCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+
+	// Invalid file
+	tempFolder.add("unified/memory.max", "ab")
+	_, err = cgroup.MemLimit()
+	assert.IsType(t, &strconv.NumError{}, err)
+
+	// No limit
+	tempFolder.add("unified/memory.max", "max")
+	value, err = cgroup.MemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+
+	// Valid values
+	tempFolder.add("unified/memory.max", "1234")
+	value, err = cgroup.MemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1234), value)
+
+	tempFolder.add("unified/memory.low", "567")
+	value, err = cgroup.SoftMemLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(567), value)
+
+	tempFolder.add("unified/memory.events", "low 0\nhigh 3\nmax 12\noom 1\noom_kill 1")
+	value, err = cgroup.FailedMemoryCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(12), value)
+}
+
+func TestCPUV2(t *testing.T) {
+	tempFolder, err := newTempFolder("cpu-stats-v2")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	cpuStats := dummyCgroupStat{
+		"usage_usec":     915266418,
+		"user_usec":      641400000,
+		"system_usec":    183270000,
+		"nr_periods":     120,
+		"nr_throttled":   10,
+		"throttled_usec": 18327,
+	}
+	tempFolder.add("unified/cpu.stat", cpuStats.String())
+	tempFolder.add("unified/cpu.weight", "39")
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, "unified")
+
+	timeStat, err := cgroup.CPU()
+	assert.Nil(t, err)
+	assert.Equal(t, "dummy", timeStat.ContainerID)
+	assert.Equal(t, uint64(64140), timeStat.User)
+	assert.Equal(t, uint64(18327), timeStat.System)
+	// the default 1024 shares are converted to a weight of 39, the conversion rounds down
+	assert.Equal(t, uint64(998), timeStat.Shares)
+	assert.InDelta(t, 91526.6418, timeStat.UsageTotal, 0.0000001)
+
+	throttled, err := cgroup.CPUNrThrottled()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), throttled)
+}
+
+func TestCPULimitV2(t *testing.T) {
+	tempFolder, err := newTempFolder("cpu-limit-v2")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, "unified")
+
+	// No file
+	limit, err := cgroup.CPULimit()
+	assert.Nil(t, err)
+	assert.Equal(t, 100.0, limit)
+
+	// Invalid file
+	tempFolder.add("unified/cpu.max", "100000")
+	_, err = cgroup.CPULimit()
+	assert.NotNil(t, err)
+
+	// Empty file
+	tempFolder.add("unified/cpu.max", "")
+	_, err = cgroup.CPULimit()
+	assert.NotNil(t, err)
+
+	// No limit
+	tempFolder.add("unified/cpu.max", "max 100000")
+	limit, err = cgroup.CPULimit()
+	assert.Nil(t, err)
+	assert.Equal(t, 100.0, limit)
+
+	// Valid value
+	tempFolder.add("unified/cpu.max", "50000 100000")
+	limit, err = cgroup.CPULimit()
+	assert.Nil(t, err)
+	assert.Equal(t, 50.0, limit)
+}
+
+func TestThreadsV2(t *testing.T) {
+	tempFolder, err := newTempFolder("threads-v2")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, "unified")
+
+	// No file
+	value, err := cgroup.ThreadCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+	value, err = cgroup.ThreadLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+
+	tempFolder.add("unified/pids.current", "123")
+	tempFolder.add("unified/pids.max", "max")
+	value, err = cgroup.ThreadCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(123), value)
+	value, err = cgroup.ThreadLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+
+	tempFolder.add("unified/pids.max", "1234")
+	value, err = cgroup.ThreadLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1234), value)
+}
+
+func TestPressure(t *testing.T) {
+	tempFolder, err := newTempFolder("pressure")
+	assert.Nil(t, err)
+	defer tempFolder.removeAll()
+
+	// v1 cgroups have no pressure stall information
+	stat, err := newDummyContainerCgroup(tempFolder.RootPath, "memory").Pressure()
+	assert.Nil(t, err)
+	assert.Equal(t, &CgroupPressureStat{ContainerID: "dummy"}, stat)
+
+	cgroup := newDummyContainerCgroup(tempFolder.RootPath, "unified")
+	tempFolder.add("unified/cpu.pressure", "some avg10=1.50 avg60=0.75 avg300=0.20 total=120934")
+	tempFolder.add("unified/memory.pressure", "some avg10=0.00 avg60=0.00 avg300=0.00 total=42\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=21")
+
+	stat, err = cgroup.Pressure()
+	assert.Nil(t, err)
+	assert.Equal(t, &CgroupPressureStat{
+		ContainerID: "dummy",
+		CPU: PressureStat{
+			Some: &PSIStat{Avg10: 1.5, Avg60: 0.75, Avg300: 0.2, Total: 120934},
+		},
+		Memory: PressureStat{
+			Some: &PSIStat{Total: 42},
+			Full: &PSIStat{Total: 21},
+		},
+	}, stat)
+
+	// Invalid file
+	tempFolder.add("unified/io.pressure", "some avg10")
+	_, err = cgroup.Pressure()
+	assert.NotNil(t, err)
+}
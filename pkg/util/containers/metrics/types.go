@@ -74,6 +74,32 @@ type CgroupIOStat struct {
 	DeviceWriteBytes map[string]uint64
 }
 
+// PSIStat stores one line of pressure stall information: the share of time, in
+// percent, during which tasks were stalled over the last 10, 60 and 300 seconds, and
+// the total stall time in microseconds.
+type PSIStat struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
+}
+
+// PressureStat stores the pressure stall information of a resource. Some is the
+// time during which at least one task was stalled, Full the time during which all
+// of them were. Either is nil when not reported by the kernel.
+type PressureStat struct {
+	Some *PSIStat
+	Full *PSIStat
+}
+
+// CgroupPressureStat stores the pressure stall information of a cgroup.
+type CgroupPressureStat struct {
+	ContainerID string
+	CPU         PressureStat
+	Memory      PressureStat
+	IO          PressureStat
+}
+
 // ContainerCgroup is a structure that stores paths and mounts for a cgroup.
 // It provides several methods for collecting stats about the cgroup using the
 // paths and mounts metadata.
@@ -61,6 +61,7 @@ type Container struct {
 	CPU            *metrics.CgroupTimesStat
 	Memory         *metrics.CgroupMemStat
 	IO             *metrics.CgroupIOStat
+	Pressure       *metrics.CgroupPressureStat
 	Network        metrics.ContainerNetStats
 	AddressList    []NetworkAddress
 	StartedAt      int64
@@ -53,8 +53,8 @@ func init() {
 		Repl:  []byte(`$1 ********`),
 	}
 	snmpReplacer = Replacer{
-		Regex: matchYAMLKey(`(community_string|authKey|privKey)`),
-		Hints: []string{"community_string", "authKey", "privKey"},
+		Regex: matchYAMLKey(`(community_string|authKey|privKey|auth_key|priv_key)`),
+		Hints: []string{"community_string", "authKey", "privKey", "auth_key", "priv_key"},
 		Repl:  []byte(`$1 ********`),
 	}
 	replacers = []Replacer{apiKeyReplacer, appKeyReplacer, uriPasswordReplacer, passwordReplacer, tokenReplacer, snmpReplacer}
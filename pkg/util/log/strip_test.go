@@ -206,6 +206,12 @@ func TestSNMPConfig(t *testing.T) {
 	assertClean(t,
 		`privKey: password`,
 		`privKey: ********`)
+	assertClean(t,
+		`auth_key: password`,
+		`auth_key: ********`)
+	assertClean(t,
+		`  - priv_key: password`,
+		`  - priv_key: ********`)
 	assertClean(t,
 		`community_string: p@ssw0r)`,
 		`community_string: ********`)
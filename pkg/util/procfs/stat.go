@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// Package procfs parses the per-process files of procfs, see proc(5).
+package procfs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Stat holds the fields of /proc/<pid>/stat used by the agent.
+type Stat struct {
+	Comm      string
+	PPid      int
+	CPUTicks  uint64 // user and system time, in clock ticks
+	Threads   uint64
+	StartTime uint64 // in clock ticks after boot, tells a process from a former one with the same pid
+	VMS       uint64 // in bytes
+	RSS       uint64 // in pages
+}
+
+// ReadStat parses a /proc/<pid>/stat file.
+func ReadStat(path string) (*Stat, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// The name of the process is enclosed in parentheses, and may contain spaces and parentheses
+	start, end := bytes.IndexByte(data, '('), bytes.LastIndexByte(data, ')')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid stat file %s", path)
+	}
+	// fields start at the state, the third field
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("invalid stat file %s: %d fields", path, len(fields)+2)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid stat file %s: %s", path, err)
+	}
+	startTime, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stat file %s: %s", path, err)
+	}
+	return &Stat{
+		Comm:      string(data[start+1 : end]),
+		PPid:      ppid,
+		CPUTicks:  parseUint(fields[11]) + parseUint(fields[12]),
+		Threads:   parseUint(fields[17]),
+		StartTime: startTime,
+		VMS:       parseUint(fields[20]),
+		RSS:       parseUint(fields[21]),
+	}, nil
+}
+
+func parseUint(s string) uint64 {
+	v, _ := strconv.ParseUint(s, 10, 64)
+	return v
+}
@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package procfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeStat(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "stat")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestReadStat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "procfs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeStat(t, dir, "42 (my (weird) proc) S 7 42 42 0 -1 4194560 100 0 0 0 150 150 0 0 20 0 3 0 1000 1048576 4 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n")
+	stat, err := ReadStat(path)
+	require.NoError(t, err)
+	assert.Equal(t, &Stat{
+		Comm:      "my (weird) proc",
+		PPid:      7,
+		CPUTicks:  300,
+		Threads:   3,
+		StartTime: 1000,
+		VMS:       1048576,
+		RSS:       4,
+	}, stat)
+
+	for _, content := range []string{
+		"42 my proc S 7",
+		"42 (proc) S 7 42 42",
+		"42 (proc) S x 42 42 0 -1 4194560 100 0 0 0 150 150 0 0 20 0 3 0 1000 1048576 4",
+	} {
+		_, err = ReadStat(writeStat(t, dir, content))
+		assert.Error(t, err, content)
+	}
+
+	_, err = ReadStat(filepath.Join(dir, "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,254 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package snmp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BER tags of the universal types, SNMP application types and exceptions used in SNMP messages
+const (
+	TagInteger        = 0x02
+	TagOctetString    = 0x04
+	TagNull           = 0x05
+	TagOID            = 0x06
+	TagSequence       = 0x30
+	TagIPAddress      = 0x40
+	TagCounter32      = 0x41
+	TagGauge32        = 0x42
+	TagTimeTicks      = 0x43
+	TagOpaque         = 0x44
+	TagCounter64      = 0x46
+	TagNoSuchObject   = 0x80
+	TagNoSuchInstance = 0x81
+	TagEndOfMibView   = 0x82
+)
+
+var errTruncated = errors.New("truncated BER data")
+
+// appendLength appends a BER length, in the short form below 128 and in the long form above.
+func appendLength(b []byte, n int) []byte {
+	if n < 0x80 {
+		return append(b, byte(n))
+	}
+	var tmp [8]byte
+	i := len(tmp)
+	for ; n > 0; n >>= 8 {
+		i--
+		tmp[i] = byte(n)
+	}
+	b = append(b, 0x80|byte(len(tmp)-i))
+	return append(b, tmp[i:]...)
+}
+
+// encodeTLV returns the BER encoding of a value, given its tag and encoded content.
+func encodeTLV(tag byte, content ...[]byte) []byte {
+	n := 0
+	for _, c := range content {
+		n += len(c)
+	}
+	b := make([]byte, 0, n+6)
+	b = append(b, tag)
+	b = appendLength(b, n)
+	for _, c := range content {
+		b = append(b, c...)
+	}
+	return b
+}
+
+// encodeInt returns the two's complement content of a BER integer, on as few bytes as possible.
+func encodeInt(v int64) []byte {
+	n := 1
+	for i := v; i > 127 || i < -128; i >>= 8 {
+		n++
+	}
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+// encodeUint returns the content of an unsigned BER integer: a leading zero byte
+// is added when the most significant bit is set, so that it isn't read as negative.
+func encodeUint(v uint64) []byte {
+	n := 1
+	for i := v; i > 127; i >>= 8 {
+		n++
+	}
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+func encodeOIDContent(oid OID) ([]byte, error) {
+	if len(oid) < 2 {
+		return nil, fmt.Errorf("invalid OID %s: at least 2 components are required", oid)
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
+		return nil, fmt.Errorf("invalid OID %s", oid)
+	}
+	b := appendBase128(nil, uint64(oid[0])*40+uint64(oid[1]))
+	for _, c := range oid[2:] {
+		b = appendBase128(b, uint64(c))
+	}
+	return b, nil
+}
+
+func appendBase128(b []byte, v uint64) []byte {
+	var tmp [10]byte
+	i := len(tmp) - 1
+	tmp[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		tmp[i] = 0x80 | byte(v&0x7f)
+	}
+	return append(b, tmp[i:]...)
+}
+
+// decoder reads consecutive BER values from a buffer.
+type decoder struct {
+	b []byte
+}
+
+func (d *decoder) empty() bool {
+	return len(d.b) == 0
+}
+
+// next reads a value and returns its tag and content. The content is a slice of
+// the buffer, it isn't copied.
+func (d *decoder) next() (byte, []byte, error) {
+	if len(d.b) < 2 {
+		return 0, nil, errTruncated
+	}
+	tag := d.b[0]
+	n := int(d.b[1])
+	i := 2
+	if n&0x80 != 0 {
+		size := n & 0x7f
+		if size == 0 || size > 4 || len(d.b) < 2+size {
+			return 0, nil, fmt.Errorf("invalid BER length")
+		}
+		n = 0
+		for _, c := range d.b[2 : 2+size] {
+			n = n<<8 | int(c)
+		}
+		i += size
+	}
+	if n < 0 || len(d.b)-i < n {
+		return 0, nil, errTruncated
+	}
+	content := d.b[i : i+n]
+	d.b = d.b[i+n:]
+	return tag, content, nil
+}
+
+// expect reads a value with the given tag and returns its content.
+func (d *decoder) expect(tag byte) ([]byte, error) {
+	t, content, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	if t != tag {
+		return nil, fmt.Errorf("unexpected BER tag 0x%02x, expected 0x%02x", t, tag)
+	}
+	return content, nil
+}
+
+// sequence reads a sequence and returns a decoder of its elements.
+func (d *decoder) sequence() (*decoder, error) {
+	content, err := d.expect(TagSequence)
+	if err != nil {
+		return nil, err
+	}
+	return &decoder{b: content}, nil
+}
+
+func (d *decoder) int() (int64, error) {
+	content, err := d.expect(TagInteger)
+	if err != nil {
+		return 0, err
+	}
+	return decodeInt(content)
+}
+
+func (d *decoder) octetString() ([]byte, error) {
+	return d.expect(TagOctetString)
+}
+
+func (d *decoder) oid() (OID, error) {
+	content, err := d.expect(TagOID)
+	if err != nil {
+		return nil, err
+	}
+	return decodeOID(content)
+}
+
+func decodeInt(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 8 {
+		return 0, fmt.Errorf("invalid BER integer of %d bytes", len(b))
+	}
+	// sign extension of the first byte
+	v := int64(int8(b[0]))
+	for _, c := range b[1:] {
+		v = v<<8 | int64(c)
+	}
+	return v, nil
+}
+
+func decodeUint(b []byte) (uint64, error) {
+	if len(b) > 0 && b[0] == 0 {
+		b = b[1:]
+	}
+	if len(b) > 8 {
+		return 0, fmt.Errorf("invalid BER unsigned integer of %d bytes", len(b))
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func decodeOID(b []byte) (OID, error) {
+	if len(b) == 0 {
+		return nil, errors.New("empty OID")
+	}
+	var oid OID
+	var v uint64
+	for i, c := range b {
+		v = v<<7 | uint64(c&0x7f)
+		if v > 0xffffffff {
+			return nil, errors.New("OID component overflow")
+		}
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return nil, errTruncated
+			}
+			continue
+		}
+		if oid == nil {
+			// the first byte(s) encode the first two components
+			switch {
+			case v < 40:
+				oid = OID{0, uint32(v)}
+			case v < 80:
+				oid = OID{1, uint32(v - 40)}
+			default:
+				oid = OID{2, uint32(v - 80)}
+			}
+		} else {
+			oid = append(oid, uint32(v))
+		}
+		v = 0
+	}
+	return oid, nil
+}
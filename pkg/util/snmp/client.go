@@ -0,0 +1,394 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultPort is the UDP port of SNMP agents
+	DefaultPort = 161
+
+	defaultTimeout        = 2 * time.Second
+	defaultMaxRepetitions = 10
+)
+
+// ErrTimeout is returned when an agent doesn't answer a request, retries included
+var ErrTimeout = errors.New("request timed out")
+
+// errorStatuses are the names of the error statuses of responses (RFC 3416)
+var errorStatuses = []string{
+	"noError", "tooBig", "noSuchName", "badValue", "readOnly", "genErr", "noAccess",
+	"wrongType", "wrongLength", "wrongEncoding", "wrongValue", "noCreation",
+	"inconsistentValue", "resourceUnavailable", "commitFailed", "undoFailed",
+	"authorizationError", "notWritable", "inconsistentName",
+}
+
+// Error statuses of responses
+const (
+	NoError    = 0
+	TooBig     = 1
+	NoSuchName = 2
+	GenErr     = 5
+)
+
+// ResponseError is returned when an agent answers a request with an error status
+type ResponseError struct {
+	Status int
+	// Index is the position, starting at 1, of the variable in error, if any
+	Index int
+}
+
+func (e *ResponseError) Error() string {
+	status := strconv.Itoa(e.Status)
+	if e.Status >= 0 && e.Status < len(errorStatuses) {
+		status = errorStatuses[e.Status]
+	}
+	if e.Index > 0 {
+		return fmt.Sprintf("agent error %s on variable %d", status, e.Index)
+	}
+	return fmt.Sprintf("agent error %s", status)
+}
+
+// Client sends requests to an SNMP agent. It is not safe for concurrent use,
+// concurrent requests need a client each.
+type Client struct {
+	Target    string
+	Port      uint16
+	Version   Version
+	Community string
+	// User and ContextName are the credentials and context of SNMPv3 requests
+	User        User
+	ContextName string
+	// Timeout is the time to wait for a response before retrying
+	Timeout time.Duration
+	Retries int
+	// MaxRepetitions is the number of variables requested by the GetBulk
+	// requests sent by Walk
+	MaxRepetitions int
+
+	conn      net.Conn
+	requestID int32
+
+	// state of the SNMPv3 engine of the agent
+	engineID    []byte
+	engineBoots int32
+	engineTime  int32
+	timeRef     time.Time
+	keys        *Keys
+}
+
+func (c *Client) String() string {
+	port := c.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	return net.JoinHostPort(c.Target, strconv.Itoa(int(port)))
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return c.Timeout
+}
+
+// Connect opens the socket to the agent, and discovers its SNMPv3 engine
+func (c *Client) Connect() error {
+	conn, err := net.DialTimeout("udp", c.String(), c.timeout())
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.requestID = int32(time.Now().UnixNano() & 0x7fffffff)
+	if c.Version == Version3 {
+		if err = c.discoverEngine(); err != nil {
+			c.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the socket to the agent
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Get returns the variables of the given OIDs
+func (c *Client) Get(oids []OID) ([]Variable, error) {
+	return c.do(GetRequest, oids, 0, 0)
+}
+
+// GetNext returns the variables following the given OIDs
+func (c *Client) GetNext(oids []OID) ([]Variable, error) {
+	return c.do(GetNextRequest, oids, 0, 0)
+}
+
+// GetBulk returns the variables following the first nonRepeaters OIDs, then up to
+// maxRepetitions variables following each of the other ones. It isn't supported by SNMPv1.
+func (c *Client) GetBulk(oids []OID, nonRepeaters, maxRepetitions int) ([]Variable, error) {
+	if c.Version == Version1 {
+		return nil, errors.New("GetBulk requests aren't supported by SNMPv1")
+	}
+	return c.do(GetBulkRequest, oids, nonRepeaters, maxRepetitions)
+}
+
+// Walk calls fn with every variable of the subtree of root, in order. It sends
+// GetBulk requests, and GetNext requests with SNMPv1.
+func (c *Client) Walk(root OID, fn func(Variable) error) error {
+	maxRepetitions := c.MaxRepetitions
+	if maxRepetitions <= 0 {
+		maxRepetitions = defaultMaxRepetitions
+	}
+	oid := root
+	for {
+		var variables []Variable
+		var err error
+		if c.Version == Version1 {
+			variables, err = c.GetNext([]OID{oid})
+			if rerr, ok := err.(*ResponseError); ok && rerr.Status == NoSuchName {
+				// end of the MIB view
+				return nil
+			}
+		} else {
+			variables, err = c.GetBulk([]OID{oid}, 0, maxRepetitions)
+		}
+		if err != nil {
+			return err
+		}
+		if len(variables) == 0 {
+			return nil
+		}
+		for _, v := range variables {
+			if v.IsException() || !v.OID.HasPrefix(root) {
+				return nil
+			}
+			if v.OID.Compare(oid) <= 0 {
+				return fmt.Errorf("the agent returned %s after %s, OIDs aren't increasing", v.OID, oid)
+			}
+			if err = fn(v); err != nil {
+				return err
+			}
+			oid = v.OID
+		}
+	}
+}
+
+func (c *Client) do(pduType byte, oids []OID, nonRepeaters, maxRepetitions int) ([]Variable, error) {
+	pdu := PDU{Type: pduType, ErrorStatus: nonRepeaters, ErrorIndex: maxRepetitions}
+	for _, oid := range oids {
+		pdu.Variables = append(pdu.Variables, Variable{OID: oid, Type: TagNull})
+	}
+	resp, err := c.request(pdu)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ErrorStatus != NoError {
+		return nil, &ResponseError{Status: resp.ErrorStatus, Index: resp.ErrorIndex}
+	}
+	return resp.Variables, nil
+}
+
+// request sends a PDU and waits for the response, retrying on timeouts.
+func (c *Client) request(pdu PDU) (PDU, error) {
+	if c.conn == nil {
+		return PDU{}, errors.New("not connected")
+	}
+	// the engine time window is re-synchronized once, as is the engine ID
+	synchronized := false
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		resp, err := c.send(pdu)
+		if err == ErrTimeout {
+			continue
+		}
+		if (err == ErrNotInTimeWindow || err == ErrUnknownEngineID) && !synchronized {
+			synchronized = true
+			if err == ErrUnknownEngineID {
+				if err = c.discoverEngine(); err != nil {
+					return PDU{}, err
+				}
+			}
+			attempt--
+			continue
+		}
+		return resp, err
+	}
+	return PDU{}, ErrTimeout
+}
+
+func (c *Client) nextID() int32 {
+	c.requestID++
+	if c.requestID <= 0 {
+		c.requestID = 1
+	}
+	return c.requestID
+}
+
+// send sends a PDU once and waits for the response until the timeout.
+func (c *Client) send(pdu PDU) (PDU, error) {
+	pdu.RequestID = c.nextID()
+	msg := &Message{Version: c.Version, Community: c.Community, PDU: pdu}
+	if c.Version == Version3 {
+		msg.MsgID = pdu.RequestID
+		msg.Flags = c.User.Flags() | FlagReportable
+		msg.SecurityParameters = USMParameters{
+			EngineID:    c.engineID,
+			EngineBoots: c.engineBoots,
+			EngineTime:  c.engineTime + int32(time.Since(c.timeRef)/time.Second),
+			UserName:    c.User.Name,
+		}
+		msg.ContextEngineID = c.engineID
+		msg.ContextName = c.ContextName
+	}
+	b, err := msg.Encode(c.keys)
+	if err != nil {
+		return PDU{}, err
+	}
+	if _, err = c.conn.Write(b); err != nil {
+		return PDU{}, err
+	}
+
+	if err = c.conn.SetReadDeadline(time.Now().Add(c.timeout())); err != nil {
+		return PDU{}, err
+	}
+	for {
+		// the decoded message references the buffer, a new one is needed for every read
+		buf := make([]byte, maxMessageSize)
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return PDU{}, ErrTimeout
+			}
+			return PDU{}, err
+		}
+		resp, err := DecodeMessage(buf[:n])
+		if err != nil || resp.Version != c.Version {
+			// garbage or late response of another client, ignored
+			continue
+		}
+		if c.Version != Version3 {
+			if resp.PDU.RequestID != pdu.RequestID {
+				continue
+			}
+			return resp.PDU, nil
+		}
+		if resp.MsgID != msg.MsgID {
+			continue
+		}
+		return c.receiveV3(resp)
+	}
+}
+
+// receiveV3 processes the response to an SNMPv3 request.
+func (c *Client) receiveV3(resp *Message) (PDU, error) {
+	sp := resp.SecurityParameters
+	if resp.Flags&FlagAuth == 0 && resp.PDU.Type == Report {
+		// unauthenticated reports are security errors, the engine time is
+		// re-synchronized on time window errors
+		err := reportError(resp.PDU)
+		if err == nil {
+			err = fmt.Errorf("unexpected report from %s", c)
+		}
+		if err == ErrNotInTimeWindow {
+			c.setEngineTime(sp.EngineBoots, sp.EngineTime)
+		}
+		return PDU{}, err
+	}
+	if resp.Flags&(FlagAuth|FlagPriv) != c.User.Flags() {
+		return PDU{}, ErrUnsupportedSecurityLevel
+	}
+	if err := resp.Unseal(c.keys); err != nil {
+		return PDU{}, err
+	}
+	if resp.PDU.Type == Report {
+		err := reportError(resp.PDU)
+		if err == ErrNotInTimeWindow {
+			c.setEngineTime(sp.EngineBoots, sp.EngineTime)
+		}
+		if err == nil {
+			err = fmt.Errorf("unexpected report from %s", c)
+		}
+		return PDU{}, err
+	}
+	if sp.EngineBoots > c.engineBoots || sp.EngineBoots == c.engineBoots && sp.EngineTime > c.engineTime {
+		c.setEngineTime(sp.EngineBoots, sp.EngineTime)
+	}
+	return resp.PDU, nil
+}
+
+func (c *Client) setEngineTime(boots, engineTime int32) {
+	c.engineBoots = boots
+	c.engineTime = engineTime
+	c.timeRef = time.Now()
+}
+
+// discoverEngine sends an empty unauthenticated request to learn the ID, boots
+// and time of the SNMPv3 engine of the agent, and localizes the keys for it (RFC 3414 4).
+func (c *Client) discoverEngine() error {
+	c.engineID = nil
+	c.keys = nil
+	var resp *Message
+	for attempt := 0; attempt <= c.Retries && resp == nil; attempt++ {
+		id := c.nextID()
+		msg := &Message{
+			Version: Version3,
+			MsgID:   id,
+			Flags:   FlagReportable,
+			PDU:     PDU{Type: GetRequest, RequestID: id},
+		}
+		b, err := msg.Encode(nil)
+		if err != nil {
+			return err
+		}
+		if _, err = c.conn.Write(b); err != nil {
+			return err
+		}
+		if err = c.conn.SetReadDeadline(time.Now().Add(c.timeout())); err != nil {
+			return err
+		}
+		for {
+			buf := make([]byte, maxMessageSize)
+			n, err := c.conn.Read(buf)
+			if err != nil {
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					break
+				}
+				return err
+			}
+			m, err := DecodeMessage(buf[:n])
+			if err == nil && m.Version == Version3 && m.MsgID == id {
+				resp = m
+				break
+			}
+		}
+	}
+	if resp == nil {
+		return ErrTimeout
+	}
+	sp := resp.SecurityParameters
+	if len(sp.EngineID) == 0 {
+		return fmt.Errorf("%s didn't report its engine ID", c)
+	}
+	keys, err := c.User.Localize(sp.EngineID)
+	if err != nil {
+		return err
+	}
+	c.engineID = sp.EngineID
+	c.keys = keys
+	c.setEngineTime(sp.EngineBoots, sp.EngineTime)
+	return nil
+}
@@ -0,0 +1,241 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package snmp_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/snmp"
+	"github.com/DataDog/datadog-agent/pkg/util/snmp/snmptest"
+)
+
+func mustParseOID(s string) snmp.OID {
+	oid, err := snmp.ParseOID(s)
+	if err != nil {
+		panic(err)
+	}
+	return oid
+}
+
+var testVariables = []snmp.Variable{
+	{OID: mustParseOID("1.3.6.1.2.1.1.1.0"), Type: snmp.TagOctetString, Value: []byte("Test device")},
+	{OID: mustParseOID("1.3.6.1.2.1.1.2.0"), Type: snmp.TagOID, Value: mustParseOID("1.3.6.1.4.1.8072.3.2.10")},
+	{OID: mustParseOID("1.3.6.1.2.1.1.3.0"), Type: snmp.TagTimeTicks, Value: uint64(4294967295)},
+	{OID: mustParseOID("1.3.6.1.2.1.2.1.0"), Type: snmp.TagInteger, Value: int64(-3)},
+	{OID: mustParseOID("1.3.6.1.2.1.2.2.1.10.1"), Type: snmp.TagCounter32, Value: uint64(100)},
+	{OID: mustParseOID("1.3.6.1.2.1.2.2.1.10.2"), Type: snmp.TagCounter32, Value: uint64(200)},
+	{OID: mustParseOID("1.3.6.1.2.1.2.2.1.10.3"), Type: snmp.TagCounter32, Value: uint64(300)},
+	{OID: mustParseOID("1.3.6.1.2.1.4.20.1.1.10.0.0.1"), Type: snmp.TagIPAddress, Value: "10.0.0.1"},
+	{OID: mustParseOID("1.3.6.1.2.1.31.1.1.1.6.1"), Type: snmp.TagCounter64, Value: uint64(1) << 63},
+}
+
+func startAgent(t *testing.T, users ...snmp.User) *snmptest.Agent {
+	agent := snmptest.NewAgent(testVariables)
+	agent.Users = users
+	require.NoError(t, agent.Start())
+	return agent
+}
+
+func connect(t *testing.T, agent *snmptest.Agent, c *snmp.Client) *snmp.Client {
+	c.Target = "127.0.0.1"
+	c.Port = agent.Port()
+	c.Timeout = 500 * time.Millisecond
+	require.NoError(t, c.Connect())
+	return c
+}
+
+func TestParseOID(t *testing.T) {
+	oid, err := snmp.ParseOID(".1.3.6.1.2.1.1.5.0")
+	require.NoError(t, err)
+	assert.Equal(t, snmp.OID{1, 3, 6, 1, 2, 1, 1, 5, 0}, oid)
+	assert.Equal(t, "1.3.6.1.2.1.1.5.0", oid.String())
+	assert.True(t, oid.HasPrefix(mustParseOID("1.3.6.1.2.1.1")))
+	assert.False(t, oid.HasPrefix(mustParseOID("1.3.6.1.2.1.1.50")))
+	assert.Equal(t, -1, mustParseOID("1.3.6.1.2").Compare(mustParseOID("1.3.6.1.2.1")))
+	assert.Equal(t, 1, mustParseOID("1.3.6.2").Compare(mustParseOID("1.3.6.1.2.1")))
+	assert.Equal(t, 0, oid.Compare(oid))
+
+	for _, s := range []string{"", "1.3.a", "1..3", "1.3.4294967296"} {
+		_, err = snmp.ParseOID(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	// RFC 3414 A.3
+	engineID := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2}
+	for protocol, expected := range map[snmp.AuthProtocol]string{
+		snmp.MD5: "526f5eed9fcce26f8964c2930787d82b",
+		snmp.SHA: "6695febc9288e36282235fc7151f128497b38f3f",
+	} {
+		user := snmp.User{AuthProtocol: protocol, AuthPassphrase: "maplesyrup"}
+		keys, err := user.Localize(engineID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, hex.EncodeToString(keys.AuthKey))
+	}
+
+	user := snmp.User{PrivProtocol: snmp.AES, PrivPassphrase: "maplesyrup"}
+	_, err := user.Localize(engineID)
+	assert.Error(t, err)
+}
+
+func TestGet(t *testing.T) {
+	agent := startAgent(t)
+	defer agent.Stop()
+
+	for _, version := range []snmp.Version{snmp.Version1, snmp.Version2c} {
+		t.Run(version.String(), func(t *testing.T) {
+			c := connect(t, agent, &snmp.Client{Version: version, Community: "public"})
+			defer c.Close()
+
+			variables, err := c.Get([]snmp.OID{
+				mustParseOID("1.3.6.1.2.1.1.1.0"),
+				mustParseOID("1.3.6.1.2.1.1.2.0"),
+				mustParseOID("1.3.6.1.2.1.1.3.0"),
+				mustParseOID("1.3.6.1.2.1.2.1.0"),
+				mustParseOID("1.3.6.1.2.1.4.20.1.1.10.0.0.1"),
+				mustParseOID("1.3.6.1.2.1.31.1.1.1.6.1"),
+			})
+			require.NoError(t, err)
+			require.Len(t, variables, 6)
+			assert.Equal(t, "Test device", variables[0].String())
+			assert.Equal(t, mustParseOID("1.3.6.1.4.1.8072.3.2.10"), variables[1].Value)
+			assert.Equal(t, uint64(4294967295), variables[2].Value)
+			v, ok := variables[3].Float()
+			assert.True(t, ok)
+			assert.Equal(t, -3.0, v)
+			assert.Equal(t, "10.0.0.1", variables[4].Value)
+			assert.Equal(t, uint64(1)<<63, variables[5].Value)
+
+			variables, err = c.GetNext([]snmp.OID{mustParseOID("1.3.6.1.2.1.2.2")})
+			require.NoError(t, err)
+			require.Len(t, variables, 1)
+			assert.Equal(t, mustParseOID("1.3.6.1.2.1.2.2.1.10.1"), variables[0].OID)
+
+			variables, err = c.Get([]snmp.OID{mustParseOID("1.3.6.1.2.1.1.1.0"), mustParseOID("1.3.6.1.2.1.1.4.0")})
+			if version == snmp.Version1 {
+				assert.EqualError(t, err, "agent error noSuchName on variable 2")
+			} else {
+				require.NoError(t, err)
+				require.Len(t, variables, 2)
+				assert.Equal(t, byte(snmp.TagNoSuchObject), variables[1].Type)
+				assert.True(t, variables[1].IsException())
+			}
+		})
+	}
+}
+
+func TestWalk(t *testing.T) {
+	agent := startAgent(t)
+	defer agent.Stop()
+
+	for _, version := range []snmp.Version{snmp.Version1, snmp.Version2c} {
+		for _, maxRepetitions := range []int{1, 2, 10} {
+			t.Run(fmt.Sprintf("%s/%d", version, maxRepetitions), func(t *testing.T) {
+				c := connect(t, agent, &snmp.Client{Version: version, Community: "public", MaxRepetitions: maxRepetitions})
+				defer c.Close()
+
+				var oids []string
+				err := c.Walk(mustParseOID("1.3.6.1.2.1.2.2.1.10"), func(v snmp.Variable) error {
+					oids = append(oids, v.OID.String())
+					return nil
+				})
+				require.NoError(t, err)
+				assert.Equal(t, []string{"1.3.6.1.2.1.2.2.1.10.1", "1.3.6.1.2.1.2.2.1.10.2", "1.3.6.1.2.1.2.2.1.10.3"}, oids)
+
+				// until the end of the MIB
+				oids = nil
+				err = c.Walk(mustParseOID("1.3.6.1.2.1.31"), func(v snmp.Variable) error {
+					oids = append(oids, v.OID.String())
+					return nil
+				})
+				require.NoError(t, err)
+				assert.Equal(t, []string{"1.3.6.1.2.1.31.1.1.1.6.1"}, oids)
+			})
+		}
+	}
+}
+
+func TestCommunityAndRetries(t *testing.T) {
+	agent := startAgent(t)
+	defer agent.Stop()
+
+	c := connect(t, agent, &snmp.Client{Version: snmp.Version2c, Community: "private"})
+	c.Timeout = 100 * time.Millisecond
+	c.Retries = 1
+	defer c.Close()
+	_, err := c.Get([]snmp.OID{mustParseOID("1.3.6.1.2.1.1.1.0")})
+	assert.Equal(t, snmp.ErrTimeout, err)
+	assert.Equal(t, 2, agent.Requests())
+
+	agent = snmptest.NewAgent(testVariables)
+	agent.Drop = 2
+	require.NoError(t, agent.Start())
+	defer agent.Stop()
+	c = connect(t, agent, &snmp.Client{Version: snmp.Version2c, Community: "public"})
+	c.Timeout = 100 * time.Millisecond
+	c.Retries = 2
+	defer c.Close()
+	variables, err := c.Get([]snmp.OID{mustParseOID("1.3.6.1.2.1.1.1.0")})
+	require.NoError(t, err)
+	assert.Equal(t, "Test device", variables[0].String())
+	assert.Equal(t, 3, agent.Requests())
+}
+
+func TestVersion3(t *testing.T) {
+	users := []snmp.User{
+		{Name: "noauth"},
+		{Name: "md5", AuthProtocol: snmp.MD5, AuthPassphrase: "md5password"},
+		{Name: "sha-des", AuthProtocol: snmp.SHA, AuthPassphrase: "shapassword", PrivProtocol: snmp.DES, PrivPassphrase: "despassword"},
+		{Name: "sha256-aes", AuthProtocol: snmp.SHA256, AuthPassphrase: "sha256password", PrivProtocol: snmp.AES, PrivPassphrase: "aespassword"},
+		{Name: "sha512-aes", AuthProtocol: snmp.SHA512, AuthPassphrase: "sha512password", PrivProtocol: snmp.AES, PrivPassphrase: "aespassword"},
+	}
+	agent := startAgent(t, users...)
+	defer agent.Stop()
+
+	for _, user := range users {
+		t.Run(user.Name, func(t *testing.T) {
+			c := connect(t, agent, &snmp.Client{Version: snmp.Version3, User: user})
+			defer c.Close()
+
+			variables, err := c.Get([]snmp.OID{mustParseOID("1.3.6.1.2.1.1.1.0")})
+			require.NoError(t, err)
+			assert.Equal(t, "Test device", variables[0].String())
+
+			var count int
+			err = c.Walk(mustParseOID("1.3.6.1.2.1.2.2"), func(v snmp.Variable) error {
+				count++
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 3, count)
+		})
+	}
+
+	for name, tc := range map[string]struct {
+		user snmp.User
+		err  error
+	}{
+		"unknown user":   {snmp.User{Name: "unknown"}, snmp.ErrUnknownUserName},
+		"wrong auth":     {snmp.User{Name: "md5", AuthProtocol: snmp.MD5, AuthPassphrase: "wrongpassword"}, snmp.ErrAuthenticationFailure},
+		"wrong protocol": {snmp.User{Name: "md5", AuthProtocol: snmp.SHA, AuthPassphrase: "md5password"}, snmp.ErrAuthenticationFailure},
+		"wrong priv":     {snmp.User{Name: "sha-des", AuthProtocol: snmp.SHA, AuthPassphrase: "shapassword", PrivProtocol: snmp.DES, PrivPassphrase: "wrongpassword"}, snmp.ErrDecryptionFailure},
+		"wrong level":    {snmp.User{Name: "sha-des", AuthProtocol: snmp.SHA, AuthPassphrase: "shapassword"}, snmp.ErrUnsupportedSecurityLevel},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := connect(t, agent, &snmp.Client{Version: snmp.Version3, User: tc.user})
+			defer c.Close()
+			_, err := c.Get([]snmp.OID{mustParseOID("1.3.6.1.2.1.1.1.0")})
+			assert.Equal(t, tc.err, err)
+		})
+	}
+}
@@ -0,0 +1,429 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package snmp
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+)
+
+// Version is the version of the SNMP protocol, as encoded in messages
+type Version int
+
+// Supported SNMP versions
+const (
+	Version1  Version = 0
+	Version2c Version = 1
+	Version3  Version = 3
+)
+
+func (v Version) String() string {
+	switch v {
+	case Version1:
+		return "v1"
+	case Version2c:
+		return "v2c"
+	case Version3:
+		return "v3"
+	}
+	return fmt.Sprintf("unknown version %d", int(v))
+}
+
+// PDU types
+const (
+	GetRequest     = 0xa0
+	GetNextRequest = 0xa1
+	GetResponse    = 0xa2
+	SetRequest     = 0xa3
+	GetBulkRequest = 0xa5
+	InformRequest  = 0xa6
+	SNMPv2Trap     = 0xa7
+	Report         = 0xa8
+)
+
+// Flags of SNMPv3 messages
+const (
+	FlagAuth       = 0x01
+	FlagPriv       = 0x02
+	FlagReportable = 0x04
+)
+
+// securityModelUSM is the identifier of the user-based security model (RFC 3414)
+const securityModelUSM = 3
+
+// maxMessageSize is the largest message accepted, also advertised in SNMPv3 messages
+const maxMessageSize = 65507
+
+// PDU is a protocol data unit, the payload of an SNMP message
+type PDU struct {
+	Type      byte
+	RequestID int32
+	// ErrorStatus holds the number of non-repeaters in GetBulk requests
+	ErrorStatus int
+	// ErrorIndex holds the maximum number of repetitions in GetBulk requests
+	ErrorIndex int
+	Variables  []Variable
+}
+
+// USMParameters are the security parameters of SNMPv3 messages
+type USMParameters struct {
+	EngineID       []byte
+	EngineBoots    int32
+	EngineTime     int32
+	UserName       string
+	AuthParameters []byte
+	PrivParameters []byte
+}
+
+// Message is an SNMP message. Community is only used by SNMPv1 and SNMPv2c,
+// the other fields but PDU by SNMPv3.
+type Message struct {
+	Version            Version
+	Community          string
+	MsgID              int32
+	Flags              byte
+	SecurityParameters USMParameters
+	ContextEngineID    []byte
+	ContextName        string
+	PDU                PDU
+
+	// raw is the message as received, and encrypted its encrypted scoped PDU,
+	// they are used by Unseal.
+	raw       []byte
+	encrypted []byte
+}
+
+func encodePDU(pdu PDU) ([]byte, error) {
+	varbinds := make([][]byte, 0, len(pdu.Variables))
+	for _, v := range pdu.Variables {
+		oid, err := encodeOIDContent(v.OID)
+		if err != nil {
+			return nil, err
+		}
+		value, err := encodeVariable(v)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode the value of %s: %s", v.OID, err)
+		}
+		varbinds = append(varbinds, encodeTLV(TagSequence, encodeTLV(TagOID, oid), value))
+	}
+	return encodeTLV(pdu.Type,
+		encodeTLV(TagInteger, encodeInt(int64(pdu.RequestID))),
+		encodeTLV(TagInteger, encodeInt(int64(pdu.ErrorStatus))),
+		encodeTLV(TagInteger, encodeInt(int64(pdu.ErrorIndex))),
+		encodeTLV(TagSequence, varbinds...),
+	), nil
+}
+
+func decodePDU(d *decoder) (PDU, error) {
+	var pdu PDU
+	tag, content, err := d.next()
+	if err != nil {
+		return pdu, err
+	}
+	if tag < GetRequest || tag > Report || tag == 0xa4 {
+		return pdu, fmt.Errorf("unsupported PDU type 0x%02x", tag)
+	}
+	pdu.Type = tag
+	d = &decoder{b: content}
+	requestID, err := d.int()
+	if err != nil {
+		return pdu, err
+	}
+	pdu.RequestID = int32(requestID)
+	errorStatus, err := d.int()
+	if err != nil {
+		return pdu, err
+	}
+	pdu.ErrorStatus = int(errorStatus)
+	errorIndex, err := d.int()
+	if err != nil {
+		return pdu, err
+	}
+	pdu.ErrorIndex = int(errorIndex)
+	varbinds, err := d.sequence()
+	if err != nil {
+		return pdu, err
+	}
+	for !varbinds.empty() {
+		varbind, err := varbinds.sequence()
+		if err != nil {
+			return pdu, err
+		}
+		oid, err := varbind.oid()
+		if err != nil {
+			return pdu, err
+		}
+		tag, content, err := varbind.next()
+		if err != nil {
+			return pdu, err
+		}
+		value, err := decodeValue(tag, content)
+		if err != nil {
+			return pdu, fmt.Errorf("could not decode the value of %s: %s", oid, err)
+		}
+		pdu.Variables = append(pdu.Variables, Variable{OID: oid, Type: tag, Value: value})
+	}
+	return pdu, nil
+}
+
+// Encode returns the BER encoding of the message. SNMPv3 messages are
+// authenticated and encrypted with keys, as set in their flags.
+func (m *Message) Encode(keys *Keys) ([]byte, error) {
+	pdu, err := encodePDU(m.PDU)
+	if err != nil {
+		return nil, err
+	}
+	version := encodeTLV(TagInteger, encodeInt(int64(m.Version)))
+	if m.Version != Version3 {
+		return encodeTLV(TagSequence, version, encodeTLV(TagOctetString, []byte(m.Community)), pdu), nil
+	}
+
+	if m.Flags&FlagPriv != 0 && m.Flags&FlagAuth == 0 {
+		return nil, errors.New("privacy requires authentication")
+	}
+	if m.Flags&FlagAuth != 0 && (keys == nil || keys.Auth == NoAuth) {
+		return nil, errors.New("no authentication key")
+	}
+	sp := m.SecurityParameters
+	data := encodeTLV(TagSequence,
+		encodeTLV(TagOctetString, m.ContextEngineID),
+		encodeTLV(TagOctetString, []byte(m.ContextName)),
+		pdu,
+	)
+	sp.AuthParameters = nil
+	if m.Flags&FlagAuth != 0 {
+		// placeholder for the HMAC, computed over the whole message
+		sp.AuthParameters = make([]byte, keys.Auth.macLength())
+	}
+	if m.Flags&FlagPriv != 0 {
+		if keys.Priv == NoPriv {
+			return nil, errors.New("no privacy key")
+		}
+		var encrypted []byte
+		encrypted, sp.PrivParameters, err = keys.encrypt(data, sp.EngineBoots, sp.EngineTime)
+		if err != nil {
+			return nil, err
+		}
+		data = encodeTLV(TagOctetString, encrypted)
+	}
+
+	b := encodeTLV(TagSequence,
+		version,
+		encodeTLV(TagSequence,
+			encodeTLV(TagInteger, encodeInt(int64(m.MsgID))),
+			encodeTLV(TagInteger, encodeInt(maxMessageSize)),
+			encodeTLV(TagOctetString, []byte{m.Flags}),
+			encodeTLV(TagInteger, encodeInt(securityModelUSM)),
+		),
+		encodeTLV(TagOctetString, encodeTLV(TagSequence,
+			encodeTLV(TagOctetString, sp.EngineID),
+			encodeTLV(TagInteger, encodeInt(int64(sp.EngineBoots))),
+			encodeTLV(TagInteger, encodeInt(int64(sp.EngineTime))),
+			encodeTLV(TagOctetString, []byte(sp.UserName)),
+			encodeTLV(TagOctetString, sp.AuthParameters),
+			encodeTLV(TagOctetString, sp.PrivParameters),
+		)),
+		data,
+	)
+	if m.Flags&FlagAuth != 0 {
+		offset, err := authParametersOffset(b)
+		if err != nil {
+			return nil, err
+		}
+		copy(b[offset:], keys.mac(b))
+	}
+	return b, nil
+}
+
+// DecodeMessage decodes a message. The scoped PDU of SNMPv3 messages sent with
+// privacy isn't decrypted, and their authentication isn't verified: Unseal does it.
+func DecodeMessage(b []byte) (*Message, error) {
+	d, err := (&decoder{b: b}).sequence()
+	if err != nil {
+		return nil, err
+	}
+	version, err := d.int()
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{Version: Version(version)}
+	switch m.Version {
+	case Version1, Version2c:
+		community, err := d.octetString()
+		if err != nil {
+			return nil, err
+		}
+		m.Community = string(community)
+		m.PDU, err = decodePDU(d)
+		return m, err
+	case Version3:
+	default:
+		return nil, fmt.Errorf("unsupported SNMP version %d", version)
+	}
+
+	m.raw = b
+	header, err := d.sequence()
+	if err != nil {
+		return nil, err
+	}
+	msgID, err := header.int()
+	if err != nil {
+		return nil, err
+	}
+	m.MsgID = int32(msgID)
+	if _, err = header.int(); err != nil {
+		return nil, err
+	}
+	flags, err := header.octetString()
+	if err != nil {
+		return nil, err
+	}
+	if len(flags) != 1 {
+		return nil, errors.New("invalid message flags")
+	}
+	m.Flags = flags[0]
+	model, err := header.int()
+	if err != nil {
+		return nil, err
+	}
+	if model != securityModelUSM {
+		return nil, fmt.Errorf("unsupported security model %d", model)
+	}
+
+	spData, err := d.octetString()
+	if err != nil {
+		return nil, err
+	}
+	sp, err := (&decoder{b: spData}).sequence()
+	if err != nil {
+		return nil, err
+	}
+	if m.SecurityParameters.EngineID, err = sp.octetString(); err != nil {
+		return nil, err
+	}
+	boots, err := sp.int()
+	if err != nil {
+		return nil, err
+	}
+	m.SecurityParameters.EngineBoots = int32(boots)
+	engineTime, err := sp.int()
+	if err != nil {
+		return nil, err
+	}
+	m.SecurityParameters.EngineTime = int32(engineTime)
+	user, err := sp.octetString()
+	if err != nil {
+		return nil, err
+	}
+	m.SecurityParameters.UserName = string(user)
+	if m.SecurityParameters.AuthParameters, err = sp.octetString(); err != nil {
+		return nil, err
+	}
+	if m.SecurityParameters.PrivParameters, err = sp.octetString(); err != nil {
+		return nil, err
+	}
+
+	if m.Flags&FlagPriv != 0 {
+		m.encrypted, err = d.octetString()
+		return m, err
+	}
+	return m, m.decodeScopedPDU(d)
+}
+
+func (m *Message) decodeScopedPDU(d *decoder) error {
+	scoped, err := d.sequence()
+	if err != nil {
+		return err
+	}
+	if m.ContextEngineID, err = scoped.octetString(); err != nil {
+		return err
+	}
+	contextName, err := scoped.octetString()
+	if err != nil {
+		return err
+	}
+	m.ContextName = string(contextName)
+	m.PDU, err = decodePDU(scoped)
+	return err
+}
+
+// Unseal verifies the authentication of an SNMPv3 message and decrypts its scoped
+// PDU, as set in its flags.
+func (m *Message) Unseal(keys *Keys) error {
+	if m.Version != Version3 || m.Flags&FlagAuth == 0 {
+		return nil
+	}
+	if keys == nil || keys.Auth == NoAuth {
+		return errors.New("no authentication key")
+	}
+	offset, err := authParametersOffset(m.raw)
+	if err != nil {
+		return err
+	}
+	mac := m.SecurityParameters.AuthParameters
+	if len(mac) != keys.Auth.macLength() {
+		return ErrAuthenticationFailure
+	}
+	b := append([]byte(nil), m.raw...)
+	copy(b[offset:offset+len(mac)], make([]byte, len(mac)))
+	if !hmac.Equal(mac, keys.mac(b)) {
+		return ErrAuthenticationFailure
+	}
+
+	if m.Flags&FlagPriv == 0 {
+		return nil
+	}
+	if keys.Priv == NoPriv {
+		return errors.New("no privacy key")
+	}
+	data, err := keys.decrypt(m.encrypted, m.SecurityParameters.PrivParameters,
+		m.SecurityParameters.EngineBoots, m.SecurityParameters.EngineTime)
+	if err != nil {
+		return err
+	}
+	// the decrypted data may be padded, the padding is ignored
+	if err = m.decodeScopedPDU(&decoder{b: data}); err != nil {
+		return ErrDecryptionFailure
+	}
+	m.encrypted = nil
+	return nil
+}
+
+// authParametersOffset returns the offset of the authentication parameters in
+// an SNMPv3 message.
+func authParametersOffset(b []byte) (int, error) {
+	d, err := (&decoder{b: b}).sequence()
+	if err != nil {
+		return 0, err
+	}
+	// version and header
+	for i := 0; i < 2; i++ {
+		if _, _, err = d.next(); err != nil {
+			return 0, err
+		}
+	}
+	spData, err := d.octetString()
+	if err != nil {
+		return 0, err
+	}
+	sp, err := (&decoder{b: spData}).sequence()
+	if err != nil {
+		return 0, err
+	}
+	// engine ID, boots, time and user name
+	for i := 0; i < 4; i++ {
+		if _, _, err = sp.next(); err != nil {
+			return 0, err
+		}
+	}
+	authParams, err := sp.octetString()
+	if err != nil {
+		return 0, err
+	}
+	// authParams is a slice of b, its offset in b is given by their capacities
+	return cap(b) - cap(authParams), nil
+}
@@ -0,0 +1,322 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+// Package snmptest provides an in-process SNMP agent for tests
+package snmptest
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/snmp"
+)
+
+// timeWindow is the engine time window of SNMPv3 requests, in seconds (RFC 3414 3.2)
+const timeWindow = 150
+
+// Agent is an SNMP agent answering Get, GetNext and GetBulk requests from a
+// fixed set of variables, over SNMPv1, SNMPv2c and SNMPv3. The exported fields
+// must be set before Start.
+type Agent struct {
+	// Community is the community of SNMPv1 and SNMPv2c requests, requests with
+	// another community are ignored
+	Community string
+	// Users are the users of SNMPv3 requests
+	Users []snmp.User
+	// EngineID is the ID of the SNMPv3 engine
+	EngineID []byte
+	// Delay is the processing time of every request
+	Delay time.Duration
+	// Drop is the number of requests ignored before answering, to test retries
+	Drop int
+
+	variables []snmp.Variable
+	conn      net.PacketConn
+	start     time.Time
+	keys      map[string]*snmp.Keys
+	users     map[string]snmp.User
+	wg        sync.WaitGroup
+
+	m           sync.Mutex
+	requests    int
+	inFlight    int
+	maxInFlight int
+}
+
+// NewAgent returns an agent serving the given variables
+func NewAgent(variables []snmp.Variable) *Agent {
+	sorted := append([]snmp.Variable(nil), variables...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].OID.Compare(sorted[j].OID) < 0
+	})
+	return &Agent{
+		Community: "public",
+		EngineID:  []byte{0x80, 0x00, 0x1f, 0x88, 0x04, 't', 'e', 's', 't'},
+		variables: sorted,
+	}
+}
+
+// Start listens on a random port of the loopback interface, and serves the
+// requests in the background
+func (a *Agent) Start() error {
+	a.keys = make(map[string]*snmp.Keys)
+	a.users = make(map[string]snmp.User)
+	for _, u := range a.Users {
+		keys, err := u.Localize(a.EngineID)
+		if err != nil {
+			return err
+		}
+		a.keys[u.Name] = keys
+		a.users[u.Name] = u
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	a.conn = conn
+	a.start = time.Now()
+	a.wg.Add(1)
+	go a.serve()
+	return nil
+}
+
+// Stop stops serving the requests
+func (a *Agent) Stop() {
+	a.conn.Close()
+	a.wg.Wait()
+}
+
+// Port returns the port the agent listens on
+func (a *Agent) Port() uint16 {
+	return uint16(a.conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+// Requests returns the number of requests received
+func (a *Agent) Requests() int {
+	a.m.Lock()
+	defer a.m.Unlock()
+	return a.requests
+}
+
+// MaxInFlight returns the largest number of requests processed concurrently
+func (a *Agent) MaxInFlight() int {
+	a.m.Lock()
+	defer a.m.Unlock()
+	return a.maxInFlight
+}
+
+func (a *Agent) serve() {
+	defer a.wg.Done()
+	for {
+		buf := make([]byte, 65535)
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		a.m.Lock()
+		a.requests++
+		drop := a.Drop > 0
+		if drop {
+			a.Drop--
+		}
+		a.m.Unlock()
+		if drop {
+			continue
+		}
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.handle(buf[:n], addr)
+		}()
+	}
+}
+
+func (a *Agent) handle(b []byte, addr net.Addr) {
+	a.m.Lock()
+	a.inFlight++
+	if a.inFlight > a.maxInFlight {
+		a.maxInFlight = a.inFlight
+	}
+	a.m.Unlock()
+	defer func() {
+		a.m.Lock()
+		a.inFlight--
+		a.m.Unlock()
+	}()
+	time.Sleep(a.Delay)
+
+	msg, err := snmp.DecodeMessage(b)
+	if err != nil {
+		return
+	}
+	var resp []byte
+	if msg.Version == snmp.Version3 {
+		resp = a.handleV3(msg)
+	} else {
+		if msg.Community != a.Community || (msg.Version == snmp.Version1 && msg.PDU.Type == snmp.GetBulkRequest) {
+			return
+		}
+		msg.PDU = a.respond(msg.Version, msg.PDU)
+		resp, _ = msg.Encode(nil)
+	}
+	if resp != nil {
+		a.conn.WriteTo(resp, addr)
+	}
+}
+
+func (a *Agent) engineTime() int32 {
+	return int32(time.Since(a.start)/time.Second) + 1000
+}
+
+// handleV3 processes an SNMPv3 request as described in RFC 3414 3.2, and returns
+// the encoded response or report.
+func (a *Agent) handleV3(msg *snmp.Message) []byte {
+	resp := &snmp.Message{
+		Version: snmp.Version3,
+		MsgID:   msg.MsgID,
+		SecurityParameters: snmp.USMParameters{
+			EngineID:    a.EngineID,
+			EngineBoots: 1,
+			EngineTime:  a.engineTime(),
+			UserName:    msg.SecurityParameters.UserName,
+		},
+		ContextEngineID: a.EngineID,
+		ContextName:     msg.ContextName,
+	}
+	report := func(err error, keys *snmp.Keys) []byte {
+		if msg.Flags&snmp.FlagReportable == 0 {
+			return nil
+		}
+		if keys == nil {
+			resp.Flags = 0
+		}
+		resp.PDU = snmp.PDU{
+			Type:      snmp.Report,
+			RequestID: msg.PDU.RequestID,
+			Variables: []snmp.Variable{{OID: snmp.UsmStatsOID(err), Type: snmp.TagCounter32, Value: uint64(1)}},
+		}
+		b, _ := resp.Encode(keys)
+		return b
+	}
+
+	sp := msg.SecurityParameters
+	if string(sp.EngineID) != string(a.EngineID) {
+		return report(snmp.ErrUnknownEngineID, nil)
+	}
+	user, ok := a.users[sp.UserName]
+	if !ok {
+		return report(snmp.ErrUnknownUserName, nil)
+	}
+	keys := a.keys[sp.UserName]
+	if msg.Flags&(snmp.FlagAuth|snmp.FlagPriv) != user.Flags() {
+		return report(snmp.ErrUnsupportedSecurityLevel, nil)
+	}
+	if err := msg.Unseal(keys); err != nil {
+		if err != snmp.ErrAuthenticationFailure {
+			err = snmp.ErrDecryptionFailure
+		}
+		return report(err, nil)
+	}
+	resp.Flags = user.Flags()
+	if msg.Flags&snmp.FlagAuth != 0 {
+		diff := sp.EngineTime - a.engineTime()
+		if sp.EngineBoots != 1 || diff > timeWindow || diff < -timeWindow {
+			// authenticated, so that the client can trust the engine time
+			resp.Flags = snmp.FlagAuth
+			return report(snmp.ErrNotInTimeWindow, keys)
+		}
+	}
+	resp.PDU = a.respond(snmp.Version3, msg.PDU)
+	b, _ := resp.Encode(keys)
+	return b
+}
+
+// respond returns the response to a request PDU.
+func (a *Agent) respond(version snmp.Version, req snmp.PDU) snmp.PDU {
+	resp := snmp.PDU{Type: snmp.GetResponse, RequestID: req.RequestID}
+	// with SNMPv1, a missing variable fails the whole request
+	fail := func(i int) snmp.PDU {
+		return snmp.PDU{
+			Type:        snmp.GetResponse,
+			RequestID:   req.RequestID,
+			ErrorStatus: snmp.NoSuchName,
+			ErrorIndex:  i + 1,
+			Variables:   req.Variables,
+		}
+	}
+	switch req.Type {
+	case snmp.GetRequest:
+		for i, v := range req.Variables {
+			found, ok := a.get(v.OID)
+			if !ok {
+				if version == snmp.Version1 {
+					return fail(i)
+				}
+				found = snmp.Variable{OID: v.OID, Type: snmp.TagNoSuchObject}
+			}
+			resp.Variables = append(resp.Variables, found)
+		}
+	case snmp.GetNextRequest:
+		for i, v := range req.Variables {
+			next, ok := a.next(v.OID)
+			if !ok && version == snmp.Version1 {
+				return fail(i)
+			}
+			resp.Variables = append(resp.Variables, next)
+		}
+	case snmp.GetBulkRequest:
+		nonRepeaters, maxRepetitions := req.ErrorStatus, req.ErrorIndex
+		if nonRepeaters > len(req.Variables) {
+			nonRepeaters = len(req.Variables)
+		}
+		for _, v := range req.Variables[:nonRepeaters] {
+			next, _ := a.next(v.OID)
+			resp.Variables = append(resp.Variables, next)
+		}
+		repeaters := req.Variables[nonRepeaters:]
+		current := make([]snmp.OID, len(repeaters))
+		for i, v := range repeaters {
+			current[i] = v.OID
+		}
+		for r := 0; r < maxRepetitions && len(repeaters) > 0; r++ {
+			ended := true
+			for i := range current {
+				next, ok := a.next(current[i])
+				ended = ended && !ok
+				current[i] = next.OID
+				resp.Variables = append(resp.Variables, next)
+			}
+			if ended {
+				break
+			}
+		}
+	default:
+		resp.ErrorStatus = snmp.GenErr
+		resp.Variables = req.Variables
+	}
+	return resp
+}
+
+func (a *Agent) get(oid snmp.OID) (snmp.Variable, bool) {
+	i := sort.Search(len(a.variables), func(i int) bool {
+		return a.variables[i].OID.Compare(oid) >= 0
+	})
+	if i < len(a.variables) && a.variables[i].OID.Compare(oid) == 0 {
+		return a.variables[i], true
+	}
+	return snmp.Variable{}, false
+}
+
+func (a *Agent) next(oid snmp.OID) (snmp.Variable, bool) {
+	i := sort.Search(len(a.variables), func(i int) bool {
+		return a.variables[i].OID.Compare(oid) > 0
+	})
+	if i < len(a.variables) {
+		return a.variables[i], true
+	}
+	return snmp.Variable{OID: oid, Type: snmp.TagEndOfMibView}, false
+}
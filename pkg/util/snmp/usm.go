@@ -0,0 +1,326 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package snmp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"sync/atomic"
+)
+
+// AuthProtocol is an authentication protocol of the user-based security model
+type AuthProtocol string
+
+// Supported authentication protocols, SHA is SHA-1
+const (
+	NoAuth AuthProtocol = ""
+	MD5    AuthProtocol = "MD5"
+	SHA    AuthProtocol = "SHA"
+	SHA224 AuthProtocol = "SHA224"
+	SHA256 AuthProtocol = "SHA256"
+	SHA384 AuthProtocol = "SHA384"
+	SHA512 AuthProtocol = "SHA512"
+)
+
+// PrivProtocol is a privacy protocol of the user-based security model
+type PrivProtocol string
+
+// Supported privacy protocols, AES is AES-128 in CFB mode
+const (
+	NoPriv PrivProtocol = ""
+	DES    PrivProtocol = "DES"
+	AES    PrivProtocol = "AES"
+)
+
+// Errors reported by the agents, or detected on their responses, when
+// processing the security parameters of SNMPv3 messages
+var (
+	ErrUnsupportedSecurityLevel = errors.New("unsupported security level")
+	ErrNotInTimeWindow          = errors.New("not in time window")
+	ErrUnknownUserName          = errors.New("unknown user name")
+	ErrUnknownEngineID          = errors.New("unknown engine ID")
+	ErrAuthenticationFailure    = errors.New("authentication failure (wrong digest)")
+	ErrDecryptionFailure        = errors.New("decryption failure")
+)
+
+// usmStats is the prefix of the usmStats counters sent in reports
+var usmStats = OID{1, 3, 6, 1, 6, 3, 15, 1, 1}
+
+// usmErrors maps the last component of the usmStats counters to the errors they report
+var usmErrors = map[uint32]error{
+	1: ErrUnsupportedSecurityLevel,
+	2: ErrNotInTimeWindow,
+	3: ErrUnknownUserName,
+	4: ErrUnknownEngineID,
+	5: ErrAuthenticationFailure,
+	6: ErrDecryptionFailure,
+}
+
+// UsmStatsOID returns the OID of the usmStats counter sent in a report for one
+// of the security errors
+func UsmStatsOID(err error) OID {
+	for i, e := range usmErrors {
+		if e == err {
+			return append(append(OID{}, usmStats...), i, 0)
+		}
+	}
+	return nil
+}
+
+// reportError returns the security error reported by a report PDU, if any.
+func reportError(pdu PDU) error {
+	for _, v := range pdu.Variables {
+		if len(v.OID) == len(usmStats)+2 && v.OID.HasPrefix(usmStats) {
+			if err, ok := usmErrors[v.OID[len(usmStats)]]; ok {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ParseAuthProtocol returns the authentication protocol of the given name, in any case
+func ParseAuthProtocol(s string) (AuthProtocol, error) {
+	p := AuthProtocol(strings.ToUpper(strings.Replace(s, "-", "", -1)))
+	switch p {
+	case NoAuth, MD5, SHA, SHA224, SHA256, SHA384, SHA512:
+		return p, nil
+	}
+	return NoAuth, fmt.Errorf("unsupported authentication protocol %q", s)
+}
+
+// ParsePrivProtocol returns the privacy protocol of the given name, in any case
+func ParsePrivProtocol(s string) (PrivProtocol, error) {
+	p := PrivProtocol(strings.ToUpper(s))
+	switch p {
+	case NoPriv, DES, AES:
+		return p, nil
+	case "AES128":
+		return AES, nil
+	}
+	return NoPriv, fmt.Errorf("unsupported privacy protocol %q", s)
+}
+
+func (p AuthProtocol) hash() func() hash.Hash {
+	switch p {
+	case MD5:
+		return md5.New
+	case SHA:
+		return sha1.New
+	case SHA224:
+		return sha256.New224
+	case SHA256:
+		return sha256.New
+	case SHA384:
+		return sha512.New384
+	case SHA512:
+		return sha512.New
+	}
+	return nil
+}
+
+// macLength returns the length of the truncated HMAC sent in the authentication
+// parameters (RFC 3414 and RFC 7860).
+func (p AuthProtocol) macLength() int {
+	switch p {
+	case MD5, SHA:
+		return 12
+	case SHA224:
+		return 16
+	case SHA256:
+		return 24
+	case SHA384:
+		return 32
+	case SHA512:
+		return 48
+	}
+	return 0
+}
+
+// User holds the credentials of an SNMPv3 user
+type User struct {
+	Name           string
+	AuthProtocol   AuthProtocol
+	AuthPassphrase string
+	PrivProtocol   PrivProtocol
+	PrivPassphrase string
+}
+
+// Flags returns the message flags matching the security level of the user
+func (u *User) Flags() byte {
+	var flags byte
+	if u.AuthProtocol != NoAuth {
+		flags |= FlagAuth
+		if u.PrivProtocol != NoPriv {
+			flags |= FlagPriv
+		}
+	}
+	return flags
+}
+
+// Keys are the keys of a user, localized for an engine
+type Keys struct {
+	Auth    AuthProtocol
+	AuthKey []byte
+	Priv    PrivProtocol
+	PrivKey []byte
+}
+
+// Localize returns the keys of the user for the engine of the given ID
+func (u *User) Localize(engineID []byte) (*Keys, error) {
+	keys := &Keys{Auth: u.AuthProtocol, Priv: u.PrivProtocol}
+	if u.AuthProtocol == NoAuth {
+		if u.PrivProtocol != NoPriv {
+			return nil, errors.New("privacy requires authentication")
+		}
+		return keys, nil
+	}
+	var err error
+	if keys.AuthKey, err = localizeKey(u.AuthProtocol, u.AuthPassphrase, engineID); err != nil {
+		return nil, err
+	}
+	if u.PrivProtocol != NoPriv {
+		// the privacy key is derived with the hash function of the authentication protocol
+		if keys.PrivKey, err = localizeKey(u.AuthProtocol, u.PrivPassphrase, engineID); err != nil {
+			return nil, err
+		}
+		if len(keys.PrivKey) < 16 {
+			return nil, errors.New("privacy key too short")
+		}
+	}
+	return keys, nil
+}
+
+// localizeKey derives a key from a passphrase and localizes it for an engine
+// with the password to key algorithm of RFC 3414 A.2.
+func localizeKey(p AuthProtocol, passphrase string, engineID []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	newHash := p.hash()
+	if newHash == nil {
+		return nil, fmt.Errorf("unsupported authentication protocol %q", p)
+	}
+	h := newHash()
+	var buf [64]byte
+	for i := 0; i < 1048576; i += len(buf) {
+		for j := range buf {
+			buf[j] = passphrase[(i+j)%len(passphrase)]
+		}
+		h.Write(buf[:])
+	}
+	key := h.Sum(nil)
+
+	h = newHash()
+	h.Write(key)
+	h.Write(engineID)
+	h.Write(key)
+	return h.Sum(nil), nil
+}
+
+func (k *Keys) mac(b []byte) []byte {
+	h := hmac.New(k.Auth.hash(), k.AuthKey)
+	h.Write(b)
+	return h.Sum(nil)[:k.Auth.macLength()]
+}
+
+// salt is the counter used to generate the salt of encrypted messages, it starts
+// at a random value.
+var salt uint64
+
+func init() {
+	var b [8]byte
+	rand.Read(b[:])
+	salt = binary.BigEndian.Uint64(b[:])
+}
+
+// encrypt encrypts a scoped PDU and returns it with the privacy parameters of the message.
+func (k *Keys) encrypt(data []byte, boots, engineTime int32) ([]byte, []byte, error) {
+	privParams := make([]byte, 8)
+	switch k.Priv {
+	case DES:
+		// RFC 3414 8.1.1.1: the salt is the engine boots followed by a local integer
+		binary.BigEndian.PutUint32(privParams, uint32(boots))
+		binary.BigEndian.PutUint32(privParams[4:], uint32(atomic.AddUint64(&salt, 1)))
+		block, err := des.NewCipher(k.PrivKey[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = k.PrivKey[8+i] ^ privParams[i]
+		}
+		if pad := len(data) % des.BlockSize; pad != 0 {
+			data = append(data, make([]byte, des.BlockSize-pad)...)
+		}
+		encrypted := make([]byte, len(data))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, data)
+		return encrypted, privParams, nil
+	case AES:
+		// RFC 3826 3.1.2.1
+		binary.BigEndian.PutUint64(privParams, atomic.AddUint64(&salt, 1))
+		block, err := aes.NewCipher(k.PrivKey[:16])
+		if err != nil {
+			return nil, nil, err
+		}
+		encrypted := make([]byte, len(data))
+		cipher.NewCFBEncrypter(block, aesIV(boots, engineTime, privParams)).XORKeyStream(encrypted, data)
+		return encrypted, privParams, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported privacy protocol %q", k.Priv)
+}
+
+func (k *Keys) decrypt(data, privParams []byte, boots, engineTime int32) ([]byte, error) {
+	if len(privParams) != 8 {
+		return nil, ErrDecryptionFailure
+	}
+	switch k.Priv {
+	case DES:
+		if len(data)%des.BlockSize != 0 {
+			return nil, ErrDecryptionFailure
+		}
+		block, err := des.NewCipher(k.PrivKey[:8])
+		if err != nil {
+			return nil, err
+		}
+		iv := make([]byte, 8)
+		for i := range iv {
+			iv[i] = k.PrivKey[8+i] ^ privParams[i]
+		}
+		decrypted := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, data)
+		return decrypted, nil
+	case AES:
+		block, err := aes.NewCipher(k.PrivKey[:16])
+		if err != nil {
+			return nil, err
+		}
+		decrypted := make([]byte, len(data))
+		cipher.NewCFBDecrypter(block, aesIV(boots, engineTime, privParams)).XORKeyStream(decrypted, data)
+		return decrypted, nil
+	}
+	return nil, fmt.Errorf("unsupported privacy protocol %q", k.Priv)
+}
+
+func aesIV(boots, engineTime int32, privParams []byte) []byte {
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint32(iv, uint32(boots))
+	binary.BigEndian.PutUint32(iv[4:], uint32(engineTime))
+	copy(iv[8:], privParams)
+	return iv
+}
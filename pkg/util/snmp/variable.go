@@ -0,0 +1,187 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-2019 Datadog, Inc.
+
+package snmp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// OID is an SNMP object identifier
+type OID []uint32
+
+// ParseOID parses an OID in dotted notation, with or without a leading dot
+func ParseOID(s string) (OID, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), ".")
+	if s == "" {
+		return nil, fmt.Errorf("empty OID")
+	}
+	parts := strings.Split(s, ".")
+	oid := make(OID, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", s)
+		}
+		oid[i] = uint32(v)
+	}
+	return oid, nil
+}
+
+// String returns the OID in dotted notation, without leading dot
+func (o OID) String() string {
+	parts := make([]string, len(o))
+	for i, c := range o {
+		parts[i] = strconv.FormatUint(uint64(c), 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// HasPrefix returns whether the OID is in the subtree of prefix
+func (o OID) HasPrefix(prefix OID) bool {
+	if len(o) < len(prefix) {
+		return false
+	}
+	for i, c := range prefix {
+		if o[i] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare returns -1, 0 or 1 when the OID is respectively before, equal to or
+// after other in the lexicographic order of the MIB tree
+func (o OID) Compare(other OID) int {
+	for i := 0; i < len(o) && i < len(other); i++ {
+		switch {
+		case o[i] < other[i]:
+			return -1
+		case o[i] > other[i]:
+			return 1
+		}
+	}
+	switch {
+	case len(o) < len(other):
+		return -1
+	case len(o) > len(other):
+		return 1
+	}
+	return 0
+}
+
+// Variable is an OID bound to a value, its Type is the BER tag of the value.
+// Value holds:
+//   - an int64 for the Integer type
+//   - an uint64 for the Counter32, Gauge32, TimeTicks and Counter64 types
+//   - a []byte for the OctetString and Opaque types
+//   - a string in dotted notation for the IPAddress type
+//   - an OID for the OID type
+//   - nil for the Null type and the NoSuchObject, NoSuchInstance and EndOfMibView exceptions
+type Variable struct {
+	OID   OID
+	Type  byte
+	Value interface{}
+}
+
+// IsException returns whether the variable is a NoSuchObject, NoSuchInstance or
+// EndOfMibView exception rather than a value
+func (v Variable) IsException() bool {
+	return v.Type == TagNoSuchObject || v.Type == TagNoSuchInstance || v.Type == TagEndOfMibView
+}
+
+// Float returns the numeric value of the variable. Octet strings holding a number,
+// as some devices report their sensors, are parsed.
+func (v Variable) Float() (float64, bool) {
+	switch value := v.Value.(type) {
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case []byte:
+		f, err := strconv.ParseFloat(strings.TrimSpace(string(value)), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// String returns the value of the variable as a tag value
+func (v Variable) String() string {
+	switch value := v.Value.(type) {
+	case []byte:
+		return string(value)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v.Value)
+}
+
+func encodeVariable(v Variable) ([]byte, error) {
+	var content []byte
+	switch v.Type {
+	case TagInteger:
+		i, ok := v.Value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("invalid value %v for an integer", v.Value)
+		}
+		content = encodeInt(i)
+	case TagCounter32, TagGauge32, TagTimeTicks, TagCounter64:
+		i, ok := v.Value.(uint64)
+		if !ok {
+			return nil, fmt.Errorf("invalid value %v for an unsigned integer", v.Value)
+		}
+		content = encodeUint(i)
+	case TagOctetString, TagOpaque:
+		b, ok := v.Value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("invalid value %v for an octet string", v.Value)
+		}
+		content = b
+	case TagIPAddress:
+		s, _ := v.Value.(string)
+		ip := net.ParseIP(s).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %v", v.Value)
+		}
+		content = ip
+	case TagOID:
+		oid, ok := v.Value.(OID)
+		if !ok {
+			return nil, fmt.Errorf("invalid value %v for an OID", v.Value)
+		}
+		var err error
+		if content, err = encodeOIDContent(oid); err != nil {
+			return nil, err
+		}
+	case TagNull, TagNoSuchObject, TagNoSuchInstance, TagEndOfMibView:
+	default:
+		return nil, fmt.Errorf("unsupported type 0x%02x", v.Type)
+	}
+	return encodeTLV(v.Type, content), nil
+}
+
+func decodeValue(tag byte, content []byte) (interface{}, error) {
+	switch tag {
+	case TagInteger:
+		return decodeInt(content)
+	case TagCounter32, TagGauge32, TagTimeTicks, TagCounter64:
+		return decodeUint(content)
+	case TagOctetString, TagOpaque:
+		return append([]byte(nil), content...), nil
+	case TagIPAddress:
+		if len(content) != 4 {
+			return nil, fmt.Errorf("invalid IP address of %d bytes", len(content))
+		}
+		return net.IP(content).String(), nil
+	case TagOID:
+		return decodeOID(content)
+	case TagNull, TagNoSuchObject, TagNoSuchInstance, TagEndOfMibView:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported type 0x%02x", tag)
+}